@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aquasecurity/table"
+)
+
+// loadAdapters reads adapter sequences from path, as FASTA or as a TSV of
+// name and sequence. Empty lines and lines starting with '#' are skipped in
+// TSV files. It returns a map from sequence to name like adapterDict.
+func loadAdapters(path string) (map[string]string, error) {
+	input, err := openInput(path)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+	return parseAdapters(input, path)
+}
+
+func parseAdapters(r io.Reader, path string) (map[string]string, error) {
+	adapters := make(map[string]string)
+	add := func(lineNum int, name, seq string) error {
+		seq = strings.ToUpper(seq)
+		switch {
+		case name == "":
+			return fmt.Errorf("%s:%d: adapter without a name", path, lineNum)
+		case seq == "":
+			return fmt.Errorf("%s:%d: adapter %s has no sequence", path, lineNum, name)
+		case strings.Trim(seq, "ACGTN") != "":
+			return fmt.Errorf("%s:%d: adapter %s has an invalid sequence %q", path, lineNum, name, seq)
+		}
+		if other, ok := adapters[seq]; ok {
+			return fmt.Errorf("%s:%d: adapter %s has the same sequence as %s", path, lineNum, name, other)
+		}
+		adapters[seq] = name
+		return nil
+	}
+
+	scanner := newLineScanner(r)
+	lineNum, fasta, tsv := 0, false, false
+	name, nameLine := "", 0
+	var seq strings.Builder
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if !fasta && !tsv && line != "" && !strings.HasPrefix(line, "#") {
+			// The first line with content tells the format.
+			fasta, tsv = strings.HasPrefix(line, ">"), !strings.HasPrefix(line, ">")
+		}
+		switch {
+		case fasta && strings.HasPrefix(line, ">"):
+			if nameLine > 0 {
+				if err := add(nameLine, name, seq.String()); err != nil {
+					return nil, err
+				}
+			}
+			name, nameLine = strings.TrimSpace(line[1:]), lineNum
+			seq.Reset()
+		case fasta:
+			seq.WriteString(line)
+		case line == "" || strings.HasPrefix(line, "#"):
+		default:
+			fields := strings.Split(line, "\t")
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("%s:%d: expected a name and a sequence separated by a tab", path, lineNum)
+			}
+			if err := add(lineNum, strings.TrimSpace(fields[0]), strings.TrimSpace(fields[1])); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if nameLine > 0 {
+		if err := add(nameLine, name, seq.String()); err != nil {
+			return nil, err
+		}
+	}
+	if len(adapters) == 0 {
+		return nil, fmt.Errorf("%s: no adapters found", path)
+	}
+	return adapters, nil
+}
+
+// printAdapters lists adapters by name.
+func printAdapters(adapters map[string]string) {
+	seqs := make([]string, 0, len(adapters))
+	for seq := range adapters {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool {
+		if adapters[seqs[i]] != adapters[seqs[j]] {
+			return adapters[seqs[i]] < adapters[seqs[j]]
+		}
+		return seqs[i] < seqs[j]
+	})
+	t := table.New(os.Stdout)
+	t.SetHeaders("Name", "Sequence")
+	t.SetHeaderStyle(table.StyleBold)
+	t.SetLineStyle(table.StyleBlue)
+	t.SetDividers(table.UnicodeRoundedDividers)
+	for _, seq := range seqs {
+		t.AddRow(adapters[seq], seq)
+	}
+	t.Render()
+}
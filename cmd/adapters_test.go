@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAdapters(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]string
+	}{
+		{"fasta", ">P7 adapter\nAGATCGGAAG\nagcacacgtc\n\n>Tn5\nCTGTCTCTTATACACATCT\n", map[string]string{"AGATCGGAAGAGCACACGTC": "P7 adapter", "CTGTCTCTTATACACATCT": "Tn5"}},
+		{"tsv", "# name\tsequence\nP7\tAGATCGGAAGAGCACACGTC\n\nTn5 ME\tctgtctcttatacacatct\n", map[string]string{"AGATCGGAAGAGCACACGTC": "P7", "CTGTCTCTTATACACATCT": "Tn5 ME"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapters, err := parseAdapters(strings.NewReader(tt.input), "adapters")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, adapters)
+		})
+	}
+}
+
+func TestParseAdaptersErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"empty", "# nothing\n", "no adapters found"},
+		{"no tab", "P7 AGATCGGAAG\n", "adapters:1: expected a name and a sequence"},
+		{"invalid base", ">P7\nAGATCXGAAG\n", "adapters:1: adapter P7 has an invalid sequence"},
+		{"no sequence", ">P7\n>P5\nACGTACGT\n", "adapter P7 has no sequence"},
+		{"duplicate", "P7\tACGTACGT\nP7b\tACGTACGT\n", "adapters:2: adapter P7b has the same sequence as P7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseAdapters(strings.NewReader(tt.input), "adapters")
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
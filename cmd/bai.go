@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// baiMaxPos is the largest position a BAI index can describe (2^29).
+const baiMaxPos = 1 << 29
+
+type baiChunk struct {
+	begin, end uint64 // BGZF virtual offsets
+}
+
+type baiReference struct {
+	bins      map[uint32][]baiChunk
+	intervals []uint64 // Smallest offset per 16kb window
+}
+
+// baiIndex is a BAM index, which maps regions to virtual file offsets: the
+// offset of a BGZF block in the file shifted left by 16 bits plus the offset
+// within its decompressed data.
+type baiIndex struct {
+	refs []baiReference
+}
+
+func readBAI(r io.Reader) (*baiIndex, error) {
+	br := bufio.NewReader(r)
+	le := binary.LittleEndian
+	var magic [4]byte
+	if _, err := io.ReadFull(br, magic[:]); err != nil || !bytes.Equal(magic[:], []byte("BAI\x01")) {
+		return nil, fmt.Errorf("not a BAI index")
+	}
+	var numRefs int32
+	if err := binary.Read(br, le, &numRefs); err != nil {
+		return nil, err
+	}
+	if numRefs < 0 {
+		return nil, fmt.Errorf("corrupt BAI index: %d references", numRefs)
+	}
+	idx := &baiIndex{refs: make([]baiReference, numRefs)}
+	for i := range idx.refs {
+		var numBins int32
+		if err := binary.Read(br, le, &numBins); err != nil {
+			return nil, err
+		}
+		if numBins < 0 {
+			return nil, fmt.Errorf("corrupt BAI index: %d bins", numBins)
+		}
+		ref := baiReference{bins: make(map[uint32][]baiChunk, numBins)}
+		for range numBins {
+			var bin uint32
+			var numChunks int32
+			if err := binary.Read(br, le, &bin); err != nil {
+				return nil, err
+			}
+			if err := binary.Read(br, le, &numChunks); err != nil {
+				return nil, err
+			}
+			if numChunks < 0 {
+				return nil, fmt.Errorf("corrupt BAI index: %d chunks", numChunks)
+			}
+			offsets := make([]uint64, 2*numChunks)
+			if err := binary.Read(br, le, offsets); err != nil {
+				return nil, err
+			}
+			chunks := make([]baiChunk, numChunks)
+			for j := range chunks {
+				chunks[j] = baiChunk{begin: offsets[2*j], end: offsets[2*j+1]}
+			}
+			ref.bins[bin] = chunks
+		}
+		var numIntervals int32
+		if err := binary.Read(br, le, &numIntervals); err != nil {
+			return nil, err
+		}
+		if numIntervals < 0 {
+			return nil, fmt.Errorf("corrupt BAI index: %d intervals", numIntervals)
+		}
+		ref.intervals = make([]uint64, numIntervals)
+		if err := binary.Read(br, le, ref.intervals); err != nil {
+			return nil, err
+		}
+		idx.refs[i] = ref
+	}
+	return idx, nil
+}
+
+// regionBins lists the bins that may hold alignments overlapping the 0-based,
+// half-open interval [beg, end), as given in the SAM specification.
+func regionBins(beg, end int) []uint32 {
+	end--
+	bins := []uint32{0}
+	for _, level := range []struct {
+		offset uint32
+		shift  int
+	}{{1, 26}, {9, 23}, {73, 20}, {585, 17}, {4681, 14}} {
+		for k := level.offset + uint32(beg>>level.shift); k <= level.offset+uint32(end>>level.shift); k++ {
+			bins = append(bins, k)
+		}
+	}
+	return bins
+}
+
+// regionOffset returns the virtual offset of the first record that may
+// overlap [beg, end) on reference refID, or false if there is none.
+func (idx *baiIndex) regionOffset(refID, beg, end int) (uint64, bool) {
+	if refID < 0 || refID >= len(idx.refs) {
+		return 0, false
+	}
+	ref := idx.refs[refID]
+	var minOffset uint64
+	if window := beg >> 14; window < len(ref.intervals) {
+		minOffset = ref.intervals[window]
+	} else if len(ref.intervals) > 0 {
+		minOffset = ref.intervals[len(ref.intervals)-1]
+	}
+	var best uint64
+	found := false
+	for _, bin := range regionBins(beg, end) {
+		for _, chunk := range ref.bins[bin] {
+			if chunk.end <= minOffset {
+				continue
+			}
+			begin := max(chunk.begin, minOffset)
+			if !found || begin < best {
+				best, found = begin, true
+			}
+		}
+	}
+	return best, found
+}
+
+// findBAI returns the index file of a BAM, either file.bam.bai or file.bai.
+func findBAI(bamPath string) string {
+	for _, candidate := range []string{bamPath + ".bai", strings.TrimSuffix(bamPath, ".bam") + ".bai"} {
+		if fileExists(candidate) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// openIndexedBAM opens a BAM positioned at the first record that may overlap
+// region, using its BAI index. It returns a nil reader if the file has no
+// index, so that the caller can stream it instead.
+func openIndexedBAM(path string, region *samRegion) (samRecordReader, io.Closer, error) {
+	indexPath := findBAI(path)
+	if indexPath == "" {
+		return nil, nil, nil
+	}
+	indexFile, err := os.Open(indexPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	idx, err := readBAI(indexFile)
+	indexFile.Close()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", indexPath, err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	header, err := newBAMReader(bufio.NewReader(gz))
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	refID := -1
+	for i, name := range header.refs {
+		if name == region.Chrom {
+			refID = i
+		}
+	}
+	beg, end := region.Start-1, region.End
+	if end == 0 || end > baiMaxPos {
+		end = baiMaxPos
+	}
+	offset, ok := idx.regionOffset(refID, beg, end)
+	if !ok {
+		return emptySAMReader{}, file, nil
+	}
+
+	if _, err := file.Seek(int64(offset>>16), io.SeekStart); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	gz, err = gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	records := bufio.NewReaderSize(gz, 64*1024)
+	if _, err := records.Discard(int(offset & 0xffff)); err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	reader := &bamReader{r: records, refs: header.refs}
+	return &regionReader{reader: reader, region: region, sorted: true}, file, nil
+}
+
+type emptySAMReader struct{}
+
+func (emptySAMReader) Read() (*samRecord, error) { return nil, io.EOF }
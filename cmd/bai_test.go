@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegionBins(t *testing.T) {
+	assert.Equal(t, []uint32{0, 1, 9, 73, 585, 4681}, regionBins(0, 100))
+	assert.Equal(t, []uint32{0, 1, 9, 73, 585, 4681, 4682}, regionBins(16000, 17000))
+}
+
+func TestReadBAINegativeCounts(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []any
+	}{
+		{"references", []any{int32(-1)}},
+		{"bins", []any{int32(1), int32(-2)}},
+		{"chunks", []any{int32(1), int32(1), uint32(0), int32(-3)}},
+		{"intervals", []any{int32(1), int32(0), int32(-4)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var index bytes.Buffer
+			index.WriteString("BAI\x01")
+			for _, v := range tt.fields {
+				require.NoError(t, binary.Write(&index, binary.LittleEndian, v))
+			}
+			_, err := readBAI(&index)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "corrupt BAI index")
+		})
+	}
+}
+
+func TestIndexedBAMRegion(t *testing.T) {
+	dir := t.TempDir()
+	bamPath := filepath.Join(dir, "sorted.bam")
+
+	// Write every record as its own gzip member and note its virtual offset.
+	var out bytes.Buffer
+	header := testBAMFile(t)
+	out.Write(header)
+	var offsets []uint64
+	for i, pos := range []int32{100, 20000, 40000} {
+		offsets = append(offsets, uint64(out.Len())<<16)
+		gz := gzip.NewWriter(&out)
+		_, err := gz.Write(testBAMRecord("read"+string(rune('1'+i)), 0, pos, 0, []uint32{10 << 4}, "ACGTACGTAC", bytes.Repeat([]byte{30}, 10), nil))
+		require.NoError(t, err)
+		require.NoError(t, gz.Close())
+	}
+	require.NoError(t, os.WriteFile(bamPath, out.Bytes(), 0o644))
+
+	// One reference with all records in bin 0 and a linear index entry
+	// for each 16kb window.
+	le := binary.LittleEndian
+	var index bytes.Buffer
+	index.WriteString("BAI\x01")
+	for _, v := range []any{int32(1), int32(1), uint32(0), int32(1), offsets[0], uint64(out.Len()) << 16, int32(3), offsets[0], offsets[1], offsets[2]} {
+		require.NoError(t, binary.Write(&index, le, v))
+	}
+	require.NoError(t, os.WriteFile(bamPath+".bai", index.Bytes(), 0o644))
+
+	readNames := func(region string) []string {
+		r, err := parseSAMRegion(region)
+		require.NoError(t, err)
+		reader := &multiSAMReader{filenames: []string{bamPath}, region: r}
+		var names []string
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				return names
+			}
+			require.NoError(t, err)
+			names = append(names, record.Name)
+		}
+	}
+	assert.Equal(t, []string{"read3"}, readNames("chr1:40001-40010"))
+	assert.Equal(t, []string{"read2"}, readNames("chr1:20005-20020"))
+	assert.Equal(t, []string{"read1", "read2", "read3"}, readNames("chr1"))
+	assert.Empty(t, readNames("chr2:1-100"))
+}
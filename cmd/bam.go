@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	bamCigarOps = "MIDNSHP=X"
+	bamSeqBases = "=ACMGRSVTWYHKDBN"
+)
+
+// bamReader decodes BAM records from the decompressed BGZF stream.
+type bamReader struct {
+	r    io.Reader
+	refs []string
+	buf  []byte
+}
+
+// newBAMReader reads the BAM header, keeping the reference names that
+// records refer to by index.
+func newBAMReader(r io.Reader) (*bamReader, error) {
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil || !bytes.Equal(magic[:], bamMagic) {
+		return nil, fmt.Errorf("not a BAM file")
+	}
+	var textLen int32
+	if err := binary.Read(r, binary.LittleEndian, &textLen); err != nil {
+		return nil, fmt.Errorf("reading BAM header: %w", err)
+	}
+	if _, err := io.CopyN(io.Discard, r, int64(textLen)); err != nil {
+		return nil, fmt.Errorf("reading BAM header: %w", err)
+	}
+	var numRefs int32
+	if err := binary.Read(r, binary.LittleEndian, &numRefs); err != nil {
+		return nil, fmt.Errorf("reading BAM header: %w", err)
+	}
+	refs := make([]string, numRefs)
+	for i := range refs {
+		var nameLen int32
+		if err := binary.Read(r, binary.LittleEndian, &nameLen); err != nil {
+			return nil, fmt.Errorf("reading BAM references: %w", err)
+		}
+		name := make([]byte, nameLen+4) // NUL-terminated name and reference length
+		if _, err := io.ReadFull(r, name); err != nil {
+			return nil, fmt.Errorf("reading BAM references: %w", err)
+		}
+		refs[i] = string(bytes.TrimRight(name[:nameLen], "\x00"))
+	}
+	return &bamReader{r: r, refs: refs}, nil
+}
+
+func (b *bamReader) Read() (*samRecord, error) {
+	var blockSize int32
+	if err := binary.Read(b.r, binary.LittleEndian, &blockSize); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("reading BAM record: %w", err)
+	}
+	if blockSize < 32 {
+		return nil, fmt.Errorf("invalid BAM record size %d", blockSize)
+	}
+	if cap(b.buf) < int(blockSize) {
+		b.buf = make([]byte, blockSize)
+	}
+	block := b.buf[:blockSize]
+	if _, err := io.ReadFull(b.r, block); err != nil {
+		return nil, fmt.Errorf("reading BAM record: %w", err)
+	}
+	return decodeBAMRecord(block, b.refs)
+}
+
+// decodeBAMRecord converts one BAM alignment block (without its size) into
+// its SAM representation.
+func decodeBAMRecord(block []byte, refs []string) (*samRecord, error) {
+	le := binary.LittleEndian
+	refName := func(id int32) string {
+		if id < 0 || int(id) >= len(refs) {
+			return "*"
+		}
+		return refs[id]
+	}
+	refID := int32(le.Uint32(block[0:]))
+	pos := int32(le.Uint32(block[4:]))
+	nameLen := int(block[8])
+	mapq := int(block[9])
+	numCigar := int(le.Uint16(block[12:]))
+	flag := int(le.Uint16(block[14:]))
+	seqLen := int(int32(le.Uint32(block[16:])))
+	nextRefID := int32(le.Uint32(block[20:]))
+	nextPos := int32(le.Uint32(block[24:]))
+	tlen := int(int32(le.Uint32(block[28:])))
+
+	offset := 32
+	need := offset + nameLen + 4*numCigar + (seqLen+1)/2 + seqLen
+	if seqLen < 0 || need > len(block) {
+		return nil, fmt.Errorf("truncated BAM record")
+	}
+	name := string(bytes.TrimRight(block[offset:offset+nameLen], "\x00"))
+	offset += nameLen
+
+	cigar := make([]uint32, numCigar)
+	for i := range cigar {
+		cigar[i] = le.Uint32(block[offset:])
+		offset += 4
+	}
+
+	seq := make([]byte, seqLen)
+	for i := range seq {
+		packed := block[offset+i/2]
+		if i%2 == 0 {
+			packed >>= 4
+		}
+		seq[i] = bamSeqBases[packed&0x0f]
+	}
+	offset += (seqLen + 1) / 2
+
+	qual := make([]byte, seqLen)
+	missingQual := seqLen > 0 && block[offset] == 0xff
+	for i := range qual {
+		qual[i] = block[offset+i] + 33
+	}
+	offset += seqLen
+
+	tags, longCigar, err := decodeBAMTags(block[offset:])
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", name, err)
+	}
+	// CIGARs with more than 65535 operations are stored in the CG tag, with
+	// a placeholder <seqLen>S<refLen>N as the CIGAR.
+	if longCigar != nil && numCigar == 2 && cigar[0] == uint32(seqLen)<<4|4 && cigar[1]&0x0f == 3 {
+		cigar = longCigar
+	}
+
+	record := &samRecord{
+		Name:  name,
+		Flag:  flag,
+		RName: refName(refID),
+		Pos:   int(pos) + 1,
+		MapQ:  mapq,
+		Cigar: formatBAMCigar(cigar),
+		RNext: refName(nextRefID),
+		PNext: int(nextPos) + 1,
+		TLen:  tlen,
+		Seq:   string(seq),
+		Qual:  string(qual),
+		Tags:  tags,
+	}
+	if record.RNext != "*" && nextRefID == refID {
+		record.RNext = "="
+	}
+	if seqLen == 0 {
+		record.Seq = "*"
+	}
+	if seqLen == 0 || missingQual {
+		record.Qual = "*"
+	}
+	return record, nil
+}
+
+func formatBAMCigar(ops []uint32) string {
+	if len(ops) == 0 {
+		return "*"
+	}
+	var b strings.Builder
+	for _, op := range ops {
+		b.WriteString(strconv.Itoa(int(op >> 4)))
+		if int(op&0x0f) < len(bamCigarOps) {
+			b.WriteByte(bamCigarOps[op&0x0f])
+		} else {
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// decodeBAMTags converts the binary optional fields to TAG:TYPE:VALUE
+// strings. The operations of a CG:B:I tag are returned separately.
+func decodeBAMTags(data []byte) ([]string, []uint32, error) {
+	le := binary.LittleEndian
+	var tags []string
+	var longCigar []uint32
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, nil, fmt.Errorf("truncated BAM tag")
+		}
+		key, typ := string(data[:2]), data[2]
+		data = data[3:]
+		var value string
+		switch typ {
+		case 'A':
+			value, data = string(data[:1]), data[1:]
+		case 'Z', 'H':
+			end := bytes.IndexByte(data, 0)
+			if end < 0 {
+				return nil, nil, fmt.Errorf("unterminated BAM tag %s", key)
+			}
+			value, data = string(data[:end]), data[end+1:]
+		case 'B':
+			if len(data) < 5 {
+				return nil, nil, fmt.Errorf("truncated BAM tag %s", key)
+			}
+			sub := data[0]
+			count := int(le.Uint32(data[1:]))
+			data = data[5:]
+			size := bamTagSize(sub)
+			if size == 0 || len(data) < count*size {
+				return nil, nil, fmt.Errorf("invalid BAM array tag %s", key)
+			}
+			values := make([]string, count)
+			for i := range values {
+				values[i] = formatBAMNumber(sub, data[i*size:])
+			}
+			if key == "CG" && sub == 'I' {
+				longCigar = make([]uint32, count)
+				for i := range longCigar {
+					longCigar[i] = le.Uint32(data[i*4:])
+				}
+				data = data[count*size:]
+				continue
+			}
+			value = string(sub)
+			if count > 0 {
+				value += "," + strings.Join(values, ",")
+			}
+			data = data[count*size:]
+		default:
+			size := bamTagSize(typ)
+			if size == 0 || len(data) < size {
+				return nil, nil, fmt.Errorf("invalid BAM tag %s of type %c", key, typ)
+			}
+			value = formatBAMNumber(typ, data)
+			data = data[size:]
+			if typ != 'f' {
+				typ = 'i'
+			}
+		}
+		tags = append(tags, key+":"+string(typ)+":"+value)
+	}
+	return tags, longCigar, nil
+}
+
+func bamTagSize(typ byte) int {
+	switch typ {
+	case 'c', 'C':
+		return 1
+	case 's', 'S':
+		return 2
+	case 'i', 'I', 'f':
+		return 4
+	}
+	return 0
+}
+
+func formatBAMNumber(typ byte, data []byte) string {
+	le := binary.LittleEndian
+	switch typ {
+	case 'c':
+		return strconv.Itoa(int(int8(data[0])))
+	case 'C':
+		return strconv.Itoa(int(data[0]))
+	case 's':
+		return strconv.Itoa(int(int16(le.Uint16(data))))
+	case 'S':
+		return strconv.Itoa(int(le.Uint16(data)))
+	case 'i':
+		return strconv.Itoa(int(int32(le.Uint32(data))))
+	case 'I':
+		return strconv.FormatUint(uint64(le.Uint32(data)), 10)
+	case 'f':
+		return strconv.FormatFloat(float64(math.Float32frombits(le.Uint32(data))), 'g', -1, 32)
+	}
+	return ""
+}
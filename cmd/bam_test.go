@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBAMRecord encodes a minimal BAM alignment block, including its size.
+func testBAMRecord(name string, refID, pos int32, flag uint16, cigar []uint32, seq string, qual []byte, tags []byte) []byte {
+	le := binary.LittleEndian
+	var body bytes.Buffer
+	write := func(v any) { _ = binary.Write(&body, le, v) }
+	write(refID)
+	write(pos)
+	write(uint8(len(name) + 1))
+	write(uint8(60))    // MAPQ
+	write(uint16(4680)) // bin
+	write(uint16(len(cigar)))
+	write(flag)
+	write(int32(len(seq)))
+	write(int32(-1)) // next refID
+	write(int32(-1)) // next pos
+	write(int32(0))  // tlen
+	body.WriteString(name + "\x00")
+	for _, op := range cigar {
+		write(op)
+	}
+	packed := make([]byte, (len(seq)+1)/2)
+	for i := range seq {
+		code := byte(strings.IndexByte(bamSeqBases, seq[i]))
+		if i%2 == 0 {
+			code <<= 4
+		}
+		packed[i/2] |= code
+	}
+	body.Write(packed)
+	body.Write(qual)
+	body.Write(tags)
+
+	var block bytes.Buffer
+	_ = binary.Write(&block, le, int32(body.Len()))
+	block.Write(body.Bytes())
+	return block.Bytes()
+}
+
+func testBAMFile(t *testing.T, records ...[]byte) []byte {
+	t.Helper()
+	le := binary.LittleEndian
+	var header bytes.Buffer
+	header.Write(bamMagic)
+	text := "@SQ\tSN:chr1\tLN:1000\n"
+	_ = binary.Write(&header, le, int32(len(text)))
+	header.WriteString(text)
+	_ = binary.Write(&header, le, int32(1))
+	_ = binary.Write(&header, le, int32(5))
+	header.WriteString("chr1\x00")
+	_ = binary.Write(&header, le, int32(1000))
+
+	// Compress the header and every record as separate gzip members, like BGZF blocks.
+	var out bytes.Buffer
+	for _, part := range append([][]byte{header.Bytes()}, records...) {
+		gz := gzip.NewWriter(&out)
+		_, err := gz.Write(part)
+		require.NoError(t, err)
+		require.NoError(t, gz.Close())
+	}
+	return out.Bytes()
+}
+
+func TestBAMReader(t *testing.T) {
+	tags := []byte("MDZ3A1\x00NMC\x01XSA+")
+	data := testBAMFile(t,
+		testBAMRecord("read1", 0, 99, 16, []uint32{2<<4 | 4, 5<<4 | 0}, "ACGTACG", []byte{30, 30, 30, 30, 30, 30, 20}, tags),
+		testBAMRecord("read2", -1, -1, 4, nil, "", nil, nil),
+	)
+	reader, err := openSAMReader(bytes.NewReader(data), "")
+	require.NoError(t, err)
+
+	record, err := reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "read1", record.Name)
+	assert.Equal(t, 16, record.Flag)
+	assert.Equal(t, "chr1", record.RName)
+	assert.Equal(t, 100, record.Pos)
+	assert.Equal(t, 60, record.MapQ)
+	assert.Equal(t, "2S5M", record.Cigar)
+	assert.Equal(t, "ACGTACG", record.Seq)
+	assert.Equal(t, "??????5", record.Qual)
+	assert.Equal(t, []string{"MD:Z:3A1", "NM:i:1", "XS:A:+"}, record.Tags)
+	md, ok := record.tag("MD")
+	assert.True(t, ok)
+	assert.Equal(t, "3A1", md)
+
+	record, err = reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "*", record.RName)
+	assert.Equal(t, 0, record.Pos)
+	assert.Equal(t, "*", record.Cigar)
+	assert.Equal(t, "*", record.Seq)
+	assert.Equal(t, "*", record.Qual)
+
+	_, err = reader.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestDecodeBAMTags(t *testing.T) {
+	le := binary.LittleEndian
+	var data bytes.Buffer
+	data.WriteString("XAs")
+	_ = binary.Write(&data, le, int16(-5))
+	data.WriteString("XFf")
+	_ = binary.Write(&data, le, float32(0.5))
+	data.WriteString("MLBC")
+	_ = binary.Write(&data, le, int32(3))
+	data.Write([]byte{0, 128, 255})
+	data.WriteString("CGBI")
+	_ = binary.Write(&data, le, int32(1))
+	_ = binary.Write(&data, le, uint32(70000<<4|0))
+
+	tags, longCigar, err := decodeBAMTags(data.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"XA:i:-5", "XF:f:0.5", "ML:B:C,0,128,255"}, tags)
+	assert.Equal(t, "70000M", formatBAMCigar(longCigar))
+
+	_, _, err = decodeBAMTags([]byte("XZZabc"))
+	assert.Error(t, err)
+}
+
+func TestOpenSAMReaderCRAMWithoutSamtools(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	_, err := openSAMReader(strings.NewReader("CRAM\x03\x00"), "ref.fa")
+	assert.ErrorContains(t, err, "samtools")
+}
+
+func TestMultiSAMReaderCloseStopsSamtools(t *testing.T) {
+	// A samtools that never stops writing records.
+	bin := t.TempDir()
+	script := "#!/bin/sh\nwhile :; do printf 'r1\\t0\\tchr1\\t1\\t60\\t1M\\t*\\t0\\t0\\tA\\tI\\n'; done\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "samtools"), []byte(script), 0o755))
+	t.Setenv("PATH", bin)
+	input := filepath.Join(t.TempDir(), "reads.cram")
+	require.NoError(t, os.WriteFile(input, []byte("CRAM\x03\x00"), 0o644))
+
+	reader := &multiSAMReader{filenames: []string{input}}
+	record, err := reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "r1", record.Name)
+	cram, ok := reader.decoder.(*cramReader)
+	require.True(t, ok)
+
+	done := make(chan struct{})
+	go func() {
+		reader.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return")
+	}
+	assert.NotNil(t, cram.cmd.ProcessState)
+	_, err = reader.Read()
+	assert.Equal(t, io.EOF, err)
+}
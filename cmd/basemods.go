@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// baseModification is a modification call on one base of a read.
+type baseModification struct {
+	Code        string  // e.g. "m" for 5mC, "h" for 5hmC, "a" for 6mA
+	Probability float64 // 0-1, from the ML tag
+}
+
+// baseModColors are the text colors of modified bases by modification code.
+var baseModColors = map[string]string{"m": "magenta", "h": "cyan", "a": "lightred"}
+
+func baseModColor(code string) string {
+	if color, ok := baseModColors[code]; ok {
+		return color
+	}
+	return "lightgreen"
+}
+
+// parseBaseModifications decodes the MM and ML tags of a record into the
+// modifications per position of SEQ. MM counts bases in the orientation the
+// read was sequenced in, so positions are mirrored for reverse-strand
+// alignments. ml holds the ML probabilities (0-255); without ML every call
+// has probability 1. If several modifications are called on one base, the
+// most likely is kept.
+func parseBaseModifications(mm string, ml []int, seq string, reverse bool) (map[int]baseModification, error) {
+	mods := make(map[int]baseModification)
+	original := seq
+	if reverse {
+		original = reverseComplement(seq, dnaComplements)
+	}
+	mlIndex := 0
+	for _, entry := range strings.Split(strings.TrimSuffix(mm, ";"), ";") {
+		if entry == "" {
+			continue
+		}
+		fields := strings.Split(entry, ",")
+		spec := fields[0]
+		if len(spec) < 3 || (spec[1] != '+' && spec[1] != '-') {
+			return nil, fmt.Errorf("invalid MM entry %q", entry)
+		}
+		base := spec[0]
+		if spec[1] == '-' {
+			base = byte(dnaComplements[rune(base)])
+		}
+		codeStr := strings.TrimRight(spec[2:], ".?")
+		var codes []string
+		if _, err := strconv.Atoi(codeStr); err == nil {
+			codes = []string{codeStr} // ChEBI identifier
+		} else {
+			codes = strings.Split(codeStr, "")
+		}
+
+		pos := -1
+		for _, skipStr := range fields[1:] {
+			skip, err := strconv.Atoi(skipStr)
+			if err != nil || skip < 0 {
+				return nil, fmt.Errorf("invalid MM skip count %q", skipStr)
+			}
+			// Move to the (skip+1)-th next occurrence of base.
+			for n := 0; n <= skip; {
+				pos++
+				if pos >= len(original) {
+					return nil, fmt.Errorf("MM tag refers past the end of the read")
+				}
+				if base == 'N' || original[pos] == base || original[pos] == base+'a'-'A' {
+					n++
+				}
+			}
+			seqPos := pos
+			if reverse {
+				seqPos = len(seq) - 1 - pos
+			}
+			for _, code := range codes {
+				prob := 1.0
+				if ml != nil {
+					if mlIndex >= len(ml) {
+						return nil, fmt.Errorf("ML tag has fewer values than MM calls")
+					}
+					prob = (float64(ml[mlIndex]) + 0.5) / 256
+					mlIndex++
+				}
+				if current, ok := mods[seqPos]; !ok || prob > current.Probability {
+					mods[seqPos] = baseModification{Code: code, Probability: prob}
+				}
+			}
+		}
+	}
+	return mods, nil
+}
+
+// recordBaseModifications returns the modification calls of record, or nil
+// if it has no MM tag or no sequence.
+func recordBaseModifications(record *samRecord) (map[int]baseModification, error) {
+	if record.Seq == "*" {
+		return nil, nil
+	}
+	mm, ok := record.tag("MM")
+	if !ok {
+		if mm, ok = record.tag("Mm"); !ok {
+			return nil, nil
+		}
+	}
+	var ml []int
+	mlTag, ok := record.tag("ML")
+	if !ok {
+		mlTag, ok = record.tag("Ml")
+	}
+	if ok {
+		values := strings.Split(mlTag, ",")
+		if len(values) > 0 && len(values[0]) == 1 && !strings.ContainsAny(values[0], "0123456789") {
+			values = values[1:] // Array subtype, e.g. "C" in ML:B:C,...
+		}
+		ml = make([]int, 0, len(values))
+		for _, v := range values {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ML value %q", v)
+			}
+			ml = append(ml, n)
+		}
+	}
+	return parseBaseModifications(mm, ml, record.Seq, record.Flag&0x10 != 0)
+}
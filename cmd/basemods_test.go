@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBaseModifications(t *testing.T) {
+	// C positions in AACGTCCA: 2, 5, 6.
+	mods, err := parseBaseModifications("C+m?,0,1;", []int{255, 10}, "AACGTCCA", false)
+	require.NoError(t, err)
+	assert.Len(t, mods, 2)
+	assert.Equal(t, "m", mods[2].Code)
+	assert.InDelta(t, 0.998, mods[2].Probability, 0.001)
+	assert.InDelta(t, 0.041, mods[6].Probability, 0.001)
+
+	// Two codes per call: the more likely one is kept.
+	mods, err = parseBaseModifications("C+mh,1", []int{50, 200}, "AACGTCCA", false)
+	require.NoError(t, err)
+	assert.Equal(t, "h", mods[5].Code)
+
+	// Reverse alignments count bases on the reverse complement of SEQ:
+	// TGGACGTT is sequenced as AACGTCCA.
+	mods, err = parseBaseModifications("C+m,0", nil, "TGGACGTT", true)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]baseModification{5: {Code: "m", Probability: 1}}, mods)
+
+	// Opposite strand calls count the complement base.
+	mods, err = parseBaseModifications("G-m,0", nil, "AACGTCCA", false)
+	require.NoError(t, err)
+	assert.Contains(t, mods, 2)
+
+	_, err = parseBaseModifications("C+m,5", nil, "AACGTCCA", false)
+	assert.Error(t, err)
+	_, err = parseBaseModifications("C+m,0,0", []int{1}, "AACGTCCA", false)
+	assert.Error(t, err)
+}
+
+func TestRecordBaseModifications(t *testing.T) {
+	record := &samRecord{Seq: "ACGA", Tags: []string{"MM:Z:A+a,1;", "ML:B:C,230"}}
+	mods, err := recordBaseModifications(record)
+	require.NoError(t, err)
+	assert.Equal(t, "a", mods[3].Code)
+
+	mods, err = recordBaseModifications(&samRecord{Seq: "ACGA"})
+	assert.NoError(t, err)
+	assert.Nil(t, mods)
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"sort"
+	"time"
+
+	"github.com/aquasecurity/table"
+	"github.com/fatih/color"
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchRuns           int
+	benchWarmup         int
+	benchIgnoreFailures bool
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench 'COMMAND' ['COMMAND'...]",
+	Short: "Benchmark shell commands and compare them",
+	Long: `Runs every command repeatedly through 'sh -c' and reports the mean, standard
+deviation, minimum and maximum wall time, and the peak memory (max RSS) of each.
+When several commands are given they are compared against the fastest one.
+The output of the commands is discarded.
+
+Example:
+  hey bench -r 5 'gzip -c -1 reads.fq > /dev/null' 'gzip -c -6 reads.fq > /dev/null'
+  hey bench -w 1 'samtools sort -@ 4 aln.bam -o /tmp/s.bam' 'samtools sort -@ 8 aln.bam -o /tmp/s.bam'`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if benchRuns < 1 {
+			return fmt.Errorf("--runs must be at least 1")
+		}
+		if benchWarmup < 0 {
+			return fmt.Errorf("--warmup cannot be negative")
+		}
+		var results []benchResult
+		for _, command := range args {
+			result, err := benchCommand(command, benchRuns, benchWarmup)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+		printBenchTable(results)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+	benchCmd.Flags().IntVarP(&benchRuns, "runs", "r", 10, "Number of timed runs per command")
+	benchCmd.Flags().IntVarP(&benchWarmup, "warmup", "w", 0, "Number of untimed warmup runs per command")
+	benchCmd.Flags().BoolVarP(&benchIgnoreFailures, "ignore-failures", "i", false, "Keep going when a command exits with a non-zero status")
+}
+
+type benchResult struct {
+	Command  string
+	Times    []float64 // Wall time of every run, in seconds
+	MaxRSS   int64     // Peak resident set size over all runs, in bytes
+	Failures int
+}
+
+func benchCommand(command string, runs, warmup int) (benchResult, error) {
+	result := benchResult{Command: command}
+
+	bar := progressbar.NewOptions(warmup+runs,
+		progressbar.OptionSetDescription("[cyan]"+command),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionShowCount(),
+		progressbar.OptionEnableColorCodes(true),
+		progressbar.OptionClearOnFinish(),
+		progressbar.OptionSetTheme(progressbar.Theme{Saucer: "[green]=[reset]", SaucerHead: "[green]>[reset]", SaucerPadding: " ", BarStart: "[", BarEnd: "]"}),
+	)
+	defer bar.Finish()
+
+	for i := 0; i < warmup+runs; i++ {
+		elapsed, rss, err := timeCommand(command)
+		_ = bar.Add(1)
+		if err != nil {
+			if !benchIgnoreFailures {
+				return result, fmt.Errorf("%q failed: %w (use --ignore-failures to continue)", command, err)
+			}
+			result.Failures++
+		}
+		if i < warmup {
+			continue
+		}
+		result.Times = append(result.Times, elapsed.Seconds())
+		result.MaxRSS = max(result.MaxRSS, rss)
+	}
+	return result, nil
+}
+
+// timeCommand runs command once and returns its wall time and max RSS in bytes.
+func timeCommand(command string) (time.Duration, int64, error) {
+	c := exec.Command("sh", "-c", command)
+	start := time.Now()
+	err := c.Run()
+	elapsed := time.Since(start)
+
+	var rss int64
+	if c.ProcessState != nil {
+		rss = maxRSS(c.ProcessState)
+	}
+	return elapsed, rss, err
+}
+
+func stddev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	m := mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
+
+// formatSeconds prints a duration given in seconds with a unit fitting its size.
+func formatSeconds(s float64) string {
+	switch {
+	case s < 1e-3:
+		return fmt.Sprintf("%.1f µs", s*1e6)
+	case s < 1:
+		return fmt.Sprintf("%.1f ms", s*1e3)
+	}
+	return fmt.Sprintf("%.3f s", s)
+}
+
+func printBenchTable(results []benchResult) {
+	sort.SliceStable(results, func(i, j int) bool { return mean(results[i].Times) < mean(results[j].Times) })
+	fastest := mean(results[0].Times)
+
+	t := table.New(os.Stdout)
+	t.SetHeaders("Command", "Mean ± σ", "Min", "Max", "Max RSS", "Relative")
+	t.SetHeaderStyle(table.StyleBold)
+	t.SetLineStyle(table.StyleBlue)
+	t.SetDividers(table.UnicodeRoundedDividers)
+	t.SetAlignment(table.AlignLeft, table.AlignRight, table.AlignRight, table.AlignRight, table.AlignRight, table.AlignRight)
+
+	for i, r := range results {
+		lo, hi := minMax(r.Times)
+		m := mean(r.Times)
+		command := r.Command
+		if r.Failures > 0 {
+			command += color.RedString(" (%d failed)", r.Failures)
+		}
+		relative := "1.00x"
+		if fastest > 0 {
+			relative = fmt.Sprintf("%.2fx", m/fastest)
+		}
+		if i == 0 && len(results) > 1 {
+			relative = color.GreenString(relative)
+		}
+		t.AddRow(
+			command,
+			fmt.Sprintf("%s ± %s", formatSeconds(m), formatSeconds(stddev(r.Times))),
+			formatSeconds(lo),
+			formatSeconds(hi),
+			humanSize(r.MaxRSS),
+			relative,
+		)
+	}
+	t.Render()
+	fmt.Printf("%d runs per command\n", len(results[0].Times))
+}
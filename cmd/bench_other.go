@@ -0,0 +1,10 @@
+//go:build !unix
+
+package cmd
+
+import "os"
+
+// maxRSS returns 0, the max RSS of a process is not known on this platform.
+func maxRSS(state *os.ProcessState) int64 {
+	return 0
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStddev(t *testing.T) {
+	assert.Equal(t, 0.0, stddev(nil))
+	assert.Equal(t, 0.0, stddev([]float64{3}))
+	assert.InDelta(t, 1.0, stddev([]float64{1, 2, 3}), 1e-9)
+	assert.InDelta(t, 2.13809, stddev([]float64{2, 4, 4, 4, 5, 5, 7, 9}), 1e-5)
+}
+
+func TestFormatSeconds(t *testing.T) {
+	tests := []struct {
+		seconds  float64
+		expected string
+	}{
+		{0.0000123, "12.3 µs"},
+		{0.0456, "45.6 ms"},
+		{1.5, "1.500 s"},
+		{125, "125.000 s"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, formatSeconds(tt.seconds))
+	}
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package cmd
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// maxRSS returns the max RSS in bytes of a finished process.
+func maxRSS(state *os.ProcessState) int64 {
+	usage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+	rss := int64(usage.Maxrss)
+	if runtime.GOOS == "linux" {
+		rss *= 1024 // Linux reports kilobytes, macOS bytes
+	}
+	return rss
+}
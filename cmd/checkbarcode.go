@@ -5,10 +5,13 @@ import (
 	"compress/gzip"
 	"fmt"
 	"io"
+	"maps"
 	"math" // Used for finding shortest length
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 
@@ -22,6 +25,8 @@ import (
 // --- Data Structures ---
 type fileToProcess struct {
 	SampleName     string
+	Run            int    // Index of the run in the YAML file
+	Read           string // R1, R2, I1 or I2
 	RelativePath   string
 	AbsolutePath   string
 	RecordsToCheck int
@@ -29,8 +34,36 @@ type fileToProcess struct {
 
 type processResult struct {
 	SampleName   string
+	Run          int
+	Read         string
 	RelativePath string
 	Barcode      string
+	Barcodes     []barcodeCount // All barcodes found, most common first
+	Flowcells    []string       // Flow cells of the read names, sorted
+	Lanes        []string       // Lanes of the read names, sorted
+	Problem      string         // Read number or index sequence not matching Read
+	Records      int            // Records read
+	MeanLength   float64
+	MeanQuality  float64 // Phred score, 0 if the file has no qualities
+}
+
+// barcodeCount is how many of the headers scanned carry a barcode.
+type barcodeCount struct {
+	Barcode string
+	Count   int
+}
+
+// barcodeScan is what is found in the first records of a FASTQ file.
+type barcodeScan struct {
+	Barcode     string // Most common header barcode, or an error message
+	Barcodes    []barcodeCount
+	ReadNumber  string // Most common read number of the headers, like "1"
+	Flowcells   []string
+	Lanes       []string
+	Sequence    string // Most common sequence, the index of I1 and I2 files
+	Records     int
+	MeanLength  float64
+	MeanQuality float64
 }
 
 // --- Global Variables / Constants ---
@@ -43,31 +76,110 @@ var (
 		"No Headers/Barcodes Found": true,
 	}
 	// Flags
-	yamlTopKey        string
-	numRecordsToCheck int
+	yamlTopKey         string
+	numRecordsToCheck  int
+	readsToCheck       []string
+	topBarcodes        int
+	barcodeFormat      string
+	sampleRegexFlag    string
+	maxBarcodeMismatch int
+	strictBarcodes     bool
+	barcodeSpread      int
+	noBarcodeCache     bool
+	expectedBarcodes   []string
+	expectedFile       string
+	showReadStats      bool
+	showDistances      bool
+	minBarcodeDistance int
 )
 
+// checkableReads are the read keys of a run that --reads accepts.
+var checkableReads = []string{"R1", "R2", "I1", "I2"}
+
 const defaultNumRecordsToCheck = 1000
 const defaultMaxWorkers = 4 // Default max concurrent workers
 
 // --- Cobra Command Definition ---
 var checkbarcodeCmd = &cobra.Command{
-	Use:   "checkbarcode [yaml-file]",
+	Use:   "checkbarcode [yaml-file | fastq-files...]",
 	Short: "Check barcode uniformity in FASTQ files listed in YAML",
 	Long: `Processes FASTQ R1 files listed in a YAML config (supports legacy and new formats),
 maintaining the original order from the YAML file.
 Extracts the most common barcode from the first N records (default 1000).
 Compares barcodes within a sample group based on the shortest length in that group,
-treating 'N' as a wildcard. Displays results in a table with automatically merged sample names,
-cyclically colored R1 file names, and highlighting for non-uniform/error barcodes.
-Use --key (-k) to specify the YAML top-level key and --num-records (-n) to change the number of records scanned.`,
-	Args: cobra.ExactArgs(1), // Requires exactly one argument: the YAML file path
+treating 'N' as a wildcard. The i7 and i5 of dual-index barcodes (ACGT+TTGG) are compared
+separately, and the index that differs is named. Use --max-mismatch N to allow N sequencing
+errors per index.
+Displays results in a table with automatically merged sample names, cyclically colored file names, and highlighting for non-uniform/error barcodes.
+Instead of a YAML file, FASTQ files, glob patterns or directories can be given. Their R1 and
+R2 files are paired and grouped into samples by name as in 'hey manifest' (dropping the read
+number and the _S1_L001 suffix), or by the first group of --sample-regex, e.g. '^([^_]+)'.
+FASTQ paths, in the YAML or given directly, can also be http(s):// or s3:// URLs, of which
+only the records checked are downloaded, with range requests for --spread. s3:// objects are
+read unsigned, so they must be public, from $AWS_ENDPOINT_URL or the bucket endpoint of
+$AWS_REGION; use presigned https URLs for private ones. Remote results are not cached.
+The flowcells and lanes of the read names are shown for each run, with a warning when the runs
+of a sample come from more than one flowcell, or the files of a run from different lanes.
+Use --key (-k) to specify the YAML top-level key and --num-records (-n) to change the number of records scanned.
+The first records can be unrepresentative, e.g. from the edges of tiles: use --spread N to read
+them from N evenly spaced places of plain files instead, or every Nth record of gzipped files.
+Results are cached in checkbarcode.json in the user cache directory (~/.cache/hey on Linux),
+so files whose size and modification time did not change are not read again when the same
+number of records is checked; use --no-cache to read every file again.
+Use --top N to list the N most common barcodes of each file with their fractions, which shows
+index hopping or contamination by another library (e.g. a 70/30 split).
+Use --expected (sample=ACGT+TTGG, or the barcode only) or --expected-file, with a barcode,
+sample and barcode, or sample, i7 and i5 per line, to preview demultiplexing before bcl2fastq:
+the records checked are assigned to the closest expected barcode within --max-mismatch, and the
+share of each sample and of Undetermined records shows how balanced the pool is.
+Use --distances to print the Hamming distances between the dominant barcodes of the samples,
+with pairs of samples closer than --min-distance (default 3, safe for demultiplexing with one
+mismatch allowed) in red; the distances of dual-index barcodes are summed over both indexes.
+Use --read-stats to add a column per read with the number of records read, their mean length
+and their mean quality, to spot truncated, corrupt or low-quality files.
+Use --reads to also check the R2, I1 and I2 files of the runs, each in its own columns:
+R1 and R2 files whose headers carry the other read number are flagged as swapped, and
+index files whose sequences differ from the barcode in their headers are flagged too.
+Use --format tsv or json for pipelines: one row (or object) per file, with the uniformity
+verdict of its sample; the JSON also lists the verdicts by sample.
+A one-line summary (checkbarcode samples=... status=OK|FAIL) is written to stderr. With --strict,
+hey exits with an error if the status is FAIL: a sample is not uniform, or a file could not be
+read or was flagged by --reads, so that the check can gate a demultiplexing pipeline.
+Barcodes are annotated with the well of the index kit they match (TruSeq LT and UD, Nextera XT
+and 10x sample indexes built in). Add kits to index_kits.yaml in the user config directory
+(~/.config/hey on Linux), mapping wells to i7 or i7+i5 sequences:
+  My kit:
+    A01: ACGTACGT+TTGGCCAA`,
+	Args: cobra.MinimumNArgs(1), // The YAML file path, or FASTQ files
 	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Compile regex once
 		barcodeRegex = regexp.MustCompile(`^[ACGTN+]+$`)
+		if barcodeFormat != "table" && barcodeFormat != "tsv" && barcodeFormat != "json" {
+			return fmt.Errorf("--format must be table, tsv or json, got %q", barcodeFormat)
+		}
+		if barcodeFormat != "table" {
+			color.Output = os.Stderr // Keep warnings out of the output
+		}
+		if maxBarcodeMismatch < 0 {
+			return fmt.Errorf("--max-mismatch must be positive, not %d", maxBarcodeMismatch)
+		}
+		if barcodeSpread < 1 {
+			return fmt.Errorf("--spread must be at least 1, not %d", barcodeSpread)
+		}
+		if minBarcodeDistance < 0 {
+			return fmt.Errorf("--min-distance must be positive, not %d", minBarcodeDistance)
+		}
+		if topBarcodes < 0 {
+			return fmt.Errorf("--top must be positive, not %d", topBarcodes)
+		}
+		for _, read := range readsToCheck {
+			if !slices.Contains(checkableReads, read) {
+				return fmt.Errorf("--reads must be some of %s, not %q", strings.Join(checkableReads, ","), read)
+			}
+		}
 		// The yamlTopKey and numRecordsToCheck variables will be populated by cobra
-		return runCheckBarcode(args[0], yamlTopKey, numRecordsToCheck)
+		return runCheckBarcode(args, yamlTopKey, numRecordsToCheck)
 	},
 }
 
@@ -76,28 +188,76 @@ func init() {
 	rootCmd.AddCommand(checkbarcodeCmd)
 	checkbarcodeCmd.Flags().StringVarP(&yamlTopKey, "key", "k", "samples", "Top-level key in YAML file containing sample definitions")
 	checkbarcodeCmd.Flags().IntVarP(&numRecordsToCheck, "num-records", "n", defaultNumRecordsToCheck, "Number of FASTQ records (x4 lines) to check per file")
+	checkbarcodeCmd.Flags().StringVar(&sampleRegexFlag, "sample-regex", "", "Regex giving the sample of FASTQ files given without YAML, from the first group or the match")
+	checkbarcodeCmd.Flags().StringVarP(&barcodeFormat, "format", "f", "table", "Output format: table, tsv or json")
+	checkbarcodeCmd.Flags().BoolVar(&strictBarcodes, "strict", false, "Exit with an error if a sample is not uniform or a file has errors or problems")
+	checkbarcodeCmd.Flags().IntVar(&maxBarcodeMismatch, "max-mismatch", 0, "Mismatches allowed per index between barcodes of a sample (N always matches)")
+	checkbarcodeCmd.Flags().IntVar(&barcodeSpread, "spread", 1, "Read the records from N places spread over each file (every Nth record of gzipped files)")
+	checkbarcodeCmd.Flags().BoolVar(&noBarcodeCache, "no-cache", false, "Read every file again instead of reusing the cached results of unchanged files")
+	checkbarcodeCmd.Flags().StringSliceVar(&expectedBarcodes, "expected", nil, "Expected barcodes, as BARCODE or SAMPLE=BARCODE, to preview demultiplexing")
+	checkbarcodeCmd.Flags().StringVar(&expectedFile, "expected-file", "", "File of expected barcodes, one 'barcode', 'sample barcode' or 'sample i7 i5' per line")
+	checkbarcodeCmd.Flags().IntVar(&topBarcodes, "top", 0, "List the N most common barcodes of each file with counts and fractions")
+	checkbarcodeCmd.Flags().BoolVar(&showDistances, "distances", false, "Print the Hamming distance matrix between the dominant barcodes of the samples")
+	checkbarcodeCmd.Flags().IntVar(&minBarcodeDistance, "min-distance", 3, "Distance below which --distances flags barcodes of different samples as unsafe to demultiplex")
+	checkbarcodeCmd.Flags().BoolVar(&showReadStats, "read-stats", false, "Add the records read, mean read length and mean quality of each file to the table")
+	checkbarcodeCmd.Flags().StringSliceVar(&readsToCheck, "reads", []string{"R1"}, "Read files of each run to check: R1, R2, I1 and/or I2")
 }
 
 // --- Core Logic ---
-func runCheckBarcode(yamlFilePath string, topKey string, recordsToCheck int) error {
-	// 1. Read and Parse YAML into generic structure
-	yamlDataAny, err := readYamlConfigGeneric(yamlFilePath)
+func runCheckBarcode(args []string, topKey string, recordsToCheck int) error {
+	expected, err := loadExpectedBarcodes(expectedBarcodes, expectedFile)
 	if err != nil {
-		return fmt.Errorf("reading YAML: %w", err)
+		return err
 	}
 
-	// 2. Gather R1 File Paths while trying to preserve original order
-	filesToProcess, err := gatherFilePathsGeneric(yamlDataAny, yamlFilePath, topKey)
-	if err != nil {
-		return fmt.Errorf("processing YAML data: %w", err)
-	}
-	if len(filesToProcess) == 0 {
-		color.Yellow("No valid R1 files found to process under key '%s' in the YAML file.", topKey)
-		return nil
+	var filesToProcess []fileToProcess
+	var sourceName string // Shown below the table
+	if len(args) == 1 && isYAMLPath(args[0]) {
+		yamlFilePath := args[0]
+		sourceName = filepath.Base(yamlFilePath)
+		// 1. Read and Parse YAML into generic structure
+		yamlDoc, err := readYamlConfigGeneric(yamlFilePath)
+		if err != nil {
+			return fmt.Errorf("reading YAML: %w", err)
+		}
+
+		// 2. Gather File Paths while trying to preserve original order
+		filesToProcess, err = gatherFilePathsGeneric(yamlDoc, yamlFilePath, topKey, readsToCheck)
+		if err != nil {
+			return fmt.Errorf("processing YAML data: %w", err)
+		}
+		if len(filesToProcess) == 0 {
+			color.Yellow("No valid %s files found to process under key '%s' in the YAML file.", strings.Join(readsToCheck, "/"), topKey)
+			return nil
+		}
+	} else {
+		// 1-2. Group FASTQ files given directly into samples and runs
+		var sampleRegex *regexp.Regexp
+		if sampleRegexFlag != "" {
+			var err error
+			if sampleRegex, err = regexp.Compile(sampleRegexFlag); err != nil {
+				return fmt.Errorf("invalid --sample-regex: %w", err)
+			}
+		}
+		paths, err := expandFastqArgs(args)
+		if err != nil {
+			return err
+		}
+		filesToProcess = gatherFastqFiles(paths, sampleRegex, readsToCheck)
+		if len(filesToProcess) == 0 {
+			color.Yellow("No %s files found among the %d FASTQ files given.", strings.Join(readsToCheck, "/"), len(paths))
+			return nil
+		}
+		sourceName = fmt.Sprintf("%d FASTQ files", len(paths))
 	}
 
 	// 3. Process Files Concurrently (results potentially out of order)
-	unorderedResults := processFilesConcurrentlySimple(filesToProcess, recordsToCheck)
+	cache := loadBarcodeCache(barcodeCachePath())
+	cache.refresh = noBarcodeCache
+	unorderedResults := processFilesConcurrentlySimple(filesToProcess, recordsToCheck, cache)
+	if err := cache.save(); err != nil {
+		color.Yellow("Warning: could not save the barcode cache: %v", err)
+	}
 
 	// 4. Prepare Data for Table
 	if len(unorderedResults) > 0 {
@@ -106,58 +266,224 @@ func runCheckBarcode(yamlFilePath string, topKey string, recordsToCheck int) err
 
 		// Perform uniformity check (order doesn't matter for this)
 		barcodeGroups := groupBarcodes(results)
-		isGroupUniform := checkGroupUniformityPrefix(barcodeGroups)
+		nonUniformIndexes := checkGroupUniformityPrefix(barcodeGroups, maxBarcodeMismatch)
+
+		// Annotate barcodes with the index kits they come from
+		kits, err := loadIndexKits()
+		if err != nil {
+			return fmt.Errorf("loading index kits: %w", err)
+		}
+
+		// Preview demultiplexing with the expected barcodes, if given
+		var demux []demuxShare
+		if len(expected) > 0 {
+			demux = demuxPreview(results, expected, maxBarcodeMismatch)
+		}
 
 		// Print table using the correctly ordered results slice
-		printResultsTableAqua(results, isGroupUniform, filepath.Base(yamlFilePath), recordsToCheck)
+		switch barcodeFormat {
+		case "tsv":
+			err = writeBarcodeTSV(os.Stdout, newBarcodeReport(results, nonUniformIndexes, kits, sourceName, recordsToCheck, topBarcodes))
+		case "json":
+			report := newBarcodeReport(results, nonUniformIndexes, kits, sourceName, recordsToCheck, topBarcodes)
+			report.Demux = demux
+			if showDistances {
+				report.CloseBarcodes = closeBarcodePairs(sampleBarcodes(results), minBarcodeDistance)
+			}
+			err = writeBarcodeJSON(os.Stdout, report)
+		default:
+			printResultsTableAqua(results, readsToCheck, nonUniformIndexes, kits, sourceName, recordsToCheck, topBarcodes, showReadStats)
+			if demux != nil {
+				printDemuxPreview(demux)
+			}
+			if showDistances {
+				printDistanceMatrix(sampleBarcodes(results), minBarcodeDistance)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		for _, warning := range flowcellWarnings(results) {
+			color.Yellow("Warning: %s", warning)
+		}
+
+		// 5. One-line summary for logs, and the verdict for --strict
+		summary := summarizeBarcodeCheck(results, nonUniformIndexes)
+		fmt.Fprintln(os.Stderr, summary)
+		if strictBarcodes && summary.failed() {
+			return fmt.Errorf("barcode check failed: %d of %d samples not uniform, %d files with errors, %d with problems",
+				summary.NonUniform, summary.Samples, summary.Errors, summary.Problems)
+		}
 	} else {
 		color.Yellow("No results to display.")
+		if strictBarcodes {
+			return fmt.Errorf("barcode check failed: no files checked")
+		}
 	}
 	return nil
 }
 
+// --- FASTQ Files Given Directly ---
+
+// isYAMLPath tells whether path is a YAML config rather than a FASTQ file.
+func isYAMLPath(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// expandFastqArgs returns the FASTQ files of args: files, glob patterns (for
+// shells that do not expand them) and directories, searched recursively.
+func expandFastqArgs(args []string) ([]string, error) {
+	var paths []string
+	for _, arg := range args {
+		matches := []string{arg}
+		if strings.ContainsAny(arg, "*?[") && !isRemotePath(arg) {
+			var err error
+			if matches, err = filepath.Glob(arg); err != nil {
+				return nil, fmt.Errorf("invalid pattern %s: %w", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %s", arg)
+			}
+		}
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && info.IsDir() {
+				found, err := findFastqFiles(match)
+				if err != nil {
+					return nil, err
+				}
+				paths = append(paths, found...)
+			} else {
+				paths = append(paths, match)
+			}
+		}
+	}
+	return paths, nil
+}
+
+// gatherFastqFiles pairs FASTQ files into runs like hey manifest and groups
+// them into samples by name, with the sample being the first group (or the
+// match) of sampleRegex in the file name if given. Runs are sorted by sample.
+func gatherFastqFiles(paths []string, sampleRegex *regexp.Regexp, reads []string) []fileToProcess {
+	runs := pairFastqFiles(paths)
+	if sampleRegex != nil {
+		for _, r := range runs {
+			name := filepath.Base(r.R1.AbsPath)
+			if m := sampleRegex.FindStringSubmatch(name); len(m) > 1 {
+				r.Sample = m[1]
+			} else if m != nil {
+				r.Sample = m[0]
+			}
+		}
+		sort.SliceStable(runs, func(i, j int) bool { return runs[i].Sample < runs[j].Sample })
+	}
+
+	var filesToProcess []fileToProcess
+	for i, r := range runs {
+		for _, read := range reads {
+			var f *manifestFile
+			switch read {
+			case "R1":
+				f = r.R1
+			case "R2":
+				f = r.R2
+			}
+			if f == nil {
+				continue
+			}
+			absPath, err := filepath.Abs(f.AbsPath)
+			if err != nil || isRemotePath(f.AbsPath) {
+				absPath = f.AbsPath
+			}
+			filesToProcess = append(filesToProcess, fileToProcess{
+				SampleName:     r.Sample,
+				Run:            i,
+				Read:           read,
+				RelativePath:   f.AbsPath, // As given on the command line
+				AbsolutePath:   absPath,
+				RecordsToCheck: defaultNumRecordsToCheck,
+			})
+		}
+	}
+	return filesToProcess
+}
+
 // --- YAML Parsing and File Path Gathering (Using 'any') ---
-func readYamlConfigGeneric(yamlFilePath string) (map[string]any, error) {
+// readYamlConfigGeneric parses the YAML file into nodes, which keep the order
+// of the samples as written (unlike a map).
+func readYamlConfigGeneric(yamlFilePath string) (*yaml.Node, error) {
 	yamlFile, err := os.ReadFile(yamlFilePath)
 	if err != nil {
 		return nil, fmt.Errorf("reading YAML file '%s': %w", yamlFilePath, err)
 	}
-	var data map[string]any // Use 'any' instead of 'interface{}'
-	err = yaml.Unmarshal(yamlFile, &data)
+	var doc yaml.Node
+	err = yaml.Unmarshal(yamlFile, &doc)
 	if err != nil {
 		return nil, fmt.Errorf("parsing YAML file '%s': %w", yamlFilePath, err)
 	}
-	return data, nil
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("parsing YAML file '%s': expected a map at the top level", yamlFilePath)
+	}
+	return doc.Content[0], nil
+}
+
+// yamlSample is the value of a sample in the YAML file.
+type yamlSample struct {
+	name  string
+	value any
+}
+
+// orderedSamples returns the samples of a mapping node in the order they are
+// written, with the entries of a name given more than once moved up to its
+// first one, so that the runs of a sample stay together.
+func orderedSamples(samplesNode *yaml.Node) ([]yamlSample, error) {
+	var names []string
+	values := make(map[string][]any)
+	for i := 0; i+1 < len(samplesNode.Content); i += 2 {
+		name := samplesNode.Content[i].Value
+		var value any
+		if err := samplesNode.Content[i+1].Decode(&value); err != nil {
+			return nil, fmt.Errorf("sample '%s': %w", name, err)
+		}
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		}
+		values[name] = append(values[name], value)
+	}
+	var samples []yamlSample
+	for _, name := range names {
+		for _, value := range values[name] {
+			samples = append(samples, yamlSample{name, value})
+		}
+	}
+	return samples, nil
 }
 
-func gatherFilePathsGeneric(yamlDataAny map[string]any, yamlFilePath string, topKey string) ([]fileToProcess, error) {
+func gatherFilePathsGeneric(yamlDoc *yaml.Node, yamlFilePath string, topKey string, reads []string) ([]fileToProcess, error) {
 	var filesToProcess []fileToProcess
 	yamlDir := filepath.Dir(yamlFilePath)
+	run := 0 // Runs numbered across samples, to put the files of a run on one row
 	if topKey == "" {
 		return nil, fmt.Errorf("YAML top-level key cannot be empty; provide using --key flag")
 	}
 	fmt.Fprintf(os.Stderr, "[dim]Using top-level key from command line: '%s'\n", topKey)
 
-	samplesAny, ok := yamlDataAny[topKey]
-	if !ok {
+	samplesNode := mappingValue(yamlDoc, topKey)
+	if samplesNode == nil {
 		return nil, fmt.Errorf("top-level key '%s' not found in YAML", topKey)
 	}
-	samplesMap, ok := samplesAny.(map[string]any) // Use 'any'
-	if !ok {
-		return nil, fmt.Errorf("expected a map of samples under the key '%s', but got %T", topKey, samplesAny)
+	if samplesNode.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a map of samples under the key '%s', but got %s", topKey, samplesNode.Tag)
 	}
 
-	// Get sample names. Note: Iteration order over a map is not guaranteed.
-	// If strict YAML source order is critical, a different YAML parsing approach (e.g., using yaml.Node) is needed.
-	sampleNames := make([]string, 0, len(samplesMap))
-	for k := range samplesMap {
-		sampleNames = append(sampleNames, k)
+	// Keep the samples in YAML order, so that the table is the same on every run
+	samples, err := orderedSamples(samplesNode)
+	if err != nil {
+		return nil, err
 	}
-	// Optional: Sort sample names alphabetically here if consistent-but-not-yaml order is desired.
-	// sort.Strings(sampleNames)
 
-	for _, sampleName := range sampleNames {
-		sampleDataAny := samplesMap[sampleName]
+	for _, sample := range samples {
+		sampleName, sampleDataAny := sample.name, sample.value
 		var runsList []any // Use 'any'
 
 		// Check for legacy format or new format with "data" key
@@ -192,57 +518,66 @@ func gatherFilePathsGeneric(yamlDataAny map[string]any, yamlFilePath string, top
 				color.Yellow("Warning: Sample '%s', run %d is not a map, skipping.", sampleName, i+1)
 				continue
 			}
-			r1Any, r1KeyExists := runMap["R1"]
-			if !r1KeyExists {
-				color.Yellow("Warning: Sample '%s', run %d has no 'R1' key, skipping.", sampleName, i+1)
-				continue
-			}
-			r1RelativePath, ok := r1Any.(string)
-			if !ok || r1RelativePath == "" {
-				color.Yellow("Warning: Sample '%s', run %d has invalid/empty 'R1' path (%T), skipping.", sampleName, i+1, r1Any)
-				continue
-			}
-
-			// Expand user home dir if path starts with ~
-			if strings.HasPrefix(r1RelativePath, "~") {
-				homeDir, err := os.UserHomeDir()
-				if err != nil {
-					color.Yellow("Warning: Cannot get home dir for path '%s', sample '%s'. Skipping.", r1RelativePath, sampleName)
+			for _, read := range reads {
+				pathAny, keyExists := runMap[read]
+				if !keyExists {
+					// Only R1 is required; single-end and non-indexed runs lack the others.
+					if read == "R1" {
+						color.Yellow("Warning: Sample '%s', run %d has no 'R1' key, skipping.", sampleName, i+1)
+					}
+					continue
+				}
+				relativePath, ok := pathAny.(string)
+				if !ok || relativePath == "" {
+					color.Yellow("Warning: Sample '%s', run %d has invalid/empty '%s' path (%T), skipping.", sampleName, i+1, read, pathAny)
 					continue
 				}
-				r1RelativePath = filepath.Join(homeDir, r1RelativePath[1:])
-			}
 
-			// Construct absolute path
-			var r1AbsPath string
-			if filepath.IsAbs(r1RelativePath) {
-				r1AbsPath = r1RelativePath
-			} else {
-				r1AbsPath = filepath.Join(yamlDir, r1RelativePath)
-			}
-			r1AbsPath = filepath.Clean(r1AbsPath)
+				// Expand user home dir if path starts with ~
+				if strings.HasPrefix(relativePath, "~") {
+					homeDir, err := os.UserHomeDir()
+					if err != nil {
+						color.Yellow("Warning: Cannot get home dir for path '%s', sample '%s'. Skipping.", relativePath, sampleName)
+						continue
+					}
+					relativePath = filepath.Join(homeDir, relativePath[1:])
+				}
 
-			// Add file details to the list to be processed
-			filesToProcess = append(filesToProcess, fileToProcess{
-				SampleName:     sampleName,
-				RelativePath:   r1RelativePath, // Store relative path for display/keying
-				AbsolutePath:   r1AbsPath,
-				RecordsToCheck: defaultNumRecordsToCheck, // Will be updated later if flag used
-			})
+				// Construct absolute path
+				var absPath string
+				if isRemotePath(relativePath) {
+					absPath = relativePath // Read over the network
+				} else if filepath.IsAbs(relativePath) {
+					absPath = filepath.Clean(relativePath)
+				} else {
+					absPath = filepath.Clean(filepath.Join(yamlDir, relativePath))
+				}
+
+				// Add file details to the list to be processed
+				filesToProcess = append(filesToProcess, fileToProcess{
+					SampleName:     sampleName,
+					Run:            run,
+					Read:           read,
+					RelativePath:   relativePath, // Store relative path for display/keying
+					AbsolutePath:   absPath,
+					RecordsToCheck: defaultNumRecordsToCheck, // Will be updated later if flag used
+				})
+			}
+			run++
 		} // End loop through runsList
 	} // End loop through samplesMap
 	return filesToProcess, nil
 }
 
 // --- Simplified Concurrent File Processing ---
-func processFilesConcurrentlySimple(files []fileToProcess, recordsToCheck int) []processResult {
+func processFilesConcurrentlySimple(files []fileToProcess, recordsToCheck int, cache *barcodeCache) []processResult {
 	// Slice to collect potentially unordered results
 	unorderedResults := make([]processResult, 0, len(files))
 	resultChannel := make(chan processResult, len(files))
 	var wg sync.WaitGroup
 
 	bar := progressbar.NewOptions(len(files),
-		progressbar.OptionSetDescription("[cyan]Processing R1 files..."),
+		progressbar.OptionSetDescription("[cyan]Processing FASTQ files..."),
 		progressbar.OptionSetWriter(os.Stderr),
 		progressbar.OptionShowCount(),
 		progressbar.OptionEnableColorCodes(true),
@@ -259,11 +594,24 @@ func processFilesConcurrentlySimple(files []fileToProcess, recordsToCheck int) [
 			defer wg.Done()
 			for job := range jobs {
 				// Ensure the correct recordsToCheck value is used from the job struct
-				barcode := getBarcodeFromFastqGo(job.AbsolutePath, job.RecordsToCheck)
+				scan, info, cached := cache.lookup(job.AbsolutePath, job.RecordsToCheck, barcodeSpread)
+				if !cached {
+					scan = scanFastqGo(job.AbsolutePath, job.RecordsToCheck, barcodeSpread)
+					cache.store(job.AbsolutePath, info, job.RecordsToCheck, barcodeSpread, scan)
+				}
 				resultChannel <- processResult{
 					SampleName:   job.SampleName,
+					Run:          job.Run,
+					Read:         job.Read,
 					RelativePath: job.RelativePath,
-					Barcode:      barcode,
+					Barcode:      scan.Barcode,
+					Barcodes:     scan.Barcodes,
+					Flowcells:    scan.Flowcells,
+					Lanes:        scan.Lanes,
+					Problem:      readProblem(job.Read, scan, maxBarcodeMismatch),
+					Records:      scan.Records,
+					MeanLength:   scan.MeanLength,
+					MeanQuality:  scan.MeanQuality,
 				}
 			}
 		}(w)
@@ -299,17 +647,19 @@ func reorderResults(originalOrder []fileToProcess, unorderedResults []processRes
 	orderedResults := make([]processResult, len(originalOrder))
 	resultsMap := make(map[string]processResult, len(unorderedResults))
 	for _, res := range unorderedResults {
-		// Use RelativePath as the key, assuming it uniquely identifies the run within the YAML context
-		resultsMap[res.RelativePath] = res
+		// Use Run and Read as the key, as the same file may be listed by several runs
+		resultsMap[fmt.Sprintf("%d %s", res.Run, res.Read)] = res
 	}
 
 	for i, fileInfo := range originalOrder {
-		if res, ok := resultsMap[fileInfo.RelativePath]; ok {
+		if res, ok := resultsMap[fmt.Sprintf("%d %s", fileInfo.Run, fileInfo.Read)]; ok {
 			orderedResults[i] = res
 		} else {
 			// Fallback for missing results
 			orderedResults[i] = processResult{
 				SampleName:   fileInfo.SampleName,
+				Run:          fileInfo.Run,
+				Read:         fileInfo.Read,
 				RelativePath: fileInfo.RelativePath,
 				Barcode:      "Result Missing?",
 			}
@@ -337,132 +687,425 @@ func extractBarcodeFromHeaderGo(headerLine string) (string, bool) {
 	return "", false
 }
 
-func getBarcodeFromFastqGo(fastqPath string, recordsToCheck int) string {
-	if recordsToCheck <= 0 {
-		return "Invalid record count"
+// headerReadNumber returns the read number of a FASTQ header in the
+// Illumina format ("@name 1:N:0:ACGT"), or "".
+func headerReadNumber(headerLine string) string {
+	parts := strings.Fields(headerLine)
+	if len(parts) < 2 {
+		return ""
 	}
-	linesToCheck := int64(recordsToCheck) * 4
-	file, err := os.Open(fastqPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return "File Not Found"
+	number, _, found := strings.Cut(parts[1], ":")
+	if !found {
+		return ""
+	}
+	return number
+}
+
+// countBarcodes returns the distinct barcodes with their counts, most common
+// first and in the order they were found on ties.
+func countBarcodes(barcodes []string) []barcodeCount {
+	index := make(map[string]int)
+	var counts []barcodeCount
+	for _, bc := range barcodes {
+		if i, ok := index[bc]; ok {
+			counts[i].Count++
+			continue
 		}
-		return fmt.Sprintf("Error Reading (%T)", err)
+		index[bc] = len(counts)
+		counts = append(counts, barcodeCount{Barcode: bc, Count: 1})
 	}
-	defer file.Close()
-	var reader io.Reader = file
-	if strings.HasSuffix(strings.ToLower(fastqPath), ".gz") {
-		gzReader, err := gzip.NewReader(file)
-		if err != nil {
-			if err == gzip.ErrHeader || err == gzip.ErrChecksum {
-				return "Not a Gzip File"
-			}
-			return fmt.Sprintf("Error Reading (%T)", err)
+	sort.SliceStable(counts, func(i, j int) bool { return counts[i].Count > counts[j].Count })
+	return counts
+}
+
+// mostCommon returns the most frequent of values, the first one on ties.
+func mostCommon(values []string) string {
+	counts := make(map[string]int)
+	maxCount := 0
+	best := ""
+	for _, v := range values {
+		counts[v]++
+		if counts[v] > maxCount {
+			maxCount = counts[v]
+			best = v
 		}
-		defer gzReader.Close()
-		reader = gzReader
 	}
-	scanner := bufio.NewScanner(reader)
+	return best
+}
+
+// barcodeTally collects what scanFastqGo finds in the records it reads.
+type barcodeTally struct {
+	barcodes    []string
+	readNumbers []string
+	sequences   []string
+	flowcells   map[string]bool
+	lanes       map[string]bool
+	bases       int
+	quals       int // Bases with a quality
+	qualSum     int
+}
+
+func (t *barcodeTally) add(header, seq, qual string) {
+	if barcode, ok := extractBarcodeFromHeaderGo(header); ok {
+		t.barcodes = append(t.barcodes, barcode)
+	}
+	if number := headerReadNumber(header); number != "" {
+		t.readNumbers = append(t.readNumbers, number)
+	}
+	if rname, err := parseRname(strings.TrimPrefix(strings.Fields(header)[0], "@")); err == nil {
+		t.flowcells[rname.FlowcellID] = true
+		t.lanes[rname.LaneID] = true
+	}
+	t.sequences = append(t.sequences, seq)
+	t.bases += len(seq)
+	t.quals += len(qual)
+	for i := 0; i < len(qual); i++ {
+		t.qualSum += int(qual[i]) - 33
+	}
+}
+
+// withStats returns scan with the records read, their mean length and their
+// mean quality.
+func (t *barcodeTally) withStats(scan barcodeScan) barcodeScan {
+	scan.Records = len(t.sequences)
+	if scan.Records > 0 {
+		scan.MeanLength = float64(t.bases) / float64(scan.Records)
+	}
+	if t.quals > 0 {
+		scan.MeanQuality = float64(t.qualSum) / float64(t.quals)
+	}
+	return scan
+}
+
+// readFastqRecords calls add with the header, sequence and quality of every stride-th
+// record of r, up to n of them, and returns the number of bytes read up to the
+// end of the last one. Lines before the first record are skipped, so r may
+// start in the middle of a record.
+func readFastqRecords(r io.Reader, n, stride int, add func(header, seq, qual string)) (int64, error) {
+	scanner := bufio.NewScanner(r)
 	scanner.Buffer(make([]byte, 512*1024), 10*1024*1024)
-	lineCounter := int64(0)
-	foundBarcodes := []string{}
-	for scanner.Scan() {
-		lineCounter++
-		if lineCounter > linesToCheck {
-			break
-		}
-		if lineCounter%4 == 1 {
-			line := scanner.Text()
-			if strings.HasPrefix(line, "@") {
-				if barcode, ok := extractBarcodeFromHeaderGo(line); ok {
-					foundBarcodes = append(foundBarcodes, barcode)
+	var window []string
+	var read, end int64
+	seen, added := 0, 0
+	for added < n && scanner.Scan() {
+		window = append(window, scanner.Text())
+		read += int64(len(scanner.Bytes())) + 1
+		if len(window) < 4 {
+			continue
+		}
+		if !strings.HasPrefix(window[0], "@") || !strings.HasPrefix(window[2], "+") {
+			window = window[1:] // Not at the start of a record yet
+			continue
+		}
+		if seen%stride == 0 {
+			add(window[0], window[1], window[3])
+			added++
+			end = read
+		}
+		seen++
+		window = window[:0]
+	}
+	return end, scanner.Err()
+}
+
+// scanFastqGo tallies the barcodes of the first recordsToCheck records of a
+// FASTQ file or URL or, with spread > 1, of records spread over the file: for
+// plain files that can be read from any offset, as many records from each of
+// spread evenly spaced offsets, and for the others, such as gzipped files,
+// every spread-th record.
+func scanFastqGo(fastqPath string, recordsToCheck int, spread int) barcodeScan {
+	if recordsToCheck <= 0 {
+		return barcodeScan{Barcode: "Invalid record count"}
+	}
+	var source fastqSource = localFastq(fastqPath)
+	name := fastqPath
+	if isRemotePath(fastqPath) {
+		source = remoteFastq(remoteURL(fastqPath))
+		name = remoteName(fastqPath)
+	}
+	gzipped := strings.HasSuffix(strings.ToLower(name), ".gz")
+
+	tally := &barcodeTally{flowcells: make(map[string]bool), lanes: make(map[string]bool)}
+	size := int64(-1)
+	if spread > 1 && !gzipped {
+		var err error
+		if size, err = source.size(); err != nil {
+			return scanError(err)
+		}
+	}
+	if size >= 0 {
+		perOffset := (recordsToCheck + spread - 1) / spread
+		var next int64 // End of the records read so far, so that none is read twice
+		for k := 0; k < spread && len(tally.sequences) < recordsToCheck; k++ {
+			offset := max(size*int64(k)/int64(spread), next)
+			if offset >= size {
+				break
+			}
+			r, err := source.openAt(offset)
+			if err != nil {
+				return scanError(err)
+			}
+			n, err := readFastqRecords(r, min(perOffset, recordsToCheck-len(tally.sequences)), 1, tally.add)
+			r.Close()
+			if err != nil {
+				return scanError(err)
+			}
+			next = offset + n
+		}
+	} else {
+		r, err := source.openAt(0)
+		if err != nil {
+			return scanError(err)
+		}
+		defer r.Close()
+		var reader io.Reader = r
+		if gzipped {
+			gzReader, err := gzip.NewReader(r)
+			if err != nil {
+				if err == gzip.ErrHeader || err == gzip.ErrChecksum {
+					return barcodeScan{Barcode: "Not a Gzip File"}
 				}
+				return scanError(err)
 			}
+			defer gzReader.Close()
+			reader = gzReader
+		}
+		if _, err := readFastqRecords(reader, recordsToCheck, max(spread, 1), tally.add); err != nil {
+			return scanError(err)
 		}
 	}
-	if err := scanner.Err(); err != nil {
-		return fmt.Sprintf("Error Reading (%T)", err)
-	}
-	if len(foundBarcodes) == 0 {
-		return "No Headers/Barcodes Found"
+
+	if len(tally.barcodes) == 0 {
+		return tally.withStats(barcodeScan{Barcode: "No Headers/Barcodes Found"})
 	}
-	counts := make(map[string]int)
-	maxCount := 0
-	mostCommon := ""
-	for _, bc := range foundBarcodes {
-		counts[bc]++
-		if counts[bc] > maxCount {
-			maxCount = counts[bc]
-			mostCommon = bc
+	return tally.withStats(barcodeScan{
+		Barcode:    mostCommon(tally.barcodes),
+		Barcodes:   countBarcodes(tally.barcodes),
+		ReadNumber: mostCommon(tally.readNumbers),
+		Flowcells:  slices.Sorted(maps.Keys(tally.flowcells)),
+		Lanes:      slices.Sorted(maps.Keys(tally.lanes)),
+		Sequence:   mostCommon(tally.sequences),
+	})
+}
+
+// readProblem tells what in scan does not fit a file given as read: R1 and
+// R2 files must carry their read number in the headers, and I1 and I2 files
+// must read the first and second index of the header barcode.
+func readProblem(read string, scan barcodeScan, maxMismatch int) string {
+	switch read {
+	case "R1", "R2":
+		if scan.ReadNumber != "" && scan.ReadNumber != read[1:] {
+			return fmt.Sprintf("headers say read %s, swapped?", scan.ReadNumber)
+		}
+	case "I1", "I2":
+		indexes := strings.Split(scan.Barcode, "+")
+		i := int(read[1] - '1')
+		if i >= len(indexes) || scan.Sequence == "" {
+			return ""
+		}
+		shortest := min(len(indexes[i]), len(scan.Sequence))
+		if shortest > 0 && !areBarcodesCompatibleGo(scan.Sequence, indexes[i], shortest, maxMismatch) {
+			return fmt.Sprintf("reads %s, not the header index", scan.Sequence)
 		}
 	}
-	return mostCommon
+	return ""
 }
 
-func areBarcodesCompatibleGo(bc1, bc2 string, minLength int) bool {
+// areBarcodesCompatibleGo tells whether the first minLength bases of two
+// barcodes differ by at most maxMismatch bases, N matching any base.
+func areBarcodesCompatibleGo(bc1, bc2 string, minLength int, maxMismatch int) bool {
 	if len(bc1) < minLength || len(bc2) < minLength {
 		return false
 	}
+	mismatches := 0
 	for i := 0; i < minLength; i++ {
 		char1 := bc1[i]
 		char2 := bc2[i]
 		if char1 != 'N' && char2 != 'N' && char1 != char2 {
-			return false
+			mismatches++
+			if mismatches > maxMismatch {
+				return false
+			}
 		}
 	}
 	return true
 }
 
+// runFlowcellsLanes returns the flow cells and lanes of the files of a run.
+func runFlowcellsLanes(files []processResult) (flowcells, lanes []string) {
+	for _, f := range files {
+		flowcells = append(flowcells, f.Flowcells...)
+		lanes = append(lanes, f.Lanes...)
+	}
+	slices.Sort(flowcells)
+	slices.Sort(lanes)
+	return slices.Compact(flowcells), slices.Compact(lanes)
+}
+
+// flowcellWarnings names the samples whose runs come from more than one flow
+// cell, and the runs whose files come from different flow cells or lanes.
+func flowcellWarnings(results []processResult) []string {
+	var warnings []string
+	var samples []string
+	sampleFlowcells := make(map[string][]string)
+	for start := 0; start < len(results); {
+		end := start + 1
+		for end < len(results) && results[end].Run == results[start].Run {
+			end++
+		}
+		sample := results[start].SampleName
+		if _, ok := sampleFlowcells[sample]; !ok {
+			samples = append(samples, sample)
+		}
+		flowcells, _ := runFlowcellsLanes(results[start:end])
+		sampleFlowcells[sample] = append(sampleFlowcells[sample], flowcells...)
+		for i := start + 1; i < end; i++ {
+			if !slices.Equal(results[i].Flowcells, results[start].Flowcells) || !slices.Equal(results[i].Lanes, results[start].Lanes) {
+				warnings = append(warnings, fmt.Sprintf("sample '%s': %s and %s of a run come from different flowcells or lanes",
+					sample, results[start].RelativePath, results[i].RelativePath))
+				break
+			}
+		}
+		start = end
+	}
+	for _, sample := range samples {
+		flowcells := sampleFlowcells[sample]
+		slices.Sort(flowcells)
+		flowcells = slices.Compact(flowcells)
+		if len(flowcells) > 1 {
+			warnings = append(warnings, fmt.Sprintf("sample '%s' spans flowcells %s", sample, strings.Join(flowcells, ", ")))
+		}
+	}
+	return warnings
+}
+
 // --- Grouping and Uniformity Check ---
+
+// isBarcodeError tells whether the barcode of a result is an error message.
+func isBarcodeError(barcode string) bool {
+	for msg := range errorMessages {
+		if strings.HasPrefix(barcode, msg) {
+			return true
+		}
+	}
+	return false
+}
+
 func groupBarcodes(results []processResult) map[string][]string {
 	groups := make(map[string][]string) // Use map for grouping by sample name
 	for _, res := range results {
-		isError := false
-		for msg := range errorMessages {
-			if strings.HasPrefix(res.Barcode, msg) {
-				isError = true
-				break
-			}
-		}
-		if !isError {
+		if !isBarcodeError(res.Barcode) {
 			groups[res.SampleName] = append(groups[res.SampleName], res.Barcode)
 		}
 	}
 	return groups
 }
 
-func checkGroupUniformityPrefix(barcodeGroups map[string][]string) map[string]bool {
-	isUniform := make(map[string]bool)
+// formatBarcodeCounts returns the count, fraction and index kit of the most
+// common of barcodes, followed by a line for each of the next ones up to top.
+func formatBarcodeCounts(barcodes []barcodeCount, top int, kits []IndexInfo, dimColor *color.Color) string {
+	total := 0
+	for _, bc := range barcodes {
+		total += bc.Count
+	}
+	var b strings.Builder
+	for i, bc := range barcodes[:min(top, len(barcodes))] {
+		if i > 0 {
+			b.WriteString("\n" + dimColor.Sprint(bc.Barcode))
+		}
+		b.WriteString(dimColor.Sprintf(" %d (%s)", bc.Count, percentOf(bc.Count, total)))
+		if kit := annotateBarcode(bc.Barcode, kits); kit != "" {
+			b.WriteString(dimColor.Sprint(" " + kit))
+		}
+	}
+	if len(barcodes) > top {
+		b.WriteString("\n" + dimColor.Sprintf("%d more", len(barcodes)-top))
+	}
+	return b.String()
+}
+
+// formatReadStats returns the records read of a file, in yellow if there were
+// fewer than recordsChecked, with their mean length and mean quality, in
+// yellow below Q30 and in red below Q20.
+func formatReadStats(res processResult, recordsChecked int, yellowColor, redColor *color.Color) string {
+	if res.Records == 0 {
+		return "-"
+	}
+	records := fmt.Sprint(res.Records)
+	if res.Records < recordsChecked {
+		records = yellowColor.Sprint(records)
+	}
+	quality := "no quality"
+	if res.MeanQuality > 0 {
+		quality = fmt.Sprintf("Q%.1f", res.MeanQuality)
+		switch {
+		case res.MeanQuality < 20:
+			quality = redColor.Sprint(quality)
+		case res.MeanQuality < 30:
+			quality = yellowColor.Sprint(quality)
+		}
+	}
+	return fmt.Sprintf("%s × %.0f bp\n%s", records, res.MeanLength, quality)
+}
+
+// indexNames names the indexes of a dual-index barcode like ACGT+TTGG.
+var indexNames = []string{"i7", "i5"}
+
+// indexName returns the name of the index at position i of a barcode.
+func indexName(i int) string {
+	if i < len(indexNames) {
+		return indexNames[i]
+	}
+	return fmt.Sprintf("index %d", i+1)
+}
+
+// checkGroupUniformityPrefix compares the indexes of the barcodes of each
+// sample separately, each up to the shortest length in the group, and returns
+// the positions of the indexes that differ in more than maxMismatch bases by
+// sample. Uniform samples have
+// none. An index missing from some barcodes is compared among the others.
+func checkGroupUniformityPrefix(barcodeGroups map[string][]string, maxMismatch int) map[string][]int {
+	nonUniform := make(map[string][]int)
 	for sample, barcodes := range barcodeGroups {
 		if len(barcodes) <= 1 {
-			isUniform[sample] = true
 			continue
 		}
-		shortestLen := math.MaxInt32
-		for _, bc := range barcodes {
-			if len(bc) < shortestLen {
-				shortestLen = len(bc)
-			}
+		indexes := make([][]string, len(barcodes))
+		numIndexes := 0
+		for i, bc := range barcodes {
+			indexes[i] = strings.Split(bc, "+")
+			numIndexes = max(numIndexes, len(indexes[i]))
 		}
-		if shortestLen == math.MaxInt32 || shortestLen == 0 {
-			isUniform[sample] = true
-			continue
-		}
-		referenceBarcode := barcodes[0]
-		allCompatible := true
-		for i := 1; i < len(barcodes); i++ {
-			if !areBarcodesCompatibleGo(referenceBarcode, barcodes[i], shortestLen) {
-				allCompatible = false
-				break
+		for k := range numIndexes {
+			var parts []string
+			for _, idx := range indexes {
+				if k < len(idx) {
+					parts = append(parts, idx[k])
+				}
+			}
+			shortestLen := math.MaxInt32
+			for _, part := range parts {
+				if len(part) < shortestLen {
+					shortestLen = len(part)
+				}
+			}
+			if len(parts) <= 1 || shortestLen == 0 {
+				continue
+			}
+			referenceIndex := parts[0]
+			for i := 1; i < len(parts); i++ {
+				if !areBarcodesCompatibleGo(referenceIndex, parts[i], shortestLen, maxMismatch) {
+					nonUniform[sample] = append(nonUniform[sample], k)
+					break
+				}
 			}
 		}
-		isUniform[sample] = allCompatible
 	}
-	return isUniform
+	return nonUniform
 }
 
 // --- Table Generation (Using SetAutoMerge, original order, re-enabled colors) ---
-func printResultsTableAqua(results []processResult, isGroupUniform map[string]bool, yamlBaseName string, recordsChecked int) {
+func printResultsTableAqua(results []processResult, reads []string, nonUniformIndexes map[string][]int, kits []IndexInfo, yamlBaseName string, recordsChecked int, top int, readStats bool) {
 	t := table.New(os.Stdout)
 	t.SetAutoMerge(true) // Enable AutoMerge
 
@@ -471,14 +1114,28 @@ func printResultsTableAqua(results []processResult, isGroupUniform map[string]bo
 	redColor := color.New(color.FgRed, color.Bold)
 	yellowColor := color.New(color.FgYellow)
 	greenColor := color.New(color.FgGreen)
+	dimColor := color.New(color.Faint)
+	headerColor := color.New(color.FgCyan, color.Bold)
 
-	// Create colored headers
-	header1 := color.New(color.FgCyan, color.Bold).Sprint("Sample")
-	header2 := color.New(color.FgCyan, color.Bold).Sprint("R1 File")
-	header3 := color.New(color.FgCyan, color.Bold).Sprintf("Most Common Barcode\n(first %d records)", recordsChecked)
+	// Create colored headers: a file and a barcode column per read
+	headers := []string{headerColor.Sprint("Sample"), headerColor.Sprint("Flowcell"), headerColor.Sprint("Lane")}
+	for _, read := range reads {
+		headers = append(headers, headerColor.Sprintf("%s File", read))
+		switch {
+		case top > 0:
+			headers = append(headers, headerColor.Sprintf("%s Top %d Barcodes\n(first %d records)", read, top, recordsChecked))
+		case len(reads) == 1:
+			headers = append(headers, headerColor.Sprintf("Most Common Barcode\n(first %d records)", recordsChecked))
+		default:
+			headers = append(headers, headerColor.Sprintf("%s Barcode", read))
+		}
+		if readStats {
+			headers = append(headers, headerColor.Sprintf("%s Reads", read))
+		}
+	}
 
 	// Set table properties
-	t.SetHeaders(header1, header2, header3)
+	t.SetHeaders(headers...)
 	t.SetHeaderStyle(table.StyleBold)
 	t.SetLineStyle(table.StyleBlue)
 	t.SetDividers(table.UnicodeRoundedDividers)
@@ -487,50 +1144,97 @@ func printResultsTableAqua(results []processResult, isGroupUniform map[string]bo
 	previousSampleNameForColor := ""
 	currentColorIndex := -1
 
-	// Iterate through results IN THE PRESERVED ORIGINAL ORDER
-	for _, row := range results {
-		currentSampleName := row.SampleName
-		displayR1 := row.RelativePath
-		displayBarcode := row.Barcode
+	// Iterate through the runs IN THE PRESERVED ORIGINAL ORDER, the files
+	// of a run being next to each other
+	for start := 0; start < len(results); {
+		end := start + 1
+		for end < len(results) && results[end].Run == results[start].Run {
+			end++
+		}
+		currentSampleName := results[start].SampleName
 
 		// --- Styling Logic ---
-		var activeColor *color.Color
-
-		// 1. R1 Color Cycling
+		// 1. File Color Cycling
 		if currentSampleName != previousSampleNameForColor {
 			currentColorIndex = (currentColorIndex + 1) % len(colorCycle)
 		}
-		activeColor = colorCycle[currentColorIndex]
-		styledR1 := activeColor.Sprint(displayR1)
+		activeColor := colorCycle[currentColorIndex]
+		nonUniform := nonUniformIndexes[currentSampleName] // Lookup uniformity for the group
 
-		// 2. Barcode Highlighting
-		styledBarcode := ""
-		isUniform := isGroupUniform[currentSampleName] // Lookup uniformity for the group
-		isError := false
-		for msg := range errorMessages {
-			if strings.HasPrefix(displayBarcode, msg) {
-				isError = true
-				break
+		flowcells, lanes := runFlowcellsLanes(results[start:end])
+		row := []string{currentSampleName, strings.Join(flowcells, ","), strings.Join(lanes, ",")} // PLAIN sample name for AutoMerge logic to work correctly
+		for _, read := range reads {
+			var result *processResult
+			for i := start; i < end; i++ {
+				if results[i].Read == read {
+					result = &results[i]
+				}
 			}
-		}
+			if result == nil {
+				row = append(row, "-", "-")
+				if readStats {
+					row = append(row, "-")
+				}
+				continue
+			}
+			displayBarcode := result.Barcode
 
-		if isError {
-			styledBarcode = yellowColor.Sprint(displayBarcode)
-		} else if !isUniform {
-			styledBarcode = redColor.Sprint(displayBarcode) // Style red if group not uniform
-		} else {
-			styledBarcode = greenColor.Sprint(displayBarcode)
-		} // Style green if uniform
+			// 2. Barcode Highlighting
+			styledBarcode := ""
+			isError := isBarcodeError(displayBarcode)
+
+			if isError {
+				styledBarcode = yellowColor.Sprint(displayBarcode)
+			} else {
+				// Style the indexes not uniform in the group red, the others green
+				indexes := strings.Split(displayBarcode, "+")
+				for k, index := range indexes {
+					if slices.Contains(nonUniform, k) {
+						indexes[k] = redColor.Sprint(index)
+					} else {
+						indexes[k] = greenColor.Sprint(index)
+					}
+				}
+				styledBarcode = strings.Join(indexes, "+")
+			}
+			if top > 0 {
+				styledBarcode += formatBarcodeCounts(result.Barcodes, top, kits, dimColor)
+			} else if kit := annotateBarcode(displayBarcode, kits); kit != "" && !isError {
+				styledBarcode += "\n" + dimColor.Sprint(kit)
+			}
+			if result.Problem != "" {
+				styledBarcode += "\n" + redColor.Sprint(result.Problem)
+			}
+			row = append(row, activeColor.Sprint(result.RelativePath), styledBarcode)
+			if readStats {
+				row = append(row, formatReadStats(*result, recordsChecked, yellowColor, redColor))
+			}
+		}
 
 		// --- Add Row Data ---
-		// Pass PLAIN sample name for AutoMerge logic to work correctly.
-		t.AddRow(currentSampleName, styledR1, styledBarcode)
+		t.AddRow(row...)
 
 		// Update tracker for the next iteration's color cycling check
 		previousSampleNameForColor = currentSampleName
+		start = end
 	}
 
 	fmt.Println()                               // Newline before table
 	t.Render()                                  // Print the table
 	fmt.Println("Processed on " + yamlBaseName) // Print caption separately
+
+	// Name the indexes that differ, in the order of the samples
+	reported := make(map[string]bool)
+	for _, res := range results {
+		nonUniform := nonUniformIndexes[res.SampleName]
+		if len(nonUniform) == 0 || reported[res.SampleName] {
+			continue
+		}
+		reported[res.SampleName] = true
+		names := make([]string, len(nonUniform))
+		for i, k := range nonUniform {
+			names[i] = indexName(k)
+		}
+		redColor.Printf("Sample '%s': %s not uniform\n", res.SampleName, strings.Join(names, " and "))
+	}
 }
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderReadNumber(t *testing.T) {
+	assert.Equal(t, "1", headerReadNumber("@LH00543:300:22WVMHLT3:1:1101:29290:1064 1:N:0:ATCACG"))
+	assert.Equal(t, "2", headerReadNumber("@read 2:N:0:ATCACG+GGTTAA"))
+	assert.Equal(t, "", headerReadNumber("@read/1"))
+	assert.Equal(t, "", headerReadNumber("@read comment"))
+}
+
+func TestReadProblem(t *testing.T) {
+	tests := []struct {
+		read string
+		scan barcodeScan
+		want string
+	}{
+		{"R1", barcodeScan{Barcode: "ACGT", ReadNumber: "1"}, ""},
+		{"R1", barcodeScan{Barcode: "ACGT", ReadNumber: "2"}, "headers say read 2, swapped?"},
+		{"R2", barcodeScan{Barcode: "ACGT", ReadNumber: "1"}, "headers say read 1, swapped?"},
+		{"R2", barcodeScan{Barcode: "ACGT"}, ""},
+		{"I1", barcodeScan{Barcode: "ACGT+GGCC", Sequence: "ACGT"}, ""},
+		{"I1", barcodeScan{Barcode: "ACGT+GGCC", Sequence: "ACNT"}, ""},
+		{"I2", barcodeScan{Barcode: "ACGT+GGCC", Sequence: "GGCC"}, ""},
+		{"I1", barcodeScan{Barcode: "ACGT+GGCC", Sequence: "GGCC"}, "reads GGCC, not the header index"},
+		{"I2", barcodeScan{Barcode: "ACGT", Sequence: "GGCC"}, ""},
+		{"I1", barcodeScan{Barcode: "File Not Found"}, ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, readProblem(tt.read, tt.scan, 0), "%s %+v", tt.read, tt.scan)
+	}
+}
+
+func TestCheckGroupUniformityPrefix(t *testing.T) {
+	nonUniform := checkGroupUniformityPrefix(map[string][]string{
+		"single":   {"ACGTAA", "ACGT"},
+		"wildcard": {"ACNT+GGCC", "ACGT+GGCN"},
+		"i5":       {"ACGT+GGCC", "ACGT+TTAA"},
+		"both":     {"ACGT+GGCC", "TTGT+GGAA", "ACGT"},
+		"mixed":    {"ACGT+GGCC", "ACGT"},
+		"alone":    {"ACGT"},
+	}, 0)
+	assert.Equal(t, map[string][]int{"i5": {1}, "both": {0, 1}}, nonUniform)
+	assert.Equal(t, "i5", indexName(1))
+	assert.Equal(t, "index 3", indexName(2))
+}
+
+func TestCountBarcodes(t *testing.T) {
+	counts := countBarcodes([]string{"AAAA", "CCCC", "CCCC", "GGGG", "AAAA", "CCCC"})
+	assert.Equal(t, []barcodeCount{{"CCCC", 3}, {"AAAA", 2}, {"GGGG", 1}}, counts)
+	assert.Equal(t, mostCommon([]string{"AAAA", "CCCC"}), countBarcodes([]string{"AAAA", "CCCC"})[0].Barcode)
+	assert.Nil(t, countBarcodes(nil))
+}
+
+func TestGatherFastqFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"A_S1_L001_R1_001.fq.gz", "A_S1_L001_R2_001.fq.gz", "A_S1_L002_R1_001.fq.gz", "B-x_S2_R1_001.fq.gz", "notes.txt"} {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+	assert.True(t, isYAMLPath("samples.YML"))
+	assert.False(t, isYAMLPath("A_R1.fq.gz"))
+
+	paths, err := expandFastqArgs([]string{filepath.Join(dir, "*_R1_*"), filepath.Join(dir, "A_S1_L001_R2_001.fq.gz")})
+	assert.NoError(t, err)
+	assert.Len(t, paths, 4)
+	all, err := expandFastqArgs([]string{dir})
+	assert.NoError(t, err)
+	assert.Len(t, all, 4)
+	_, err = expandFastqArgs([]string{filepath.Join(dir, "*.bam")})
+	assert.Error(t, err)
+
+	files := gatherFastqFiles(paths, nil, []string{"R1", "R2"})
+	var got []string
+	for _, f := range files {
+		got = append(got, fmt.Sprintf("%s %d %s %s", f.SampleName, f.Run, f.Read, filepath.Base(f.RelativePath)))
+	}
+	assert.Equal(t, []string{
+		"A 0 R1 A_S1_L001_R1_001.fq.gz",
+		"A 0 R2 A_S1_L001_R2_001.fq.gz",
+		"A 1 R1 A_S1_L002_R1_001.fq.gz",
+		"B-x 2 R1 B-x_S2_R1_001.fq.gz",
+	}, got)
+
+	files = gatherFastqFiles(paths, regexp.MustCompile(`^[^_-]+`), []string{"R1"})
+	assert.Len(t, files, 3)
+	assert.Equal(t, "B", files[2].SampleName)
+}
+
+func TestBarcodeMismatch(t *testing.T) {
+	assert.True(t, areBarcodesCompatibleGo("ACGTAC", "ACGTTC", 6, 1))
+	assert.False(t, areBarcodesCompatibleGo("ACGTAC", "ACGTTC", 6, 0))
+	assert.False(t, areBarcodesCompatibleGo("ACGTAC", "TCGTTC", 6, 1))
+	assert.True(t, areBarcodesCompatibleGo("ACNTAC", "TCGTTC", 6, 2))
+
+	groups := map[string][]string{"a": {"ACGTAC+GGCC", "ACGTTC+GGCC", "ACGTAC+GGCA"}}
+	assert.Equal(t, map[string][]int{"a": {0, 1}}, checkGroupUniformityPrefix(groups, 0))
+	assert.Empty(t, checkGroupUniformityPrefix(groups, 1))
+	assert.Equal(t, "", readProblem("I1", barcodeScan{Barcode: "ACGTAC", Sequence: "ACGTTC"}, 1))
+}
+
+func TestGatherFilePathsGenericOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "samples.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`samples:
+  zeta:
+    - {R1: z1.fq}
+  alpha:
+    data:
+      - {R1: a1.fq, R2: a2.fq}
+  mid: [{R1: m1.fq}]
+  zeta:
+    - {R1: z2.fq}
+`), 0o644))
+	doc, err := readYamlConfigGeneric(path)
+	assert.NoError(t, err)
+	files, err := gatherFilePathsGeneric(doc, path, "samples", []string{"R1", "R2"})
+	assert.NoError(t, err)
+	var got []string
+	for _, f := range files {
+		got = append(got, fmt.Sprintf("%s %d %s", f.SampleName, f.Run, f.RelativePath))
+	}
+	assert.Equal(t, []string{"zeta 0 z1.fq", "zeta 1 z2.fq", "alpha 2 a1.fq", "alpha 2 a2.fq", "mid 3 m1.fq"}, got)
+
+	_, err = gatherFilePathsGeneric(doc, path, "other", []string{"R1"})
+	assert.Error(t, err)
+}
+
+func TestFlowcellWarnings(t *testing.T) {
+	results := []processResult{
+		{SampleName: "a", Run: 0, RelativePath: "a_R1.fq", Flowcells: []string{"FC1"}, Lanes: []string{"1"}},
+		{SampleName: "a", Run: 0, RelativePath: "a_R2.fq", Flowcells: []string{"FC1"}, Lanes: []string{"1"}},
+		{SampleName: "a", Run: 1, RelativePath: "a2_R1.fq", Flowcells: []string{"FC1"}, Lanes: []string{"2"}},
+		{SampleName: "b", Run: 2, RelativePath: "b_R1.fq", Flowcells: []string{"FC1"}, Lanes: []string{"1"}},
+		{SampleName: "b", Run: 2, RelativePath: "b_R2.fq", Flowcells: []string{"FC2"}, Lanes: []string{"1"}},
+	}
+	assert.Equal(t, []string{
+		"sample 'b': b_R1.fq and b_R2.fq of a run come from different flowcells or lanes",
+		"sample 'b' spans flowcells FC1, FC2",
+	}, flowcellWarnings(results))
+
+	flowcells, lanes := runFlowcellsLanes(results[3:])
+	assert.Equal(t, []string{"FC1", "FC2"}, flowcells)
+	assert.Equal(t, []string{"1"}, lanes)
+}
+
+func TestScanFastqSpread(t *testing.T) {
+	barcodeRegex = regexp.MustCompile(`^[ACGTN+]+$`)
+	var fastq strings.Builder
+	for i := range 100 {
+		barcode := "AAAAAA"
+		if i >= 50 {
+			barcode = "CCCCCC"
+		}
+		fmt.Fprintf(&fastq, "@M00001:1:FC1:1:1:%d:1 1:N:0:%s\nACGT\n+\nIIII\n", i, barcode)
+	}
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "reads.fq")
+	assert.NoError(t, os.WriteFile(plain, []byte(fastq.String()), 0o644))
+	gzipped := filepath.Join(dir, "reads.fq.gz")
+	f, err := os.Create(gzipped)
+	assert.NoError(t, err)
+	gz := gzip.NewWriter(f)
+	_, err = gz.Write([]byte(fastq.String()))
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+	assert.NoError(t, f.Close())
+
+	for _, path := range []string{plain, gzipped} {
+		assert.Equal(t, []barcodeCount{{"AAAAAA", 10}}, scanFastqGo(path, 10, 1).Barcodes, path)
+	}
+	// Two records from each fifth of the file.
+	assert.Equal(t, []barcodeCount{{"AAAAAA", 6}, {"CCCCCC", 4}}, scanFastqGo(plain, 10, 5).Barcodes)
+	// Every 10th record.
+	assert.Equal(t, []barcodeCount{{"AAAAAA", 5}, {"CCCCCC", 5}}, scanFastqGo(gzipped, 10, 10).Barcodes)
+	// No record is read twice when the file is smaller than asked for.
+	scan := scanFastqGo(plain, 1000, 4)
+	assert.Equal(t, []barcodeCount{{"AAAAAA", 50}, {"CCCCCC", 50}}, scan.Barcodes)
+	assert.Equal(t, []string{"FC1"}, scan.Flowcells)
+}
+
+func TestScanFastqReadStats(t *testing.T) {
+	barcodeRegex = regexp.MustCompile(`^[ACGTN+]+$`)
+	path := filepath.Join(t.TempDir(), "reads.fq")
+	fastq := "@r1 1:N:0:ACGT\nACGTAC\n+\nIIIIII\n@r2 1:N:0:ACGT\nAC\n+\n55\n@r3\nACGT\n+\n####\n"
+	assert.NoError(t, os.WriteFile(path, []byte(fastq), 0o644))
+	scan := scanFastqGo(path, 10, 1)
+	assert.Equal(t, 3, scan.Records)
+	assert.Equal(t, 4.0, scan.MeanLength)
+	assert.Equal(t, 24.0, scan.MeanQuality) // (6*40 + 2*20 + 4*2) / 12
+
+	noColor := color.New()
+	assert.Equal(t, "3 × 4 bp\nQ25.0", formatReadStats(processResult{Records: 3, MeanLength: 4, MeanQuality: 25}, 3, noColor, noColor))
+	assert.Equal(t, "-", formatReadStats(processResult{Barcode: "File Not Found"}, 3, noColor, noColor))
+}
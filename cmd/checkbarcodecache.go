@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// barcodeCache keeps the barcode scans of files between runs of checkbarcode,
+// so that files that did not change are not read again.
+type barcodeCache struct {
+	path    string // "" if there is no cache directory
+	mu      sync.Mutex
+	entries map[string]cachedScan // By absolute path
+	changed bool
+	refresh bool // Lookups miss, as with --no-cache, but scans are still stored
+}
+
+// barcodeCacheVersion is the version of cachedScan. Entries of other versions
+// are read again.
+const barcodeCacheVersion = 2
+
+// cachedScan is the scan of a file as it was when it was read.
+type cachedScan struct {
+	Version int         `json:"version"`
+	Size    int64       `json:"size"`
+	ModTime time.Time   `json:"mtime"`
+	Records int         `json:"records"`
+	Spread  int         `json:"spread"`
+	Scan    barcodeScan `json:"scan"`
+}
+
+// barcodeCachePath is checkbarcode.json in the hey directory of the user
+// cache directory (~/.cache/hey on Linux), or "".
+func barcodeCachePath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "hey", "checkbarcode.json")
+}
+
+// loadBarcodeCache reads the cache at path. A missing or unreadable cache is
+// an empty one; it is rewritten by save.
+func loadBarcodeCache(path string) *barcodeCache {
+	c := &barcodeCache{path: path, entries: make(map[string]cachedScan)}
+	if path == "" {
+		return c
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil || c.entries == nil {
+		c.entries = make(map[string]cachedScan)
+	}
+	return c
+}
+
+// lookup returns the cached scan of the file at absPath if the file still has
+// the same size and modification time and was scanned the same way. It also
+// returns the file info to store a new scan with.
+func (c *barcodeCache) lookup(absPath string, records, spread int) (barcodeScan, os.FileInfo, bool) {
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return barcodeScan{}, nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[absPath]
+	if !ok || c.refresh || entry.Version != barcodeCacheVersion || entry.Size != info.Size() || !entry.ModTime.Equal(info.ModTime()) || entry.Records != records || entry.Spread != spread {
+		return barcodeScan{}, info, false
+	}
+	return entry.Scan, info, true
+}
+
+// store caches the scan of the file at absPath as it was described by info.
+// Scans that failed are not cached.
+func (c *barcodeCache) store(absPath string, info os.FileInfo, records, spread int, scan barcodeScan) {
+	if info == nil || isBarcodeError(scan.Barcode) {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[absPath] = cachedScan{Version: barcodeCacheVersion, Size: info.Size(), ModTime: info.ModTime(), Records: records, Spread: spread, Scan: scan}
+	c.changed = true
+}
+
+// save writes the cache if it changed, through a temporary file so that
+// concurrent runs do not leave a partial one.
+func (c *barcodeCache) save() error {
+	if c.path == "" || !c.changed {
+		return nil
+	}
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), "checkbarcode-*.json")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path)
+	}
+	if err != nil {
+		return errors.Join(err, os.Remove(tmp.Name()))
+	}
+	return nil
+}
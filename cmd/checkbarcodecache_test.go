@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBarcodeCache(t *testing.T) {
+	dir := t.TempDir()
+	fastq := filepath.Join(dir, "a_R1.fq")
+	require.NoError(t, os.WriteFile(fastq, []byte("@r 1:N:0:ACGT\nA\n+\nI\n"), 0o644))
+	cachePath := filepath.Join(dir, "cache", "checkbarcode.json")
+	scan := barcodeScan{Barcode: "ACGT", Barcodes: []barcodeCount{{"ACGT", 1}}, ReadNumber: "1"}
+
+	cache := loadBarcodeCache(cachePath)
+	_, info, ok := cache.lookup(fastq, 1000, 1)
+	assert.False(t, ok)
+	cache.store(fastq, info, 1000, 1, scan)
+	cache.store(filepath.Join(dir, "missing.fq"), nil, 1000, 1, barcodeScan{Barcode: "File Not Found"})
+	require.NoError(t, cache.save())
+
+	cache = loadBarcodeCache(cachePath)
+	got, _, ok := cache.lookup(fastq, 1000, 1)
+	assert.True(t, ok)
+	assert.Equal(t, scan, got)
+	assert.Len(t, cache.entries, 1)
+
+	// Scanned differently, or refreshed with --no-cache
+	_, _, ok = cache.lookup(fastq, 500, 1)
+	assert.False(t, ok)
+	_, _, ok = cache.lookup(fastq, 1000, 4)
+	assert.False(t, ok)
+	cache.refresh = true
+	_, _, ok = cache.lookup(fastq, 1000, 1)
+	assert.False(t, ok)
+	cache.refresh = false
+
+	// Changed since
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(fastq, later, later))
+	_, _, ok = cache.lookup(fastq, 1000, 1)
+	assert.False(t, ok)
+
+	// A corrupt cache is an empty one
+	require.NoError(t, os.WriteFile(cachePath, []byte("{"), 0o644))
+	assert.Empty(t, loadBarcodeCache(cachePath).entries)
+}
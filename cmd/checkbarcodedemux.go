@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aquasecurity/table"
+	"github.com/fatih/color"
+)
+
+// expectedBarcode is a barcode the pool should contain, given with
+// --expected or --expected-file.
+type expectedBarcode struct {
+	Sample  string // "" if not named
+	Barcode string // i7 or i7+i5
+}
+
+// demuxShare is how many of the records checked would be demultiplexed to a
+// sample, or to Undetermined.
+type demuxShare struct {
+	Sample   string   `json:"sample"`
+	Barcodes []string `json:"barcodes,omitempty"`
+	Count    int      `json:"count"`
+	Fraction float64  `json:"fraction"`
+}
+
+// parseExpectedBarcode parses a barcode like ACGT+TTGG, optionally named as
+// in sample=ACGT+TTGG.
+func parseExpectedBarcode(value string) (expectedBarcode, error) {
+	sample, barcode, named := strings.Cut(value, "=")
+	if !named {
+		sample, barcode = "", value
+	}
+	barcode = strings.ToUpper(strings.TrimSpace(barcode))
+	if !indexSeqRegex.MatchString(barcode) {
+		return expectedBarcode{}, fmt.Errorf("invalid expected barcode %q, use i7 or i7+i5 bases", value)
+	}
+	return expectedBarcode{Sample: strings.TrimSpace(sample), Barcode: barcode}, nil
+}
+
+// readExpectedBarcodes reads a file of expected barcodes, one per line as
+// "barcode", "sample barcode" or "sample i7 i5", separated by spaces, tabs or
+// commas. Empty lines and lines starting with # are skipped.
+func readExpectedBarcodes(path string) ([]expectedBarcode, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var expected []expectedBarcode
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		var value string
+		switch len(fields) {
+		case 1:
+			value = fields[0]
+		case 2:
+			value = fields[0] + "=" + fields[1]
+		case 3:
+			value = fields[0] + "=" + fields[1] + "+" + fields[2]
+		default:
+			return nil, fmt.Errorf("%s:%d: expected a barcode, sample and barcode, or sample, i7 and i5", path, line)
+		}
+		e, err := parseExpectedBarcode(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		expected = append(expected, e)
+	}
+	return expected, scanner.Err()
+}
+
+// loadExpectedBarcodes returns the barcodes of --expected followed by those
+// of --expected-file, if given.
+func loadExpectedBarcodes(values []string, path string) ([]expectedBarcode, error) {
+	var expected []expectedBarcode
+	for _, value := range values {
+		e, err := parseExpectedBarcode(value)
+		if err != nil {
+			return nil, err
+		}
+		expected = append(expected, e)
+	}
+	if path != "" {
+		fromFile, err := readExpectedBarcodes(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading expected barcodes: %w", err)
+		}
+		expected = append(expected, fromFile...)
+	}
+	return expected, nil
+}
+
+// matchExpected returns the mismatches between a barcode read and an expected
+// one, and whether each index is within maxMismatch. Only the indexes of the
+// expected barcode are compared, up to their length; N matches any base.
+func matchExpected(barcode, expected string, maxMismatch int) (int, bool) {
+	indexes := strings.Split(barcode, "+")
+	total := 0
+	for i, index := range strings.Split(expected, "+") {
+		if i >= len(indexes) || len(indexes[i]) < len(index) {
+			return 0, false
+		}
+		mismatches := 0
+		for j := range len(index) {
+			if a, b := indexes[i][j], index[j]; a != 'N' && b != 'N' && a != b {
+				mismatches++
+			}
+		}
+		if mismatches > maxMismatch {
+			return 0, false
+		}
+		total += mismatches
+	}
+	return total, true
+}
+
+// assignBarcode returns the position of the expected barcode closest to
+// barcode within maxMismatch, or -1 if none is, or two are equally close.
+func assignBarcode(barcode string, expected []expectedBarcode, maxMismatch int) int {
+	best, bestMismatches, tie := -1, 0, false
+	for i, e := range expected {
+		mismatches, ok := matchExpected(barcode, e.Barcode, maxMismatch)
+		switch {
+		case !ok:
+		case best < 0 || mismatches < bestMismatches:
+			best, bestMismatches, tie = i, mismatches, false
+		case mismatches == bestMismatches:
+			tie = true
+		}
+	}
+	if tie {
+		return -1
+	}
+	return best
+}
+
+// demuxPreview counts the records checked of each run that each sample of
+// expected would get, by the barcodes of their headers, the records of a run
+// being counted once whatever --reads is. Expected barcodes without a sample
+// name are samples of their own. Undetermined records come last.
+func demuxPreview(results []processResult, expected []expectedBarcode, maxMismatch int) []demuxShare {
+	var shares []demuxShare
+	sampleShare := make(map[string]int)
+	owners := make([]int, len(expected))
+	for i, e := range expected {
+		name := e.Sample
+		if name == "" {
+			name = e.Barcode
+		}
+		j, ok := sampleShare[name]
+		if !ok {
+			j = len(shares)
+			sampleShare[name] = j
+			shares = append(shares, demuxShare{Sample: name})
+		}
+		shares[j].Barcodes = append(shares[j].Barcodes, e.Barcode)
+		owners[i] = j
+	}
+	undetermined := demuxShare{Sample: "Undetermined"}
+
+	counted := make(map[int]bool) // Runs
+	total := 0
+	for _, res := range results {
+		if isBarcodeError(res.Barcode) || counted[res.Run] {
+			continue
+		}
+		counted[res.Run] = true
+		for _, bc := range res.Barcodes {
+			total += bc.Count
+			if i := assignBarcode(bc.Barcode, expected, maxMismatch); i >= 0 {
+				shares[owners[i]].Count += bc.Count
+			} else {
+				undetermined.Count += bc.Count
+			}
+		}
+	}
+	shares = append(shares, undetermined)
+	if total > 0 {
+		for i := range shares {
+			shares[i].Fraction = float64(shares[i].Count) / float64(total)
+		}
+	}
+	return shares
+}
+
+// printDemuxPreview prints the shares of the samples, in yellow if a sample
+// gets less than half of an even share of the pool and in red if it gets
+// nothing.
+func printDemuxPreview(shares []demuxShare) {
+	t := table.New(os.Stdout)
+	headerColor := color.New(color.FgCyan, color.Bold)
+	redColor := color.New(color.FgRed, color.Bold)
+	yellowColor := color.New(color.FgYellow)
+	greenColor := color.New(color.FgGreen)
+	dimColor := color.New(color.Faint)
+
+	t.SetHeaders(headerColor.Sprint("Expected Sample"), headerColor.Sprint("Barcodes"), headerColor.Sprint("Records"), headerColor.Sprint("Share"))
+	t.SetHeaderStyle(table.StyleBold)
+	t.SetLineStyle(table.StyleBlue)
+	t.SetDividers(table.UnicodeRoundedDividers)
+
+	samples := len(shares) - 1 // Without Undetermined
+	total := 0
+	for _, s := range shares {
+		total += s.Count
+	}
+	for i, s := range shares {
+		share := percentOf(s.Count, total)
+		switch {
+		case i == samples:
+			share = dimColor.Sprint(share)
+		case s.Count == 0:
+			share = redColor.Sprint(share)
+		case s.Fraction < 0.5/float64(samples):
+			share = yellowColor.Sprint(share)
+		default:
+			share = greenColor.Sprint(share)
+		}
+		t.AddRow(s.Sample, strings.Join(s.Barcodes, "\n"), fmt.Sprint(s.Count), share)
+	}
+
+	fmt.Println()
+	t.Render()
+	fmt.Println("Demultiplexing preview of the records checked")
+}
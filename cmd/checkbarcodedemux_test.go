@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadExpectedBarcodes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "expected.csv")
+	require.NoError(t, os.WriteFile(path, []byte("# pool 1\nb,GGTT\nc\tACGT\tTTAA\n\nccgg\n"), 0o644))
+	expected, err := loadExpectedBarcodes([]string{"a=ATCACG+ggttaa"}, path)
+	require.NoError(t, err)
+	assert.Equal(t, []expectedBarcode{
+		{"a", "ATCACG+GGTTAA"}, {"b", "GGTT"}, {"c", "ACGT+TTAA"}, {"", "CCGG"},
+	}, expected)
+
+	_, err = loadExpectedBarcodes([]string{"a=AC-GT"}, "")
+	assert.Error(t, err)
+	require.NoError(t, os.WriteFile(path, []byte("a b c d\n"), 0o644))
+	_, err = loadExpectedBarcodes(nil, path)
+	assert.ErrorContains(t, err, "expected.csv:1")
+}
+
+func TestAssignBarcode(t *testing.T) {
+	expected := []expectedBarcode{{"a", "ACGT+TTGG"}, {"b", "ACGA+TTGG"}, {"c", "GGGG"}}
+	assert.Equal(t, 0, assignBarcode("ACGT+TTGG", expected, 1))
+	assert.Equal(t, 0, assignBarcode("ACGTNN+TTGGAA", expected, 0))
+	assert.Equal(t, 2, assignBarcode("GGGC+AAAA", expected, 1))
+	assert.Equal(t, -1, assignBarcode("ACGC+TTGG", expected, 1)) // As close to a as to b
+	assert.Equal(t, -1, assignBarcode("ACGT", expected[:2], 1))  // No i5
+	assert.Equal(t, -1, assignBarcode("TTTT", expected, 1))
+}
+
+func TestDemuxPreview(t *testing.T) {
+	results := []processResult{
+		{SampleName: "a", Run: 0, Read: "R1", Barcode: "ACGT", Barcodes: []barcodeCount{{"ACGT", 6}, {"GGGG", 1}, {"TTTT", 1}}},
+		{SampleName: "a", Run: 0, Read: "R2", Barcode: "ACGT", Barcodes: []barcodeCount{{"ACGT", 8}}},
+		{SampleName: "b", Run: 1, Read: "R1", Barcode: "File Not Found"},
+		{SampleName: "b", Run: 1, Read: "R2", Barcode: "GGGG", Barcodes: []barcodeCount{{"GGGG", 2}}},
+	}
+	shares := demuxPreview(results, []expectedBarcode{{"a", "ACGT"}, {"", "GGGG"}, {"c", "CCCC"}}, 0)
+	assert.Equal(t, []demuxShare{
+		{Sample: "a", Barcodes: []string{"ACGT"}, Count: 6, Fraction: 0.6},
+		{Sample: "GGGG", Barcodes: []string{"GGGG"}, Count: 3, Fraction: 0.3},
+		{Sample: "c", Barcodes: []string{"CCCC"}, Count: 0, Fraction: 0},
+		{Sample: "Undetermined", Count: 1, Fraction: 0.1},
+	}, shares)
+}
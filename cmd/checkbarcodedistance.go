@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aquasecurity/table"
+	"github.com/fatih/color"
+)
+
+// sampleBarcode is a dominant barcode found in the files of a sample.
+type sampleBarcode struct {
+	Sample  string `json:"sample"`
+	Barcode string `json:"barcode"`
+}
+
+// barcodePair is the distance between the barcodes of two samples.
+type barcodePair struct {
+	A        sampleBarcode `json:"a"`
+	B        sampleBarcode `json:"b"`
+	Distance int           `json:"distance"`
+}
+
+// sampleBarcodes returns the distinct dominant barcodes of each sample, in the
+// order of the results.
+func sampleBarcodes(results []processResult) []sampleBarcode {
+	var barcodes []sampleBarcode
+	seen := make(map[sampleBarcode]bool)
+	for _, res := range results {
+		sb := sampleBarcode{Sample: res.SampleName, Barcode: res.Barcode}
+		if isBarcodeError(res.Barcode) || seen[sb] {
+			continue
+		}
+		seen[sb] = true
+		barcodes = append(barcodes, sb)
+	}
+	return barcodes
+}
+
+// barcodeDistance returns the Hamming distance between two barcodes, summed
+// over their indexes, each compared up to the shorter length. N matches any
+// base, and an index missing from one barcode is not compared.
+func barcodeDistance(a, b string) int {
+	indexesA, indexesB := strings.Split(a, "+"), strings.Split(b, "+")
+	distance := 0
+	for i := range min(len(indexesA), len(indexesB)) {
+		x, y := indexesA[i], indexesB[i]
+		for j := range min(len(x), len(y)) {
+			if x[j] != 'N' && y[j] != 'N' && x[j] != y[j] {
+				distance++
+			}
+		}
+	}
+	return distance
+}
+
+// closeBarcodePairs returns the pairs of barcodes of different samples that
+// are less than minDistance apart, closest first.
+func closeBarcodePairs(barcodes []sampleBarcode, minDistance int) []barcodePair {
+	var pairs []barcodePair
+	for i, a := range barcodes {
+		for _, b := range barcodes[i+1:] {
+			if a.Sample == b.Sample {
+				continue
+			}
+			if d := barcodeDistance(a.Barcode, b.Barcode); d < minDistance {
+				pairs = append(pairs, barcodePair{A: a, B: b, Distance: d})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].Distance < pairs[j].Distance })
+	return pairs
+}
+
+// printDistanceMatrix prints the distances between the barcodes, numbered in
+// the columns, with those of different samples below minDistance in red and
+// those within a sample dimmed, then lists the close pairs.
+func printDistanceMatrix(barcodes []sampleBarcode, minDistance int) {
+	t := table.New(os.Stdout)
+	headerColor := color.New(color.FgCyan, color.Bold)
+	redColor := color.New(color.FgRed, color.Bold)
+	greenColor := color.New(color.FgGreen)
+	dimColor := color.New(color.Faint)
+
+	headers := []string{headerColor.Sprint("#"), headerColor.Sprint("Sample"), headerColor.Sprint("Barcode")}
+	for i := range barcodes {
+		headers = append(headers, headerColor.Sprint(i+1))
+	}
+	t.SetHeaders(headers...)
+	t.SetHeaderStyle(table.StyleBold)
+	t.SetLineStyle(table.StyleBlue)
+	t.SetDividers(table.UnicodeRoundedDividers)
+
+	for i, a := range barcodes {
+		row := []string{fmt.Sprint(i + 1), a.Sample, a.Barcode}
+		for j, b := range barcodes {
+			d := barcodeDistance(a.Barcode, b.Barcode)
+			switch {
+			case i == j:
+				row = append(row, dimColor.Sprint("-"))
+			case a.Sample == b.Sample:
+				row = append(row, dimColor.Sprint(d))
+			case d < minDistance:
+				row = append(row, redColor.Sprint(d))
+			default:
+				row = append(row, greenColor.Sprint(d))
+			}
+		}
+		t.AddRow(row...)
+	}
+
+	fmt.Println()
+	t.Render()
+	fmt.Printf("Hamming distances between the dominant barcodes (safe from %d)\n", minDistance)
+	for _, p := range closeBarcodePairs(barcodes, minDistance) {
+		redColor.Printf("Samples '%s' (%s) and '%s' (%s) are %d apart\n", p.A.Sample, p.A.Barcode, p.B.Sample, p.B.Barcode, p.Distance)
+	}
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBarcodeDistance(t *testing.T) {
+	assert.Equal(t, 0, barcodeDistance("ACGT", "ACGT"))
+	assert.Equal(t, 2, barcodeDistance("ACGT", "TCGA"))
+	assert.Equal(t, 0, barcodeDistance("ACGN", "ACGT"))
+	assert.Equal(t, 1, barcodeDistance("ACGTAA", "ACGA")) // Up to the shorter
+	assert.Equal(t, 3, barcodeDistance("ACGT+TTGG", "ACGA+TTCC"))
+	assert.Equal(t, 1, barcodeDistance("ACGT+TTGG", "ACGA")) // i5 not compared
+}
+
+func TestCloseBarcodePairs(t *testing.T) {
+	results := []processResult{
+		{SampleName: "a", Barcode: "AAAAAA"},
+		{SampleName: "a", Barcode: "AAAAAT"},
+		{SampleName: "b", Barcode: "AAAATT"},
+		{SampleName: "c", Barcode: "CCCCCC"},
+		{SampleName: "d", Barcode: "File Not Found"},
+		{SampleName: "e", Barcode: "AAAAAT"},
+	}
+	barcodes := sampleBarcodes(results)
+	assert.Len(t, barcodes, 5)
+	assert.Equal(t, []barcodePair{
+		{A: sampleBarcode{"a", "AAAAAT"}, B: sampleBarcode{"e", "AAAAAT"}, Distance: 0},
+		{A: sampleBarcode{"a", "AAAAAA"}, B: sampleBarcode{"e", "AAAAAT"}, Distance: 1},
+		{A: sampleBarcode{"a", "AAAAAT"}, B: sampleBarcode{"b", "AAAATT"}, Distance: 1},
+		{A: sampleBarcode{"b", "AAAATT"}, B: sampleBarcode{"e", "AAAAAT"}, Distance: 1},
+		{A: sampleBarcode{"a", "AAAAAA"}, B: sampleBarcode{"b", "AAAATT"}, Distance: 2},
+	}, closeBarcodePairs(barcodes, 3))
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// fastqSource is where checkbarcode reads a FASTQ file from: a local file or
+// a URL.
+type fastqSource interface {
+	// size returns the size of the file if it can be read from any offset,
+	// or -1.
+	size() (int64, error)
+	// openAt returns the file from offset on.
+	openAt(offset int64) (io.ReadCloser, error)
+}
+
+// localFastq is a FASTQ file on disk.
+type localFastq string
+
+func (f localFastq) size() (int64, error) {
+	info, err := os.Stat(string(f))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (f localFastq) openAt(offset int64) (io.ReadCloser, error) {
+	file, err := os.Open(string(f))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// remoteFastq is the http or https URL of a FASTQ file. Only what is read of
+// the response is downloaded, and offsets are read with range requests.
+type remoteFastq string
+
+// remoteClient bounds each request, including reading its records.
+var remoteClient = &http.Client{Timeout: 2 * time.Minute}
+
+// remoteStatusError is an unexpected status of a server.
+type remoteStatusError struct {
+	Status string
+}
+
+func (e *remoteStatusError) Error() string {
+	return "server returned " + e.Status
+}
+
+// checkRemoteStatus returns an error wrapping fs.ErrNotExist for 404, as for
+// a missing local file, and a remoteStatusError for other failures.
+func checkRemoteStatus(resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%s: %w", resp.Request.URL, fs.ErrNotExist)
+	case resp.StatusCode >= 300:
+		return &remoteStatusError{Status: resp.Status}
+	}
+	return nil
+}
+
+func (u remoteFastq) size() (int64, error) {
+	resp, err := remoteClient.Head(string(u))
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if err := checkRemoteStatus(resp); err != nil {
+		return 0, err
+	}
+	if resp.Header.Get("Accept-Ranges") != "bytes" || resp.ContentLength <= 0 {
+		return -1, nil
+	}
+	return resp.ContentLength, nil
+}
+
+func (u remoteFastq) openAt(offset int64) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, string(u), nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := remoteClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRemoteStatus(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if offset > 0 && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, &remoteStatusError{Status: resp.Status + " to a range request"}
+	}
+	return resp.Body, nil
+}
+
+// isRemotePath tells whether path is an http, https or s3 URL rather than a
+// local file.
+func isRemotePath(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "s3://")
+}
+
+// remoteURL returns the URL to read path from. s3://bucket/key is read
+// without signing, so the object must be public: from $AWS_ENDPOINT_URL if
+// set, for S3-compatible stores, or else from the bucket endpoint in
+// $AWS_REGION.
+func remoteURL(path string) string {
+	rest, ok := strings.CutPrefix(path, "s3://")
+	if !ok {
+		return path
+	}
+	bucket, key, _ := strings.Cut(rest, "/")
+	if endpoint := os.Getenv("AWS_ENDPOINT_URL"); endpoint != "" {
+		return strings.TrimSuffix(endpoint, "/") + (&url.URL{Path: "/" + bucket + "/" + key}).EscapedPath()
+	}
+	host := bucket + ".s3.amazonaws.com"
+	if region := cmp.Or(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION")); region != "" {
+		host = bucket + ".s3." + region + ".amazonaws.com"
+	}
+	return (&url.URL{Scheme: "https", Host: host, Path: "/" + key}).String()
+}
+
+// remoteName returns the file name of a URL, without its query, such as
+// reads_R1.fastq.gz for a presigned URL.
+func remoteName(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		return path.Base(u.Path)
+	}
+	return path.Base(rawURL)
+}
+
+// scanError returns the scan of a file that could not be read.
+func scanError(err error) barcodeScan {
+	var statusErr *remoteStatusError
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return barcodeScan{Barcode: "File Not Found"}
+	case errors.As(err, &statusErr):
+		return barcodeScan{Barcode: fmt.Sprintf("Error Reading (%s)", statusErr.Status)}
+	}
+	return barcodeScan{Barcode: fmt.Sprintf("Error Reading (%T)", err)}
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanFastqRemote(t *testing.T) {
+	barcodeRegex = regexp.MustCompile(`^[ACGTN+]+$`)
+	var fastq strings.Builder
+	for i := range 100 {
+		barcode := "AAAAAA"
+		if i >= 50 {
+			barcode = "CCCCCC"
+		}
+		fmt.Fprintf(&fastq, "@M00001:1:FC1:1:1:%d:1 1:N:0:%s\nACGT\n+\nIIII\n", i, barcode)
+	}
+	data := []byte(fastq.String())
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ranged/", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "reads.fq", time.Time{}, bytes.NewReader(data))
+	})
+	mux.HandleFunc("/plain/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data) // No range requests
+	})
+	mux.HandleFunc("/denied/", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "denied", http.StatusForbidden)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	assert.Equal(t, []barcodeCount{{"AAAAAA", 10}}, scanFastqGo(server.URL+"/plain/reads.fq?sig=1", 10, 1).Barcodes)
+	// Two records from each fifth of the file, with range requests.
+	assert.Equal(t, []barcodeCount{{"AAAAAA", 6}, {"CCCCCC", 4}}, scanFastqGo(server.URL+"/ranged/reads.fq", 10, 5).Barcodes)
+	// Every 10th record without them.
+	assert.Equal(t, []barcodeCount{{"AAAAAA", 5}, {"CCCCCC", 5}}, scanFastqGo(server.URL+"/plain/reads.fq", 10, 10).Barcodes)
+
+	assert.Equal(t, "File Not Found", scanFastqGo(server.URL+"/missing.fq", 10, 1).Barcode)
+	assert.Equal(t, "Error Reading (403 Forbidden)", scanFastqGo(server.URL+"/denied/reads.fq", 10, 1).Barcode)
+}
+
+func TestRemoteURL(t *testing.T) {
+	t.Setenv("AWS_ENDPOINT_URL", "")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	assert.Equal(t, "https://host/a.fq", remoteURL("https://host/a.fq"))
+	assert.Equal(t, "https://bucket.s3.amazonaws.com/run%201/a_R1.fq.gz", remoteURL("s3://bucket/run 1/a_R1.fq.gz"))
+	t.Setenv("AWS_REGION", "eu-west-1")
+	assert.Equal(t, "https://bucket.s3.eu-west-1.amazonaws.com/a.fq", remoteURL("s3://bucket/a.fq"))
+	t.Setenv("AWS_ENDPOINT_URL", "http://localhost:9000/")
+	assert.Equal(t, "http://localhost:9000/bucket/a.fq", remoteURL("s3://bucket/a.fq"))
+
+	assert.True(t, isRemotePath("S3://bucket/a.fq"))
+	assert.False(t, isRemotePath("data/a.fq"))
+	assert.Equal(t, "a_R1.fq.gz", remoteName("https://host/run/a_R1.fq.gz?X-Amz-Signature=abc"))
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// barcodeReport is the result of checkbarcode for --format tsv and json.
+type barcodeReport struct {
+	YAML           string               `json:"yaml"`
+	RecordsChecked int                  `json:"records_checked"`
+	Files          []barcodeFileReport  `json:"files"`
+	Samples        []barcodeSampleCheck `json:"samples"`
+	Demux          []demuxShare         `json:"demux,omitempty"`          // With --expected
+	CloseBarcodes  []barcodePair        `json:"close_barcodes,omitempty"` // With --distances
+}
+
+// barcodeFileReport is what was found in one FASTQ file.
+type barcodeFileReport struct {
+	Sample      string         `json:"sample"`
+	Run         int            `json:"run"` // 1-based, in YAML order
+	Read        string         `json:"read"`
+	File        string         `json:"file"`
+	Flowcell    string         `json:"flowcell"` // Comma-separated if several
+	Lane        string         `json:"lane"`
+	Barcode     string         `json:"barcode"`
+	Count       int            `json:"count"`
+	Fraction    float64        `json:"fraction"`
+	Kit         string         `json:"kit,omitempty"`
+	Uniform     bool           `json:"uniform"` // Verdict of the sample
+	Problem     string         `json:"problem,omitempty"`
+	Error       string         `json:"error,omitempty"`
+	Records     int            `json:"records"`
+	MeanLength  float64        `json:"mean_length"`
+	MeanQuality float64        `json:"mean_quality"`
+	Top         []barcodeShare `json:"top,omitempty"`
+}
+
+// barcodeShare is a barcode with its share of the records checked.
+type barcodeShare struct {
+	Barcode  string  `json:"barcode"`
+	Count    int     `json:"count"`
+	Fraction float64 `json:"fraction"`
+	Kit      string  `json:"kit,omitempty"`
+}
+
+// barcodeSampleCheck is the uniformity verdict of a sample.
+type barcodeSampleCheck struct {
+	Sample            string   `json:"sample"`
+	Uniform           bool     `json:"uniform"`
+	NonUniformIndexes []string `json:"non_uniform_indexes,omitempty"`
+}
+
+// newBarcodeReport collects the results, in YAML order, with their index
+// kits and the verdicts of their samples. top is the number of barcodes
+// listed per file, 0 for none.
+func newBarcodeReport(results []processResult, nonUniformIndexes map[string][]int, kits []IndexInfo, yamlBaseName string, recordsChecked int, top int) *barcodeReport {
+	report := &barcodeReport{YAML: yamlBaseName, RecordsChecked: recordsChecked, Files: []barcodeFileReport{}, Samples: []barcodeSampleCheck{}}
+	seen := make(map[string]bool)
+	for _, res := range results {
+		nonUniform := nonUniformIndexes[res.SampleName]
+		file := barcodeFileReport{
+			Sample:      res.SampleName,
+			Run:         res.Run + 1,
+			Read:        res.Read,
+			File:        res.RelativePath,
+			Flowcell:    strings.Join(res.Flowcells, ","),
+			Lane:        strings.Join(res.Lanes, ","),
+			Uniform:     len(nonUniform) == 0,
+			Problem:     res.Problem,
+			Records:     res.Records,
+			MeanLength:  res.MeanLength,
+			MeanQuality: res.MeanQuality,
+		}
+		if isBarcodeError(res.Barcode) {
+			file.Error = res.Barcode
+		} else {
+			total := 0
+			for _, bc := range res.Barcodes {
+				total += bc.Count
+			}
+			for i, bc := range res.Barcodes {
+				share := barcodeShare{Barcode: bc.Barcode, Count: bc.Count, Fraction: float64(bc.Count) / float64(total), Kit: annotateBarcode(bc.Barcode, kits)}
+				if i == 0 {
+					file.Barcode, file.Count, file.Fraction, file.Kit = share.Barcode, share.Count, share.Fraction, share.Kit
+				}
+				if i < top {
+					file.Top = append(file.Top, share)
+				}
+			}
+		}
+		report.Files = append(report.Files, file)
+
+		if !seen[res.SampleName] {
+			seen[res.SampleName] = true
+			check := barcodeSampleCheck{Sample: res.SampleName, Uniform: len(nonUniform) == 0}
+			for _, k := range nonUniform {
+				check.NonUniformIndexes = append(check.NonUniformIndexes, indexName(k))
+			}
+			report.Samples = append(report.Samples, check)
+		}
+	}
+	return report
+}
+
+// barcodeCheckSummary counts what checkbarcode found, for --strict.
+type barcodeCheckSummary struct {
+	Samples    int
+	NonUniform int // Samples
+	Files      int
+	Errors     int // Files that could not be read
+	Problems   int // Files flagged by --reads
+}
+
+func summarizeBarcodeCheck(results []processResult, nonUniformIndexes map[string][]int) barcodeCheckSummary {
+	summary := barcodeCheckSummary{Files: len(results)}
+	seen := make(map[string]bool)
+	for _, res := range results {
+		if !seen[res.SampleName] {
+			seen[res.SampleName] = true
+			summary.Samples++
+			if len(nonUniformIndexes[res.SampleName]) > 0 {
+				summary.NonUniform++
+			}
+		}
+		if isBarcodeError(res.Barcode) {
+			summary.Errors++
+		}
+		if res.Problem != "" {
+			summary.Problems++
+		}
+	}
+	return summary
+}
+
+func (s barcodeCheckSummary) failed() bool {
+	return s.NonUniform > 0 || s.Errors > 0 || s.Problems > 0
+}
+
+// String returns the summary as key=value pairs on one line.
+func (s barcodeCheckSummary) String() string {
+	status := "OK"
+	if s.failed() {
+		status = "FAIL"
+	}
+	return fmt.Sprintf("checkbarcode samples=%d non_uniform=%d files=%d errors=%d problems=%d status=%s",
+		s.Samples, s.NonUniform, s.Files, s.Errors, s.Problems, status)
+}
+
+// writeBarcodeTSV writes a row per file, with a top column of
+// barcode=count pairs if barcodes were listed.
+func writeBarcodeTSV(w io.Writer, report *barcodeReport) error {
+	withTop := false
+	for _, f := range report.Files {
+		withTop = withTop || len(f.Top) > 0
+	}
+	header := []string{"sample", "run", "read", "file", "flowcell", "lane", "barcode", "count", "fraction", "kit", "uniform", "non_uniform_indexes", "problem", "error", "records", "mean_length", "mean_quality"}
+	if withTop {
+		header = append(header, "top")
+	}
+	nonUniform := make(map[string]string)
+	for _, s := range report.Samples {
+		nonUniform[s.Sample] = strings.Join(s.NonUniformIndexes, ",")
+	}
+	if _, err := fmt.Fprintln(w, strings.Join(header, "\t")); err != nil {
+		return err
+	}
+	for _, f := range report.Files {
+		row := []string{
+			f.Sample, strconv.Itoa(f.Run), f.Read, f.File, f.Flowcell, f.Lane,
+			f.Barcode, strconv.Itoa(f.Count), strconv.FormatFloat(f.Fraction, 'f', 4, 64), f.Kit,
+			strconv.FormatBool(f.Uniform), nonUniform[f.Sample], f.Problem, f.Error,
+			strconv.Itoa(f.Records), strconv.FormatFloat(f.MeanLength, 'f', 1, 64), strconv.FormatFloat(f.MeanQuality, 'f', 1, 64),
+		}
+		if withTop {
+			top := make([]string, len(f.Top))
+			for i, share := range f.Top {
+				top[i] = fmt.Sprintf("%s=%d", share.Barcode, share.Count)
+			}
+			row = append(row, strings.Join(top, ";"))
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBarcodeJSON writes the report as indented JSON.
+func writeBarcodeJSON(w io.Writer, report *barcodeReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
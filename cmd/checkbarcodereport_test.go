@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testBarcodeReport(top int) *barcodeReport {
+	results := []processResult{
+		{SampleName: "a", Run: 0, Read: "R1", RelativePath: "a1.fq", Barcode: "ATCACG",
+			Barcodes: []barcodeCount{{"ATCACG", 3}, {"GGGGGG", 1}}, Flowcells: []string{"FC1"}, Lanes: []string{"1"},
+			Records: 4, MeanLength: 151, MeanQuality: 35.25},
+		{SampleName: "a", Run: 1, Read: "R1", RelativePath: "a2.fq", Barcode: "File Not Found"},
+		{SampleName: "b", Run: 2, Read: "R1", RelativePath: "b1.fq", Barcode: "ATCACG+GGTTAA",
+			Barcodes: []barcodeCount{{"ATCACG+GGTTAA", 2}}, Problem: "headers say read 2, swapped?"},
+	}
+	return newBarcodeReport(results, map[string][]int{"b": {1}}, IndexKits, "s.yaml", 1000, top)
+}
+
+func TestNewBarcodeReport(t *testing.T) {
+	report := testBarcodeReport(1)
+	assert.Len(t, report.Files, 3)
+	assert.Equal(t, barcodeFileReport{Sample: "a", Run: 1, Read: "R1", File: "a1.fq", Flowcell: "FC1", Lane: "1", Barcode: "ATCACG", Count: 3, Fraction: 0.75,
+		Kit: "TruSeq LT AD001", Uniform: true, Records: 4, MeanLength: 151, MeanQuality: 35.25, Top: []barcodeShare{{"ATCACG", 3, 0.75, "TruSeq LT AD001"}}}, report.Files[0])
+	assert.Equal(t, "File Not Found", report.Files[1].Error)
+	assert.Equal(t, "", report.Files[1].Barcode)
+	assert.Equal(t, []barcodeSampleCheck{{Sample: "a", Uniform: true}, {Sample: "b", NonUniformIndexes: []string{"i5"}}}, report.Samples)
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeBarcodeJSON(&buf, report))
+	var decoded barcodeReport
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, *report, decoded)
+}
+
+func TestWriteBarcodeTSV(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, writeBarcodeTSV(&buf, testBarcodeReport(0)))
+	assert.Equal(t, "sample\trun\tread\tfile\tflowcell\tlane\tbarcode\tcount\tfraction\tkit\tuniform\tnon_uniform_indexes\tproblem\terror\trecords\tmean_length\tmean_quality\n"+
+		"a\t1\tR1\ta1.fq\tFC1\t1\tATCACG\t3\t0.7500\tTruSeq LT AD001\ttrue\t\t\t\t4\t151.0\t35.2\n"+
+		"a\t2\tR1\ta2.fq\t\t\t\t0\t0.0000\t\ttrue\t\t\tFile Not Found\t0\t0.0\t0.0\n"+
+		"b\t3\tR1\tb1.fq\t\t\tATCACG+GGTTAA\t2\t1.0000\tTruSeq LT AD001\tfalse\ti5\theaders say read 2, swapped?\t\t0\t0.0\t0.0\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, writeBarcodeTSV(&buf, testBarcodeReport(2)))
+	assert.Contains(t, buf.String(), "\ttop\n")
+	assert.Contains(t, buf.String(), "\tATCACG=3;GGGGGG=1\n")
+}
+
+func TestSummarizeBarcodeCheck(t *testing.T) {
+	results := []processResult{
+		{SampleName: "a", Barcode: "ATCACG"},
+		{SampleName: "a", Barcode: "File Not Found"},
+		{SampleName: "b", Barcode: "ATCACG", Problem: "headers say read 2, swapped?"},
+	}
+	summary := summarizeBarcodeCheck(results, map[string][]int{"b": {0}})
+	assert.Equal(t, barcodeCheckSummary{Samples: 2, NonUniform: 1, Files: 3, Errors: 1, Problems: 1}, summary)
+	assert.True(t, summary.failed())
+	assert.Equal(t, "checkbarcode samples=2 non_uniform=1 files=3 errors=1 problems=1 status=FAIL", summary.String())
+
+	ok := summarizeBarcodeCheck(results[:1], nil)
+	assert.False(t, ok.failed())
+	assert.Equal(t, "checkbarcode samples=1 non_uniform=0 files=1 errors=0 problems=0 status=OK", ok.String())
+}
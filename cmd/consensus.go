@@ -0,0 +1,319 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	consensusThreshold float64
+	consensusHeight    int
+	consensusFrequency bool
+	consensusTable     bool
+)
+
+var consensusCmd = &cobra.Command{
+	Use:   "consensus [filename]",
+	Short: "Print the consensus and a sequence logo of aligned sequences",
+	Long: `Reads equal-length sequences (FASTA, or one sequence per line) from a file or
+stdin, such as extracted barcodes or motif hits, and computes the base
+frequencies at every position.
+
+It draws a sequence logo in the terminal, where the height of each column is
+the information content of the position (0-2 bits, or the fraction of A/C/G/T
+with --frequency), and prints the IUPAC consensus: the fewest bases that
+together reach --threshold of the sequences at a position. Gaps ('-' or '.')
+and other characters are counted but not drawn.
+
+Example:
+  hey consensus barcodes.txt
+  hey consensus motif_hits.fa --table > frequencies.tsv
+  cut -f 2 hits.tsv | hey consensus -t 0.9 -H 12`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if consensusThreshold <= 0 || consensusThreshold > 1 {
+			return fmt.Errorf("--threshold must be in (0, 1]")
+		}
+		if consensusHeight < 1 {
+			return fmt.Errorf("--height must be at least 1")
+		}
+		filename := ""
+		if len(args) == 1 {
+			filename = args[0]
+		}
+		input, err := openInput(filename)
+		if err != nil {
+			return err
+		}
+		defer input.Close()
+		seqs, err := readAlignedSequences(input)
+		if err != nil {
+			return err
+		}
+		if len(seqs) == 0 {
+			return fmt.Errorf("no sequences found")
+		}
+		counts := countBases(seqs)
+		consensus := iupacConsensus(counts, consensusThreshold)
+
+		if consensusTable {
+			printBaseFrequencies(os.Stdout, counts, consensus)
+			return nil
+		}
+		printSequenceLogo(counts, consensus, consensusHeight, consensusFrequency)
+		fmt.Fprintf(os.Stderr, "%d sequences of length %d\n", len(seqs), len(counts))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(consensusCmd)
+	consensusCmd.Flags().Float64VarP(&consensusThreshold, "threshold", "t", 0.75, "Fraction of sequences the consensus bases must cover at a position")
+	consensusCmd.Flags().IntVarP(&consensusHeight, "height", "H", 8, "Height of the sequence logo in lines")
+	consensusCmd.Flags().BoolVarP(&consensusFrequency, "frequency", "f", false, "Scale logo columns by base frequency instead of information content")
+	consensusCmd.Flags().BoolVar(&consensusTable, "table", false, "Print per-position base frequencies as TSV instead of the logo")
+}
+
+// logoBases are the bases drawn in the logo; every other character is
+// counted in the last slot.
+const logoBases = "ACGT"
+
+// baseCounts holds the counts of A, C, G, T, gaps and other characters at one position.
+type baseCounts [6]int
+
+func (c baseCounts) total() int {
+	sum := 0
+	for _, n := range c {
+		sum += n
+	}
+	return sum
+}
+
+// readAlignedSequences reads FASTA records or plain lines and checks that all
+// sequences have the same length.
+func readAlignedSequences(r io.Reader) ([]string, error) {
+	var seqs []string
+	var current strings.Builder
+	inRecord := false
+	flush := func() {
+		if inRecord {
+			seqs = append(seqs, current.String())
+			current.Reset()
+		}
+	}
+	scanner := newLineScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, ">"):
+			flush()
+			inRecord = true
+		case line == "":
+		case inRecord:
+			current.WriteString(line)
+		default:
+			seqs = append(seqs, line)
+		}
+	}
+	flush()
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for i, seq := range seqs {
+		if len(seq) != len(seqs[0]) {
+			return nil, fmt.Errorf("sequence %d has length %d, expected %d: sequences must be aligned", i+1, len(seq), len(seqs[0]))
+		}
+	}
+	return seqs, nil
+}
+
+func countBases(seqs []string) []baseCounts {
+	if len(seqs) == 0 {
+		return nil
+	}
+	counts := make([]baseCounts, len(seqs[0]))
+	for _, seq := range seqs {
+		for i := 0; i < len(seq); i++ {
+			switch seq[i] {
+			case 'A', 'a':
+				counts[i][0]++
+			case 'C', 'c':
+				counts[i][1]++
+			case 'G', 'g':
+				counts[i][2]++
+			case 'T', 't', 'U', 'u':
+				counts[i][3]++
+			case '-', '.':
+				counts[i][4]++
+			default:
+				counts[i][5]++
+			}
+		}
+	}
+	return counts
+}
+
+var iupacCodes = map[string]byte{
+	"A": 'A', "C": 'C', "G": 'G', "T": 'T',
+	"AC": 'M', "AG": 'R', "AT": 'W', "CG": 'S', "CT": 'Y', "GT": 'K',
+	"ACG": 'V', "ACT": 'H', "AGT": 'D', "CGT": 'B', "ACGT": 'N',
+}
+
+// iupacConsensus picks, for every position, the most frequent bases until they
+// cover threshold of all sequences and writes their IUPAC code. Positions
+// where gaps are the majority become '-'.
+func iupacConsensus(counts []baseCounts, threshold float64) string {
+	consensus := make([]byte, len(counts))
+	for pos, c := range counts {
+		total := c.total()
+		if c[4]*2 > total {
+			consensus[pos] = '-'
+			continue
+		}
+		order := []int{0, 1, 2, 3}
+		sort.SliceStable(order, func(i, j int) bool { return c[order[i]] > c[order[j]] })
+		var picked []byte
+		covered := 0
+		for _, b := range order {
+			if c[b] == 0 || float64(covered) >= threshold*float64(total) {
+				break
+			}
+			picked = append(picked, logoBases[b])
+			covered += c[b]
+		}
+		if len(picked) == 0 || float64(covered) < threshold*float64(total) {
+			consensus[pos] = 'N'
+			continue
+		}
+		sort.Slice(picked, func(i, j int) bool { return picked[i] < picked[j] })
+		consensus[pos] = iupacCodes[string(picked)]
+	}
+	return string(consensus)
+}
+
+// informationContent returns 2 minus the Shannon entropy of the A/C/G/T
+// frequencies, scaled by the fraction of sequences with a base at the position.
+func informationContent(c baseCounts) float64 {
+	bases := c[0] + c[1] + c[2] + c[3]
+	if bases == 0 {
+		return 0
+	}
+	entropy := 0.0
+	for _, n := range c[:4] {
+		if n > 0 {
+			p := float64(n) / float64(bases)
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return (2 - entropy) * float64(bases) / float64(c.total())
+}
+
+// logoColumn returns the letters of one logo column from bottom to top. The
+// column is height lines tall at full scale, and every base gets a share of
+// it proportional to its frequency, with the most frequent base on top.
+func logoColumn(c baseCounts, height int, byFrequency bool) []byte {
+	bases := c[0] + c[1] + c[2] + c[3]
+	if bases == 0 || height <= 0 {
+		return nil
+	}
+	scale := informationContent(c) / 2
+	if byFrequency {
+		scale = float64(bases) / float64(c.total())
+	}
+	lines := int(math.Round(scale * float64(height)))
+
+	order := []int{0, 1, 2, 3}
+	sort.SliceStable(order, func(i, j int) bool { return c[order[i]] < c[order[j]] })
+	column := make([]byte, 0, lines)
+	for line := range lines {
+		// Pick the base whose share of the column contains the middle of this line.
+		mid := (float64(line) + 0.5) / float64(lines) * float64(bases)
+		cumulative := 0
+		for _, b := range order {
+			cumulative += c[b]
+			if float64(cumulative) >= mid {
+				column = append(column, logoBases[b])
+				break
+			}
+		}
+	}
+	return column
+}
+
+var logoColors = map[byte]*color.Color{
+	'A': color.New(color.FgGreen, color.Bold),
+	'C': color.New(color.FgBlue, color.Bold),
+	'G': color.New(color.FgYellow, color.Bold),
+	'T': color.New(color.FgRed, color.Bold),
+}
+
+func colorBases(seq string) string {
+	var b strings.Builder
+	for i := 0; i < len(seq); i++ {
+		if c, ok := logoColors[seq[i]]; ok {
+			b.WriteString(c.Sprint(string(seq[i])))
+		} else {
+			b.WriteByte(seq[i])
+		}
+	}
+	return b.String()
+}
+
+// printSequenceLogo draws the logo columns above an axis with the consensus
+// and position ticks.
+func printSequenceLogo(counts []baseCounts, consensus string, height int, byFrequency bool) {
+	columns := make([][]byte, len(counts))
+	for i, c := range counts {
+		columns[i] = logoColumn(c, height, byFrequency)
+	}
+	for line := height - 1; line >= 0; line-- {
+		label := "    "
+		if line == height-1 {
+			if byFrequency {
+				label = "1.0 "
+			} else {
+				label = "2b  "
+			}
+		}
+		var row strings.Builder
+		for _, column := range columns {
+			if line < len(column) {
+				row.WriteByte(column[line])
+			} else {
+				row.WriteByte(' ')
+			}
+		}
+		fmt.Println(label + "│" + strings.TrimRight(colorBases(row.String()), " "))
+	}
+
+	fmt.Println("    └" + strings.Repeat("─", len(counts)))
+	fmt.Println("     " + colorBases(consensus))
+
+	ticks := []byte(strings.Repeat(" ", len(counts)))
+	for pos := 1; pos <= len(counts); pos++ {
+		if label := fmt.Sprint(pos); (pos == 1 || pos%5 == 0) && pos-1+len(label) <= len(ticks) {
+			copy(ticks[pos-1:], label)
+		}
+	}
+	fmt.Println("     " + strings.TrimRight(string(ticks), " "))
+}
+
+func printBaseFrequencies(w io.Writer, counts []baseCounts, consensus string) {
+	fmt.Fprintln(w, "position\tA\tC\tG\tT\tgap\tother\tbits\tconsensus")
+	for pos, c := range counts {
+		total := float64(c.total())
+		fmt.Fprintf(w, "%d", pos+1)
+		for _, n := range c {
+			fmt.Fprintf(w, "\t%.3f", float64(n)/total)
+		}
+		fmt.Fprintf(w, "\t%.3f\t%c\n", informationContent(c), consensus[pos])
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadAlignedSequences(t *testing.T) {
+	seqs, err := readAlignedSequences(strings.NewReader(">a\nAC\nGT\n>b\nACGA\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ACGT", "ACGA"}, seqs)
+
+	seqs, err = readAlignedSequences(strings.NewReader("ACG\n\nTTT\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ACG", "TTT"}, seqs)
+
+	_, err = readAlignedSequences(strings.NewReader("ACG\nTT\n"))
+	assert.ErrorContains(t, err, "sequence 2 has length 2")
+}
+
+func TestIupacConsensus(t *testing.T) {
+	tests := []struct {
+		name      string
+		seqs      []string
+		threshold float64
+		want      string
+	}{
+		{"identical", []string{"ACGT", "ACGT"}, 0.75, "ACGT"},
+		{"two bases", []string{"A", "G", "A", "G"}, 0.75, "R"},
+		{"majority", []string{"A", "A", "A", "C"}, 0.75, "A"},
+		{"strict threshold", []string{"A", "A", "A", "C"}, 1, "M"},
+		{"gaps", []string{"A-", "--", "--"}, 0.75, "--"},
+		{"lowercase and U", []string{"acgu"}, 0.75, "ACGT"},
+		{"only N", []string{"N"}, 0.75, "N"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, iupacConsensus(countBases(tt.seqs), tt.threshold))
+		})
+	}
+}
+
+func TestInformationContent(t *testing.T) {
+	assert.InDelta(t, 2.0, informationContent(baseCounts{4, 0, 0, 0, 0, 0}), 1e-9)
+	assert.InDelta(t, 0.0, informationContent(baseCounts{1, 1, 1, 1, 0, 0}), 1e-9)
+	assert.InDelta(t, 1.0, informationContent(baseCounts{2, 0, 0, 0, 2, 0}), 1e-9)
+}
+
+func TestLogoColumn(t *testing.T) {
+	assert.Equal(t, []byte("AAAA"), logoColumn(baseCounts{4, 0, 0, 0, 0, 0}, 4, false))
+	assert.Empty(t, logoColumn(baseCounts{1, 1, 1, 1, 0, 0}, 4, false))
+	// Most frequent base on top.
+	assert.Equal(t, []byte("CAAA"), logoColumn(baseCounts{3, 1, 0, 0, 0, 0}, 4, true))
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+var cramMagic = []byte("CRAM")
+
+// cramReader decodes CRAM by streaming it through 'samtools view', since
+// CRAM's codecs and reference-based compression are not reimplemented here.
+type cramReader struct {
+	cmd    *exec.Cmd
+	text   *textSAMReader
+	stderr bytes.Buffer
+	waited bool // samtools has exited and was waited for
+}
+
+// newCRAMReader starts samtools on r. reference is the FASTA the CRAM was
+// compressed against; without it samtools falls back to REF_PATH/REF_CACHE
+// or the M5 checksums of the header.
+func newCRAMReader(r io.Reader, reference string) (*cramReader, error) {
+	samtools, err := exec.LookPath("samtools")
+	if err != nil {
+		return nil, fmt.Errorf("CRAM input needs samtools in PATH")
+	}
+	args := []string{"view", "-h"}
+	if reference != "" {
+		args = append(args, "--reference", reference)
+	}
+	args = append(args, "-")
+	c := &cramReader{cmd: exec.Command(samtools, args...)}
+	c.cmd.Stdin = r
+	c.cmd.Stderr = &c.stderr
+	stdout, err := c.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting samtools: %w", err)
+	}
+	c.text = &textSAMReader{scanner: newLineScanner(stdout)}
+	return c, nil
+}
+
+func (c *cramReader) Read() (*samRecord, error) {
+	record, err := c.text.Read()
+	if err != io.EOF {
+		return record, err
+	}
+	c.waited = true
+	if err := c.cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(c.stderr.String()); msg != "" {
+			return nil, fmt.Errorf("decoding CRAM with samtools: %s", msg)
+		}
+		return nil, fmt.Errorf("decoding CRAM with samtools: %w", err)
+	}
+	return nil, io.EOF
+}
+
+// Close stops samtools if it has not finished, as when reading stops before
+// the end of the CRAM.
+func (c *cramReader) Close() error {
+	if c.waited {
+		return nil
+	}
+	c.waited = true
+	_ = c.cmd.Process.Kill()
+	// Do not wait for the copying of a stdin that is still open.
+	c.cmd.WaitDelay = time.Second
+	_ = c.cmd.Wait()
+	return nil
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	cutFields string
+	cutSep    string
+	cutRegex  bool
+)
+
+var cutCmd = &cobra.Command{
+	Use:   "cut -f NAME[,NAME...] [filename]",
+	Short: "Select and reorder columns by header name",
+	Long: `Selects columns from a delimited file by their header name instead of by index.
+Columns are printed in the order given to -f, so the command can also reorder columns.
+Reads from stdin when no filename (or '-') is given. Gzip files (.gz) are supported.
+
+Example:
+  hey cut -f geneID,padj,log2FC deseq.tsv.gz
+  hey cut -r -f '^gene','_count$' counts.tsv`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if cutFields == "" {
+			return fmt.Errorf("no columns selected; use -f NAME[,NAME...]")
+		}
+		filename := "-"
+		if len(args) == 1 {
+			filename = args[0]
+		}
+		return runCut(filename)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cutCmd)
+	cutCmd.Flags().StringVarP(&cutFields, "fields", "f", "", "Comma-separated column names to select")
+	cutCmd.Flags().StringVarP(&cutSep, "sep", "s", "\t", "Column separator (default: tab)")
+	cutCmd.Flags().BoolVarP(&cutRegex, "regex", "r", false, "Treat column names as regular expressions")
+}
+
+func runCut(filename string) error {
+	input, err := openInput(filename)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", filename, err)
+	}
+	defer input.Close()
+
+	scanner := newLineScanner(input)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("reading %s: %w", filename, err)
+		}
+		return fmt.Errorf("%s is empty", filename)
+	}
+	headers := strings.Split(strings.TrimSuffix(scanner.Text(), "\r"), cutSep)
+
+	indices, err := resolveColumns(headers, strings.Split(cutFields, ","), cutRegex)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	row := make([]string, len(indices))
+	writeRow := func(fields []string) {
+		for i, idx := range indices {
+			row[i] = ""
+			if idx < len(fields) {
+				row[i] = fields[idx]
+			}
+		}
+		fmt.Fprintln(out, strings.Join(row, cutSep))
+	}
+
+	writeRow(headers)
+	for scanner.Scan() {
+		writeRow(strings.Split(strings.TrimSuffix(scanner.Text(), "\r"), cutSep))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading %s: %w", filename, err)
+	}
+	return nil
+}
+
+// resolveColumns maps the requested names onto header indices, keeping the
+// order of the request. In regex mode a pattern may select several columns,
+// which are then returned in header order.
+func resolveColumns(headers, names []string, useRegex bool) ([]int, error) {
+	var indices []int
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		if useRegex {
+			re, err := regexp.Compile(name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid column pattern %q: %w", name, err)
+			}
+			for i, h := range headers {
+				if re.MatchString(h) {
+					indices = append(indices, i)
+					found = true
+				}
+			}
+		} else {
+			for i, h := range headers {
+				if h == name {
+					indices = append(indices, i)
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("column %q not found in header", name)
+		}
+	}
+	if len(indices) == 0 {
+		return nil, fmt.Errorf("no columns selected")
+	}
+	return indices, nil
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveColumns(t *testing.T) {
+	headers := []string{"geneID", "baseMean", "log2FC", "pvalue", "padj"}
+
+	tests := []struct {
+		name     string
+		names    []string
+		useRegex bool
+		expected []int
+		wantErr  bool
+	}{
+		{
+			name:     "select by name",
+			names:    []string{"geneID", "padj"},
+			expected: []int{0, 4},
+		},
+		{
+			name:     "reorder columns",
+			names:    []string{"padj", "log2FC", "geneID"},
+			expected: []int{4, 2, 0},
+		},
+		{
+			name:     "whitespace around names",
+			names:    []string{" geneID ", "pvalue"},
+			expected: []int{0, 3},
+		},
+		{
+			name:    "missing column",
+			names:   []string{"geneID", "qvalue"},
+			wantErr: true,
+		},
+		{
+			name:     "regex matches several columns",
+			names:    []string{"^p", "geneID"},
+			useRegex: true,
+			expected: []int{3, 4, 0},
+		},
+		{
+			name:     "invalid regex",
+			names:    []string{"("},
+			useRegex: true,
+			wantErr:  true,
+		},
+		{
+			name:    "empty selection",
+			names:   []string{""},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := resolveColumns(headers, tt.names, tt.useRegex)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
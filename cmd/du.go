@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/aquasecurity/table"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	duDepth   int
+	duExclude []string
+	duTop     int
+)
+
+var duCmd = &cobra.Command{
+	Use:   "du [path]",
+	Short: "Summarize disk usage of the largest files and directories",
+	Long: `Walks a directory tree concurrently and lists the largest entries at the given
+depth, sorted by size, with a bar showing their share of the total.
+Sizes are apparent file sizes; symbolic links are not followed.
+
+Example:
+  hey du
+  hey du /project/data -d 2 -n 10 -e '*.bam' -e .snakemake`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := "."
+		if len(args) == 1 {
+			root = args[0]
+		}
+		if duDepth < 1 {
+			return fmt.Errorf("--depth must be at least 1")
+		}
+		for _, pattern := range duExclude {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid --exclude pattern %q: %w", pattern, err)
+			}
+		}
+		entries, err := collectDuEntries(root, duDepth, duExclude)
+		if err != nil {
+			return err
+		}
+		measureDuEntries(entries, duExclude)
+		printDuTable(root, entries, duTop)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(duCmd)
+	duCmd.Flags().IntVarP(&duDepth, "depth", "d", 1, "Depth of the listed entries below the root")
+	duCmd.Flags().StringSliceVarP(&duExclude, "exclude", "e", nil, "Glob pattern of names to skip (repeatable)")
+	duCmd.Flags().IntVarP(&duTop, "top", "n", 20, "Number of entries to show (0 = all)")
+}
+
+type duEntry struct {
+	Path  string
+	IsDir bool
+	Size  int64
+	Files int64
+	Err   error
+}
+
+func isExcluded(name string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// collectDuEntries lists the entries found at the given depth below root.
+// Files that live above that depth are listed as well, since they cannot be
+// expanded further.
+func collectDuEntries(root string, depth int, exclude []string) ([]*duEntry, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("cannot access %q: %w", root, err)
+	}
+	if !info.IsDir() {
+		return []*duEntry{{Path: root, Size: info.Size(), Files: 1}}, nil
+	}
+
+	var entries []*duEntry
+	var walk func(dir string, level int) error
+	walk = func(dir string, level int) error {
+		children, err := os.ReadDir(dir)
+		if err != nil {
+			return fmt.Errorf("reading %q: %w", dir, err)
+		}
+		for _, child := range children {
+			if isExcluded(child.Name(), exclude) {
+				continue
+			}
+			path := filepath.Join(dir, child.Name())
+			if child.IsDir() && level < depth {
+				if err := walk(path, level+1); err != nil {
+					entries = append(entries, &duEntry{Path: path, IsDir: true, Err: err})
+				}
+				continue
+			}
+			entries = append(entries, &duEntry{Path: path, IsDir: child.IsDir()})
+		}
+		return nil
+	}
+	if err := walk(root, 1); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// measureDuEntries fills in the size of every entry using a pool of workers.
+func measureDuEntries(entries []*duEntry, exclude []string) {
+	jobs := make(chan *duEntry, len(entries))
+	var wg sync.WaitGroup
+	numWorkers := min(runtime.NumCPU()*2, len(entries))
+	wg.Add(numWorkers)
+	for range numWorkers {
+		go func() {
+			defer wg.Done()
+			for e := range jobs {
+				if e.Err == nil {
+					e.Size, e.Files, e.Err = pathSize(e.Path, exclude)
+				}
+			}
+		}()
+	}
+	for _, e := range entries {
+		jobs <- e
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// pathSize returns the total size and number of regular files under path.
+func pathSize(path string, exclude []string) (int64, int64, error) {
+	var size, files int64
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Keep counting what can be read instead of giving up on the whole entry.
+			if d != nil && d.IsDir() && p != path {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if p != path && isExcluded(d.Name(), exclude) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err == nil {
+				size += info.Size()
+				files++
+			}
+		}
+		return nil
+	})
+	return size, files, err
+}
+
+// humanSize formats a byte count using binary units, e.g. 1.5 GiB.
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func printDuTable(root string, entries []*duEntry, top int) {
+	if len(entries) == 0 {
+		color.Yellow("Nothing to show under %s.", root)
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Size > entries[j].Size })
+
+	var total, totalFiles int64
+	for _, e := range entries {
+		total += e.Size
+		totalFiles += e.Files
+	}
+
+	shown := entries
+	if top > 0 && len(shown) > top {
+		shown = shown[:top]
+	}
+
+	t := table.New(os.Stdout)
+	t.SetHeaders("Size", "%", "", "Files", "Path")
+	t.SetHeaderStyle(table.StyleBold)
+	t.SetLineStyle(table.StyleBlue)
+	t.SetDividers(table.UnicodeRoundedDividers)
+	t.SetAlignment(table.AlignRight, table.AlignRight, table.AlignLeft, table.AlignRight, table.AlignLeft)
+
+	const barWidth = 20
+	for _, e := range shown {
+		pct := 0.0
+		if total > 0 {
+			pct = float64(e.Size) / float64(total)
+		}
+		bar := renderBar(pct * barWidth)
+		bar += strings.Repeat(" ", barWidth-utf8.RuneCountInString(bar))
+		name, _ := filepath.Rel(root, e.Path)
+		if e.IsDir {
+			name = color.BlueString(name + "/")
+		}
+		if e.Err != nil {
+			name += color.RedString(" (%v)", e.Err)
+		}
+		t.AddRow(humanSize(e.Size), fmt.Sprintf("%.1f", pct*100), color.CyanString(bar), fmt.Sprint(e.Files), name)
+	}
+	t.Render()
+	if len(shown) < len(entries) {
+		fmt.Printf("Showing %d of %d entries. ", len(shown), len(entries))
+	}
+	fmt.Printf("Total: %s in %s under %s\n", humanSize(total), countNoun(int(totalFiles), "file", "files"), root)
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestTree creates files of the given sizes below dir.
+func writeTestTree(t *testing.T, dir string, files map[string]int) {
+	t.Helper()
+	for name, size := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, make([]byte, size), 0o644))
+	}
+}
+
+func TestCollectDuEntries(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]int{
+		"top.txt":               1,
+		"raw/a.fq.gz":           100,
+		"raw/b.bam":             1000,
+		"raw/lane1/c.fq.gz":     10,
+		".snakemake/log/x.log":  5,
+		"results/deep/d/e.tsv":  7,
+		"results/deep/d/f.bam":  70,
+		"results/summary.html":  3,
+		"results/deep/g.fq.gz":  2,
+		"results/deep/d/h.fq":   4,
+		"results/deep/d/i/j.fq": 6,
+	})
+
+	tests := []struct {
+		name    string
+		depth   int
+		exclude []string
+		sizes   map[string]int64
+	}{
+		{
+			name:  "depth 1",
+			depth: 1,
+			sizes: map[string]int64{"top.txt": 1, "raw": 1110, ".snakemake": 5, "results": 92},
+		},
+		{
+			name:    "depth 2 with excluded names",
+			depth:   2,
+			exclude: []string{"*.bam", ".snakemake"},
+			sizes: map[string]int64{
+				"top.txt": 1, "raw/a.fq.gz": 100, "raw/lane1": 10,
+				"results/deep": 19, "results/summary.html": 3,
+			},
+		},
+		{
+			name:    "files above the depth are listed",
+			depth:   3,
+			exclude: []string{"raw", ".*"},
+			sizes: map[string]int64{
+				"top.txt": 1, "results/summary.html": 3,
+				"results/deep/d": 87, "results/deep/g.fq.gz": 2,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := collectDuEntries(dir, tt.depth, tt.exclude)
+			require.NoError(t, err)
+			measureDuEntries(entries, tt.exclude)
+			sizes := make(map[string]int64)
+			for _, e := range entries {
+				require.NoError(t, e.Err)
+				rel, _ := filepath.Rel(dir, e.Path)
+				sizes[filepath.ToSlash(rel)] = e.Size
+			}
+			assert.Equal(t, tt.sizes, sizes)
+		})
+	}
+}
+
+func TestPathSize(t *testing.T) {
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]int{"a.fq": 10, "b.bam": 20, "tmp/c.fq": 30})
+
+	size, files, err := pathSize(dir, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{60, 3}, []int64{size, files})
+
+	size, files, err = pathSize(dir, []string{"*.bam", "tmp"})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{10, 1}, []int64{size, files})
+
+	_, err = collectDuEntries(filepath.Join(dir, "missing"), 1, nil)
+	assert.Error(t, err)
+}
+
+func TestHumanSize(t *testing.T) {
+	for n, want := range map[int64]string{0: "0 B", 1023: "1023 B", 1024: "1.0 KiB", 1536: "1.5 KiB", 5 << 30: "5.0 GiB"} {
+		assert.Equal(t, want, humanSize(n))
+	}
+}
@@ -1,64 +1,424 @@
 package cmd
 
 import (
-	"bufio"
-	"compress/gzip"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"math"
+	"math/rand"
 	"os"
 	"os/signal"
+	"regexp"
 	"strings"
-	"syscall"
 	"sync/atomic"
+	"syscall"
+	"time"
 	"unicode/utf8"
 
 	"github.com/liamg/tml"
+	"github.com/mattn/go-runewidth"
 	"github.com/spf13/cobra"
+	"github.com/yech1990/hey/internal/render"
 )
 
 var (
-	fastqMaxRecords int
-	fastqCompactLen int
+	fastqNumRecords  int
+	fastqTail        int
+	fastqGrep        string
+	fastqRevcomp     bool
+	fastqStatsMode   bool
+	fastqValidate    bool
+	fastqProfile     bool
+	fastqOverrep     bool
+	fastqKmer        int     // K-mer length for fastqOverrep, 0 for whole sequences
+	fastqSample      float64 // Fraction of records to show
+	fastqSeed        int64   // Random seed for fastqSample
+	fastqUMILen      int
+	fastqUMIPattern  string
+	fastqUMIRegex    string
+	fastqQualCutoff  int // Highlight bases below this quality
+	fastqCompactLen  int
+	fastqPalette     string
+	fastqSideBySide  bool
+	fastqInteractive bool
+	fastqGC          bool
+	fastqMarkDups    bool
+	fastqHomopolymer int    // Underline runs of at least this many identical bases, 0 for none
+	fastqColorBy     string // "base" or "quality"
+	fastqMinLen      int
+	fastqMaxLen      int // 0 for no limit
+	fastqInterleaved bool
+	fastqSeparate    bool
+	fastqHTML        string // Write the reads to this HTML file
+
+	fastqAdapters     string
+	fastqListAdapters bool
 )
 
+// fastqStyle is how hey fastq renders bases, set from --palette, -q and
+// --color-by. Bases below -q are cyan.
+var fastqStyle = render.Options{Palette: render.Palettes["default"], LowQualityColor: "cyan"}
+
+// fastqOut is where the reads and their summary are written: stdout, or the
+// --html report.
+var fastqOut io.Writer = os.Stdout
+
+// adapterDict maps the sequences of the adapters that are searched for to
+// their names. --adapters replaces it.
 var adapterDict = map[string]string{
-	"AGATCGGAAGAGCACACGTC":  "TruSeq P7",
-	"GATCGGAAGAGCACACGTCT":  "TruSeq P7(-1)",
-	"TGGAATTCTCGGGTGCCAAG":  "3' RNA (legacy)",
-	"AGATCGGAAGAGCGTCGTGT":  "TruSeq P5",
-	"GATCGTCGGACTGTAGAACT":  "5' RNA P5",
-	"CTGTCTCTTATACACATCT":   "Tn5 ME",
+	"AGATCGGAAGAGCACACGTC": "TruSeq P7",
+	"GATCGGAAGAGCACACGTCT": "TruSeq P7(-1)",
+	"TGGAATTCTCGGGTGCCAAG": "3' RNA (legacy)",
+	"AGATCGGAAGAGCGTCGTGT": "TruSeq P5",
+	"GATCGTCGGACTGTAGAACT": "5' RNA P5",
+	"CTGTCTCTTATACACATCT":  "Tn5 ME",
 }
 
 var fastqCmd = &cobra.Command{
-	Use:   "fastq [filename]",
-	Short: "Colorize and visualize FASTQ",
+	Use:   "fastq [R1] [R2]",
+	Short: "Colorize and visualize FASTQ and FASTA",
 	Long: `Colorize nucleotides, visualize quality with colored blocks, and detect adapters.
+FASTA files are shown the same way, with each record on one line and without
+the quality bar. Files ending in .gz, .bz2, .xz or .zst are decompressed.
 
 Output:
-  - Bases: A=red, T=green, G=yellow, C=blue
+  - Bases: A=red, T=green, G=yellow, C=blue (see --palette)
   - Quality bar: green(≥30), yellow(20-29), red(10-19), grey(<10)
   - Per-read quality stats: avgQ[min..max] appended to quality line
   - Adapter region highlighted with black background
 
+Paired-end:
+  Given two files, mates are read together and R2 is shown below R1, or next
+  to it with --side-by-side. Read names must pair up (comments and /1, /2
+  suffixes are ignored), otherwise hey stops at the first unpaired read.
+  Adapters are searched in both mates; pairs where both mates run into an
+  adapter are counted as read-through in the summary. --interleaved shows the
+  reads of an interleaved file (R1 and R2 of each pair one after the other)
+  as pairs the same way, if its first two reads are mates.
+
+Several files:
+  Given one file, or more than two (or two with --separate), the files are
+  shown one after the other, each with a banner and its own summary. -n and
+  --tail apply to every file.
+
+HTML report:
+  --html report.html writes the reads, as they would be shown, to a standalone
+  web page (colors, quality bars, adapters and summary included) that can be
+  sent to anyone without hey, e.g. a QC snapshot for the wet lab.
+
+Adapters:
+  A built-in set of Illumina and Tn5 adapters is searched (see --list-adapters).
+  --adapters replaces it with the sequences of a FASTA file or of a TSV file
+  with a name and a sequence per line, e.g. for a core's own primer set.
+  Adapters are found at the 3' end, where the read runs into them (anywhere in
+  the read for a whole adapter), and at the 5' end, where ligation-based
+  protocols leave the end of an adapter or primer before the insert (8 bases
+  or more). Both are shown on a black background and named in the name line.
+
+UMIs:
+  --umi-len 8 highlights the first 8 bases of every read as its UMI, and
+  --umi-pattern takes a layout of the read start like the --bc-pattern of
+  umi_tools, e.g. CCCCCCNNNNNNNN for a 6 base cell barcode followed by an 8
+  base UMI. UMI bases are shown in light magenta and barcode bases in light
+  cyan. For reads whose UMI was already moved into the name, --umi-regex
+  takes a regular expression for the name instead, whose first group is the
+  UMI, e.g. '_([ACGTN]+)$' (umi_tools) or 'RX:Z:(\S+)' (fgbio). The UMI found
+  is shown after the read name.
+
+Quality:
+  -q 20 shows the bases with a quality below 20 in cyan instead of their base
+  color, like -q of sam2pairwise, so that low-quality stretches stand out in
+  the sequence line too.
+
+Color by quality:
+  --color-by quality colors every base by its quality instead of its
+  nucleotide, on a gradient from light green (Q35 and up) over green,
+  yellow and red to grey (below Q10), so that a local drop in quality, e.g.
+  where a read runs into the adapter, stands out in the sequence itself.
+  FASTA records, which have no qualities, are still colored by base.
+
+Homopolymers:
+  --homopolymer 5 underlines every run of 5 or more identical bases, the main
+  source of indel errors of nanopore and Ion Torrent reads, so that their
+  length can be counted along the underline.
+
+Interactive:
+  -i opens the file in a pager instead of printing it: j/k or the arrow keys
+  scroll, PgUp/PgDn page, g/G go to the start or end, :N jumps to record N
+  and / searches names and sequences like --grep as you type (n/N for the
+  next or previous match). Records are read as you scroll.
+
+GC content:
+  --gc adds the GC% of every read (of its A, C, G and T bases) to its name
+  line and a histogram of the GC% of the reads shown to the summary. A second
+  peak away from the main one often means contamination with another species.
+
+Duplicates:
+  --mark-dups flags every read whose exact sequence was already shown, with
+  how many times it was seen so far and the fraction of duplicate reads up to
+  it, and adds the number of duplicates to the summary. Many duplicates among
+  the first reads hint at a library of low complexity (or over-amplified).
+  Mates are compared with the mates of the same side.
+
+Statistics:
+  --stats reads every file given (any number) and prints one table row per
+  file: reads, bases, min/mean/max length, % bases with Q20 and Q30, GC and N.
+
+Validation:
+  --validate checks every record of every file given, like fqlint: a header
+  starting with @, a + line (naming the same read, if it names one), as many
+  quality scores as bases, only IUPAC bases and printable quality characters,
+  and no truncated last record. Problems are listed with their line numbers
+  and hey exits with an error if there are any, e.g. in a pipeline after a
+  transfer. Compressed files are checked as they are decompressed.
+
+Quality profile:
+  --qual-profile plots the quality by cycle of the first 100000 reads (or -n)
+  of every file given, like the per base quality plot of FastQC: the median
+  quality of each cycle as a block colored like the quality bar, the quartiles
+  as a shaded box and the 10th-90th percentiles as a whisker.
+
+Overrepresented sequences:
+  --overrep counts the sequences of the first 100000 reads (or -n) of every
+  file given and lists the most frequent ones that make up at least 0.1% of
+  the reads, like the overrepresented sequences of FastQC, with the adapter
+  (from --adapters) or homopolymer they may come from. Reads longer than 75
+  bases are counted by their first 50. --kmer 7 counts and lists the most
+  frequent 7-mers instead, which also finds adapters at varying positions.
+
 Options:
-  -n limit   Show only first N records (default: unlimited)
+  -n limit   Show only first N records, or N pairs (default: unlimited)
+  --tail N   Show only the last N records, or N pairs; the whole file is read
+
+Sampling:
+  The first reads of a run are often biased (e.g. by the flow cell edges).
+  --sample 0.01 shows a random 1% of the records from the whole file instead,
+  and --seed makes the choice repeatable. Combined with -n, sampling stops
+  after N records are shown.
+
+Length:
+  --min-len and --max-len show only the reads whose length is within the
+  window, e.g. --min-len 18 --max-len 30 for the miRNAs of a small RNA
+  library. Both mates of a pair have to be within it. Like --grep, the window
+  applies before -n, --tail and --sample, and to --qual-profile, --overrep
+  and -i too.
+
+Search:
+  --grep PATTERN shows only the records whose name contains PATTERN or whose
+  sequence contains it, where IUPAC codes match any of their bases (R=A/G,
+  N=any, ...). Add --revcomp to find the pattern on the reverse strand too,
+  e.g. a primer. -n and --tail count matching records; of pairs, one mate
+  has to match.
   -c compact Truncate sequences longer than this width (default: 80; 0=off)`,
-	Args: cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) > 0 {
-			renderFASTQ(args[0])
-		} else {
-			renderFASTQ("-")
+	Args:         cobra.ArbitraryArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		palette, err := loadPalette(fastqPalette)
+		if err != nil {
+			return err
+		}
+		fastqStyle = render.Options{Palette: palette, QualityCutoff: fastqQualCutoff, LowQualityColor: "cyan",
+			ColorByQuality: fastqColorBy == "quality"}
+		if fastqNumRecords > 0 && fastqTail > 0 {
+			return fmt.Errorf("use either -n/--num-records or --tail")
+		}
+		activeUMI = nil
+		umiOptions := 0
+		for _, set := range []bool{fastqUMILen > 0, fastqUMIPattern != "", fastqUMIRegex != ""} {
+			if set {
+				umiOptions++
+			}
+		}
+		switch {
+		case umiOptions > 1:
+			return fmt.Errorf("use only one of --umi-len, --umi-pattern and --umi-regex")
+		case fastqUMILen < 0:
+			return fmt.Errorf("--umi-len must not be negative")
+		case fastqUMILen > 0:
+			activeUMI = &fastqUMI{layout: strings.Repeat("N", fastqUMILen)}
+		case fastqUMIPattern != "":
+			if activeUMI, err = parseUMIPattern(fastqUMIPattern); err != nil {
+				return err
+			}
+		case fastqUMIRegex != "":
+			if activeUMI, err = parseUMIRegex(fastqUMIRegex); err != nil {
+				return err
+			}
+		}
+		if fastqColorBy != "base" && fastqColorBy != "quality" {
+			return fmt.Errorf("--color-by must be base or quality, not %q", fastqColorBy)
+		}
+		if fastqHomopolymer < 0 {
+			return fmt.Errorf("--homopolymer must not be negative")
+		}
+		if fastqMinLen < 0 || fastqMaxLen < 0 {
+			return fmt.Errorf("--min-len and --max-len must not be negative")
+		}
+		if fastqMaxLen > 0 && fastqMinLen > fastqMaxLen {
+			return fmt.Errorf("--min-len %d is greater than --max-len %d", fastqMinLen, fastqMaxLen)
+		}
+		if fastqSample <= 0 || fastqSample > 1 {
+			return fmt.Errorf("--sample must be a fraction between 0 and 1")
+		}
+		if fastqQualCutoff < 0 {
+			return fmt.Errorf("--quality-cutoff must not be negative")
+		}
+		if fastqNumRecords < 0 || fastqTail < 0 {
+			return fmt.Errorf("-n/--num-records and --tail must not be negative")
+		}
+		if fastqAdapters != "" {
+			adapters, err := loadAdapters(fastqAdapters)
+			if err != nil {
+				return err
+			}
+			adapterDict = adapters
+		}
+		if fastqListAdapters {
+			printAdapters(adapterDict)
+			return nil
+		}
+		if fastqHTML != "" && (fastqValidate || fastqStatsMode || fastqProfile || fastqOverrep || fastqInteractive) {
+			return fmt.Errorf("--html writes the reads, not --validate, --stats, --qual-profile, --overrep or -i")
+		}
+		if fastqValidate {
+			if len(args) == 0 {
+				args = []string{"-"}
+			}
+			return printFASTQValidation(args)
+		}
+		if fastqStatsMode {
+			if len(args) == 0 {
+				args = []string{"-"}
+			}
+			return printFASTQStats(args)
+		}
+		if fastqProfile && fastqOverrep {
+			return fmt.Errorf("use either --qual-profile or --overrep")
+		}
+		if fastqKmer < 0 {
+			return fmt.Errorf("--kmer must not be negative")
+		}
+		if fastqProfile || fastqOverrep {
+			render := renderQualityProfile
+			if fastqOverrep {
+				render = renderOverrepresented
+			}
+			if len(args) == 0 {
+				args = []string{"-"}
+			}
+			for i, filename := range args {
+				if len(args) > 1 {
+					printFileBanner(filename, i == 0)
+				}
+				if err := render(filename); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if fastqInteractive {
+			if len(args) > 1 {
+				return fmt.Errorf("-i/--interactive shows one file")
+			}
+			if len(args) == 0 {
+				args = []string{"-"}
+			}
+			return runFASTQViewer(args[0])
+		}
+		if fastqInterleaved && len(args) == 2 && !fastqSeparate {
+			return fmt.Errorf("--interleaved reads the mates from one file, use --separate to show two files")
+		}
+
+		continueProcessing, stop := watchFASTQInterrupt()
+		defer stop()
+		if fastqHTML == "" {
+			return renderFASTQFiles(args, continueProcessing)
+		}
+		report, err := newHTMLReport(fastqHTML, "hey fastq "+strings.Join(args, " "))
+		if err != nil {
+			return fmt.Errorf("creating HTML report: %w", err)
 		}
+		fastqOut = report
+		err = renderFASTQFiles(args, continueProcessing)
+		fastqOut = os.Stdout
+		if closeErr := report.Close(); closeErr != nil {
+			return fmt.Errorf("writing HTML report: %w", closeErr)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote the reads to %s\n", fastqHTML)
+		return err
 	},
 }
 
+// renderFASTQFiles shows the reads of the files given: R1 and R2 pairs of two
+// files, or else every file in turn (stdin if there are none).
+func renderFASTQFiles(args []string, continueProcessing *int32) error {
+	switch {
+	case len(args) == 0:
+		return renderFASTQ("-", continueProcessing)
+	case len(args) == 2 && !fastqSeparate:
+		return renderPairedFASTQ(args[0], args[1], continueProcessing)
+	}
+	for i, filename := range args {
+		if atomic.LoadInt32(continueProcessing) == 0 {
+			break
+		}
+		if len(args) > 1 {
+			printFileBanner(filename, i == 0)
+		}
+		if err := renderFASTQ(filename, continueProcessing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printFileBanner prints the name of the next of several files, after a blank
+// line unless it is the first.
+func printFileBanner(filename string, first bool) {
+	if !first {
+		fmt.Fprintln(fastqOut)
+	}
+	line := "<bold><bg-blue><white> " + filename + " </white></bg-blue></bold>"
+	if rendered, err := tml.Parse(line); err == nil {
+		line = rendered
+	}
+	fmt.Fprintln(fastqOut, line)
+}
+
 func init() {
 	rootCmd.AddCommand(fastqCmd)
-	fastqCmd.Flags().IntVarP(&fastqMaxRecords, "max-records", "n", 0, "Limit to first N records (0=unlimited)")
+	fastqCmd.Flags().IntVarP(&fastqNumRecords, "num-records", "n", 0, "Show only the first N records, or pairs (0=unlimited)")
+	fastqCmd.Flags().IntVar(&fastqNumRecords, "max-records", 0, "Show only the first N records")
+	fastqCmd.Flags().MarkDeprecated("max-records", "use --num-records instead")
+	fastqCmd.Flags().IntVar(&fastqTail, "tail", 0, "Show only the last N records, or pairs")
+	fastqCmd.Flags().BoolVar(&fastqProfile, "qual-profile", false, "Plot the quality at every cycle of the first reads (-n, default 100000) instead of the reads")
+	fastqCmd.Flags().BoolVar(&fastqOverrep, "overrep", false, "List the overrepresented sequences of the first reads (-n, default 100000) instead of the reads")
+	fastqCmd.Flags().IntVar(&fastqKmer, "kmer", 0, "With --overrep, count k-mers of this length instead of whole sequences")
+	fastqCmd.Flags().Float64Var(&fastqSample, "sample", 1, "Show a random fraction of the records, or pairs, e.g. 0.01")
+	fastqCmd.Flags().Int64Var(&fastqSeed, "seed", 0, "Random seed for --sample (default 0, different every run)")
+	fastqCmd.Flags().IntVar(&fastqMinLen, "min-len", 0, "Show only reads of at least this length")
+	fastqCmd.Flags().IntVar(&fastqMaxLen, "max-len", 0, "Show only reads of at most this length (0=unlimited)")
+	fastqCmd.Flags().StringVar(&fastqGrep, "grep", "", "Show only records whose name or sequence (IUPAC codes allowed) contains PATTERN")
+	fastqCmd.Flags().IntVar(&fastqUMILen, "umi-len", 0, "Highlight the first N bases of every read as its UMI")
+	fastqCmd.Flags().StringVar(&fastqUMIPattern, "umi-pattern", "", "Highlight UMIs by a layout of the read start like NNNNNNXX (C = cell barcode)")
+	fastqCmd.Flags().StringVar(&fastqUMIRegex, "umi-regex", "", "Show the UMI in the read name matched by a regular expression (first group)")
+	fastqCmd.Flags().IntVarP(&fastqQualCutoff, "quality-cutoff", "q", 0, "Show bases below this quality in cyan (default 0, disabled)")
+	fastqCmd.Flags().BoolVar(&fastqValidate, "validate", false, "Check the structure of every record and list the problems, exiting with an error if any")
+	fastqCmd.Flags().BoolVar(&fastqStatsMode, "stats", false, "Print a table of read, length, quality, GC and N statistics per file instead of the reads")
+	fastqCmd.Flags().BoolVar(&fastqRevcomp, "revcomp", false, "With --grep, also search the reverse complement of the sequence pattern")
 	fastqCmd.Flags().IntVarP(&fastqCompactLen, "compact", "c", 80, "Truncate reads longer than this length (0=off)")
+	fastqCmd.Flags().StringVar(&fastqPalette, "palette", "", "Base colors: default, igv, colorblind or a palette from the config file (default $HEY_PALETTE)")
+	fastqCmd.Flags().StringVar(&fastqColorBy, "color-by", "base", "Color the bases by base or by quality")
+	fastqCmd.Flags().IntVar(&fastqHomopolymer, "homopolymer", 0, "Underline runs of at least this many identical bases (default 0, off)")
+	fastqCmd.Flags().BoolVar(&fastqMarkDups, "mark-dups", false, "Flag reads whose exact sequence was shown before and count duplicates in the summary")
+	fastqCmd.Flags().BoolVar(&fastqGC, "gc", false, "Show the GC content of every read and a histogram of it in the summary")
+	fastqCmd.Flags().BoolVarP(&fastqInteractive, "interactive", "i", false, "Browse the records in an interactive pager with search")
+	fastqCmd.Flags().StringVar(&fastqHTML, "html", "", "Write the reads to this standalone HTML file instead of the terminal")
+	fastqCmd.Flags().BoolVar(&fastqInterleaved, "interleaved", false, "Show the mates of a file with alternating R1 and R2 reads as pairs, if its first reads pair up")
+	fastqCmd.Flags().BoolVar(&fastqSeparate, "separate", false, "Show two files one after the other instead of as R1 and R2")
+	fastqCmd.Flags().BoolVar(&fastqSideBySide, "side-by-side", false, "Show paired mates next to each other instead of R2 below R1")
+	fastqCmd.Flags().StringVar(&fastqAdapters, "adapters", "", "FASTA or TSV (name, sequence) file with the adapters to search for")
+	fastqCmd.Flags().BoolVar(&fastqListAdapters, "list-adapters", false, "List the adapters that are searched for and exit")
 }
 
 type readQualStats struct {
@@ -82,6 +442,60 @@ type fastqStats struct {
 	totalLen       int64
 	totalQual      int64
 	baseQualCount  int64
+	pairs          int
+	readThrough    int   // Pairs with an adapter in both mates
+	gcCounts       []int // Reads by GC% in fastqGCBins bins, for --gc
+	gcSum          float64
+	seqCounts      map[uint64]int // Times each sequence was seen, by hash, for --mark-dups
+	duplicates     int            // Reads whose sequence was seen before
+}
+
+// addSeq counts the sequence of a read of mate ("" unless paired) for
+// --mark-dups and returns how many times it was seen, including this read.
+// Sequences are kept as 64-bit hashes so that long files fit in memory.
+func (s *fastqStats) addSeq(mate, seq string) int {
+	if s.seqCounts == nil {
+		s.seqCounts = make(map[uint64]int)
+	}
+	h := fnv.New64a()
+	h.Write([]byte(mate + ":" + strings.ToUpper(seq)))
+	key := h.Sum64()
+	s.seqCounts[key]++
+	if s.seqCounts[key] > 1 {
+		s.duplicates++
+	}
+	return s.seqCounts[key]
+}
+
+// fastqGCBins is the number of bins of the --gc histogram, 5% wide.
+const fastqGCBins = 20
+
+// gcPercent returns the GC content of seq in percent, ignoring Ns and other
+// ambiguous bases, and false if seq has no A, C, G or T.
+func gcPercent(seq string) (float64, bool) {
+	gc, acgt := 0, 0
+	for i := 0; i < len(seq); i++ {
+		switch seq[i] {
+		case 'G', 'C', 'g', 'c':
+			gc++
+			acgt++
+		case 'A', 'T', 'a', 't':
+			acgt++
+		}
+	}
+	if acgt == 0 {
+		return 0, false
+	}
+	return float64(gc) / float64(acgt) * 100, true
+}
+
+// addGC adds the GC content of a read to the --gc histogram.
+func (s *fastqStats) addGC(gc float64) {
+	if s.gcCounts == nil {
+		s.gcCounts = make([]int, fastqGCBins)
+	}
+	s.gcCounts[min(int(gc/100*fastqGCBins), fastqGCBins-1)]++
+	s.gcSum += gc
 }
 
 func (s *fastqStats) avgQuality() float64 {
@@ -98,123 +512,319 @@ func (s *fastqStats) avgLength() float64 {
 	return float64(s.totalLen) / float64(s.totalRecords)
 }
 
-func renderFASTQ(filename string) {
-	var reader io.Reader
-
-	if filename == "" || filename == "-" {
-		reader = os.Stdin
-	} else if strings.HasSuffix(filename, ".gz") {
-		file, err := os.Open(filename)
+// renderFASTQ shows the records of filename, or its pairs with
+// --interleaved if it is interleaved, until continueProcessing is cleared.
+func renderFASTQ(filename string, continueProcessing *int32) error {
+	input, err := openInput(filename)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	reader := newFASTQReader(input)
+	if fastqInterleaved {
+		interleaved, err := detectInterleaved(reader)
 		if err != nil {
-			fmt.Println("Error opening file:", err)
-			return
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		if interleaved {
+			return renderPairs(reader, reader, filename, filename, continueProcessing)
 		}
-		defer file.Close()
+		fmt.Fprintf(os.Stderr, "%s is not interleaved: the first two reads are not mates\n", filename)
+	}
 
-		gz, err := gzip.NewReader(file)
-		if err != nil {
-			fmt.Println("Error opening gzip file:", err)
-			return
+	stats := &fastqStats{
+		adapterHits: make(map[string]int),
+	}
+	show := func(record *fastqRecord) {
+		lines, _ := formatFASTQRecord(record, "", stats)
+		for _, line := range lines {
+			fmt.Fprintln(fastqOut, line)
 		}
-		defer gz.Close()
-		reader = gz
-	} else {
-		file, err := os.Open(filename)
-		if err != nil {
-			fmt.Println("Error opening file:", err)
-			return
+	}
+	matcher := newFASTQMatcher(fastqGrep, fastqRevcomp)
+	sample := fastqSampler()
+	tail := newTailBuffer[*fastqRecord](fastqTail)
+	for atomic.LoadInt32(continueProcessing) == 1 {
+		record, readErr := reader.Read()
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			err = fmt.Errorf("%s: %w", filename, readErr)
+			break
+		}
+		if !inLengthWindow(record) || !matcher.match(record) || !sample() {
+			continue
+		}
+		if fastqTail > 0 {
+			tail.add(record)
+			continue
+		}
+		show(record)
+		if fastqNumRecords > 0 && stats.totalRecords >= fastqNumRecords {
+			break
 		}
-		defer file.Close()
-		reader = file
+	}
+	for _, record := range tail.items() {
+		show(record)
 	}
 
-	interruptChan := make(chan os.Signal, 1)
-	signal.Notify(interruptChan, syscall.SIGINT, syscall.SIGTERM)
-	defer signal.Stop(interruptChan)
+	if stats.totalRecords > 0 {
+		printSummary(fastqOut, stats)
+	}
+	return err
+}
 
-	scanner := bufio.NewScanner(reader)
-	buf := make([]byte, 512*1024)
-	scanner.Buffer(buf, 10*1024*1024)
-	lineCount := 0
-	continueProcessing := int32(1)
-	readName := ""
+// renderPairedFASTQ shows the mates of r1 and r2 together, stopping with an
+// error at the first pair whose read names do not match.
+func renderPairedFASTQ(r1, r2 string, continueProcessing *int32) error {
+	input1, err := openInput(r1)
+	if err != nil {
+		return err
+	}
+	defer input1.Close()
+	input2, err := openInput(r2)
+	if err != nil {
+		return err
+	}
+	defer input2.Close()
+	return renderPairs(newFASTQReader(input1), newFASTQReader(input2), r1, r2, continueProcessing)
+}
 
+// renderPairs shows the mates read from reader1 and reader2 (of files r1 and
+// r2) together. Both are the same reader for an interleaved file.
+func renderPairs(reader1, reader2 *fastqReader, r1, r2 string, continueProcessing *int32) error {
+	var err error
 	stats := &fastqStats{
 		adapterHits: make(map[string]int),
 	}
-
-	go func() {
-		<-interruptChan
-		fmt.Println("\nReceived interrupt. Finishing current record...")
-		atomic.StoreInt32(&continueProcessing, 0)
-	}()
-
-	for scanner.Scan() && atomic.LoadInt32(&continueProcessing) == 1 {
-		line := scanner.Text()
-		lineCount++
-
-		switch lineCount % 4 {
-		case 1: // Header
-			readName = ""
-			if len(line) > 1 {
-				readName = line[1:]
-			}
-		case 2: // Sequence
-			stats.totalLen += int64(len(line))
-			adapterName := ""
-			adapterPos := -1
-			for _, info := range findAdapterWithMismatch(line, 5, 0.05) {
-				adapterName = info.name
-				adapterPos = info.pos
-				stats.adapterRecords++
-				stats.adapterHits[adapterName]++
-				break
+	leftWidth := 0
+	show := func(mate1, mate2 *fastqRecord) {
+		lines1, adapter1 := formatFASTQRecord(mate1, "R1", stats)
+		lines2, adapter2 := formatFASTQRecord(mate2, "R2", stats)
+		stats.pairs++
+		if adapter1 && adapter2 {
+			stats.readThrough++
+		}
+		if fastqSideBySide {
+			for _, line := range lines1 {
+				leftWidth = max(leftWidth, visibleWidth(line))
 			}
-
-			printLabel(readName, adapterName, adapterPos, len(line))
-			printSequence(line, adapterPos)
-		case 3: // "+" (skip)
-		case 0: // Quality
-			// Compute per-read quality stats
-			currQual := &readQualStats{min: math.MaxInt32}
-			for i := 0; i < len(line); i++ {
-				score := int(line[i]) - 33
-				if score < 0 {
-					score = 0
+			for i := range max(len(lines1), len(lines2)) {
+				left, right := "", ""
+				if i < len(lines1) {
+					left = lines1[i]
 				}
-				currQual.sum += int64(score)
-				currQual.count++
-				if score < currQual.min {
-					currQual.min = score
+				if i < len(lines2) {
+					right = lines2[i]
 				}
-				if score > currQual.max {
-					currQual.max = score
-				}
-				stats.totalQual += int64(score)
-			}
-			if currQual.count == 0 {
-				currQual.min = 0
-			}
-			stats.baseQualCount += int64(len(line))
-			printQuality(line, currQual)
-			stats.totalRecords++
-
-			if fastqMaxRecords > 0 && stats.totalRecords >= fastqMaxRecords {
-				atomic.StoreInt32(&continueProcessing, 0)
+				fmt.Fprintln(fastqOut, left+strings.Repeat(" ", leftWidth-visibleWidth(left)+3)+right)
 			}
+			return
+		}
+		if stats.pairs > 1 {
+			fmt.Fprintln(fastqOut)
+		}
+		for _, line := range append(lines1, lines2...) {
+			fmt.Fprintln(fastqOut, line)
 		}
 	}
+	matcher := newFASTQMatcher(fastqGrep, fastqRevcomp)
+	sample := fastqSampler()
+	tail := newTailBuffer[[2]*fastqRecord](fastqTail)
+	for n := 1; atomic.LoadInt32(continueProcessing) == 1; n++ {
+		mate1, err1 := reader1.Read()
+		mate2, err2 := reader2.Read()
+		if err1 == io.EOF && err2 == io.EOF {
+			break
+		}
+		switch {
+		case err1 != nil && err1 != io.EOF:
+			err = fmt.Errorf("%s: %w", r1, err1)
+		case err2 != nil && err2 != io.EOF:
+			err = fmt.Errorf("%s: %w", r2, err2)
+		case reader1 == reader2 && err2 == io.EOF:
+			err = fmt.Errorf("%s ends with the unpaired read %s", r1, mate1.id())
+		case err1 == io.EOF:
+			err = fmt.Errorf("%s has fewer reads than %s", r1, r2)
+		case err2 == io.EOF:
+			err = fmt.Errorf("%s has fewer reads than %s", r2, r1)
+		case mateID(mate1.Name) != mateID(mate2.Name):
+			err = fmt.Errorf("read %d is not paired: %s in %s, %s in %s", n, mate1.id(), r1, mate2.id(), r2)
+		}
+		if err != nil {
+			break
+		}
+		if !inLengthWindow(mate1) || !inLengthWindow(mate2) ||
+			(!matcher.match(mate1) && !matcher.match(mate2)) || !sample() {
+			continue
+		}
 
-	if err := scanner.Err(); err != nil {
-		fmt.Println("Error reading file:", err)
+		if fastqTail > 0 {
+			tail.add([2]*fastqRecord{mate1, mate2})
+			continue
+		}
+		show(mate1, mate2)
+		if fastqNumRecords > 0 && stats.pairs >= fastqNumRecords {
+			break
+		}
+	}
+	for _, pair := range tail.items() {
+		show(pair[0], pair[1])
 	}
 
 	if stats.totalRecords > 0 {
-		printSummary(stats)
+		printSummary(fastqOut, stats)
 	}
+	return err
 }
 
-func printLabel(readName, adapterName string, adapterPos, seqLen int) {
+// fastqSampler returns a function that tells whether to show the next record
+// with --sample.
+func fastqSampler() func() bool {
+	if fastqSample >= 1 {
+		return func() bool { return true }
+	}
+	seed := fastqSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+	return func() bool { return rng.Float64() < fastqSample }
+}
+
+// tailBuffer keeps the last n items added to it.
+type tailBuffer[T any] struct {
+	buf  []T
+	n    int
+	next int // Index of the oldest item once the buffer is full
+}
+
+func newTailBuffer[T any](n int) *tailBuffer[T] {
+	return &tailBuffer[T]{n: n}
+}
+
+func (t *tailBuffer[T]) add(item T) {
+	if len(t.buf) < t.n {
+		t.buf = append(t.buf, item)
+		return
+	}
+	t.buf[t.next] = item
+	t.next = (t.next + 1) % t.n
+}
+
+// items returns the items from oldest to newest.
+func (t *tailBuffer[T]) items() []T {
+	items := make([]T, 0, len(t.buf))
+	items = append(items, t.buf[t.next:]...)
+	return append(items, t.buf[:t.next]...)
+}
+
+// watchFASTQInterrupt returns a flag that is cleared on SIGINT or SIGTERM so
+// that the current record is finished before stopping, and a function that
+// stops watching.
+func watchFASTQInterrupt() (*int32, func()) {
+	continueProcessing := int32(1)
+	interruptChan := make(chan os.Signal, 1)
+	signal.Notify(interruptChan, syscall.SIGINT, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-interruptChan:
+			fmt.Println("\nReceived interrupt. Finishing current record...")
+			atomic.StoreInt32(&continueProcessing, 0)
+		case <-done:
+		}
+	}()
+	return &continueProcessing, func() {
+		signal.Stop(interruptChan)
+		close(done)
+	}
+}
+
+// formatFASTQRecord returns the label, sequence and quality lines of record,
+// adding it to stats. mate ("R1" or "R2") is shown before the name of paired
+// reads. It also tells whether an adapter was found.
+func formatFASTQRecord(record *fastqRecord, mate string, stats *fastqStats) ([]string, bool) {
+	stats.totalLen += int64(len(record.Seq))
+	hits := findFivePrimeAdapter(record.Seq, fivePrimeMinMatch, 0.05)
+	adapterStart := 0
+	if len(hits) > 0 {
+		adapterStart = hits[0].pos
+	}
+	adapterPos := -1
+	for _, info := range findAdapterWithMismatch(record.Seq, 5, 0.05) {
+		if info.pos >= adapterStart {
+			adapterPos = info.pos
+			hits = append(hits, info)
+		}
+		break
+	}
+	if len(hits) > 0 {
+		stats.adapterRecords++
+	}
+	for _, info := range hits {
+		name := info.name
+		if info.fivePrime {
+			name += " (5')"
+		}
+		stats.adapterHits[name]++
+	}
+
+	// Compute per-read quality stats
+	currQual := &readQualStats{min: math.MaxInt32}
+	for i := 0; i < len(record.Qual); i++ {
+		score := int(record.Qual[i]) - 33
+		if score < 0 {
+			score = 0
+		}
+		currQual.sum += int64(score)
+		currQual.count++
+		if score < currQual.min {
+			currQual.min = score
+		}
+		if score > currQual.max {
+			currQual.max = score
+		}
+		stats.totalQual += int64(score)
+	}
+	if currQual.count == 0 {
+		currQual.min = 0
+	}
+	stats.baseQualCount += int64(len(record.Qual))
+	stats.totalRecords++
+
+	label := formatLabel(record.Name, len(record.Seq), hits)
+	if activeUMI != nil {
+		if umi := activeUMI.extract(record); umi != "" {
+			label += tml.Sprintf(" <lightmagenta>UMI %s</lightmagenta>", umi)
+		}
+	}
+	if fastqGC {
+		if gc, ok := gcPercent(record.Seq); ok {
+			stats.addGC(gc)
+			label += tml.Sprintf(" <darkgrey>GC %.0f%%</darkgrey>", gc)
+		}
+	}
+	if fastqMarkDups {
+		if seen := stats.addSeq(mate, record.Seq); seen > 1 {
+			label += tml.Sprintf(" <yellow>dup %d×</yellow> <darkgrey>(%.1f%% dups)</darkgrey>", seen,
+				float64(stats.duplicates)/float64(stats.totalRecords)*100)
+		}
+	}
+	if mate != "" {
+		label = tml.Sprintf("<bold>%s</bold> ", mate) + label
+	}
+	lines := []string{label, formatSequence(record.Seq, record.Qual, adapterStart, adapterPos)}
+	if record.Qual != "" {
+		lines = append(lines, formatQuality(record.Qual, currQual))
+	}
+	return lines, adapterPos >= 0
+}
+
+// formatLabel returns the name line of a read with the adapters found in it:
+// the length of a 5' adapter, and the number of bases from a 3' adapter on.
+func formatLabel(readName string, seqLen int, hits []adapterInfo) string {
 	parts := strings.Fields(readName)
 	shortName := readName
 	if len(parts) >= 1 {
@@ -223,47 +833,75 @@ func printLabel(readName, adapterName string, adapterPos, seqLen int) {
 
 	line := tml.Sprintf("<italic>%s [%d bp]</italic>", shortName, seqLen)
 
-	if adapterName != "" {
-		remaining := seqLen - adapterPos
+	for _, info := range hits {
+		if info.fivePrime {
+			line += " " + tml.Sprintf("<bold><bg-red>5' %s %dnt</bg-red></bold>", info.name, info.pos)
+			continue
+		}
+		remaining := seqLen - info.pos
 		line += " " + tml.Sprintf("<bold><bg-red>%s +%dnt</bg-red></bold>",
-			adapterName, remaining)
+			info.name, remaining)
 	}
+	return line
+}
 
-	tml.Println(line)
+// formatSequence returns the colored bases of seq, truncated to the compact
+// width, with the 5' adapter before adapterStart and the 3' adapter from
+// adapterPos on (if >= 0) on a black background. qual is the quality of seq,
+// or "" for FASTA.
+func formatSequence(seq, qual string, adapterStart, adapterPos int) string {
+	markup := ""
+	if adapterStart > 0 {
+		markup = "<bg-black><darkgrey>" + seq[:adapterStart] + "</darkgrey></bg-black>"
+		seq = seq[adapterStart:]
+		if len(qual) >= adapterStart {
+			qual = qual[adapterStart:]
+		}
+		if adapterPos >= 0 {
+			adapterPos -= adapterStart
+		}
+	}
+	return renderMarkup(markup + insertMarkup(seq, qual, adapterPos))
 }
 
-func printSequence(seq string, adapterPos int) {
+// insertMarkup returns the markup of the bases of a read after any 5' adapter
+// for formatSequence.
+func insertMarkup(seq, qual string, adapterPos int) string {
 	truncLen := fastqCompactLen
 
 	if adapterPos >= 0 {
 		before := seq[:adapterPos]
-		after := seq[adapterPos:]
+		after := "<bg-black><darkgrey>" + seq[adapterPos:] + "</darkgrey></bg-black>"
 
 		if truncLen > 0 && utf8.RuneCountInString(before) > truncLen-6 {
-			limit := truncLen - 6
-			idx := byteAtRune(before, limit)
-			fmt.Print(colorizeSeq(before[:idx]))
-			fmt.Println(tml.Sprintf(" <grey>...</grey>" +
-				"<bg-black><darkgrey>%s</darkgrey></bg-black>", after))
-		} else {
-			fmt.Print(colorizeSeq(before))
-			fmt.Println(tml.Sprintf("<bg-black><darkgrey>%s</darkgrey></bg-black>", after))
-		}
-	} else {
-		displaySeq := seq
-		trimmed := false
-		if truncLen > 0 && utf8.RuneCountInString(seq) > truncLen {
-			idx := byteAtRune(seq, truncLen-3)
-			displaySeq = seq[:idx]
-			trimmed = true
-		}
-		fmt.Print(colorizeSeq(displaySeq))
-		if trimmed {
-			fmt.Println(tml.Sprintf(" <grey>...</grey>"))
-		} else {
-			fmt.Println()
+			idx := byteAtRune(before, truncLen-6)
+			return readMarkup(before[:idx], qual) + " <darkgrey>...</darkgrey>" + after
 		}
+		return readMarkup(before, qual) + after
 	}
+	if truncLen > 0 && utf8.RuneCountInString(seq) > truncLen {
+		idx := byteAtRune(seq, truncLen-3)
+		return readMarkup(seq[:idx], qual) + " <darkgrey>...</darkgrey>"
+	}
+	return readMarkup(seq, qual)
+}
+
+// renderMarkup turns tml markup into colored text, leaving it as it is if it
+// is not valid markup.
+func renderMarkup(markup string) string {
+	rendered, err := tml.Parse(markup)
+	if err != nil {
+		return markup
+	}
+	return rendered
+}
+
+var ansiEscapeRegex = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// visibleWidth returns the number of terminal columns line takes, ignoring
+// color escape sequences.
+func visibleWidth(line string) int {
+	return runewidth.StringWidth(ansiEscapeRegex.ReplaceAllString(line, ""))
 }
 
 func byteAtRune(s string, n int) int {
@@ -280,7 +918,9 @@ func byteAtRune(s string, n int) int {
 	return i
 }
 
-func printQuality(q string, qs *readQualStats) {
+// formatQuality returns the quality blocks of q, followed by the per-read
+// stats qs.
+func formatQuality(q string, qs *readQualStats) string {
 	display := q
 	trimmed := false
 	maxDisplay := fastqCompactLen
@@ -288,95 +928,83 @@ func printQuality(q string, qs *readQualStats) {
 		display = q[:maxDisplay-3]
 		trimmed = true
 	}
-	fmt.Print(visualizeQuality(display))
+	markup := fastqStyle.Quality(display)
 	if trimmed {
-		fmt.Print(tml.Sprintf(" <grey>...</grey>"))
+		markup += " <darkgrey>...</darkgrey>"
 	}
-	fmt.Print(tml.Sprintf(" <grey>Q%.1f[%d..%d]</grey>", qs.avg(), qs.min, qs.max))
-	fmt.Println()
+	return renderMarkup(markup + fmt.Sprintf(" <darkgrey>Q%.1f[%d..%d]</darkgrey>", qs.avg(), qs.min, qs.max))
 }
 
-func colorizeSeq(seq string) string {
+// readMarkup returns the markup of the bases of seq, a prefix of a read with
+// quality qual, rendered by fastqStyle except for the UMI and cell barcode
+// bases of the layout, and with homopolymer runs underlined.
+func readMarkup(seq, qual string) string {
+	umiLayout := ""
+	if activeUMI != nil {
+		umiLayout = activeUMI.layout
+	}
+	if umiLayout == "" && fastqHomopolymer == 0 {
+		return fastqStyle.Sequence(seq, qual)
+	}
+	runs := homopolymerRuns(seq, fastqHomopolymer)
 	var sb strings.Builder
-	sb.Grow(len(seq) * 24)
 	for i := 0; i < len(seq); i++ {
-		c := seq[i]
-		switch c {
-		case 'A':
-			sb.WriteString(tml.Sprintf("<bg-red>A</bg-red>"))
-		case 'T':
-			sb.WriteString(tml.Sprintf("<bg-green>T</bg-green>"))
-		case 'G':
-			sb.WriteString(tml.Sprintf("<bg-yellow>G</bg-yellow>"))
-		case 'C':
-			sb.WriteString(tml.Sprintf("<bg-blue>C</bg-blue>"))
+		layout := byte('X')
+		if i < len(umiLayout) {
+			layout = umiLayout[i]
+		}
+		if runs != nil && runs[i] {
+			sb.WriteString("<underline>")
+		}
+		switch layout {
 		case 'N':
-			sb.WriteString(tml.Sprintf("<darkgrey>N</darkgrey>"))
+			sb.WriteString("<bg-lightmagenta><black>" + seq[i:i+1] + "</black></bg-lightmagenta>")
+		case 'C':
+			sb.WriteString("<bg-lightcyan><black>" + seq[i:i+1] + "</black></bg-lightcyan>")
 		default:
-			sb.WriteByte(c)
+			sb.WriteString(fastqStyle.Sequence(seq[i:i+1], qual[min(i, len(qual)):min(i+1, len(qual))]))
+		}
+		if runs != nil && runs[i] {
+			sb.WriteString("</underline>")
 		}
 	}
 	return sb.String()
 }
 
-func visualizeQuality(q string) string {
-	var sb strings.Builder
-	sb.Grow(len(q) * 24)
-
-	batchColor := ""
-	for i := 0; i < len(q); i++ {
-		score := int(q[i]) - 33
-		if score < 0 {
-			score = 0
+// homopolymerRuns marks the bases of seq that are part of a run of at least
+// minLen identical bases (not Ns), or returns nil if minLen is 0.
+func homopolymerRuns(seq string, minLen int) []bool {
+	if minLen == 0 {
+		return nil
+	}
+	runs := make([]bool, len(seq))
+	for start := 0; start < len(seq); {
+		end := start + 1
+		for end < len(seq) && upperBase(seq[end]) == upperBase(seq[start]) {
+			end++
 		}
-		ch := blockChar(score)
-		newColor := qualityColor(score)
-
-		if newColor != batchColor {
-			if i > 0 {
-				sb.WriteString(fmt.Sprintf("</%s>", batchColor))
+		if end-start >= minLen && upperBase(seq[start]) != 'N' {
+			for i := start; i < end; i++ {
+				runs[i] = true
 			}
-			batchColor = newColor
-			sb.WriteString(fmt.Sprintf("<%s>", newColor))
 		}
-		sb.WriteRune(ch)
-	}
-	if batchColor != "" {
-		sb.WriteString(fmt.Sprintf("</%s>", batchColor))
+		start = end
 	}
-	return sb.String()
+	return runs
 }
 
-func qualityColor(score int) string {
-	if score >= 30 {
-		return "green"
-	} else if score >= 20 {
-		return "yellow"
-	} else if score >= 10 {
-		return "red"
+// upperBase returns base in upper case.
+func upperBase(base byte) byte {
+	if 'a' <= base && base <= 'z' {
+		return base - 'a' + 'A'
 	}
-	return "darkgrey"
-}
-
-func blockChar(score int) rune {
-	if score >= 40 {
-		return '│'
-	}
-	if score >= 30 {
-		return '▓'
-	}
-	if score >= 20 {
-		return '▒'
-	}
-	if score >= 10 {
-		return '░'
-	}
-	return '·'
+	return base
 }
 
 type adapterInfo struct {
-	name string
-	pos  int
+	name      string
+	pos       int  // Start of a 3' adapter, or end of a 5' one
+	fivePrime bool // Adapter at the start of the read
 }
 
 func findAdapterWithMismatch(sequence string, minLength int, maxMismatchPercentage float64) []adapterInfo {
@@ -412,11 +1040,41 @@ func findAdapterWithMismatch(sequence string, minLength int, maxMismatchPercenta
 	}
 
 	if bestMatchPos != math.MaxInt && bestMatchLength >= minLength {
-		results = append(results, adapterInfo{bestAdapterName, bestMatchPos})
+		results = append(results, adapterInfo{name: bestAdapterName, pos: bestMatchPos})
 	}
 	return results
 }
 
+// fivePrimeMinMatch is the number of bases an adapter end has to share with
+// the start of a read to be reported. It is longer than the 5 bases of 3'
+// adapters, which are cut off by the end of the read, since any read may
+// start like the end of some adapter by chance.
+const fivePrimeMinMatch = 8
+
+// findFivePrimeAdapter looks for the end of an adapter at the start of
+// sequence, as left by ligation-based protocols that put adapters or primers
+// before the insert. pos of the hit is the length of the adapter part, the
+// longest one found.
+func findFivePrimeAdapter(sequence string, minLength int, maxMismatchPercentage float64) []adapterInfo {
+	var best adapterInfo
+	for adapterSeq, adapterName := range adapterDict {
+		for overlapLen := min(len(adapterSeq), len(sequence)); overlapLen >= minLength; overlapLen-- {
+			miss := mismatches(sequence[:overlapLen], adapterSeq[len(adapterSeq)-overlapLen:])
+			if float64(miss)/float64(overlapLen) > maxMismatchPercentage {
+				continue
+			}
+			if overlapLen > best.pos || (overlapLen == best.pos && adapterName < best.name) {
+				best = adapterInfo{name: adapterName, pos: overlapLen, fivePrime: true}
+			}
+			break
+		}
+	}
+	if best.pos == 0 {
+		return nil
+	}
+	return []adapterInfo{best}
+}
+
 func mismatches(seq1, seq2 string) int {
 	count := 0
 	for i := 0; i < len(seq1); i++ {
@@ -427,23 +1085,45 @@ func mismatches(seq1, seq2 string) int {
 	return count
 }
 
-func printSummary(stats *fastqStats) {
-	fmt.Println()
+func printSummary(w io.Writer, stats *fastqStats) {
+	fmt.Fprintln(w)
 	sep := tml.Sprintf(" <blue>%s</blue>", strings.Repeat("─", 50))
-	fmt.Println(sep)
+	fmt.Fprintln(w, sep)
 
-	tml.Printf(" <bold>FASTQ Summary</bold>\n")
-	tml.Printf(" <blue>Records</blue>     : %d\n", stats.totalRecords)
-	tml.Printf(" <blue>Avg Length</blue>  : %.0f bp\n", stats.avgLength())
-	tml.Printf(" <blue>Avg Quality</blue> : Q%.1f\n", stats.avgQuality())
+	tml.Fprintf(w, " <bold>FASTQ Summary</bold>\n")
+	tml.Fprintf(w, " <blue>Records</blue>     : %d\n", stats.totalRecords)
+	if stats.pairs > 0 {
+		tml.Fprintf(w, " <blue>Pairs</blue>       : %d\n", stats.pairs)
+	}
+	tml.Fprintf(w, " <blue>Avg Length</blue>  : %.0f bp\n", stats.avgLength())
+	if stats.baseQualCount > 0 {
+		tml.Fprintf(w, " <blue>Avg Quality</blue> : Q%.1f\n", stats.avgQuality())
+	}
 
 	if stats.adapterRecords > 0 {
 		pct := float64(stats.adapterRecords) / float64(stats.totalRecords) * 100
-		tml.Printf(" <blue>Adapter Hits</blue>: %d (%.1f%%)\n", stats.adapterRecords, pct)
+		tml.Fprintf(w, " <blue>Adapter Hits</blue>: %d (%.1f%%)\n", stats.adapterRecords, pct)
 		for name, cnt := range stats.adapterHits {
 			p := float64(cnt) / float64(stats.totalRecords) * 100
-			tml.Printf("   · %s: %d (%.1f%%)\n", name, cnt, p)
+			tml.Fprintf(w, "   · %s: %d (%.1f%%)\n", name, cnt, p)
+		}
+	}
+	if stats.pairs > 0 {
+		pct := float64(stats.readThrough) / float64(stats.pairs) * 100
+		tml.Fprintf(w, " <blue>Read-through</blue>: %d pairs (%.1f%%)\n", stats.readThrough, pct)
+	}
+	if stats.seqCounts != nil {
+		pct := float64(stats.duplicates) / float64(stats.totalRecords) * 100
+		tml.Fprintf(w, " <blue>Duplicates</blue>  : %d (%.1f%%)\n", stats.duplicates, pct)
+	}
+	if stats.gcCounts != nil {
+		reads := 0
+		for _, c := range stats.gcCounts {
+			reads += c
 		}
+		tml.Fprintf(w, " <blue>Avg GC</blue>      : %.1f%%\n", stats.gcSum/float64(reads))
+		fmt.Fprintln(w)
+		printHistogram(w, stats.gcCounts, 0, 100, 30, false)
 	}
-	fmt.Println(sep)
+	fmt.Fprintln(w, sep)
 }
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yech1990/hey/internal/render"
+)
+
+func TestTailBuffer(t *testing.T) {
+	tests := []struct {
+		name  string
+		n     int
+		added int
+		want  []int
+	}{
+		{"fewer than n", 3, 2, []int{1, 2}},
+		{"exactly n", 3, 3, []int{1, 2, 3}},
+		{"wraps around", 3, 7, []int{5, 6, 7}},
+		{"disabled", 0, 0, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tail := newTailBuffer[int](tt.n)
+			for i := 1; i <= tt.added; i++ {
+				tail.add(i)
+			}
+			assert.Equal(t, tt.want, tail.items())
+		})
+	}
+}
+
+func TestFASTQSampler(t *testing.T) {
+	defer func(sample float64, seed int64) {
+		fastqSample, fastqSeed = sample, seed
+	}(fastqSample, fastqSeed)
+
+	// picks returns which of n records the sampler keeps.
+	picks := func(n int) []int {
+		sample := fastqSampler()
+		var kept []int
+		for i := range n {
+			if sample() {
+				kept = append(kept, i)
+			}
+		}
+		return kept
+	}
+
+	fastqSample, fastqSeed = 0.1, 7
+	first := picks(1000)
+	assert.Equal(t, first, picks(1000))
+	assert.InDelta(t, 100, len(first), 50)
+	fastqSeed = 8
+	assert.NotEqual(t, first, picks(1000))
+
+	fastqSample, fastqSeed = 1, 0
+	assert.Len(t, picks(1000), 1000)
+}
+
+func TestReadMarkup(t *testing.T) {
+	defer func(style render.Options, umi *fastqUMI, homopolymer int) {
+		fastqStyle, activeUMI, fastqHomopolymer = style, umi, homopolymer
+	}(fastqStyle, activeUMI, fastqHomopolymer)
+	fastqStyle = render.Options{Palette: render.Palettes["default"], QualityCutoff: 20, LowQualityColor: "cyan"}
+	activeUMI, fastqHomopolymer = nil, 0
+
+	assert.Equal(t, fastqStyle.Sequence("AGT", "I#I"), readMarkup("AGT", "I#I"))
+
+	activeUMI = &fastqUMI{layout: "NC"}
+	assert.Equal(t, "<bg-lightmagenta><black>A</black></bg-lightmagenta><bg-lightcyan><black>G</black></bg-lightcyan><bg-green>T</bg-green>",
+		readMarkup("AGT", "III"))
+
+	activeUMI, fastqHomopolymer = nil, 2
+	assert.Equal(t, "<bg-red>A</bg-red><underline><cyan>T</cyan></underline><underline><bg-green>T</bg-green></underline>",
+		readMarkup("ATT", "I#"))
+}
+
+func TestGCPercent(t *testing.T) {
+	gc, ok := gcPercent("GCgcATNN")
+	assert.True(t, ok)
+	assert.Equal(t, float64(4)/6*100, gc)
+	_, ok = gcPercent("NNN")
+	assert.False(t, ok)
+
+	stats := &fastqStats{}
+	for _, gc := range []float64{0, 42, 44.9, 100} {
+		stats.addGC(gc)
+	}
+	assert.Equal(t, 1, stats.gcCounts[0])
+	assert.Equal(t, 2, stats.gcCounts[8])
+	assert.Equal(t, 1, stats.gcCounts[fastqGCBins-1])
+}
+
+func TestFASTQStatsAddSeq(t *testing.T) {
+	stats := &fastqStats{}
+	assert.Equal(t, 1, stats.addSeq("", "ACGT"))
+	assert.Equal(t, 2, stats.addSeq("", "acgt"))
+	assert.Equal(t, 1, stats.addSeq("", "ACGTA"))
+	assert.Equal(t, 1, stats.addSeq("R2", "ACGT")) // Mates are counted apart
+	assert.Equal(t, 3, stats.addSeq("", "ACGT"))
+	assert.Equal(t, 2, stats.duplicates)
+}
+
+func TestHomopolymerRuns(t *testing.T) {
+	assert.Nil(t, homopolymerRuns("AAAA", 0))
+	assert.Equal(t, []bool{false, true, true, true, false, false, false, false, true, true, true},
+		homopolymerRuns("CAaAGNNNTTT", 3))
+	assert.Equal(t, []bool{true, true}, homopolymerRuns("GG", 2))
+}
+
+func TestFindFivePrimeAdapter(t *testing.T) {
+	defer func(adapters map[string]string) { adapterDict = adapters }(adapterDict)
+	adapterDict = map[string]string{"AGATCGGAAGAGC": "A1", "TTTTTGGGCCCAAA": "A2"}
+
+	assert.Equal(t, []adapterInfo{{name: "A1", pos: 9, fivePrime: true}}, findFivePrimeAdapter("CGGAAGAGCTTACGTA", 8, 0.05))
+	assert.Equal(t, []adapterInfo{{name: "A2", pos: 14, fivePrime: true}}, findFivePrimeAdapter("TTTTTGGGCCCAAAACGT", 8, 0.05))
+	// Too short, or with a mismatch.
+	assert.Nil(t, findFivePrimeAdapter("GAAGAGCTTACGTA", 8, 0.05))
+	assert.Nil(t, findFivePrimeAdapter("CGGTAGAGCTTACGTA", 8, 0.05))
+	assert.Equal(t, []adapterInfo{{name: "A1", pos: 9, fivePrime: true}}, findFivePrimeAdapter("CGGTAGAGCTTACGTA", 8, 0.15))
+}
+
+func TestFormatSequenceFivePrime(t *testing.T) {
+	defer func(compact int) { fastqCompactLen = compact }(fastqCompactLen)
+	fastqCompactLen = 0
+	adapter := "<bg-black><darkgrey>GGG</darkgrey></bg-black>"
+	assert.Equal(t, renderMarkup(adapter+fastqStyle.Sequence("AC", "")+"<bg-black><darkgrey>TT</darkgrey></bg-black>"),
+		formatSequence("GGGACTT", "", 3, 5))
+	assert.Equal(t, renderMarkup(adapter+fastqStyle.Sequence("ACTT", "")), formatSequence("GGGACTT", "", 3, -1))
+}
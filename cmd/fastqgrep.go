@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"regexp"
+	"strings"
+)
+
+// fastqMatcher selects the records of --grep: those whose name contains the
+// pattern, or whose sequence contains it when the pattern is made of IUPAC
+// codes, optionally on either strand.
+type fastqMatcher struct {
+	pattern string
+	seq     *regexp.Regexp // nil if the pattern is not a sequence
+}
+
+func newFASTQMatcher(pattern string, revcomp bool) *fastqMatcher {
+	m := &fastqMatcher{pattern: pattern}
+	if pattern == "" {
+		return m
+	}
+	expr := iupacRegexp(pattern)
+	if expr == "" {
+		return m
+	}
+	if rc := iupacRegexp(reverseComplement(strings.ToUpper(pattern), dnaComplements)); revcomp && rc != expr {
+		expr += "|" + rc
+	}
+	m.seq = regexp.MustCompile("(?i)" + expr)
+	return m
+}
+
+// match tells whether record is selected. Without a pattern every record is.
+func (m *fastqMatcher) match(record *fastqRecord) bool {
+	if m.pattern == "" {
+		return true
+	}
+	if strings.Contains(record.Name, m.pattern) {
+		return true
+	}
+	return m.seq != nil && m.seq.MatchString(record.Seq)
+}
+
+// inLengthWindow tells whether the length of record is within --min-len and
+// --max-len (0 for no limit).
+func inLengthWindow(record *fastqRecord) bool {
+	return len(record.Seq) >= fastqMinLen && (fastqMaxLen == 0 || len(record.Seq) <= fastqMaxLen)
+}
+
+// iupacRegexp returns a regular expression matching the bases that the IUPAC
+// codes of pattern stand for, or "" if pattern is not made of IUPAC codes.
+func iupacRegexp(pattern string) string {
+	var b strings.Builder
+	for _, code := range strings.ToUpper(pattern) {
+		bases := ""
+		for set, c := range iupacCodes {
+			if rune(c) == code {
+				bases = set
+			}
+		}
+		switch {
+		case bases == "":
+			return ""
+		case len(bases) == 1:
+			b.WriteString(bases)
+		default:
+			b.WriteString("[" + bases + "]")
+		}
+	}
+	return b.String()
+}
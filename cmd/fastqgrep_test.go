@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIUPACRegexp(t *testing.T) {
+	assert.Equal(t, "AC[AG][ACGT]", iupacRegexp("acRN"))
+	assert.Equal(t, "", iupacRegexp("read1"))
+}
+
+func TestFASTQMatcher(t *testing.T) {
+	record := &fastqRecord{Name: "read1 1:N:0", Seq: "TTGACGTAAC"}
+	tests := []struct {
+		pattern string
+		revcomp bool
+		want    bool
+	}{
+		{"", false, true},
+		{"read1", false, true},
+		{"read2", false, false},
+		{"GACG", false, true},
+		{"gryg", false, true},
+		{"GTTA", false, false},
+		{"GTTA", true, true},
+		{"CCCC", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			assert.Equal(t, tt.want, newFASTQMatcher(tt.pattern, tt.revcomp).match(record))
+		})
+	}
+}
+
+func TestInLengthWindow(t *testing.T) {
+	defer func(minLen, maxLen int) { fastqMinLen, fastqMaxLen = minLen, maxLen }(fastqMinLen, fastqMaxLen)
+	record := &fastqRecord{Seq: "ACGTACGTAC"}
+	tests := []struct {
+		minLen, maxLen int
+		want           bool
+	}{
+		{0, 0, true},
+		{10, 10, true},
+		{11, 0, false},
+		{0, 9, false},
+		{5, 20, true},
+	}
+	for _, tt := range tests {
+		fastqMinLen, fastqMaxLen = tt.minLen, tt.maxLen
+		assert.Equal(t, tt.want, inLengthWindow(record), "%d-%d", tt.minLen, tt.maxLen)
+	}
+}
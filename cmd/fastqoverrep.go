@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aquasecurity/table"
+	"github.com/liamg/tml"
+)
+
+// overrepCounter counts the sequences, or the k-mers, of reads for
+// --overrep.
+type overrepCounter struct {
+	k      int // K-mer length, 0 to count whole sequences
+	reads  int
+	total  int // Sequences or k-mers counted
+	counts map[string]int
+}
+
+func newOverrepCounter(k int) *overrepCounter {
+	return &overrepCounter{k: k, counts: make(map[string]int)}
+}
+
+// overrepSeqLen is the length reads longer than 75 bases are cut to before
+// they are counted, as in FastQC, so that sequencing errors towards the end
+// of long reads do not hide duplicates.
+const overrepSeqLen = 50
+
+func (c *overrepCounter) add(seq string) {
+	c.reads++
+	if c.k == 0 {
+		if len(seq) > 75 {
+			seq = seq[:overrepSeqLen]
+		}
+		c.counts[seq]++
+		c.total++
+		return
+	}
+	for i := 0; i+c.k <= len(seq); i++ {
+		kmer := seq[i : i+c.k]
+		if strings.IndexByte(kmer, 'N') >= 0 {
+			continue
+		}
+		c.counts[kmer]++
+		c.total++
+	}
+}
+
+type overrepSequence struct {
+	Seq    string
+	Count  int
+	Source string
+}
+
+// top returns the n most frequent sequences or k-mers that make up at least
+// minFraction of the total, with their possible source.
+func (c *overrepCounter) top(n int, minFraction float64) []overrepSequence {
+	var found []overrepSequence
+	for seq, count := range c.counts {
+		if count > 1 && float64(count) >= minFraction*float64(c.total) {
+			found = append(found, overrepSequence{Seq: seq, Count: count})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Count != found[j].Count {
+			return found[i].Count > found[j].Count
+		}
+		return found[i].Seq < found[j].Seq
+	})
+	if len(found) > n {
+		found = found[:n]
+	}
+	for i := range found {
+		found[i].Source = overrepSource(found[i].Seq)
+	}
+	return found
+}
+
+// overrepMinMatch is the length of the exact match between a sequence and an
+// adapter that makes the adapter a possible source.
+const overrepMinMatch = 12
+
+// overrepSource names the adapter that seq or its reverse complement shares
+// at least overrepMinMatch bases (all of a shorter seq) with, or a
+// homopolymer like the poly-G of two-color chemistry, or returns "".
+func overrepSource(seq string) string {
+	for _, base := range "ACGTN" {
+		if len(seq) >= 10 && strings.Count(seq, string(base)) >= len(seq)*9/10 {
+			return "poly-" + string(base)
+		}
+	}
+	rc := reverseComplement(seq, dnaComplements)
+	names := make([]string, 0, len(adapterDict))
+	for adapter := range adapterDict {
+		names = append(names, adapter)
+	}
+	sort.Strings(names) // Report the same adapter on every run
+	for _, adapter := range names {
+		size := min(min(overrepMinMatch, len(seq)), len(adapter))
+		for i := 0; i+size <= len(adapter); i++ {
+			window := adapter[i : i+size]
+			if strings.Contains(seq, window) {
+				return adapterDict[adapter]
+			}
+			if strings.Contains(rc, window) {
+				return adapterDict[adapter] + " (rc)"
+			}
+		}
+	}
+	return ""
+}
+
+// overrepReads is the number of reads counted for --overrep without -n.
+const overrepReads = 100000
+
+// overrepShown is the number of sequences or k-mers listed.
+const overrepShown = 20
+
+// renderOverrepresented counts the sequences, or k-mers, of the first reads of
+// filename and prints the most frequent ones. Like FastQC, sequences are
+// listed if they make up at least 0.1% of the reads.
+func renderOverrepresented(filename string) error {
+	input, err := openInput(filename)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	reader := newFASTQReader(input)
+	limit := fastqNumRecords
+	if limit == 0 {
+		limit = overrepReads
+	}
+	matcher := newFASTQMatcher(fastqGrep, fastqRevcomp)
+	sample := fastqSampler()
+	counter := newOverrepCounter(fastqKmer)
+	for counter.reads < limit {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		if inLengthWindow(record) && matcher.match(record) && sample() {
+			counter.add(strings.ToUpper(record.Seq))
+		}
+	}
+	if counter.reads == 0 {
+		return fmt.Errorf("%s: no reads", filename)
+	}
+
+	minFraction := 0.001
+	what, of := "sequences", "reads"
+	if fastqKmer > 0 {
+		minFraction = 0
+		what, of = fmt.Sprintf("%d-mers", fastqKmer), "k-mers"
+	}
+	found := counter.top(overrepShown, minFraction)
+	if len(found) == 0 {
+		tml.Printf("<green>No overrepresented %s in %d reads.</green>\n", what, counter.reads)
+		return nil
+	}
+	t := table.New(os.Stdout)
+	t.SetHeaders("Sequence", "Count", "% of "+of, "Possible source")
+	t.SetHeaderStyle(table.StyleBold)
+	t.SetLineStyle(table.StyleBlue)
+	t.SetDividers(table.UnicodeRoundedDividers)
+	t.SetAlignment(table.AlignLeft, table.AlignRight, table.AlignRight, table.AlignLeft)
+	for _, s := range found {
+		t.AddRow(s.Seq, fmt.Sprint(s.Count), percentOf(s.Count, counter.total), s.Source)
+	}
+	t.Render()
+	tml.Printf("<darkgrey>Most frequent %s of %d reads.</darkgrey>\n", what, counter.reads)
+	return nil
+}
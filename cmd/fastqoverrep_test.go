@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverrepCounter(t *testing.T) {
+	c := newOverrepCounter(0)
+	long := strings.Repeat("ACGT", 20)
+	c.add(long)
+	c.add(long[:50] + "TTTT") // 54 bases, not cut
+	c.add(long[:76])          // Cut to the same 50 bases as long
+	c.add("CCCC")
+	assert.Equal(t, 4, c.reads)
+	assert.Equal(t, []overrepSequence{{Seq: long[:50], Count: 2}}, c.top(20, 0))
+	assert.Empty(t, c.top(20, 0.6))
+
+	k := newOverrepCounter(3)
+	k.add("AAAAN")
+	k.add("AAAC")
+	assert.Equal(t, 4, k.total) // AAA, AAA, AAA, AAC; k-mers with N skipped
+	assert.Equal(t, []overrepSequence{{Seq: "AAA", Count: 3}}, k.top(20, 0))
+}
+
+func TestOverrepSource(t *testing.T) {
+	tests := []struct {
+		seq  string
+		want string
+	}{
+		{"TTTTAGATCGGAAGAGCACACGTCTGAACTCC", "TruSeq P7"},
+		{reverseComplement("CCCCCTGTCTCTTATACACATCTAAAA", dnaComplements), "Tn5 ME (rc)"},
+		{"GGGGGGGGGGGGGGGGGGGAGGGG", "poly-G"},
+		{"GGAAGAG", "TruSeq P7"}, // Shorter than overrepMinMatch
+		{"ACGTTGCAACGTTGCA", ""},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, overrepSource(test.seq), test.seq)
+	}
+}
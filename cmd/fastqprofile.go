@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/liamg/tml"
+	"github.com/yech1990/hey/internal/render"
+)
+
+// qualityProfile counts the base qualities at every cycle (position in the
+// read), for --qual-profile.
+type qualityProfile struct {
+	reads  int
+	counts [][]int // counts[cycle][quality]
+}
+
+func (p *qualityProfile) add(qual string) {
+	p.reads++
+	for len(p.counts) < len(qual) {
+		p.counts = append(p.counts, nil)
+	}
+	for i := 0; i < len(qual); i++ {
+		score := max(int(qual[i])-33, 0)
+		for len(p.counts[i]) <= score {
+			p.counts[i] = append(p.counts[i], 0)
+		}
+		p.counts[i][score]++
+	}
+}
+
+// binned merges every size consecutive cycles into one.
+func (p *qualityProfile) binned(size int) *qualityProfile {
+	if size <= 1 {
+		return p
+	}
+	b := &qualityProfile{reads: p.reads}
+	for start := 0; start < len(p.counts); start += size {
+		var merged []int
+		for _, counts := range p.counts[start:min(start+size, len(p.counts))] {
+			for len(merged) < len(counts) {
+				merged = append(merged, 0)
+			}
+			for score, n := range counts {
+				merged[score] += n
+			}
+		}
+		b.counts = append(b.counts, merged)
+	}
+	return b
+}
+
+// quantile returns the quality below which fraction q of the bases at cycle
+// fall.
+func (p *qualityProfile) quantile(cycle int, q float64) int {
+	total := 0
+	for _, n := range p.counts[cycle] {
+		total += n
+	}
+	seen := 0
+	for score, n := range p.counts[cycle] {
+		seen += n
+		if float64(seen) >= q*float64(total) && seen > 0 {
+			return score
+		}
+	}
+	return 0
+}
+
+// printQualityProfile draws a box plot of the quality at every cycle: the
+// median as a block in its quality color, the quartiles as a shaded box and
+// the 10th to 90th percentiles as a whisker. Cycles are binned to fit width
+// columns, and every row spans two quality scores.
+func printQualityProfile(p *qualityProfile, width int) {
+	if len(p.counts) == 0 {
+		return
+	}
+	binSize := (len(p.counts) + width - 1) / width
+	b := p.binned(binSize)
+
+	maxScore := 41
+	for _, counts := range b.counts {
+		maxScore = max(maxScore, len(counts)-1)
+	}
+	type box struct{ p10, q1, median, q3, p90 int }
+	boxes := make([]box, len(b.counts))
+	for i := range b.counts {
+		boxes[i] = box{b.quantile(i, 0.1), b.quantile(i, 0.25), b.quantile(i, 0.5), b.quantile(i, 0.75), b.quantile(i, 0.9)}
+	}
+
+	for row := maxScore / 2; row >= 0; row-- {
+		lo, hi := row*2, row*2+2
+		label := "    "
+		if lo%10 == 0 {
+			label = fmt.Sprintf("%3d ", lo)
+		}
+		var line strings.Builder
+		for _, bx := range boxes {
+			switch {
+			case bx.median >= lo && bx.median < hi:
+				color := render.QualityColor(bx.median)
+				line.WriteString("<" + color + ">█</" + color + ">")
+			case bx.q3 >= lo && bx.q1 < hi:
+				color := render.QualityColor(bx.median)
+				line.WriteString("<" + color + ">▒</" + color + ">")
+			case bx.p90 >= lo && bx.p10 < hi:
+				line.WriteString("<darkgrey>│</darkgrey>")
+			default:
+				line.WriteByte(' ')
+			}
+		}
+		printMarkup("<darkgrey>" + label + "┤</darkgrey>" + strings.TrimRight(line.String(), " "))
+	}
+	fmt.Println("    └" + strings.Repeat("─", len(boxes)))
+
+	ticks := []byte(strings.Repeat(" ", len(boxes)))
+	for i := 0; i < len(boxes); i += 10 {
+		if label := fmt.Sprint(i*binSize + 1); i+len(label) <= len(ticks) {
+			copy(ticks[i:], label)
+		}
+	}
+	fmt.Println("     " + strings.TrimRight(string(ticks), " "))
+	legend := fmt.Sprintf("     █ median  ▒ 25-75%%  │ 10-90%%   %d reads, %d cycles", p.reads, len(p.counts))
+	if binSize > 1 {
+		legend += fmt.Sprintf(", %d cycles per column", binSize)
+	}
+	tml.Printf("<darkgrey>%s</darkgrey>\n", legend)
+}
+
+// qualProfileReads is the number of reads of the quality profile without -n.
+const qualProfileReads = 100000
+
+// renderQualityProfile plots the quality profile of the first reads of
+// filename.
+func renderQualityProfile(filename string) error {
+	input, err := openInput(filename)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	reader := newFASTQReader(input)
+	limit := fastqNumRecords
+	if limit == 0 {
+		limit = qualProfileReads
+	}
+	matcher := newFASTQMatcher(fastqGrep, fastqRevcomp)
+	sample := fastqSampler()
+	profile := &qualityProfile{}
+	for profile.reads < limit {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		if inLengthWindow(record) && matcher.match(record) && sample() {
+			profile.add(record.Qual)
+		}
+	}
+	if profile.reads == 0 {
+		return fmt.Errorf("%s: no reads", filename)
+	}
+	if len(profile.counts) == 0 {
+		return fmt.Errorf("%s: no base qualities, is it FASTA?", filename)
+	}
+	width := terminalWidth()
+	if width == 0 {
+		width = 100
+	}
+	printQualityProfile(profile, max(width-6, 10))
+	return nil
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQualityProfile(t *testing.T) {
+	p := &qualityProfile{}
+	for _, qual := range []string{"I#", "5#", "+", "!"} {
+		p.add(qual)
+	}
+	assert.Equal(t, 4, p.reads)
+	assert.Len(t, p.counts, 2)
+	assert.Equal(t, 0, p.quantile(0, 0.1))
+	assert.Equal(t, 10, p.quantile(0, 0.5))
+	assert.Equal(t, 40, p.quantile(0, 0.9))
+	assert.Equal(t, 2, p.quantile(1, 0.5))
+
+	b := p.binned(2)
+	assert.Len(t, b.counts, 1)
+	assert.Equal(t, 2, b.quantile(0, 0.5))
+	assert.Equal(t, 40, b.quantile(0, 1))
+}
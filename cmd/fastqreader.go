@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// fastqRecord is one read of a FASTQ or FASTA file.
+type fastqRecord struct {
+	Name string // Header without the leading '@' or '>', including any comment
+	Seq  string
+	Qual string // Empty for FASTA
+}
+
+// id returns the read name up to the first whitespace.
+func (r *fastqRecord) id() string {
+	if fields := strings.Fields(r.Name); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// fastqReader reads FASTQ records one by one and returns io.EOF at the end.
+// It also reads FASTA, where a record may span several lines and has no
+// qualities.
+type fastqReader struct {
+	scanner *bufio.Scanner
+	line    int
+	pending string         // FASTA header read with the previous record
+	unread  []*fastqRecord // Records to return again before reading on
+}
+
+func newFASTQReader(r io.Reader) *fastqReader {
+	return &fastqReader{scanner: newLineScanner(r)}
+}
+
+func (f *fastqReader) Read() (*fastqRecord, error) {
+	if len(f.unread) > 0 {
+		record := f.unread[0]
+		f.unread = f.unread[1:]
+		return record, nil
+	}
+	header := f.pending
+	f.pending = ""
+	for header == "" {
+		if !f.scanner.Scan() {
+			if err := f.scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		f.line++
+		header = f.scanner.Text()
+	}
+	switch header[0] {
+	case '>':
+		return f.readFASTA(header)
+	case '@':
+	default:
+		return nil, fmt.Errorf("line %d: expected a FASTQ header starting with '@' or a FASTA header starting with '>'", f.line)
+	}
+	var lines [3]string
+	for i := range lines {
+		if !f.scanner.Scan() {
+			if err := f.scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("line %d: truncated record %s", f.line, header[1:])
+		}
+		f.line++
+		lines[i] = f.scanner.Text()
+	}
+	return &fastqRecord{Name: header[1:], Seq: lines[0], Qual: lines[2]}, nil
+}
+
+// readFASTA reads the sequence lines of the FASTA record with header, up to
+// the next header.
+func (f *fastqReader) readFASTA(header string) (*fastqRecord, error) {
+	var seq strings.Builder
+	for f.scanner.Scan() {
+		f.line++
+		line := f.scanner.Text()
+		if strings.HasPrefix(line, ">") {
+			f.pending = line
+			break
+		}
+		seq.WriteString(strings.TrimSpace(line))
+	}
+	if err := f.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return &fastqRecord{Name: header[1:], Seq: seq.String()}, nil
+}
+
+// detectInterleaved tells whether the first two records of reader are the
+// mates of a pair, as in an interleaved paired-end file. The records are read
+// again afterwards.
+func detectInterleaved(reader *fastqReader) (bool, error) {
+	var records []*fastqRecord
+	for len(records) < 2 {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return false, err
+		}
+		records = append(records, record)
+	}
+	reader.unread = append(records, reader.unread...)
+	return len(records) == 2 && mateID(records[0].Name) == mateID(records[1].Name), nil
+}
+
+// mateID returns the read name that both mates of a pair share: the name up
+// to the first whitespace without a /1 or /2 suffix.
+func mateID(name string) string {
+	if fields := strings.Fields(name); len(fields) > 0 {
+		name = fields[0]
+	}
+	if strings.HasSuffix(name, "/1") || strings.HasSuffix(name, "/2") {
+		name = name[:len(name)-2]
+	}
+	return name
+}
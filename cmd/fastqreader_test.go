@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFASTQReader(t *testing.T) {
+	reader := newFASTQReader(strings.NewReader("@r1 1:N:0\nACGT\n+\nIIII\n\n@r2\nAC\n+r2\n#I\n"))
+	record, err := reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, &fastqRecord{Name: "r1 1:N:0", Seq: "ACGT", Qual: "IIII"}, record)
+	assert.Equal(t, "r1", record.id())
+	record, err = reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, &fastqRecord{Name: "r2", Seq: "AC", Qual: "#I"}, record)
+	_, err = reader.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestFASTQReaderFASTA(t *testing.T) {
+	reader := newFASTQReader(strings.NewReader(">chr1 test\nACGT\nacg\n\n>chr2\n>chr3\nNN\n"))
+	for _, want := range []*fastqRecord{{Name: "chr1 test", Seq: "ACGTacg"}, {Name: "chr2"}, {Name: "chr3", Seq: "NN"}} {
+		record, err := reader.Read()
+		require.NoError(t, err)
+		assert.Equal(t, want, record)
+	}
+	_, err := reader.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestFASTQReaderErrors(t *testing.T) {
+	_, err := newFASTQReader(strings.NewReader("@r1\nACGT\n+\n")).Read()
+	assert.ErrorContains(t, err, "truncated record r1")
+	_, err = newFASTQReader(strings.NewReader("r1\nACGT\n")).Read()
+	assert.ErrorContains(t, err, "line 1")
+}
+
+func TestDetectInterleaved(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"@r1/1\nAC\n+\nII\n@r1/2\nGT\n+\nII\n@r2/1\nAC\n+\nII\n", true},
+		{"@r1 1:N:0\nAC\n+\nII\n@r1 2:N:0\nGT\n+\nII\n", true},
+		{"@r1\nAC\n+\nII\n@r2\nGT\n+\nII\n", false},
+		{"@r1\nAC\n+\nII\n", false},
+	}
+	for _, tt := range tests {
+		reader := newFASTQReader(strings.NewReader(tt.input))
+		interleaved, err := detectInterleaved(reader)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, interleaved, tt.input)
+
+		// The records detected on are read again.
+		record, err := reader.Read()
+		require.NoError(t, err)
+		assert.Equal(t, "AC", record.Seq)
+	}
+
+	_, err := detectInterleaved(newFASTQReader(strings.NewReader("r1\nAC\n")))
+	assert.Error(t, err)
+}
+
+func TestMateID(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"r1/1", "r1"},
+		{"r1/2", "r1"},
+		{"A00123:8:HXXX:1:1101:1000:2000 1:N:0:ACGT", "A00123:8:HXXX:1:1101:1000:2000"},
+		{"SRR1.1 length=100", "SRR1.1"},
+		{"r1/3", "r1/3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mateID(tt.name))
+		})
+	}
+}
+
+func TestVisibleWidth(t *testing.T) {
+	assert.Equal(t, 4, visibleWidth("\x1b[0m\x1b[41mA\x1b[49m\x1b[0mCG▓"))
+}
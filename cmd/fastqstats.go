@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aquasecurity/table"
+)
+
+// fastqFileSummary holds the statistics of one FASTQ file for --stats.
+type fastqFileSummary struct {
+	Reads  int
+	Bases  int
+	MinLen int
+	MaxLen int
+	Q20    int // Bases with quality >= 20
+	Q30    int
+	Quals  int // Bases with a quality, none for FASTA
+	GC     int
+	N      int
+}
+
+func (s *fastqFileSummary) add(record *fastqRecord) {
+	if s.Reads == 0 || len(record.Seq) < s.MinLen {
+		s.MinLen = len(record.Seq)
+	}
+	s.MaxLen = max(s.MaxLen, len(record.Seq))
+	s.Reads++
+	s.Bases += len(record.Seq)
+	for i := 0; i < len(record.Seq); i++ {
+		switch record.Seq[i] {
+		case 'G', 'C', 'g', 'c':
+			s.GC++
+		case 'N', 'n':
+			s.N++
+		}
+	}
+	s.Quals += len(record.Qual)
+	for i := 0; i < len(record.Qual); i++ {
+		score := int(record.Qual[i]) - 33
+		if score >= 20 {
+			s.Q20++
+		}
+		if score >= 30 {
+			s.Q30++
+		}
+	}
+}
+
+func (s *fastqFileSummary) meanLen() float64 {
+	if s.Reads == 0 {
+		return 0
+	}
+	return float64(s.Bases) / float64(s.Reads)
+}
+
+// summarizeFASTQ reads all records of filename.
+func summarizeFASTQ(filename string) (*fastqFileSummary, error) {
+	input, err := openInput(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+	reader := newFASTQReader(input)
+	summary := &fastqFileSummary{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return summary, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		summary.add(record)
+	}
+}
+
+// printFASTQStats prints a table with the statistics of every file.
+func printFASTQStats(filenames []string) error {
+	t := table.New(os.Stdout)
+	t.SetHeaders("File", "Reads", "Bases", "Min len", "Mean len", "Max len", "Q20", "Q30", "GC", "N")
+	t.SetHeaderStyle(table.StyleBold)
+	t.SetLineStyle(table.StyleBlue)
+	t.SetDividers(table.UnicodeRoundedDividers)
+	t.SetAlignment(table.AlignLeft, table.AlignRight, table.AlignRight, table.AlignRight, table.AlignRight,
+		table.AlignRight, table.AlignRight, table.AlignRight, table.AlignRight, table.AlignRight)
+	for _, filename := range filenames {
+		s, err := summarizeFASTQ(filename)
+		if err != nil {
+			return err
+		}
+		t.AddRow(filename, fmt.Sprint(s.Reads), fmt.Sprint(s.Bases),
+			fmt.Sprint(s.MinLen), fmt.Sprintf("%.1f", s.meanLen()), fmt.Sprint(s.MaxLen),
+			percentOf(s.Q20, s.Quals), percentOf(s.Q30, s.Quals), percentOf(s.GC, s.Bases), percentOf(s.N, s.Bases))
+	}
+	t.Render()
+	return nil
+}
@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFASTQFileSummary(t *testing.T) {
+	s := &fastqFileSummary{}
+	s.add(&fastqRecord{Seq: "ACGTN", Qual: "I5+#I"})
+	s.add(&fastqRecord{Seq: "gg", Qual: "II"})
+	assert.Equal(t, &fastqFileSummary{Reads: 2, Bases: 7, MinLen: 2, MaxLen: 5, Q20: 5, Q30: 4, Quals: 7, GC: 4, N: 1}, s)
+	assert.InDelta(t, 3.5, s.meanLen(), 1e-9)
+
+	fasta := &fastqFileSummary{}
+	fasta.add(&fastqRecord{Seq: "ACGT"})
+	assert.Equal(t, 0, fasta.Quals)
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// fastqUMI tells where the UMIs of --umi-len, --umi-pattern and --umi-regex
+// are: at the start of the read, as a layout of UMI (N), cell barcode (C) and
+// other (X) bases like the --bc-pattern of umi_tools, or in the read name,
+// matched by a regular expression.
+type fastqUMI struct {
+	layout string
+	name   *regexp.Regexp
+}
+
+// activeUMI is the UMI layout of hey fastq, nil without --umi-len,
+// --umi-pattern or --umi-regex.
+var activeUMI *fastqUMI
+
+var umiLayoutRegex = regexp.MustCompile(`^[NCX]+$`)
+
+// parseUMIPattern returns the UMI layout of pattern, a layout of the read
+// start like "NNNNNNXXX".
+func parseUMIPattern(pattern string) (*fastqUMI, error) {
+	if !umiLayoutRegex.MatchString(pattern) {
+		return nil, fmt.Errorf("invalid --umi-pattern %q: use N (UMI), C (cell barcode) and X (other) bases, or --umi-regex for the read name", pattern)
+	}
+	return &fastqUMI{layout: pattern}, nil
+}
+
+// parseUMIRegex returns the UMI layout of a regular expression for the read
+// name whose first group, or whole match, is the UMI, e.g. "_([ACGTN]+)$"
+// for umi_tools or "RX:Z:(\S+)" for fgbio.
+func parseUMIRegex(pattern string) (*fastqUMI, error) {
+	name, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --umi-regex: %w", err)
+	}
+	return &fastqUMI{name: name}, nil
+}
+
+// extract returns the UMI of record, with the bases of a cell barcode
+// before it separated by '+', or "" if it has none.
+func (u *fastqUMI) extract(record *fastqRecord) string {
+	if u.name != nil {
+		m := u.name.FindStringSubmatch(record.Name)
+		switch {
+		case m == nil:
+			return ""
+		case len(m) > 1:
+			return m[1]
+		}
+		return m[0]
+	}
+	var umi, barcode strings.Builder
+	for i := 0; i < len(u.layout) && i < len(record.Seq); i++ {
+		switch u.layout[i] {
+		case 'N':
+			umi.WriteByte(record.Seq[i])
+		case 'C':
+			barcode.WriteByte(record.Seq[i])
+		}
+	}
+	if barcode.Len() > 0 {
+		return barcode.String() + "+" + umi.String()
+	}
+	return umi.String()
+}
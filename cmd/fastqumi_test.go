@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFASTQUMI(t *testing.T) {
+	tests := []struct {
+		parse   func(string) (*fastqUMI, error)
+		pattern string
+		record  fastqRecord
+		want    string
+	}{
+		{parseUMIPattern, "NNNNXX", fastqRecord{Name: "r1", Seq: "ACGTAAGG"}, "ACGT"},
+		{parseUMIPattern, "CCNNN", fastqRecord{Name: "r1", Seq: "ACGTAAGG"}, "AC+GTA"},
+		{parseUMIPattern, "NNNNNNNNNN", fastqRecord{Name: "r1", Seq: "ACG"}, "ACG"},
+		{parseUMIRegex, `_([ACGTN]+)$`, fastqRecord{Name: "r1_ACGTNA", Seq: "GG"}, "ACGTNA"},
+		{parseUMIRegex, `RX:Z:(\S+)`, fastqRecord{Name: "r1 RX:Z:AAC-GGT", Seq: "GG"}, "AAC-GGT"},
+		{parseUMIRegex, `[ACGT]{4}$`, fastqRecord{Name: "a:b:ACGT", Seq: "GG"}, "ACGT"},
+		{parseUMIRegex, `_([ACGTN]+)$`, fastqRecord{Name: "r1", Seq: "GG"}, ""},
+		// A name regex made only of layout letters is still a regex.
+		{parseUMIRegex, "NNNN", fastqRecord{Name: "r1_NNNN", Seq: "ACGTAA"}, "NNNN"},
+		{parseUMIRegex, "NNNN", fastqRecord{Name: "r1_ACGT", Seq: "ACGTAA"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			umi, err := tt.parse(tt.pattern)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, umi.extract(&tt.record))
+		})
+	}
+
+	_, err := parseUMIRegex("(")
+	assert.ErrorContains(t, err, "invalid --umi-regex")
+	_, err = parseUMIPattern(`_([ACGTN]+)$`)
+	assert.ErrorContains(t, err, "invalid --umi-pattern")
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/liamg/tml"
+)
+
+// fastqValidBases are the characters allowed in a sequence line: bases and
+// IUPAC codes in either case.
+const fastqValidBases = "ACGTUNRYKMSWBDHVacgtunrykmswbdhv"
+
+// fastqProblemsShown is the number of problems listed per file; the rest are
+// only counted.
+const fastqProblemsShown = 20
+
+// fastqProblem is a structural error of a FASTQ file at a line.
+type fastqProblem struct {
+	Line int
+	Msg  string
+}
+
+// validateFASTQ checks the structure of the FASTQ records read from r and
+// calls report for every problem found. It returns the number of records.
+// Records are read four lines at a time, so a missing line also shows up as
+// problems with the records after it.
+func validateFASTQ(r io.Reader, report func(fastqProblem)) (int, error) {
+	scanner := newLineScanner(r)
+	line := 0
+	records := 0
+	blank := 0 // Blank lines since the last record, fine at the end only
+	for {
+		var lines [4]string
+		n := 0
+		for n < 4 && scanner.Scan() {
+			line++
+			text := strings.TrimSuffix(scanner.Text(), "\r")
+			if n == 0 && text == "" {
+				blank++
+				continue
+			}
+			if blank > 0 {
+				report(fastqProblem{line - 1, "blank line between records"})
+				blank = 0
+			}
+			lines[n] = text
+			n++
+		}
+		if err := scanner.Err(); err != nil {
+			return records, err
+		}
+		if n == 0 {
+			return records, nil
+		}
+		records++
+		start := line - n + 1
+		if !strings.HasPrefix(lines[0], "@") {
+			report(fastqProblem{start, fmt.Sprintf("expected a header starting with '@', found %q", truncateWidth(lines[0], 30))})
+		} else if len(lines[0]) == 1 {
+			report(fastqProblem{start, "empty read name"})
+		}
+		if n < 4 {
+			missing := [4]string{"", "sequence line", "'+' line", "quality line"}[n]
+			report(fastqProblem{line, fmt.Sprintf("truncated record %s: no %s", strings.TrimPrefix(lines[0], "@"), missing)})
+			return records, nil
+		}
+		seq, plus, qual := lines[1], lines[2], lines[3]
+		if i := strings.IndexFunc(seq, func(r rune) bool { return !strings.ContainsRune(fastqValidBases, r) }); i >= 0 {
+			report(fastqProblem{start + 1, fmt.Sprintf("invalid base %q at column %d", seq[i], i+1)})
+		}
+		switch {
+		case !strings.HasPrefix(plus, "+"):
+			report(fastqProblem{start + 2, fmt.Sprintf("expected a '+' line, found %q", truncateWidth(plus, 30))})
+		case len(plus) > 1 && plus[1:] != lines[0][min(1, len(lines[0])):]:
+			report(fastqProblem{start + 2, "name on the '+' line differs from the header"})
+		}
+		if len(qual) != len(seq) {
+			report(fastqProblem{start + 3, fmt.Sprintf("%d quality scores for %d bases", len(qual), len(seq))})
+		}
+		if i := strings.IndexFunc(qual, func(r rune) bool { return r < '!' || r > '~' }); i >= 0 {
+			report(fastqProblem{start + 3, fmt.Sprintf("invalid quality character %q at column %d", qual[i], i+1)})
+		}
+	}
+}
+
+// printFASTQValidation validates every file and lists the problems found with
+// their line numbers. It returns an error if any file has problems, so that
+// hey exits with a non-zero status.
+func printFASTQValidation(filenames []string) error {
+	failed := 0
+	for _, filename := range filenames {
+		input, err := openInput(filename)
+		if err != nil {
+			return err
+		}
+		problems := 0
+		records, err := validateFASTQ(input, func(p fastqProblem) {
+			problems++
+			if problems <= fastqProblemsShown {
+				tml.Printf("<red>%s:%d:</red> %s\n", filename, p.Line, p.Msg)
+			}
+		})
+		input.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", filename, err)
+		}
+		switch {
+		case problems > fastqProblemsShown:
+			tml.Printf("<red>%s: ... and %d more</red>\n", filename, problems-fastqProblemsShown)
+			fallthrough
+		case problems > 0:
+			tml.Printf("<red><bold>%s: %d problems in %d records</bold></red>\n", filename, problems, records)
+			failed++
+		default:
+			tml.Printf("<green>%s: %d records OK</green>\n", filename, records)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files are not valid FASTQ", failed, len(filenames))
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateFASTQ(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		records int
+		want    []fastqProblem
+	}{
+		{"valid", "@r1 1:N\nACGTN\n+\nIII#I\n@r2\nacgr\n+r2\nIIII\n\n", 2, nil},
+		{"crlf", "@r1\r\nACGT\r\n+\r\nIIII\r\n", 1, nil},
+		{"bad base", "@r1\nACGX\n+\nIIII\n", 1, []fastqProblem{{2, "invalid base 'X' at column 4"}}},
+		{"plus name", "@r1\nACGT\n+r2\nIIII\n", 1, []fastqProblem{{3, "name on the '+' line differs from the header"}}},
+		{"no plus", "@r1\nACGT\nIIII\n@r2\n", 1, []fastqProblem{
+			{3, `expected a '+' line, found "IIII"`}, {4, "3 quality scores for 4 bases"}}},
+		{"lengths", "@r1\nACGT\n+\nIII\n", 1, []fastqProblem{{4, "3 quality scores for 4 bases"}}},
+		{"quality", "@r1\nAC\n+\nI \n", 1, []fastqProblem{{4, "invalid quality character ' ' at column 2"}}},
+		{"header", "r1\nAC\n+\nII\n@\nAC\n+\nII\n", 2, []fastqProblem{
+			{1, `expected a header starting with '@', found "r1"`}, {5, "empty read name"}}},
+		{"blank", "@r1\nAC\n+\nII\n\n@r2\nAC\n+\nII\n", 2, []fastqProblem{{5, "blank line between records"}}},
+		{"truncated", "@r1\nAC\n+\nII\n@r2\nAC\n", 2, []fastqProblem{{6, "truncated record r2: no '+' line"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var problems []fastqProblem
+			records, err := validateFASTQ(strings.NewReader(tt.input), func(p fastqProblem) {
+				problems = append(problems, p)
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tt.records, records)
+			assert.Equal(t, tt.want, problems)
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/yech1990/hey/internal/render"
+)
+
+// fastqViewData holds the records of the interactive viewer, read from the
+// file on demand like the rows of hey tsv.
+type fastqViewData struct {
+	reader  *fastqReader
+	records []*fastqRecord
+	done    bool
+	err     error
+}
+
+// load reads up to n more records within --min-len and --max-len and returns
+// how many were read.
+func (d *fastqViewData) load(n int) int {
+	loaded := 0
+	for loaded < n && !d.done {
+		record, err := d.reader.Read()
+		if err != nil {
+			d.done = true
+			if err != io.EOF {
+				d.err = err
+			}
+			break
+		}
+		if inLengthWindow(record) {
+			d.records = append(d.records, record)
+			loaded++
+		}
+	}
+	return loaded
+}
+
+// ensure reads records until index i exists or the file ends.
+func (d *fastqViewData) ensure(i int) {
+	for len(d.records) <= i && !d.done {
+		d.load(max(200, i+1-len(d.records)))
+	}
+}
+
+// find returns the index of the first record from start on (searching
+// backwards if backwards is set) that matches pattern, like --grep, or -1.
+func (d *fastqViewData) find(pattern string, start int, backwards bool) int {
+	if pattern == "" {
+		return -1
+	}
+	matcher := newFASTQMatcher(pattern, fastqRevcomp)
+	if backwards {
+		for i := min(start, len(d.records)-1); i >= 0; i-- {
+			if matcher.match(d.records[i]) {
+				return i
+			}
+		}
+		return -1
+	}
+	for i := max(start, 0); ; i++ {
+		d.ensure(i)
+		if i >= len(d.records) {
+			return -1
+		}
+		if matcher.match(d.records[i]) {
+			return i
+		}
+	}
+}
+
+// fastqPager is the termui widget of the interactive viewer.
+type fastqPager struct {
+	ui.Block
+	data   *fastqViewData
+	offset int    // Index of the first record shown
+	prompt string // ":" while typing a record number, "/" while searching
+	input  string
+	search string // Last search pattern
+	notice string
+}
+
+// fastqRecordRows is the number of rows a record takes: name, sequence,
+// quality (also left blank for FASTA) and a blank line.
+const fastqRecordRows = 4
+
+func (p *fastqPager) pageSize() int {
+	return max(1, (p.Inner.Dy()-1)/fastqRecordRows)
+}
+
+// scroll moves the first record shown by n, loading records as needed.
+func (p *fastqPager) scroll(n int) {
+	p.data.ensure(p.offset + n + p.pageSize())
+	p.offset = max(0, min(p.offset+n, len(p.data.records)-1))
+}
+
+// termuiColors maps the tml colors of the palette to termui colors.
+var termuiColors = map[string]ui.Color{
+	"black": ui.ColorBlack, "red": ui.ColorRed, "green": ui.ColorGreen, "yellow": ui.ColorYellow,
+	"blue": ui.ColorBlue, "magenta": ui.ColorMagenta, "cyan": ui.ColorCyan, "lightgrey": ui.ColorWhite,
+	"darkgrey": 8, "lightred": 9, "lightgreen": 10, "lightyellow": 11, "lightblue": 12,
+	"lightmagenta": 13, "lightcyan": 14, "white": 15,
+}
+
+// baseStyle returns the style of a base with quality score q (-1 for FASTA),
+// like fastqStyle: palette backgrounds (or quality ones with --color-by
+// quality), cyan below -q and grey Ns.
+func baseStyle(base byte, q int) ui.Style {
+	switch {
+	case fastqStyle.QualityCutoff > 0 && q >= 0 && q < fastqStyle.QualityCutoff:
+		return ui.NewStyle(termuiColors[fastqStyle.LowQualityColor])
+	case fastqStyle.ColorByQuality && q >= 0:
+		return ui.NewStyle(ui.ColorBlack, termuiColors[render.QualityGradientColor(q)])
+	case base == 'N':
+		return ui.NewStyle(termuiColors["darkgrey"])
+	}
+	if color, ok := termuiColors[fastqStyle.Palette.Color(base)]; ok && strings.IndexByte("ACGT", base) >= 0 {
+		return ui.NewStyle(ui.ColorBlack, color)
+	}
+	return ui.NewStyle(ui.ColorWhite)
+}
+
+func (p *fastqPager) Draw(buf *ui.Buffer) {
+	p.Block.Draw(buf)
+	width := p.Inner.Dx()
+	y := p.Inner.Min.Y
+	for i := p.offset; i < len(p.data.records) && y+fastqRecordRows-1 < p.Inner.Max.Y; i++ {
+		record := p.data.records[i]
+		name := fmt.Sprintf("%d  %s [%d bp]", i+1, record.Name, len(record.Seq))
+		buf.SetString(truncateWidth(name, width), ui.NewStyle(ui.ColorWhite, ui.ColorClear, ui.ModifierBold), image.Pt(p.Inner.Min.X, y))
+		runs := homopolymerRuns(record.Seq, fastqHomopolymer)
+		for j := 0; j < len(record.Seq) && j < width; j++ {
+			q := -1
+			if j < len(record.Qual) {
+				q = render.Score(record.Qual[j])
+				buf.SetCell(ui.NewCell(render.QualityChar(q), ui.NewStyle(termuiColors[render.QualityColor(q)])), image.Pt(p.Inner.Min.X+j, y+2))
+			}
+			style := baseStyle(record.Seq[j], q)
+			if runs != nil && runs[j] {
+				style.Modifier |= ui.ModifierUnderline
+			}
+			buf.SetCell(ui.NewCell(rune(record.Seq[j]), style), image.Pt(p.Inner.Min.X+j, y+1))
+		}
+		y += fastqRecordRows
+	}
+
+	status := fmt.Sprintf(" Record %d/%d", min(p.offset+1, len(p.data.records)), len(p.data.records))
+	if !p.data.done {
+		status += "+"
+	}
+	switch {
+	case p.prompt != "":
+		status = p.prompt + p.input
+	case p.notice != "":
+		status += "  " + p.notice
+	default:
+		status += "  [j/k] scroll [PgUp/PgDn] page [g/G] start/end [:] jump [/] search [n/N] next/prev [q] quit"
+	}
+	buf.SetString(truncateWidth(status+fmt.Sprintf("%*s", max(0, width-len(status)), ""), width),
+		ui.NewStyle(ui.ColorBlack, ui.ColorWhite), image.Pt(p.Inner.Min.X, p.Inner.Max.Y-1))
+}
+
+// truncateWidth cuts s to at most width bytes.
+func truncateWidth(s string, width int) string {
+	if len(s) > width {
+		return s[:max(0, width)]
+	}
+	return s
+}
+
+// handleKey updates the pager for a key press and reports whether to quit.
+func (p *fastqPager) handleKey(key string) bool {
+	if p.prompt != "" {
+		switch key {
+		case "<Escape>", "<C-c>":
+			p.prompt, p.input = "", ""
+		case "<Enter>":
+			if p.prompt == ":" {
+				if n, err := strconv.Atoi(p.input); err == nil && n > 0 {
+					p.data.ensure(n - 1)
+					if n > len(p.data.records) {
+						p.notice = fmt.Sprintf("only %d records", len(p.data.records))
+					}
+					p.offset = max(0, min(n-1, len(p.data.records)-1))
+				}
+			}
+			p.prompt, p.input = "", ""
+		case "<Backspace>", "<C-<Backspace>>":
+			if p.input != "" {
+				p.input = p.input[:len(p.input)-1]
+			}
+		case "<Space>":
+			p.input += " "
+		default:
+			if len(key) == 1 {
+				p.input += key
+			}
+		}
+		if p.prompt == "/" {
+			// Live search: follow the pattern as it is typed.
+			p.search = p.input
+			if i := p.data.find(p.search, p.offset, false); i >= 0 {
+				p.offset, p.notice = i, ""
+			} else if p.search != "" {
+				p.notice = "no match for " + p.search
+			}
+		}
+		return false
+	}
+
+	p.notice = ""
+	switch key {
+	case "q", "<C-c>":
+		return true
+	case "<Down>", "j":
+		p.scroll(1)
+	case "<Up>", "k":
+		p.scroll(-1)
+	case "<PageDown>", "<Space>":
+		p.scroll(p.pageSize())
+	case "<PageUp>":
+		p.scroll(-p.pageSize())
+	case "<Home>", "g":
+		p.offset = 0
+	case "<End>", "G":
+		p.data.ensure(int(^uint(0) >> 1))
+		p.offset = max(0, len(p.data.records)-p.pageSize())
+	case ":", "/":
+		p.prompt, p.input = key, ""
+	case "n", "N":
+		i := p.data.find(p.search, p.offset+1, false)
+		if key == "N" {
+			i = p.data.find(p.search, p.offset-1, true)
+		}
+		if i >= 0 {
+			p.offset = i
+		} else if p.search != "" {
+			p.notice = "no more matches for " + p.search
+		}
+	}
+	return false
+}
+
+// runFASTQViewer shows the records of filename in an interactive pager.
+func runFASTQViewer(filename string) error {
+	input, err := openInput(filename)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	data := &fastqViewData{reader: newFASTQReader(input)}
+	data.load(200)
+	if data.err != nil {
+		return fmt.Errorf("%s: %w", filename, data.err)
+	}
+	if len(data.records) == 0 {
+		return fmt.Errorf("%s has no records", filename)
+	}
+
+	if err := ui.Init(); err != nil {
+		return fmt.Errorf("failed to initialize termui: %w", err)
+	}
+	defer ui.Close()
+
+	pager := &fastqPager{Block: *ui.NewBlock(), data: data}
+	pager.Border = false
+	termWidth, termHeight := ui.TerminalDimensions()
+	pager.SetRect(0, 0, termWidth, termHeight)
+	ui.Render(pager)
+
+	for e := range ui.PollEvents() {
+		switch e.Type {
+		case ui.KeyboardEvent:
+			if pager.handleKey(e.ID) {
+				return data.err
+			}
+		case ui.ResizeEvent:
+			payload := e.Payload.(ui.Resize)
+			pager.SetRect(0, 0, payload.Width, payload.Height)
+			ui.Clear()
+		}
+		ui.Render(pager)
+	}
+	return data.err
+}
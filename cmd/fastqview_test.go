@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFASTQViewData(n int) *fastqViewData {
+	var sb strings.Builder
+	for i := 1; i <= n; i++ {
+		seq := "AAAA"
+		if i%100 == 0 {
+			seq = "ACGT"
+		}
+		fmt.Fprintf(&sb, "@read%d\n%s\n+\nIIII\n", i, seq)
+	}
+	return &fastqViewData{reader: newFASTQReader(strings.NewReader(sb.String()))}
+}
+
+func TestFASTQViewDataFind(t *testing.T) {
+	data := newTestFASTQViewData(450)
+	data.load(10)
+
+	// Searching loads records until a match.
+	assert.Equal(t, 99, data.find("ACGT", 0, false))
+	assert.False(t, data.done)
+	assert.Equal(t, 199, data.find("ACGT", 100, false))
+	assert.Equal(t, 99, data.find("ACGT", 198, true))
+	assert.Equal(t, 41, data.find("read42", 0, false))
+	assert.Equal(t, -1, data.find("GGGG", 0, false))
+	assert.True(t, data.done)
+	assert.Len(t, data.records, 450)
+	assert.Equal(t, -1, data.find("", 0, false))
+}
+
+func TestFASTQPagerKeys(t *testing.T) {
+	p := &fastqPager{data: newTestFASTQViewData(450)}
+	p.SetRect(0, 0, 80, 43) // 10 records per page inside the block
+	p.data.load(10)
+
+	for _, key := range []string{":", "3", "2", "0", "<Enter>"} {
+		p.handleKey(key)
+	}
+	assert.Equal(t, 319, p.offset)
+	assert.Equal(t, "", p.prompt)
+
+	p.handleKey("<PageUp>")
+	assert.Equal(t, 309, p.offset)
+	for _, key := range []string{"/", "A", "C", "G"} {
+		p.handleKey(key)
+	}
+	assert.Equal(t, 399, p.offset)
+	p.handleKey("<Escape>")
+	p.handleKey("N")
+	assert.Equal(t, 299, p.offset)
+	p.handleKey("n")
+	assert.Equal(t, 399, p.offset)
+	p.handleKey("n")
+	assert.Equal(t, 399, p.offset)
+	assert.Contains(t, p.notice, "no more matches")
+
+	p.handleKey("G")
+	assert.Equal(t, 440, p.offset)
+	p.handleKey("g")
+	assert.Equal(t, 0, p.offset)
+	p.handleKey("k")
+	assert.Equal(t, 0, p.offset)
+	assert.True(t, p.handleKey("q"))
+}
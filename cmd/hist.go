@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/liamg/tml"
+	"github.com/spf13/cobra"
+)
+
+var (
+	histBins   int
+	histColumn int
+	histSep    string
+	histMin    float64
+	histMax    float64
+	histLog    bool
+	histWidth  int
+)
+
+var histCmd = &cobra.Command{
+	Use:   "hist [filename]",
+	Short: "Print a histogram of numbers in the terminal",
+	Long: `Reads numbers from stdin or a file (one per line, or from a chosen column)
+and prints a unicode bar-chart histogram. Non-numeric values such as headers are skipped.
+
+Example:
+  samtools view aln.bam | hey hist -c 9 --min 0 --max 1000
+  hey hist -c 2 --log -b 30 insert_sizes.tsv.gz`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if histBins < 1 {
+			return fmt.Errorf("--bins must be at least 1")
+		}
+		filename := "-"
+		if len(args) == 1 {
+			filename = args[0]
+		}
+		values, skipped, err := readNumbers(filename, histColumn, histSep)
+		if err != nil {
+			return err
+		}
+		if len(values) == 0 {
+			return fmt.Errorf("no numeric values found in %s", filename)
+		}
+
+		dataMin, dataMax := minMax(values)
+		lo, hi := dataMin, dataMax
+		if cmd.Flags().Changed("min") {
+			lo = histMin
+		}
+		if cmd.Flags().Changed("max") {
+			hi = histMax
+		}
+		if hi < lo {
+			return fmt.Errorf("--max (%g) is smaller than --min (%g)", hi, lo)
+		}
+
+		counts, outside := computeHistogram(values, histBins, lo, hi)
+		printHistogram(os.Stdout, counts, lo, hi, histWidth, histLog)
+		summary := fmt.Sprintf("n=%d, min=%g, max=%g, mean=%.4g", len(values), dataMin, dataMax, mean(values))
+		if outside > 0 {
+			summary += fmt.Sprintf(", %d outside range", outside)
+		}
+		if skipped > 0 {
+			summary += fmt.Sprintf(", %d non-numeric skipped", skipped)
+		}
+		tml.Printf("<darkgrey>%s</darkgrey>\n", summary)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(histCmd)
+	histCmd.Flags().IntVarP(&histBins, "bins", "b", 20, "Number of bins")
+	histCmd.Flags().IntVarP(&histColumn, "column", "c", 1, "1-based column holding the values")
+	histCmd.Flags().StringVarP(&histSep, "sep", "s", "\t", "Column separator (default: tab)")
+	histCmd.Flags().Float64Var(&histMin, "min", 0, "Lower bound of the histogram (default: data minimum)")
+	histCmd.Flags().Float64Var(&histMax, "max", 0, "Upper bound of the histogram (default: data maximum)")
+	histCmd.Flags().BoolVarP(&histLog, "log", "l", false, "Scale bar lengths logarithmically")
+	histCmd.Flags().IntVarP(&histWidth, "width", "w", 50, "Maximum bar width in characters")
+}
+
+// readNumbers parses the given 1-based column of each line as a float and
+// returns the values together with the number of lines that were not numeric.
+func readNumbers(filename string, column int, sep string) ([]float64, int, error) {
+	if column < 1 {
+		return nil, 0, fmt.Errorf("column must be 1 or larger, got %d", column)
+	}
+	input, err := openInput(filename)
+	if err != nil {
+		return nil, 0, fmt.Errorf("opening %s: %w", filename, err)
+	}
+	defer input.Close()
+
+	var values []float64
+	skipped := 0
+	scanner := newLineScanner(input)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var fields []string
+		if sep == " " {
+			fields = strings.Fields(line)
+		} else {
+			fields = strings.Split(line, sep)
+		}
+		if column > len(fields) {
+			skipped++
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(fields[column-1]), ",", ""), 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			skipped++
+			continue
+		}
+		values = append(values, v)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("reading %s: %w", filename, err)
+	}
+	return values, skipped, nil
+}
+
+func minMax(values []float64) (float64, float64) {
+	lo, hi := values[0], values[0]
+	for _, v := range values[1:] {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	return lo, hi
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// computeHistogram counts values into equal-width bins over [lo, hi]. The last
+// bin is closed so that hi itself is counted. Values outside the range are
+// reported separately.
+func computeHistogram(values []float64, bins int, lo, hi float64) ([]int, int) {
+	counts := make([]int, bins)
+	outside := 0
+	width := (hi - lo) / float64(bins)
+	for _, v := range values {
+		if v < lo || v > hi {
+			outside++
+			continue
+		}
+		idx := bins - 1
+		if width > 0 {
+			idx = int((v - lo) / width)
+			if idx >= bins {
+				idx = bins - 1
+			}
+		}
+		counts[idx]++
+	}
+	return counts, outside
+}
+
+// renderBar draws a bar of the given fractional length using eighth blocks.
+func renderBar(length float64) string {
+	eighths := []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+	full := int(length)
+	rest := int((length - float64(full)) * 8)
+	return strings.Repeat("█", full) + eighths[rest]
+}
+
+func printHistogram(w io.Writer, counts []int, lo, hi float64, width int, logScale bool) {
+	maxCount := 0
+	for _, c := range counts {
+		if c > maxCount {
+			maxCount = c
+		}
+	}
+	scale := func(c int) float64 {
+		if maxCount == 0 {
+			return 0
+		}
+		if logScale {
+			return math.Log1p(float64(c)) / math.Log1p(float64(maxCount)) * float64(width)
+		}
+		return float64(c) / float64(maxCount) * float64(width)
+	}
+
+	binWidth := (hi - lo) / float64(len(counts))
+	labels := make([]string, len(counts))
+	labelWidth := 0
+	for i := range counts {
+		left := lo + float64(i)*binWidth
+		right := left + binWidth
+		closing := ")"
+		if i == len(counts)-1 {
+			closing = "]"
+		}
+		labels[i] = fmt.Sprintf("[%.4g, %.4g%s", left, right, closing)
+		labelWidth = max(labelWidth, len(labels[i]))
+	}
+	countWidth := len(strconv.Itoa(maxCount))
+
+	for i, c := range counts {
+		bar := renderBar(scale(c))
+		tml.Fprintf(w, "%*s <blue>%s</blue>%s <green>%*d</green>\n",
+			labelWidth, labels[i], bar,
+			strings.Repeat(" ", max(0, width-len([]rune(bar)))), countWidth, c)
+	}
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeHistogram(t *testing.T) {
+	tests := []struct {
+		name            string
+		values          []float64
+		bins            int
+		lo, hi          float64
+		expectedCounts  []int
+		expectedOutside int
+	}{
+		{
+			name:           "evenly spread values",
+			values:         []float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9},
+			bins:           5,
+			lo:             0,
+			hi:             10,
+			expectedCounts: []int{2, 2, 2, 2, 2},
+		},
+		{
+			name:           "upper bound falls in last bin",
+			values:         []float64{0, 5, 10},
+			bins:           2,
+			lo:             0,
+			hi:             10,
+			expectedCounts: []int{1, 2},
+		},
+		{
+			name:            "values outside the range",
+			values:          []float64{-1, 1, 2, 11},
+			bins:            2,
+			lo:              0,
+			hi:              10,
+			expectedCounts:  []int{2, 0},
+			expectedOutside: 2,
+		},
+		{
+			name:           "all values identical",
+			values:         []float64{3, 3, 3},
+			bins:           4,
+			lo:             3,
+			hi:             3,
+			expectedCounts: []int{0, 0, 0, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts, outside := computeHistogram(tt.values, tt.bins, tt.lo, tt.hi)
+			assert.Equal(t, tt.expectedCounts, counts)
+			assert.Equal(t, tt.expectedOutside, outside)
+		})
+	}
+}
+
+func TestRenderBar(t *testing.T) {
+	assert.Equal(t, "", renderBar(0))
+	assert.Equal(t, "██", renderBar(2))
+	assert.Equal(t, "█▌", renderBar(1.5))
+	assert.Equal(t, "▏", renderBar(0.125))
+}
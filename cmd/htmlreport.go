@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const htmlReportHeader = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <title>%s</title>
+    <style>
+        body { margin: 0; padding: 20px; background-color: #0b1d26; color: #e6e6e6; }
+        pre { font-family: "SF Mono", Menlo, Consolas, monospace; font-size: 14px; line-height: 1.35; }
+        .black { color: #000000; } .red { color: #cd3131; } .green { color: #5f9e3a; }
+        .yellow { color: #b58900; } .blue { color: #3b78c2; } .magenta { color: #bc3fbc; }
+        .cyan { color: #11a8cd; } .white { color: #e5e5e5; } .lightgrey { color: #c0c0c0; }
+        .darkgrey { color: #6c7a80; } .lightred { color: #f14c4c; } .lightgreen { color: #23d18b; }
+        .lightyellow { color: #f5f543; } .lightblue { color: #3b8eea; } .lightmagenta { color: #d670d6; }
+        .lightcyan { color: #29b8db; }
+        .bg-black { background-color: #000000; } .bg-red { background-color: #cd3131; }
+        .bg-green { background-color: #5f9e3a; } .bg-yellow { background-color: #b58900; }
+        .bg-blue { background-color: #3b78c2; } .bg-magenta { background-color: #bc3fbc; }
+        .bg-cyan { background-color: #11a8cd; } .bg-white { background-color: #e5e5e5; color: #000000; }
+        .bg-lightgrey { background-color: #c0c0c0; color: #000000; } .bg-darkgrey { background-color: #6c7a80; }
+        .bg-lightred { background-color: #f14c4c; } .bg-lightgreen { background-color: #23d18b; }
+        .bg-lightyellow { background-color: #f5f543; color: #000000; } .bg-lightblue { background-color: #3b8eea; }
+        .bg-lightmagenta { background-color: #d670d6; } .bg-lightcyan { background-color: #29b8db; }
+        .bold { font-weight: bold; } .italic { font-style: italic; } .underline { text-decoration: underline; }
+        .dim { opacity: 0.6; }
+    </style>
+</head>
+<body>
+<pre>
+`
+
+const htmlReportFooter = `</pre>
+</body>
+</html>
+`
+
+// htmlReport writes rendered alignments or reads as a standalone HTML page.
+// Lines with tml markup are added by writeLines; text with ANSI colors, as
+// printed to the terminal, is written to it as an io.Writer.
+type htmlReport struct {
+	file   *os.File
+	w      *bufio.Writer
+	ansi   ansiStyle // Style of the ANSI text written so far
+	inSpan bool      // Whether a span of the style is open
+}
+
+func newHTMLReport(path, title string) (*htmlReport, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	h := &htmlReport{file: file, w: bufio.NewWriter(file)}
+	fmt.Fprintf(h.w, htmlReportHeader, html.EscapeString(title))
+	return h, nil
+}
+
+// writeLines adds lines containing tml markup, followed by an empty line.
+func (h *htmlReport) writeLines(lines []string) {
+	for _, line := range lines {
+		h.w.WriteString(markupToHTML(line))
+		h.w.WriteByte('\n')
+	}
+	h.w.WriteByte('\n')
+}
+
+// Write adds text with ANSI color codes, converted into spans with the CSS
+// classes of the tml tags of the same colors.
+func (h *htmlReport) Write(p []byte) (int, error) {
+	text := string(p)
+	for len(text) > 0 {
+		plain := text
+		loc := ansiEscapeRegex.FindStringIndex(text)
+		if loc != nil {
+			plain = text[:loc[0]]
+		}
+		if plain != "" {
+			if classes := h.ansi.classes(); classes != "" && !h.inSpan {
+				h.w.WriteString(`<span class="` + classes + `">`)
+				h.inSpan = true
+			}
+			h.w.WriteString(html.EscapeString(plain))
+		}
+		if loc == nil {
+			break
+		}
+		if h.inSpan {
+			h.w.WriteString("</span>")
+			h.inSpan = false
+		}
+		h.ansi.apply(text[loc[0]:loc[1]])
+		text = text[loc[1]:]
+	}
+	return len(p), nil
+}
+
+func (h *htmlReport) Close() error {
+	if h.inSpan {
+		h.w.WriteString("</span>")
+	}
+	h.w.WriteString(htmlReportFooter)
+	if err := h.w.Flush(); err != nil {
+		h.file.Close()
+		return err
+	}
+	return h.file.Close()
+}
+
+// ansiColors are the names of the tml colors by ANSI color code, foreground
+// codes for bg- classes being 10 lower.
+var ansiColors = map[int]string{
+	30: "black", 31: "red", 32: "green", 33: "yellow", 34: "blue", 35: "magenta", 36: "cyan",
+	37: "lightgrey", 90: "darkgrey", 91: "lightred", 92: "lightgreen", 93: "lightyellow",
+	94: "lightblue", 95: "lightmagenta", 96: "lightcyan", 97: "white",
+}
+
+// ansiStyle is the style set by the ANSI SGR codes seen so far.
+type ansiStyle struct {
+	fg, bg                       string
+	bold, dim, italic, underline bool
+}
+
+// apply updates the style for an escape sequence like "\x1b[1;31m". Other
+// escape sequences are ignored.
+func (s *ansiStyle) apply(seq string) {
+	if !strings.HasSuffix(seq, "m") {
+		return
+	}
+	params := strings.TrimSuffix(strings.TrimPrefix(seq, "\x1b["), "m")
+	for _, param := range strings.Split(params, ";") {
+		code, err := strconv.Atoi(param)
+		if param == "" {
+			code, err = 0, nil
+		}
+		if err != nil {
+			continue
+		}
+		switch {
+		case code == 0:
+			*s = ansiStyle{}
+		case code == 1:
+			s.bold = true
+		case code == 2:
+			s.dim = true
+		case code == 3:
+			s.italic = true
+		case code == 4:
+			s.underline = true
+		case code == 22:
+			s.bold, s.dim = false, false
+		case code == 23:
+			s.italic = false
+		case code == 24:
+			s.underline = false
+		case code == 39:
+			s.fg = ""
+		case code == 49:
+			s.bg = ""
+		case ansiColors[code] != "":
+			s.fg = ansiColors[code]
+		case ansiColors[code-10] != "":
+			s.bg = "bg-" + ansiColors[code-10]
+		}
+	}
+}
+
+// classes returns the CSS classes of the style, separated by spaces.
+func (s *ansiStyle) classes() string {
+	var classes []string
+	for _, class := range []string{s.fg, s.bg} {
+		if class != "" {
+			classes = append(classes, class)
+		}
+	}
+	for _, attr := range []struct {
+		class string
+		set   bool
+	}{{"bold", s.bold}, {"dim", s.dim}, {"italic", s.italic}, {"underline", s.underline}} {
+		if attr.set {
+			classes = append(classes, attr.class)
+		}
+	}
+	return strings.Join(classes, " ")
+}
+
+// markupToHTML converts tml tags such as <bg-red> and </darkgrey> into spans
+// with the CSS class of the same name, escaping all other text.
+func markupToHTML(line string) string {
+	var b strings.Builder
+	for len(line) > 0 {
+		start := strings.IndexByte(line, '<')
+		if start < 0 {
+			b.WriteString(html.EscapeString(line))
+			break
+		}
+		b.WriteString(html.EscapeString(line[:start]))
+		line = line[start:]
+		end := strings.IndexByte(line, '>')
+		tag := ""
+		if end > 0 {
+			tag = line[1:end]
+		}
+		switch {
+		case strings.HasPrefix(tag, "/") && isMarkupTag(tag[1:]):
+			b.WriteString("</span>")
+		case isMarkupTag(tag):
+			b.WriteString(`<span class="` + tag + `">`)
+		default:
+			b.WriteString("&lt;")
+			line = line[1:]
+			continue
+		}
+		line = line[end+1:]
+	}
+	return b.String()
+}
+
+// stripMarkup removes the tml tags from line, leaving plain text.
+func stripMarkup(line string) string {
+	var b strings.Builder
+	for len(line) > 0 {
+		start := strings.IndexByte(line, '<')
+		if start < 0 {
+			b.WriteString(line)
+			break
+		}
+		b.WriteString(line[:start])
+		line = line[start:]
+		end := strings.IndexByte(line, '>')
+		if end > 0 && isMarkupTag(strings.TrimPrefix(line[1:end], "/")) {
+			line = line[end+1:]
+			continue
+		}
+		b.WriteByte('<')
+		line = line[1:]
+	}
+	return b.String()
+}
+
+func isMarkupTag(tag string) bool {
+	if tag == "" {
+		return false
+	}
+	for _, r := range tag {
+		if (r < 'a' || r > 'z') && r != '-' {
+			return false
+		}
+	}
+	return true
+}
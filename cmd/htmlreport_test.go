@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkupToHTML(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"ACGT", "ACGT"},
+		{"A<bg-red>C</bg-red>G", `A<span class="bg-red">C</span>G`},
+		{"<darkgrey><italic>r1 & r2</italic></darkgrey>", `<span class="darkgrey"><span class="italic">r1 &amp; r2</span></span>`},
+		{"a < b > c", "a &lt; b &gt; c"},
+		{"<1M>", "&lt;1M&gt;"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, markupToHTML(tt.input), tt.input)
+	}
+}
+
+func TestStripMarkup(t *testing.T) {
+	assert.Equal(t, "ACGT", stripMarkup("A<bg-red>C</bg-red><darkgrey>G</darkgrey>T"))
+	assert.Equal(t, "a < b", stripMarkup("a < b"))
+	assert.Equal(t, "<1M>", stripMarkup("<1M>"))
+}
+
+func TestHTMLReportWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.html")
+	report, err := newHTMLReport(path, "reads")
+	require.NoError(t, err)
+	fmt.Fprintln(report, "\x1b[1m\x1b[41mA\x1b[49m<C>\x1b[0m\x1b[90mG\x1b[39m")
+	fmt.Fprint(report, "\x1b[3;32mT")
+	require.NoError(t, report.Close())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "<title>reads</title>")
+	assert.Contains(t, string(content), `<span class="bg-red bold">A</span><span class="bold">&lt;C&gt;</span><span class="darkgrey">G</span>`+"\n")
+	assert.Contains(t, string(content), `<span class="green italic">T</span></pre>`)
+}
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IndexInfo is an index of a library prep kit: the i7 and i5 sequences of a
+// well, as in a sample sheet. Combinatorial kits list their i7 and i5
+// indexes separately, with the other one empty.
+type IndexInfo struct {
+	Kit  string
+	Name string
+	I7   string
+	I5   string
+}
+
+// IndexKits are the built-in index kits checkbarcode annotates barcodes with.
+// More can be added in the index kit config file.
+var IndexKits = []IndexInfo{
+	{"TruSeq LT", "AD001", "ATCACG", ""},
+	{"TruSeq LT", "AD002", "CGATGT", ""},
+	{"TruSeq LT", "AD003", "TTAGGC", ""},
+	{"TruSeq LT", "AD004", "TGACCA", ""},
+	{"TruSeq LT", "AD005", "ACAGTG", ""},
+	{"TruSeq LT", "AD006", "GCCAAT", ""},
+	{"TruSeq LT", "AD007", "CAGATC", ""},
+	{"TruSeq LT", "AD008", "ACTTGA", ""},
+	{"TruSeq LT", "AD009", "GATCAG", ""},
+	{"TruSeq LT", "AD010", "TAGCTT", ""},
+	{"TruSeq LT", "AD011", "GGCTAC", ""},
+	{"TruSeq LT", "AD012", "CTTGTA", ""},
+	{"TruSeq LT", "AD013", "AGTCAA", ""},
+	{"TruSeq LT", "AD014", "AGTTCC", ""},
+	{"TruSeq LT", "AD015", "ATGTCA", ""},
+	{"TruSeq LT", "AD016", "CCGTCC", ""},
+	{"TruSeq LT", "AD018", "GTCCGC", ""},
+	{"TruSeq LT", "AD019", "GTGAAA", ""},
+	{"TruSeq LT", "AD020", "GTGGCC", ""},
+	{"TruSeq LT", "AD021", "GTTTCG", ""},
+	{"TruSeq LT", "AD022", "CGTACG", ""},
+	{"TruSeq LT", "AD023", "GAGTGG", ""},
+	{"TruSeq LT", "AD025", "ACTGAT", ""},
+	{"TruSeq LT", "AD027", "ATTCCT", ""},
+	{"TruSeq UD", "UDI0001", "CCGCGGTT", "AGCGCTAG"},
+	{"TruSeq UD", "UDI0002", "TTATAACC", "GATATCGA"},
+	{"TruSeq UD", "UDI0003", "GGACTTGG", "CGCAGACG"},
+	{"TruSeq UD", "UDI0004", "AAGTCCAA", "TATGAGTA"},
+	{"TruSeq UD", "UDI0005", "ATCCACTG", "AGGTGCGT"},
+	{"TruSeq UD", "UDI0006", "GCTTGTCA", "GAACATAC"},
+	{"TruSeq UD", "UDI0007", "CAAGCTAG", "ACATAGCG"},
+	{"TruSeq UD", "UDI0008", "TGGATCGA", "GTGCGATA"},
+	{"TruSeq UD", "UDI0009", "AGTTCAGG", "CCAACAGA"},
+	{"TruSeq UD", "UDI0010", "GACCTGAA", "TTGGTGAG"},
+	{"TruSeq UD", "UDI0011", "TCTCTACT", "CGCGGTTC"},
+	{"TruSeq UD", "UDI0012", "CTCTCGTC", "TATAACCT"},
+	{"Nextera XT", "N701", "TAAGGCGA", ""},
+	{"Nextera XT", "N702", "CGTACTAG", ""},
+	{"Nextera XT", "N703", "AGGCAGAA", ""},
+	{"Nextera XT", "N704", "TCCTGAGC", ""},
+	{"Nextera XT", "N705", "GGACTCCT", ""},
+	{"Nextera XT", "N706", "TAGGCATG", ""},
+	{"Nextera XT", "N707", "CTCTCTAC", ""},
+	{"Nextera XT", "N708", "CAGAGAGG", ""},
+	{"Nextera XT", "N709", "GCTACGCT", ""},
+	{"Nextera XT", "N710", "CGAGGCTG", ""},
+	{"Nextera XT", "N711", "AAGAGGCA", ""},
+	{"Nextera XT", "N712", "GTAGAGGA", ""},
+	{"Nextera XT", "S502", "", "CTCTCTAT"},
+	{"Nextera XT", "S503", "", "TATCCTCT"},
+	{"Nextera XT", "S505", "", "GTAAGGAG"},
+	{"Nextera XT", "S506", "", "ACTGCATA"},
+	{"Nextera XT", "S507", "", "AAGGAGTA"},
+	{"Nextera XT", "S508", "", "CTAAGCCT"},
+	{"Nextera XT", "S510", "", "CGTCTAAT"},
+	{"Nextera XT", "S511", "", "TCTCTCCG"},
+	{"Nextera XT", "S513", "", "TCGACTAG"},
+	{"Nextera XT", "S515", "", "TTCTAGCT"},
+	{"Nextera XT", "S516", "", "CCTAGAGT"},
+	{"Nextera XT", "S517", "", "GCGTAAGA"},
+	{"Nextera XT", "S518", "", "CTATTAAG"},
+	{"Nextera XT", "S520", "", "AAGGCTAT"},
+	{"Nextera XT", "S521", "", "GAGCCTTA"},
+	{"Nextera XT", "S522", "", "TTATGCGA"},
+	// 10x sample indexes are sets of four oligos, any of which can be read.
+	{"10x", "SI-GA-A1", "GGTTTACT", ""},
+	{"10x", "SI-GA-A1", "CTAAACGG", ""},
+	{"10x", "SI-GA-A1", "TCGGCGTC", ""},
+	{"10x", "SI-GA-A1", "AACCGTAA", ""},
+	{"10x", "SI-GA-A2", "TTTCATGA", ""},
+	{"10x", "SI-GA-A2", "ACGTCCCT", ""},
+	{"10x", "SI-GA-A2", "CGCATGTG", ""},
+	{"10x", "SI-GA-A2", "GAAGGAAC", ""},
+	{"10x", "SI-GA-A3", "CAGTACTG", ""},
+	{"10x", "SI-GA-A3", "AGTAGTCT", ""},
+	{"10x", "SI-GA-A3", "GCAGTAGA", ""},
+	{"10x", "SI-GA-A3", "TTCCCGAC", ""},
+	{"10x", "SI-GA-A4", "TATGATTC", ""},
+	{"10x", "SI-GA-A4", "CCCACAGT", ""},
+	{"10x", "SI-GA-A4", "ATGCTGAA", ""},
+	{"10x", "SI-GA-A4", "GGATGCCG", ""},
+}
+
+// indexKitConfigPath is the file with user-defined index kits, mapping wells
+// to their i7 or i7+i5 sequences, for example:
+//
+//	My kit:
+//	  A01: ACGTACGT+TTGGCCAA
+//	  B01: GGAACCTT
+func indexKitConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "hey", "index_kits.yaml")
+}
+
+var indexSeqRegex = regexp.MustCompile(`^[ACGTN]+(\+[ACGTN]+)?$`)
+
+// loadIndexKits returns the built-in index kits followed by those of the
+// index kit config file, if there is one.
+func loadIndexKits() ([]IndexInfo, error) {
+	kits := IndexKits
+	path := indexKitConfigPath()
+	if path == "" {
+		return kits, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return kits, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var custom map[string]map[string]string
+	if err := yaml.Unmarshal(data, &custom); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	user, err := customIndexKits(custom)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return append(append([]IndexInfo(nil), kits...), user...), nil
+}
+
+// customIndexKits turns the kits of the config file into indexes, sorted by
+// kit and well.
+func customIndexKits(custom map[string]map[string]string) ([]IndexInfo, error) {
+	var indexes []IndexInfo
+	for kit, wells := range custom {
+		for well, seq := range wells {
+			seq = strings.ToUpper(strings.TrimSpace(seq))
+			if !indexSeqRegex.MatchString(seq) {
+				return nil, fmt.Errorf("invalid index %q for %s %s, use i7 or i7+i5 bases", seq, kit, well)
+			}
+			i7, i5, _ := strings.Cut(seq, "+")
+			indexes = append(indexes, IndexInfo{Kit: kit, Name: well, I7: i7, I5: i5})
+		}
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		if indexes[i].Kit != indexes[j].Kit {
+			return indexes[i].Kit < indexes[j].Kit
+		}
+		return indexes[i].Name < indexes[j].Name
+	})
+	return indexes, nil
+}
+
+// indexMatches tells whether the index read as seq is index, which may be
+// shorter than seq as an 8-base read of a 6-base index is. N matches any base.
+func indexMatches(seq, index string) bool {
+	return len(index) > 0 && areBarcodesCompatibleGo(seq, index, len(index), 0)
+}
+
+// annotateBarcode returns the kits and wells of indexes that a barcode like
+// ACGT or ACGT+TTGG matches, such as "Nextera XT N701/S502", or "". The i5
+// also matches reverse complemented, as some instruments read it that way.
+func annotateBarcode(barcode string, indexes []IndexInfo) string {
+	i7, i5, _ := strings.Cut(barcode, "+")
+	i5rc := reverseComplement(i5, dnaComplements)
+	var kits []string
+	wells := make(map[string][]string)
+	for _, index := range indexes {
+		i5OK := index.I5 != "" && (indexMatches(i5, index.I5) || indexMatches(i5rc, index.I5))
+		var matched bool
+		switch {
+		case index.I7 == "":
+			matched = i5OK
+		case index.I5 == "":
+			matched = indexMatches(i7, index.I7)
+		default:
+			matched = indexMatches(i7, index.I7) && i5OK
+		}
+		if !matched || slices.Contains(wells[index.Kit], index.Name) {
+			continue
+		}
+		if wells[index.Kit] == nil {
+			kits = append(kits, index.Kit)
+		}
+		wells[index.Kit] = append(wells[index.Kit], index.Name)
+	}
+	annotations := make([]string, len(kits))
+	for i, kit := range kits {
+		annotations[i] = kit + " " + strings.Join(wells[kit], "/")
+	}
+	return strings.Join(annotations, ", ")
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnotateBarcode(t *testing.T) {
+	tests := []struct {
+		barcode string
+		want    string
+	}{
+		{"ATCACG", "TruSeq LT AD001"},
+		{"ATCACGAT", "TruSeq LT AD001"}, // 8-base read of a 6-base index
+		{"ATCNCG", "TruSeq LT AD001"},
+		{"CCGCGGTT+AGCGCTAG", "TruSeq UD UDI0001"},
+		{"CCGCGGTT+CTAGCGCT", "TruSeq UD UDI0001"}, // i5 reverse complemented
+		{"CCGCGGTT+GATATCGA", ""},                  // i5 of another well
+		{"TAAGGCGA+CTCTCTAT", "Nextera XT N701/S502"},
+		{"CTAAACGG", "10x SI-GA-A1"},
+		{"GGGGGGGG", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, annotateBarcode(tt.barcode, IndexKits), tt.barcode)
+	}
+}
+
+func TestCustomIndexKits(t *testing.T) {
+	indexes, err := customIndexKits(map[string]map[string]string{
+		"Mine": {"B01": "ggaacctt", "A01": "ACGTACGT+TTGGCCAA"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []IndexInfo{
+		{Kit: "Mine", Name: "A01", I7: "ACGTACGT", I5: "TTGGCCAA"},
+		{Kit: "Mine", Name: "B01", I7: "GGAACCTT"},
+	}, indexes)
+	assert.Equal(t, "Mine A01", annotateBarcode("ACGTACGT+TTGGCCAA", indexes))
+
+	_, err = customIndexKits(map[string]map[string]string{"Mine": {"A01": "ACGT+"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	initPattern string
+	initOutput  string
+	initKey     string
+	initForce   bool
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init <fastq-dir>",
+	Short: "Generate a sample YAML from a directory of FASTQ files",
+	Long: `Scans a directory recursively, groups the FASTQ files into samples and runs,
+and writes a sample YAML in the format used by 'checkbarcode', 'link' and
+'validate'.
+
+--pattern describes the file names. {sample} captures the sample name, {read}
+the read number (1 or 2), and * matches anything. Patterns containing a '/'
+are matched against the path below the directory, others against the file
+name. Files with the same name apart from the read number form one run.
+Without --pattern, names are parsed like 'hey manifest' does (_R1/_R2, _1/_2,
+Illumina _S1_L001 suffixes).
+
+Paths in the YAML are relative to the output file.
+
+Example:
+  hey init /data/fastq
+  hey init /data/fastq --pattern '{sample}_S*_L*_R{read}_001.fastq.gz' -o samples.yaml
+  hey init raw --pattern '{sample}/*_{read}.fq.gz'`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !initForce && fileExists(initOutput) {
+			return fmt.Errorf("%s already exists, use --force to overwrite it", initOutput)
+		}
+		var runs []*manifestRun
+		if initPattern == "" {
+			paths, err := findFastqFiles(args[0])
+			if err != nil {
+				return err
+			}
+			runs = pairFastqFiles(paths)
+		} else {
+			re, err := compileFilePattern(initPattern)
+			if err != nil {
+				return err
+			}
+			runs, err = groupFilesByPattern(args[0], re, strings.Contains(initPattern, "/"))
+			if err != nil {
+				return err
+			}
+		}
+		if len(runs) == 0 {
+			return fmt.Errorf("no matching files found in %s", args[0])
+		}
+		if err := writeSampleYAML(initOutput, initKey, runs); err != nil {
+			return err
+		}
+		samples := make(map[string]bool)
+		for _, r := range runs {
+			samples[r.Sample] = true
+		}
+		color.Green("Wrote %d samples with %d runs to %s", len(samples), len(runs), initOutput)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+	initCmd.Flags().StringVarP(&initPattern, "pattern", "p", "", "File name pattern with {sample} and {read} placeholders")
+	initCmd.Flags().StringVarP(&initOutput, "output", "o", "samples.yaml", "YAML file to write")
+	initCmd.Flags().StringVarP(&initKey, "key", "k", "samples", "Top-level key of the YAML output")
+	initCmd.Flags().BoolVarP(&initForce, "force", "f", false, "Overwrite the output file if it exists")
+}
+
+// compileFilePattern turns a pattern such as '{sample}_S*_R{read}_001.fastq.gz'
+// into an anchored regular expression with 'sample' and 'read' groups.
+func compileFilePattern(pattern string) (*regexp.Regexp, error) {
+	if strings.Count(pattern, "{sample}") != 1 || strings.Count(pattern, "{read}") > 1 {
+		return nil, fmt.Errorf("pattern %q must contain {sample} once and {read} at most once", pattern)
+	}
+	var expr strings.Builder
+	expr.WriteString("^")
+	for rest := pattern; rest != ""; {
+		switch {
+		case strings.HasPrefix(rest, "{sample}"):
+			expr.WriteString(`(?P<sample>[^/]+?)`)
+			rest = rest[len("{sample}"):]
+		case strings.HasPrefix(rest, "{read}"):
+			expr.WriteString(`(?P<read>[12])`)
+			rest = rest[len("{read}"):]
+		case rest[0] == '*':
+			expr.WriteString(`[^/]*?`)
+			rest = rest[1:]
+		default:
+			expr.WriteString(regexp.QuoteMeta(rest[:1]))
+			rest = rest[1:]
+		}
+	}
+	expr.WriteString("$")
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// matchFilePattern returns the sample, the read number (0 without {read}) and
+// the run key shared by both mates of a matching name.
+func matchFilePattern(re *regexp.Regexp, name string) (sample string, read int, runKey string, ok bool) {
+	m := re.FindStringSubmatchIndex(name)
+	if m == nil {
+		return "", 0, "", false
+	}
+	sampleIdx := re.SubexpIndex("sample")
+	sample = name[m[2*sampleIdx]:m[2*sampleIdx+1]]
+	runKey = name
+	if readIdx := re.SubexpIndex("read"); readIdx >= 0 {
+		start, end := m[2*readIdx], m[2*readIdx+1]
+		read = int(name[start] - '0')
+		runKey = name[:start] + "{read}" + name[end:]
+	}
+	return sample, read, runKey, true
+}
+
+func groupFilesByPattern(dir string, re *regexp.Regexp, matchPath bool) ([]*manifestRun, error) {
+	byKey := make(map[string]*manifestRun)
+	var runs []*manifestRun
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		name := d.Name()
+		if matchPath {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			name = filepath.ToSlash(rel)
+		}
+		sample, read, key, ok := matchFilePattern(re, name)
+		if !ok {
+			return nil
+		}
+		key = filepath.Join(filepath.Dir(path), key)
+		r, ok := byKey[key]
+		if !ok {
+			r = &manifestRun{Sample: sample, Run: key}
+			byKey[key] = r
+			runs = append(runs, r)
+		}
+		if read == 2 {
+			r.R2 = &manifestFile{AbsPath: path}
+		} else {
+			r.R1 = &manifestFile{AbsPath: path}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning %s: %w", dir, err)
+	}
+	for _, r := range runs {
+		if r.R1 == nil {
+			r.R1, r.R2 = r.R2, nil
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		if runs[i].Sample != runs[j].Sample {
+			return runs[i].Sample < runs[j].Sample
+		}
+		return runs[i].Run < runs[j].Run
+	})
+	return runs, nil
+}
+
+type sampleYAMLRun struct {
+	R1 string `yaml:"R1"`
+	R2 string `yaml:"R2,omitempty"`
+}
+
+type sampleYAMLEntry struct {
+	Data []sampleYAMLRun `yaml:"data"`
+}
+
+func writeSampleYAML(output, key string, runs []*manifestRun) error {
+	baseDir, err := filepath.Abs(filepath.Dir(output))
+	if err != nil {
+		return err
+	}
+	relative := func(path string) string {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return path
+		}
+		if rel, err := filepath.Rel(baseDir, abs); err == nil {
+			return rel
+		}
+		return abs
+	}
+
+	samples := make(map[string]*sampleYAMLEntry)
+	for _, r := range runs {
+		entry, ok := samples[r.Sample]
+		if !ok {
+			entry = &sampleYAMLEntry{}
+			samples[r.Sample] = entry
+		}
+		run := sampleYAMLRun{R1: relative(r.R1.AbsPath)}
+		if r.R2 != nil {
+			run.R2 = relative(r.R2.AbsPath)
+		}
+		entry.Data = append(entry.Data, run)
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", output, err)
+	}
+	defer file.Close()
+	encoder := yaml.NewEncoder(file)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(map[string]any{key: samples}); err != nil {
+		return fmt.Errorf("writing %s: %w", output, err)
+	}
+	return encoder.Close()
+}
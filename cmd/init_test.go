@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchFilePattern(t *testing.T) {
+	tests := []struct {
+		pattern        string
+		name           string
+		ok             bool
+		expectedSample string
+		expectedRead   int
+		expectedKey    string
+	}{
+		{"{sample}_S*_L*_R{read}_001.fastq.gz", "liver_rep1_S3_L002_R2_001.fastq.gz", true, "liver_rep1", 2, "liver_rep1_S3_L002_R{read}_001.fastq.gz"},
+		{"{sample}_S*_L*_R{read}_001.fastq.gz", "liver_S3_L002_R1_001.fq.gz", false, "", 0, ""},
+		{"{sample}_{read}.fq.gz", "SRR1_1.fq.gz", true, "SRR1", 1, "SRR1_{read}.fq.gz"},
+		{"{sample}/*_{read}.fq.gz", "heart/lane1_2.fq.gz", true, "heart", 2, "heart/lane1_{read}.fq.gz"},
+		{"{sample}.fastq", "single.fastq", true, "single", 0, "single.fastq"},
+		{"{sample}.fastq", "a.b.fastq", true, "a.b", 0, "a.b.fastq"},
+	}
+	for _, tt := range tests {
+		re, err := compileFilePattern(tt.pattern)
+		require.NoError(t, err)
+		sample, read, key, ok := matchFilePattern(re, tt.name)
+		assert.Equal(t, tt.ok, ok, tt.name)
+		assert.Equal(t, tt.expectedSample, sample, tt.name)
+		assert.Equal(t, tt.expectedRead, read, tt.name)
+		assert.Equal(t, tt.expectedKey, key, tt.name)
+	}
+
+	for _, bad := range []string{"*_R{read}.fq", "{sample}_{sample}.fq", "{sample}_{read}_{read}.fq"} {
+		_, err := compileFilePattern(bad)
+		assert.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// decompressReadCloser closes both the decompressing stream, if it needs
+// closing, and the underlying file.
+type decompressReadCloser struct {
+	io.Reader
+	close func()
+	file  *os.File
+}
+
+func (d *decompressReadCloser) Close() error {
+	if d.close != nil {
+		d.close()
+	}
+	return d.file.Close()
+}
+
+// decompressors open the compressed files that openInput reads, by extension.
+var decompressors = map[string]func(io.Reader) (io.Reader, func(), error){
+	".gz": func(r io.Reader) (io.Reader, func(), error) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return gz, func() { gz.Close() }, nil
+	},
+	".bz2": func(r io.Reader) (io.Reader, func(), error) {
+		return bzip2.NewReader(r), nil, nil
+	},
+	".xz": func(r io.Reader) (io.Reader, func(), error) {
+		x, err := xz.NewReader(bufio.NewReader(r))
+		return x, nil, err
+	},
+	".zst": func(r io.Reader) (io.Reader, func(), error) {
+		z, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return z, z.Close, nil
+	},
+}
+
+// openInput opens filename for reading, transparently decompressing files
+// ending in .gz, .bz2, .xz or .zst. An empty filename or "-" reads from stdin.
+func openInput(filename string) (io.ReadCloser, error) {
+	if filename == "" || filename == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	for ext, decompress := range decompressors {
+		if !strings.HasSuffix(filename, ext) {
+			continue
+		}
+		r, closeReader, err := decompress(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &decompressReadCloser{Reader: r, close: closeReader, file: file}, nil
+	}
+	return file, nil
+}
+
+// newLineScanner returns a scanner that tolerates lines up to 10MB, which
+// wide tables and long-read records can easily exceed.
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	return scanner
+}
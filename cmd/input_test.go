@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
+)
+
+func TestOpenInputDecompresses(t *testing.T) {
+	const record = "@r1\nACGT\n+\nIIII\n"
+	compress := map[string]func(io.Writer) io.WriteCloser{
+		".gz": func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) },
+		".xz": func(w io.Writer) io.WriteCloser {
+			x, err := xz.NewWriter(w)
+			require.NoError(t, err)
+			return x
+		},
+		".zst": func(w io.Writer) io.WriteCloser {
+			z, err := zstd.NewWriter(w)
+			require.NoError(t, err)
+			return z
+		},
+		"": func(w io.Writer) io.WriteCloser { return nopWriteCloser{w} },
+	}
+	dir := t.TempDir()
+	for ext, newWriter := range compress {
+		t.Run("ext"+ext, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := newWriter(&buf)
+			_, err := io.WriteString(w, record)
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+			path := filepath.Join(dir, "reads.fq"+ext)
+			require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+
+			input, err := openInput(path)
+			require.NoError(t, err)
+			defer input.Close()
+			data, err := io.ReadAll(input)
+			require.NoError(t, err)
+			assert.Equal(t, record, string(data))
+		})
+	}
+
+	// The standard library cannot write bzip2.
+	path := filepath.Join(dir, "reads.fq.bz2")
+	require.NoError(t, os.WriteFile(path, bzip2Record, 0o644))
+	input, err := openInput(path)
+	require.NoError(t, err)
+	defer input.Close()
+	data, err := io.ReadAll(input)
+	require.NoError(t, err)
+	assert.Equal(t, record, string(data))
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// bzip2Record is "@r1\nACGT\n+\nIIII\n" compressed with bzip2.
+var bzip2Record = []byte{0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xaf, 0x85, 0x72, 0x8b, 0x00, 0x00, 0x03, 0xde, 0x80, 0x40, 0x10, 0x00, 0x08, 0x20, 0x00, 0x68, 0xa0, 0x04, 0x00, 0x10, 0x00, 0x20, 0x00, 0x22, 0x01, 0xa3, 0x4d, 0x08, 0x06, 0x9a, 0x68, 0x3d, 0x20, 0x05, 0x0c, 0x78, 0xbd, 0x25, 0xe2, 0xee, 0x48, 0xa7, 0x0a, 0x12, 0x15, 0xf0, 0xae, 0x51, 0x60}
@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aquasecurity/table"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	jobsUser    string
+	jobsHistory time.Duration
+	jobsWatch   time.Duration
+)
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "Show SLURM jobs in a pretty table",
+	Long: `Lists your SLURM jobs using squeue, with color-coded states and the elapsed
+time compared to the requested time limit.
+
+Use --history to also include jobs that finished recently (via sacct), and
+--watch to refresh the table until Ctrl+C.
+
+Example:
+  hey jobs
+  hey jobs --history 24h
+  hey jobs -w 30s`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if jobsUser == "" {
+			u, err := user.Current()
+			if err != nil {
+				return fmt.Errorf("cannot determine current user, use --user: %w", err)
+			}
+			jobsUser = u.Username
+		}
+		if _, err := exec.LookPath("squeue"); err != nil {
+			return fmt.Errorf("squeue not found in PATH; is this a SLURM cluster?")
+		}
+		if jobsWatch <= 0 {
+			jobs, err := fetchJobs(jobsUser, jobsHistory)
+			if err != nil {
+				return err
+			}
+			printJobsTable(jobs)
+			return nil
+		}
+		return watchJobs(jobsUser, jobsHistory, jobsWatch)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(jobsCmd)
+	jobsCmd.Flags().StringVarP(&jobsUser, "user", "u", "", "Show jobs of this user (default: current user)")
+	jobsCmd.Flags().DurationVarP(&jobsHistory, "history", "H", 0, "Also show jobs finished within this period (e.g. 24h), via sacct")
+	jobsCmd.Flags().DurationVarP(&jobsWatch, "watch", "w", 0, "Refresh interval, e.g. 10s (0 = print once)")
+}
+
+type slurmJob struct {
+	ID        string
+	Name      string
+	State     string
+	Elapsed   string
+	TimeLimit string
+	Nodes     string
+	CPUs      string
+	Partition string
+	Reason    string
+}
+
+const (
+	squeueFormat = "%i|%j|%T|%M|%l|%D|%C|%P|%R"
+	sacctFormat  = "JobID,JobName,State,Elapsed,Timelimit,NNodes,NCPUS,Partition,NodeList"
+)
+
+func fetchJobs(username string, history time.Duration) ([]slurmJob, error) {
+	out, err := runSlurmCommand("squeue", "-u", username, "-h", "-o", squeueFormat)
+	if err != nil {
+		return nil, err
+	}
+	jobs := parseSlurmJobs(out)
+
+	if history > 0 {
+		if _, err := exec.LookPath("sacct"); err != nil {
+			return nil, fmt.Errorf("sacct not found in PATH, cannot show --history")
+		}
+		start := time.Now().Add(-history).Format("2006-01-02T15:04:05")
+		out, err := runSlurmCommand("sacct", "-u", username, "-X", "-n", "-P", "-S", start, "-o", sacctFormat)
+		if err != nil {
+			return nil, err
+		}
+		active := make(map[string]bool, len(jobs))
+		for _, j := range jobs {
+			active[j.ID] = true
+		}
+		for _, j := range parseSlurmJobs(out) {
+			if !active[j.ID] {
+				jobs = append(jobs, j)
+			}
+		}
+	}
+	return jobs, nil
+}
+
+func runSlurmCommand(name string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	c := exec.Command(name, args...)
+	c.Stderr = &stderr
+	out, err := c.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// parseSlurmJobs parses '|' separated squeue/sacct output in the column order
+// shared by squeueFormat and sacctFormat.
+func parseSlurmJobs(output string) []slurmJob {
+	var jobs []slurmJob
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(strings.TrimSpace(line), "|")
+		if len(fields) < 9 {
+			continue
+		}
+		jobs = append(jobs, slurmJob{
+			ID:        fields[0],
+			Name:      fields[1],
+			State:     strings.Fields(fields[2] + " ")[0], // "CANCELLED by 123" -> "CANCELLED"
+			Elapsed:   fields[3],
+			TimeLimit: fields[4],
+			Nodes:     fields[5],
+			CPUs:      fields[6],
+			Partition: fields[7],
+			Reason:    fields[8],
+		})
+	}
+	return jobs
+}
+
+// parseSlurmDuration parses SLURM time strings such as "1-02:03:04",
+// "1-02:03", "1-02", "02:03:04", "03:04" or "03". It reports false for
+// UNLIMITED and invalid values.
+func parseSlurmDuration(s string) (time.Duration, bool) {
+	days, hasDays := 0, false
+	if d, rest, ok := strings.Cut(s, "-"); ok {
+		n, err := strconv.Atoi(d)
+		if err != nil {
+			return 0, false
+		}
+		days, hasDays = n, true
+		s = rest
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) == 0 || len(parts) > 3 {
+		return 0, false
+	}
+	var units []int
+	for _, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return 0, false
+		}
+		units = append(units, n)
+	}
+	// After days the fields start with hours: "1-02:03" is hours:minutes,
+	// while "02:03" alone is minutes:seconds.
+	var h, m, sec int
+	switch {
+	case len(units) == 1 && hasDays:
+		h = units[0]
+	case len(units) == 2 && hasDays:
+		h, m = units[0], units[1]
+	case len(units) == 1:
+		m = units[0]
+	case len(units) == 2:
+		m, sec = units[0], units[1]
+	default:
+		h, m, sec = units[0], units[1], units[2]
+	}
+	return time.Duration(days)*24*time.Hour +
+		time.Duration(h)*time.Hour +
+		time.Duration(m)*time.Minute +
+		time.Duration(sec)*time.Second, true
+}
+
+func jobStateColor(state string) *color.Color {
+	switch state {
+	case "RUNNING", "COMPLETING":
+		return color.New(color.FgGreen, color.Bold)
+	case "PENDING", "CONFIGURING", "SUSPENDED", "REQUEUED":
+		return color.New(color.FgYellow)
+	case "COMPLETED":
+		return color.New(color.FgCyan)
+	case "CANCELLED", "PREEMPTED":
+		return color.New(color.FgMagenta)
+	case "FAILED", "TIMEOUT", "OUT_OF_MEMORY", "NODE_FAIL", "BOOT_FAIL", "DEADLINE":
+		return color.New(color.FgRed, color.Bold)
+	}
+	return color.New(color.Reset)
+}
+
+// formatJobTime shows elapsed/limit together with the used fraction, turning
+// red when a running job is close to its time limit.
+func formatJobTime(job slurmJob) string {
+	text := fmt.Sprintf("%s / %s", job.Elapsed, job.TimeLimit)
+	elapsed, ok1 := parseSlurmDuration(job.Elapsed)
+	limit, ok2 := parseSlurmDuration(job.TimeLimit)
+	if !ok1 || !ok2 || limit == 0 {
+		return text
+	}
+	frac := float64(elapsed) / float64(limit)
+	text += fmt.Sprintf(" (%.0f%%)", frac*100)
+	if job.State == "RUNNING" && frac >= 0.9 {
+		return color.RedString(text)
+	}
+	return text
+}
+
+func printJobsTable(jobs []slurmJob) {
+	if len(jobs) == 0 {
+		color.Yellow("No jobs found.")
+		return
+	}
+	t := table.New(os.Stdout)
+	t.SetHeaders("Job ID", "Name", "State", "Elapsed / Limit", "Nodes", "CPUs", "Partition", "Nodelist (Reason)")
+	t.SetHeaderStyle(table.StyleBold)
+	t.SetLineStyle(table.StyleBlue)
+	t.SetDividers(table.UnicodeRoundedDividers)
+
+	counts := make(map[string]int)
+	for _, j := range jobs {
+		counts[j.State]++
+		t.AddRow(
+			j.ID,
+			j.Name,
+			jobStateColor(j.State).Sprint(j.State),
+			formatJobTime(j),
+			j.Nodes,
+			j.CPUs,
+			j.Partition,
+			j.Reason,
+		)
+	}
+	t.Render()
+
+	var summary []string
+	for _, state := range []string{"RUNNING", "PENDING", "COMPLETED", "FAILED", "TIMEOUT", "OUT_OF_MEMORY", "CANCELLED"} {
+		if counts[state] > 0 {
+			summary = append(summary, jobStateColor(state).Sprintf("%s: %d", state, counts[state]))
+		}
+	}
+	fmt.Printf("%d jobs  %s\n", len(jobs), strings.Join(summary, "  "))
+}
+
+func watchJobs(username string, history, interval time.Duration) error {
+	interruptChan := make(chan os.Signal, 1)
+	signal.Notify(interruptChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interruptChan)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		jobs, err := fetchJobs(username, history)
+		if err != nil {
+			return err
+		}
+		fmt.Print("\033[H\033[2J") // Clear the screen before redrawing
+		printJobsTable(jobs)
+		fmt.Printf("Updated %s, refreshing every %s (Ctrl+C to stop)\n", time.Now().Format("15:04:05"), interval)
+
+		select {
+		case <-interruptChan:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSlurmDuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"05:30", 5*time.Minute + 30*time.Second, true},
+		{"02:03:04", 2*time.Hour + 3*time.Minute + 4*time.Second, true},
+		{"1-00:00:00", 24 * time.Hour, true},
+		{"2-12:00:00", 60 * time.Hour, true},
+		{"1-12", 36 * time.Hour, true},
+		{"1-02:03", 26*time.Hour + 3*time.Minute, true},
+		{"1-", 0, false},
+		{"x-02", 0, false},
+		{"30", 30 * time.Minute, true},
+		{"UNLIMITED", 0, false},
+		{"INVALID", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			d, ok := parseSlurmDuration(tt.input)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, d)
+		})
+	}
+}
+
+func TestParseSlurmJobs(t *testing.T) {
+	output := "123|align|RUNNING|1:02:03|4:00:00|1|8|broadwl|node01\n" +
+		"124|sort|CANCELLED by 1000|00:10|1:00:00|1|1|broadwl|None\n" +
+		"bad line\n"
+	jobs := parseSlurmJobs(output)
+	assert.Len(t, jobs, 2)
+	assert.Equal(t, "align", jobs[0].Name)
+	assert.Equal(t, "node01", jobs[0].Reason)
+	assert.Equal(t, "CANCELLED", jobs[1].State)
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	joinOn   string
+	joinHow  string
+	joinSep  string
+	joinFill string
+)
+
+var joinCmd = &cobra.Command{
+	Use:   "join <left> <right> --on KEY",
+	Short: "Join two delimited files on a key column",
+	Long: `Joins two delimited files with headers on a shared key column.
+The right file is loaded into memory and the left file is streamed, so put the
+larger file first. The output header is the left header followed by the right
+header without its key column. Gzip files (.gz) are supported and '-' reads stdin.
+
+Join types (--how):
+  inner  Only keys present in both files (default)
+  left   All rows of the left file; missing right values are filled
+  outer  All rows of both files; missing values are filled
+
+Use LEFT:RIGHT with --on when the key column is named differently in each file.
+
+Example:
+  hey join counts.tsv.gz annotation.tsv --on geneID --how left`,
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if joinOn == "" {
+			return fmt.Errorf("no key column given; use --on KEY")
+		}
+		switch joinHow {
+		case "inner", "left", "outer":
+		default:
+			return fmt.Errorf("--how must be one of inner, left or outer, got %q", joinHow)
+		}
+		if args[0] == "-" && args[1] == "-" {
+			return fmt.Errorf("only one of the inputs can be read from stdin")
+		}
+		leftKey, rightKey := splitJoinOn(joinOn)
+		return runJoin(args[0], args[1], leftKey, rightKey, os.Stdout)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(joinCmd)
+	joinCmd.Flags().StringVar(&joinOn, "on", "", "Key column name (or LEFT:RIGHT for different names)")
+	joinCmd.Flags().StringVar(&joinHow, "how", "inner", "Join type: inner, left or outer")
+	joinCmd.Flags().StringVarP(&joinSep, "sep", "s", "\t", "Column separator (default: tab)")
+	joinCmd.Flags().StringVar(&joinFill, "fill", "NA", "Value used for missing cells")
+}
+
+// splitJoinOn returns the left and right key columns of --on, given as KEY
+// or LEFT:RIGHT.
+func splitJoinOn(on string) (string, string) {
+	if l, r, ok := strings.Cut(on, ":"); ok {
+		return l, r
+	}
+	return on, on
+}
+
+// joinTable holds the right-hand side of a join, indexed by key.
+type joinTable struct {
+	headers []string
+	keyIdx  int
+	rows    map[string][][]string
+	order   []string // keys in first-seen order, for outer joins
+}
+
+func splitJoinRow(line string, width int) []string {
+	fields := strings.Split(strings.TrimSuffix(line, "\r"), joinSep)
+	for len(fields) < width {
+		fields = append(fields, joinFill)
+	}
+	return fields[:width]
+}
+
+func headerIndex(headers []string, name string) int {
+	for i, h := range headers {
+		if h == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func loadJoinTable(filename, key string) (*joinTable, error) {
+	input, err := openInput(filename)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", filename, err)
+	}
+	defer input.Close()
+
+	scanner := newLineScanner(input)
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("%s is empty", filename)
+	}
+	t := &joinTable{
+		headers: strings.Split(strings.TrimSuffix(scanner.Text(), "\r"), joinSep),
+		rows:    make(map[string][][]string),
+	}
+	t.keyIdx = headerIndex(t.headers, key)
+	if t.keyIdx < 0 {
+		return nil, fmt.Errorf("key column %q not found in %s", key, filename)
+	}
+	for scanner.Scan() {
+		fields := splitJoinRow(scanner.Text(), len(t.headers))
+		k := fields[t.keyIdx]
+		if _, seen := t.rows[k]; !seen {
+			t.order = append(t.order, k)
+		}
+		t.rows[k] = append(t.rows[k], fields)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", filename, err)
+	}
+	return t, nil
+}
+
+// withoutKey returns the row with the key column removed.
+func (t *joinTable) withoutKey(fields []string) []string {
+	out := make([]string, 0, len(fields)-1)
+	out = append(out, fields[:t.keyIdx]...)
+	return append(out, fields[t.keyIdx+1:]...)
+}
+
+func runJoin(leftFile, rightFile, leftKey, rightKey string, w io.Writer) error {
+	right, err := loadJoinTable(rightFile, rightKey)
+	if err != nil {
+		return err
+	}
+
+	input, err := openInput(leftFile)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", leftFile, err)
+	}
+	defer input.Close()
+
+	scanner := newLineScanner(input)
+	if !scanner.Scan() {
+		return fmt.Errorf("%s is empty", leftFile)
+	}
+	leftHeaders := strings.Split(strings.TrimSuffix(scanner.Text(), "\r"), joinSep)
+	leftKeyIdx := headerIndex(leftHeaders, leftKey)
+	if leftKeyIdx < 0 {
+		return fmt.Errorf("key column %q not found in %s", leftKey, leftFile)
+	}
+
+	out := bufio.NewWriter(w)
+	defer out.Flush()
+	writeRow := func(parts ...[]string) {
+		var row []string
+		for _, p := range parts {
+			row = append(row, p...)
+		}
+		fmt.Fprintln(out, strings.Join(row, joinSep))
+	}
+
+	emptyRight := make([]string, len(right.headers)-1)
+	for i := range emptyRight {
+		emptyRight[i] = joinFill
+	}
+	matched := make(map[string]bool)
+
+	writeRow(leftHeaders, right.withoutKey(right.headers))
+	for scanner.Scan() {
+		fields := splitJoinRow(scanner.Text(), len(leftHeaders))
+		k := fields[leftKeyIdx]
+		if rows, ok := right.rows[k]; ok {
+			matched[k] = true
+			for _, r := range rows {
+				writeRow(fields, right.withoutKey(r))
+			}
+		} else if joinHow != "inner" {
+			writeRow(fields, emptyRight)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading %s: %w", leftFile, err)
+	}
+
+	if joinHow == "outer" {
+		emptyLeft := make([]string, len(leftHeaders))
+		for _, k := range right.order {
+			if matched[k] {
+				continue
+			}
+			for _, r := range right.rows[k] {
+				for i := range emptyLeft {
+					emptyLeft[i] = joinFill
+				}
+				emptyLeft[leftKeyIdx] = k
+				writeRow(emptyLeft, right.withoutKey(r))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitJoinOn(t *testing.T) {
+	l, r := splitJoinOn("geneID")
+	assert.Equal(t, []string{"geneID", "geneID"}, []string{l, r})
+	l, r = splitJoinOn("gene:id")
+	assert.Equal(t, []string{"gene", "id"}, []string{l, r})
+}
+
+func TestRunJoin(t *testing.T) {
+	defer func(how, sep, fill string) { joinHow, joinSep, joinFill = how, sep, fill }(joinHow, joinSep, joinFill)
+	joinSep, joinFill = "\t", "NA"
+
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+	left := write("left.tsv", "gene\tcount\ng1\t10\ng2\t20\ng3\n")
+	right := write("right.tsv", "gene\tsymbol\tbiotype\ng1\tACTB\tcoding\ng1\tACTB2\tcoding\ng4\tMALAT1\tlncRNA\n")
+	renamed := write("renamed.tsv", "id\tsymbol\ng2\tGAPDH\n")
+
+	tests := []struct {
+		name      string
+		how       string
+		right     string
+		leftKey   string
+		rightKey  string
+		fill      string
+		expected  string
+		wantError string
+	}{
+		{
+			name: "inner with duplicate right keys", how: "inner", right: right, leftKey: "gene", rightKey: "gene",
+			expected: "gene\tcount\tsymbol\tbiotype\ng1\t10\tACTB\tcoding\ng1\t10\tACTB2\tcoding\n",
+		},
+		{
+			name: "left fills missing right values", how: "left", right: right, leftKey: "gene", rightKey: "gene",
+			expected: "gene\tcount\tsymbol\tbiotype\ng1\t10\tACTB\tcoding\ng1\t10\tACTB2\tcoding\n" +
+				"g2\t20\tNA\tNA\ng3\tNA\tNA\tNA\n",
+		},
+		{
+			name: "outer adds unmatched right rows", how: "outer", right: right, leftKey: "gene", rightKey: "gene",
+			expected: "gene\tcount\tsymbol\tbiotype\ng1\t10\tACTB\tcoding\ng1\t10\tACTB2\tcoding\n" +
+				"g2\t20\tNA\tNA\ng3\tNA\tNA\tNA\ng4\tNA\tMALAT1\tlncRNA\n",
+		},
+		{
+			name: "short rows padded with fill", how: "left", right: renamed, leftKey: "gene", rightKey: "id", fill: "-",
+			expected: "gene\tcount\tsymbol\ng1\t10\t-\ng2\t20\tGAPDH\ng3\t-\t-\n",
+		},
+		{
+			name: "different key names", how: "inner", right: renamed, leftKey: "gene", rightKey: "id",
+			expected: "gene\tcount\tsymbol\ng2\t20\tGAPDH\n",
+		},
+		{
+			name: "missing left key column", how: "inner", right: right, leftKey: "geneID", rightKey: "gene",
+			wantError: `key column "geneID" not found`,
+		},
+		{
+			name: "missing right key column", how: "inner", right: renamed, leftKey: "gene", rightKey: "gene",
+			wantError: `key column "gene" not found`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			joinHow, joinFill = tt.how, "NA"
+			if tt.fill != "" {
+				joinFill = tt.fill
+			}
+			var out strings.Builder
+			err := runJoin(left, tt.right, tt.leftKey, tt.rightKey, &out)
+			if tt.wantError != "" {
+				assert.ErrorContains(t, err, tt.wantError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, out.String())
+		})
+	}
+}
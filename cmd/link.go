@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aquasecurity/table"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	linkOutDir   string
+	linkKey      string
+	linkRelative bool
+	linkForce    bool
+)
+
+var linkCmd = &cobra.Command{
+	Use:   "link <yaml-file>",
+	Short: "Create standardized FASTQ symlinks from a sample YAML",
+	Long: `Creates a directory of symlinks with standardized names pointing at the raw
+FASTQ files listed in a sample YAML (the format used by 'checkbarcode').
+
+Links are named <sample>_R1.fastq.gz and <sample>_R2.fastq.gz; samples with
+several runs get <sample>_run<N>_R1.fastq.gz. Files that do not exist are
+reported and no link is created for them. Existing links are only replaced
+with --force.
+
+Example:
+  hey link samples.yaml --out data/
+  hey link samples.yaml -o data/ --relative`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadSampleConfig(args[0], linkKey)
+		if err != nil {
+			return err
+		}
+		for _, issue := range cfg.Issues {
+			color.Yellow("Warning: %s:%d: %s", args[0], issue.Line, issue.Message)
+		}
+		if len(cfg.Runs) == 0 {
+			return fmt.Errorf("no runs found in %s", args[0])
+		}
+		if err := os.MkdirAll(linkOutDir, 0o755); err != nil {
+			return fmt.Errorf("creating output directory: %w", err)
+		}
+		results := createSampleLinks(cfg, linkOutDir)
+		printLinkTable(results)
+
+		failed := 0
+		for _, r := range results {
+			if r.Err != nil {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d links could not be created", failed, len(results))
+		}
+		color.Green("Created %d links in %s", len(results), linkOutDir)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(linkCmd)
+	linkCmd.Flags().StringVarP(&linkOutDir, "out", "o", "data", "Directory to create the links in")
+	linkCmd.Flags().StringVarP(&linkKey, "key", "k", "samples", "Top-level key in YAML file containing sample definitions")
+	linkCmd.Flags().BoolVarP(&linkRelative, "relative", "r", false, "Create relative instead of absolute symlinks")
+	linkCmd.Flags().BoolVarP(&linkForce, "force", "f", false, "Replace existing files or links in the output directory")
+}
+
+type linkResult struct {
+	Sample string
+	Link   string
+	Target string
+	Err    error
+}
+
+// fastqLinkName builds the standardized link name of one read file.
+func fastqLinkName(sample string, run, runsInSample int, read string, source string) string {
+	ext := ".fastq"
+	if strings.HasSuffix(strings.ToLower(source), ".gz") {
+		ext = ".fastq.gz"
+	}
+	if runsInSample > 1 {
+		return fmt.Sprintf("%s_run%d_%s%s", sample, run, read, ext)
+	}
+	return fmt.Sprintf("%s_%s%s", sample, read, ext)
+}
+
+func createSampleLinks(cfg *sampleConfig, outDir string) []linkResult {
+	runsPerSample := make(map[string]int)
+	for _, r := range cfg.Runs {
+		runsPerSample[r.Sample]++
+	}
+
+	var results []linkResult
+	seen := make(map[string]int)
+	for _, run := range cfg.Runs {
+		// Number runs per sample name, so a sample listed twice does not reuse names.
+		seen[run.Sample]++
+		for _, read := range []struct{ name, path string }{{"R1", run.R1}, {"R2", run.R2}} {
+			if read.path == "" {
+				continue
+			}
+			name := fastqLinkName(run.Sample, seen[run.Sample], runsPerSample[run.Sample], read.name, read.path)
+			result := linkResult{Sample: run.Sample, Link: filepath.Join(outDir, name), Target: cfg.resolve(read.path)}
+			result.Err = createLink(result.Target, result.Link)
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+func createLink(target, link string) error {
+	if _, err := os.Stat(target); err != nil {
+		return fmt.Errorf("target missing")
+	}
+	if _, err := os.Lstat(link); err == nil {
+		if !linkForce {
+			if existing, err := os.Readlink(link); err == nil && sameLinkTarget(link, existing, target) {
+				return nil
+			}
+			return fmt.Errorf("already exists (use --force)")
+		}
+		if err := os.Remove(link); err != nil {
+			return err
+		}
+	}
+	dest := target
+	if linkRelative {
+		linkDir, err := filepath.Abs(filepath.Dir(link))
+		if err != nil {
+			return err
+		}
+		if rel, err := filepath.Rel(linkDir, target); err == nil {
+			dest = rel
+		}
+	}
+	return os.Symlink(dest, link)
+}
+
+// sameLinkTarget reports whether an existing link already points at target.
+func sameLinkTarget(link, existing, target string) bool {
+	if !filepath.IsAbs(existing) {
+		existing = filepath.Join(filepath.Dir(link), existing)
+	}
+	abs, err := filepath.Abs(existing)
+	return err == nil && abs == target
+}
+
+func printLinkTable(results []linkResult) {
+	t := table.New(os.Stdout)
+	t.SetHeaders("Sample", "Link", "Target", "Status")
+	t.SetHeaderStyle(table.StyleBold)
+	t.SetLineStyle(table.StyleBlue)
+	t.SetDividers(table.UnicodeRoundedDividers)
+	for _, r := range results {
+		status := color.GreenString("OK")
+		if r.Err != nil {
+			status = color.RedString("%v", r.Err)
+		}
+		t.AddRow(r.Sample, filepath.Base(r.Link), r.Target, status)
+	}
+	t.Render()
+}
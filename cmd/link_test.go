@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFastqLinkName(t *testing.T) {
+	assert.Equal(t, "liver_R1.fastq.gz", fastqLinkName("liver", 1, 1, "R1", "x/L_1.fq.gz"))
+	assert.Equal(t, "liver_run2_R2.fastq.gz", fastqLinkName("liver", 2, 3, "R2", "x/L_2.FQ.GZ"))
+	assert.Equal(t, "liver_R1.fastq", fastqLinkName("liver", 1, 1, "R1", "x/L_1.fq"))
+}
+
+func TestCreateSampleLinks(t *testing.T) {
+	defer func(relative, force bool) { linkRelative, linkForce = relative, force }(linkRelative, linkForce)
+	linkRelative, linkForce = false, false
+
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "raw")
+	out := filepath.Join(dir, "data")
+	require.NoError(t, os.MkdirAll(raw, 0o755))
+	require.NoError(t, os.MkdirAll(out, 0o755))
+	for _, name := range []string{"a_1.fq.gz", "a_2.fq.gz", "b_1.fq.gz"} {
+		require.NoError(t, os.WriteFile(filepath.Join(raw, name), nil, 0o644))
+	}
+	cfg := &sampleConfig{
+		Path: filepath.Join(dir, "samples.yaml"),
+		Runs: []sampleRun{
+			{Sample: "s1", R1: "raw/a_1.fq.gz", R2: "raw/a_2.fq.gz"},
+			{Sample: "s2", R1: "raw/b_1.fq.gz", R2: "raw/missing_2.fq.gz"},
+		},
+	}
+
+	results := createSampleLinks(cfg, out)
+	require.Len(t, results, 4)
+	for _, r := range results[:3] {
+		require.NoError(t, r.Err, r.Link)
+		target, err := os.Readlink(r.Link)
+		require.NoError(t, err)
+		assert.Equal(t, r.Target, target)
+	}
+	assert.Equal(t, filepath.Join(out, "s1_R1.fastq.gz"), results[0].Link)
+	assert.Equal(t, filepath.Join(raw, "a_1.fq.gz"), results[0].Target)
+	assert.EqualError(t, results[3].Err, "target missing")
+	_, err := os.Lstat(filepath.Join(out, "s2_R2.fastq.gz"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Linking again keeps links that already point at the target.
+	for _, r := range createSampleLinks(cfg, out)[:3] {
+		assert.NoError(t, r.Err)
+	}
+}
+
+func TestCreateLink(t *testing.T) {
+	defer func(relative, force bool) { linkRelative, linkForce = relative, force }(linkRelative, linkForce)
+	dir := t.TempDir()
+	target := filepath.Join(dir, "a.fq.gz")
+	other := filepath.Join(dir, "b.fq.gz")
+	require.NoError(t, os.WriteFile(target, nil, 0o644))
+	require.NoError(t, os.WriteFile(other, nil, 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "data"), 0o755))
+	link := filepath.Join(dir, "data", "s1_R1.fastq.gz")
+
+	linkRelative, linkForce = true, false
+	require.NoError(t, createLink(target, link))
+	dest, err := os.Readlink(link)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("..", "a.fq.gz"), dest)
+
+	assert.ErrorContains(t, createLink(other, link), "already exists")
+	linkForce = true
+	require.NoError(t, createLink(other, link))
+	dest, err = os.Readlink(link)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("..", "b.fq.gz"), dest)
+
+	assert.EqualError(t, createLink(filepath.Join(dir, "missing.fq.gz"), link), "target missing")
+}
@@ -0,0 +1,371 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	manifestOutput  string
+	manifestFormat  string
+	manifestKey     string
+	manifestWorkers int
+)
+
+var manifestCmd = &cobra.Command{
+	Use:   "manifest <dir>",
+	Short: "Generate a manifest of the FASTQ files in a delivery directory",
+	Long: `Scans a directory recursively for FASTQ files, pairs R1 and R2 files, and
+computes the size, read count and MD5 checksum of every file concurrently.
+
+Files are grouped into samples by their name: the read number (_R1/_R2, _1/_2)
+and the Illumina sample/lane suffix (_S1_L001) are stripped, and every lane
+becomes a separate run of the sample.
+
+The YAML format uses the same layout as the 'checkbarcode' config
+(<key>: <sample>: data: [{R1: ..., R2: ...}]), so the manifest can be checked
+directly. Paths are written relative to the directory of the output file
+(or the current directory when writing to stdout).
+
+Example:
+  hey manifest delivery/ -o manifest.tsv
+  hey manifest delivery/ -f yaml -o samples.yaml && hey checkbarcode samples.yaml`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if manifestFormat != "tsv" && manifestFormat != "yaml" {
+			return fmt.Errorf("--format must be tsv or yaml, got %q", manifestFormat)
+		}
+		if manifestWorkers < 1 {
+			return fmt.Errorf("--jobs must be at least 1")
+		}
+		paths, err := findFastqFiles(args[0])
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no FASTQ files found in %s", args[0])
+		}
+
+		baseDir := "."
+		if manifestOutput != "" {
+			baseDir = filepath.Dir(manifestOutput)
+		}
+		runs := pairFastqFiles(paths)
+		measureManifestFiles(runs, baseDir, manifestWorkers)
+
+		out := os.Stdout
+		if manifestOutput != "" {
+			out, err = os.Create(manifestOutput)
+			if err != nil {
+				return fmt.Errorf("creating %s: %w", manifestOutput, err)
+			}
+			defer out.Close()
+		}
+		if manifestFormat == "yaml" {
+			err = writeManifestYAML(out, runs, manifestKey)
+		} else {
+			err = writeManifestTSV(out, runs)
+		}
+		if err != nil {
+			return fmt.Errorf("writing manifest: %w", err)
+		}
+
+		failed := 0
+		for _, r := range runs {
+			for _, f := range r.files() {
+				if f.Err != nil {
+					failed++
+					color.Red("%s: %v", f.Path, f.Err)
+				}
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d files could not be read", failed)
+		}
+		if manifestOutput != "" {
+			color.Green("Wrote %d runs to %s", len(runs), manifestOutput)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(manifestCmd)
+	manifestCmd.Flags().StringVarP(&manifestOutput, "output", "o", "", "Output file (default: stdout)")
+	manifestCmd.Flags().StringVarP(&manifestFormat, "format", "f", "tsv", "Output format: tsv or yaml")
+	manifestCmd.Flags().StringVarP(&manifestKey, "key", "k", "samples", "Top-level key of the YAML output")
+	manifestCmd.Flags().IntVarP(&manifestWorkers, "jobs", "j", defaultMaxWorkers, "Number of files to read at the same time")
+}
+
+type manifestFile struct {
+	Path    string // Relative to the manifest location
+	AbsPath string
+	Size    int64
+	Reads   int64
+	MD5     string
+	Err     error
+}
+
+type manifestRun struct {
+	Sample string
+	Run    string
+	R1     *manifestFile
+	R2     *manifestFile
+}
+
+func (r *manifestRun) files() []*manifestFile {
+	files := []*manifestFile{r.R1}
+	if r.R2 != nil {
+		files = append(files, r.R2)
+	}
+	return files
+}
+
+var (
+	fastqExtRegex    = regexp.MustCompile(`\.(fastq|fq)(\.gz)?$`)
+	fastqReadRegex   = regexp.MustCompile(`^(.*?)[._]R?([12])(_\d{3})?$`)
+	illuminaSuffixRe = regexp.MustCompile(`_S\d+(_L\d{3})?$`)
+)
+
+func findFastqFiles(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && fastqExtRegex.MatchString(d.Name()) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scanning %s: %w", dir, err)
+	}
+	return paths, nil
+}
+
+// parseFastqName splits a FASTQ file name into the run name shared by its mates,
+// the sample name, and the read number (0 when the name has none).
+func parseFastqName(name string) (run, sample string, read int) {
+	stem := fastqExtRegex.ReplaceAllString(name, "")
+	run = stem
+	if m := fastqReadRegex.FindStringSubmatch(stem); m != nil {
+		run = m[1]
+		read, _ = strconv.Atoi(m[2])
+	}
+	sample = illuminaSuffixRe.ReplaceAllString(run, "")
+	return run, sample, read
+}
+
+// pairFastqFiles groups files into runs, matching R1 with R2 within a directory.
+// A file without an R1 mate is kept as a single-end run of its own.
+func pairFastqFiles(paths []string) []*manifestRun {
+	byKey := make(map[string]*manifestRun)
+	var runs []*manifestRun
+	for _, p := range paths {
+		run, sample, read := parseFastqName(filepath.Base(p))
+		key := filepath.Join(filepath.Dir(p), run)
+		r, ok := byKey[key]
+		if !ok || (read != 2 && r.R1 != nil) || (read == 2 && r.R2 != nil) {
+			r = &manifestRun{Sample: sample, Run: run}
+			byKey[key] = r
+			runs = append(runs, r)
+		}
+		f := &manifestFile{AbsPath: p}
+		if read == 2 {
+			r.R2 = f
+		} else {
+			r.R1 = f
+		}
+	}
+	// An R2 without R1 is reported as the only read of its run.
+	for _, r := range runs {
+		if r.R1 == nil {
+			r.R1, r.R2 = r.R2, nil
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		if runs[i].Sample != runs[j].Sample {
+			return runs[i].Sample < runs[j].Sample
+		}
+		return runs[i].Run < runs[j].Run
+	})
+	return runs
+}
+
+func measureManifestFiles(runs []*manifestRun, baseDir string, workers int) {
+	var files []*manifestFile
+	for _, r := range runs {
+		files = append(files, r.files()...)
+	}
+
+	absBase, _ := filepath.Abs(baseDir)
+	for _, f := range files {
+		f.Path = f.AbsPath
+		if abs, err := filepath.Abs(f.AbsPath); err == nil {
+			f.AbsPath = abs
+			if rel, err := filepath.Rel(absBase, abs); err == nil {
+				f.Path = rel
+			}
+		}
+	}
+
+	bar := progressbar.NewOptions(len(files),
+		progressbar.OptionSetDescription("[cyan]Checksumming files..."),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionShowCount(),
+		progressbar.OptionShowElapsedTimeOnFinish(),
+		progressbar.OptionEnableColorCodes(true),
+		progressbar.OptionSetTheme(progressbar.Theme{Saucer: "[green]=[reset]", SaucerHead: "[green]>[reset]", SaucerPadding: " ", BarStart: "[", BarEnd: "]"}),
+	)
+	jobs := make(chan *manifestFile, len(files))
+	var wg sync.WaitGroup
+	numWorkers := min(workers, len(files))
+	wg.Add(numWorkers)
+	for range numWorkers {
+		go func() {
+			defer wg.Done()
+			for f := range jobs {
+				f.Size, f.Reads, f.MD5, f.Err = fastqFileStats(f.AbsPath)
+				_ = bar.Add(1)
+			}
+		}()
+	}
+	for _, f := range files {
+		jobs <- f
+	}
+	close(jobs)
+	wg.Wait()
+	_ = bar.Finish()
+	fmt.Fprintln(os.Stderr)
+}
+
+// fastqFileStats reads a (gzipped) FASTQ file once, computing the MD5 of the
+// file as stored on disk and the number of records in it.
+func fastqFileStats(path string) (size, reads int64, checksum string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	defer file.Close()
+
+	hash := md5.New()
+	raw := io.TeeReader(file, hash)
+	var content io.Reader = raw
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(raw)
+		if err != nil {
+			return 0, 0, "", fmt.Errorf("not a gzip file: %w", err)
+		}
+		defer gz.Close()
+		content = gz
+	}
+
+	var lines int64
+	buf := make([]byte, 256*1024)
+	for {
+		n, err := content.Read(buf)
+		lines += int64(bytes.Count(buf[:n], []byte{'\n'}))
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, 0, "", err
+		}
+	}
+	// Hash any trailing bytes the gzip reader did not need.
+	size, err = io.Copy(io.Discard, raw)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
+	}
+	return size, lines / 4, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func writeManifestTSV(w io.Writer, runs []*manifestRun) error {
+	rows := [][]string{{"sample", "run", "R1", "R2", "R1_reads", "R2_reads", "R1_size", "R2_size", "R1_md5", "R2_md5"}}
+	for _, r := range runs {
+		r2 := r.R2
+		if r2 == nil {
+			r2 = &manifestFile{}
+		}
+		rows = append(rows, []string{
+			r.Sample, r.Run,
+			r.R1.Path, r2.Path,
+			formatOptionalInt(r.R1.Reads, r.R1.Path), formatOptionalInt(r2.Reads, r2.Path),
+			formatOptionalInt(r.R1.Size, r.R1.Path), formatOptionalInt(r2.Size, r2.Path),
+			r.R1.MD5, r2.MD5,
+		})
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatOptionalInt leaves the column empty for a missing mate file.
+func formatOptionalInt(n int64, path string) string {
+	if path == "" {
+		return ""
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+type manifestYAMLRun struct {
+	R1      string `yaml:"R1"`
+	R2      string `yaml:"R2,omitempty"`
+	R1Reads int64  `yaml:"R1_reads"`
+	R2Reads int64  `yaml:"R2_reads,omitempty"`
+	R1Size  int64  `yaml:"R1_size"`
+	R2Size  int64  `yaml:"R2_size,omitempty"`
+	R1MD5   string `yaml:"R1_md5"`
+	R2MD5   string `yaml:"R2_md5,omitempty"`
+}
+
+type manifestYAMLSample struct {
+	Data []manifestYAMLRun `yaml:"data"`
+}
+
+func writeManifestYAML(w io.Writer, runs []*manifestRun, key string) error {
+	samples := make(map[string]*manifestYAMLSample)
+	for _, r := range runs {
+		s, ok := samples[r.Sample]
+		if !ok {
+			s = &manifestYAMLSample{}
+			samples[r.Sample] = s
+		}
+		entry := manifestYAMLRun{R1: r.R1.Path, R1Reads: r.R1.Reads, R1Size: r.R1.Size, R1MD5: r.R1.MD5}
+		if r.R2 != nil {
+			entry.R2, entry.R2Reads, entry.R2Size, entry.R2MD5 = r.R2.Path, r.R2.Reads, r.R2.Size, r.R2.MD5
+		}
+		s.Data = append(s.Data, entry)
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(map[string]any{key: samples}); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFastqName(t *testing.T) {
+	tests := []struct {
+		name           string
+		expectedRun    string
+		expectedSample string
+		expectedRead   int
+	}{
+		{"liver_S1_L001_R1_001.fastq.gz", "liver_S1_L001", "liver", 1},
+		{"liver_S1_L002_R2_001.fastq.gz", "liver_S1_L002", "liver", 2},
+		{"SRR123_1.fastq.gz", "SRR123", "SRR123", 1},
+		{"SRR123_2.fq", "SRR123", "SRR123", 2},
+		{"ctrl_1_R2.fq.gz", "ctrl_1", "ctrl_1", 2},
+		{"sample.R1.fq.gz", "sample", "sample", 1},
+		{"single.fastq.gz", "single", "single", 0},
+	}
+	for _, tt := range tests {
+		run, sample, read := parseFastqName(tt.name)
+		assert.Equal(t, tt.expectedRun, run, tt.name)
+		assert.Equal(t, tt.expectedSample, sample, tt.name)
+		assert.Equal(t, tt.expectedRead, read, tt.name)
+	}
+}
+
+func TestPairFastqFiles(t *testing.T) {
+	runs := pairFastqFiles([]string{
+		"d/b_S2_L001_R2_001.fastq.gz",
+		"d/a_S1_L001_R1_001.fastq.gz",
+		"d/a_S1_L001_R2_001.fastq.gz",
+		"d/a_S1_L002_R1_001.fastq.gz",
+		"d/single.fq.gz",
+		"e/a_S1_L001_R1_001.fastq.gz",
+	})
+	require.Len(t, runs, 5)
+
+	assert.Equal(t, "a", runs[0].Sample)
+	assert.Equal(t, "d/a_S1_L001_R1_001.fastq.gz", runs[0].R1.AbsPath)
+	assert.Equal(t, "d/a_S1_L001_R2_001.fastq.gz", runs[0].R2.AbsPath)
+
+	// Same name in another directory is a separate run.
+	assert.Equal(t, "a_S1_L001", runs[1].Run)
+	assert.Equal(t, "e/a_S1_L001_R1_001.fastq.gz", runs[1].R1.AbsPath)
+	assert.Nil(t, runs[1].R2)
+
+	assert.Equal(t, "a_S1_L002", runs[2].Run)
+	assert.Nil(t, runs[2].R2)
+
+	// A lone R2 is reported as the run's only file.
+	assert.Equal(t, "b", runs[3].Sample)
+	assert.Equal(t, "d/b_S2_L001_R2_001.fastq.gz", runs[3].R1.AbsPath)
+	assert.Nil(t, runs[3].R2)
+
+	assert.Equal(t, "single", runs[4].Sample)
+}
@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/aquasecurity/table"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var metaTSV bool
+
+const ncbiEutilsAPI = "https://eutils.ncbi.nlm.nih.gov/entrez/eutils"
+
+// geoAccessionRegex matches GEO series and sample accessions, which are not
+// known to ENA and are resolved through NCBI SRA instead.
+var geoAccessionRegex = regexp.MustCompile(`^(GSE|GSM)\d+$`)
+
+var metaCmd = &cobra.Command{
+	Use:   "meta <accession>...",
+	Short: "Fetch run and sample metadata from ENA/GEO",
+	Long: `Prints the sequencing runs below an accession together with their sample,
+library layout, instrument, read count and FASTQ download links.
+
+Study, sample, experiment and run accessions (PRJNA/PRJEB, SRP/ERP, SRS, SRX,
+SRR/ERR/DRR, ...) are looked up in the ENA portal API. GEO accessions (GSE/GSM)
+are resolved through the NCBI SRA run info.
+
+Use --tsv to get a tab-separated table that can be edited into a sample sheet.
+
+Example:
+  hey meta PRJNA123456
+  hey meta GSE12345 --tsv > samples.tsv`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var runs []metaRun
+		for _, accession := range args {
+			var found []metaRun
+			var err error
+			if geoAccessionRegex.MatchString(accession) {
+				found, err = fetchNCBIRunInfo(accession)
+			} else {
+				found, err = fetchENAMeta(accession)
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", accession, err)
+			}
+			if len(found) == 0 {
+				return fmt.Errorf("no runs found for %s", accession)
+			}
+			runs = append(runs, found...)
+		}
+		if metaTSV {
+			printMetaTSV(runs)
+		} else {
+			printMetaTable(runs)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(metaCmd)
+	metaCmd.Flags().BoolVar(&metaTSV, "tsv", false, "Print tab-separated values instead of a table")
+}
+
+type metaRun struct {
+	Run        string
+	Sample     string
+	SampleName string
+	Layout     string
+	Instrument string
+	Reads      string
+	Bases      string
+	Links      []string
+}
+
+var metaHeaders = []string{"run", "sample", "sample_name", "layout", "instrument", "reads", "bases", "fastq"}
+
+func (r metaRun) fields() []string {
+	return []string{r.Run, r.Sample, r.SampleName, r.Layout, r.Instrument, r.Reads, r.Bases, strings.Join(r.Links, ";")}
+}
+
+func fetchENAMeta(accession string) ([]metaRun, error) {
+	rows, err := enaFileReport(accession, []string{
+		"run_accession", "sample_accession", "sample_alias", "sample_title",
+		"library_layout", "instrument_model", "read_count", "base_count", "fastq_ftp",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return metaRunsFromENA(rows), nil
+}
+
+func metaRunsFromENA(rows []map[string]string) []metaRun {
+	runs := make([]metaRun, 0, len(rows))
+	for _, row := range rows {
+		name := row["sample_title"]
+		if name == "" {
+			name = row["sample_alias"]
+		}
+		run := metaRun{
+			Run:        row["run_accession"],
+			Sample:     row["sample_accession"],
+			SampleName: name,
+			Layout:     row["library_layout"],
+			Instrument: row["instrument_model"],
+			Reads:      row["read_count"],
+			Bases:      row["base_count"],
+		}
+		if row["fastq_ftp"] != "" {
+			for _, u := range strings.Split(row["fastq_ftp"], ";") {
+				run.Links = append(run.Links, "https://"+strings.TrimPrefix(u, "ftp://"))
+			}
+		}
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// fetchNCBIRunInfo searches the SRA database for an accession and downloads the
+// run info table of all matching runs.
+func fetchNCBIRunInfo(accession string) ([]metaRun, error) {
+	query := url.Values{}
+	query.Set("db", "sra")
+	query.Set("term", accession)
+	query.Set("usehistory", "y")
+	query.Set("retmode", "json")
+	body, err := httpGetBody(ncbiEutilsAPI + "/esearch.fcgi?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	var search struct {
+		Result struct {
+			Count    string `json:"count"`
+			WebEnv   string `json:"webenv"`
+			QueryKey string `json:"querykey"`
+		} `json:"esearchresult"`
+	}
+	if err := json.Unmarshal(body, &search); err != nil {
+		return nil, fmt.Errorf("parsing NCBI search result: %w", err)
+	}
+	if search.Result.Count == "" || search.Result.Count == "0" {
+		return nil, nil
+	}
+
+	query = url.Values{}
+	query.Set("db", "sra")
+	query.Set("rettype", "runinfo")
+	query.Set("retmode", "text")
+	query.Set("WebEnv", search.Result.WebEnv)
+	query.Set("query_key", search.Result.QueryKey)
+	body, err = httpGetBody(ncbiEutilsAPI + "/efetch.fcgi?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	return metaRunsFromRunInfo(string(body))
+}
+
+func httpGetBody(reqURL string) ([]byte, error) {
+	resp, err := http.Get(reqURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", resp.Request.URL.Host, resp.Status)
+	}
+	return body, nil
+}
+
+// metaRunsFromRunInfo parses the CSV run info table returned by NCBI efetch.
+func metaRunsFromRunInfo(text string) ([]metaRun, error) {
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parsing run info: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	col := make(map[string]int, len(records[0]))
+	for i, h := range records[0] {
+		col[h] = i
+	}
+	get := func(record []string, name string) string {
+		if i, ok := col[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var runs []metaRun
+	for _, record := range records[1:] {
+		// efetch repeats the header line between batches.
+		if get(record, "Run") == "" || get(record, "Run") == "Run" {
+			continue
+		}
+		run := metaRun{
+			Run:        get(record, "Run"),
+			Sample:     get(record, "BioSample"),
+			SampleName: get(record, "SampleName"),
+			Layout:     get(record, "LibraryLayout"),
+			Instrument: get(record, "Model"),
+			Reads:      get(record, "spots"),
+			Bases:      get(record, "bases"),
+		}
+		if link := get(record, "download_path"); link != "" {
+			run.Links = []string{link}
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+func printMetaTSV(runs []metaRun) {
+	fmt.Println(strings.Join(metaHeaders, "\t"))
+	for _, r := range runs {
+		fmt.Println(strings.Join(r.fields(), "\t"))
+	}
+}
+
+func printMetaTable(runs []metaRun) {
+	t := table.New(os.Stdout)
+	t.SetHeaders("Run", "Sample", "Name", "Layout", "Instrument", "Reads", "Bases", "Files")
+	t.SetHeaderStyle(table.StyleBold)
+	t.SetLineStyle(table.StyleBlue)
+	t.SetDividers(table.UnicodeRoundedDividers)
+	for _, r := range runs {
+		links := make([]string, len(r.Links))
+		for i, l := range r.Links {
+			links[i] = color.CyanString(l)
+		}
+		t.AddRow(r.Run, r.Sample, r.SampleName, r.Layout, r.Instrument, r.Reads, r.Bases, strings.Join(links, "\n"))
+	}
+	t.Render()
+	fmt.Printf("%d runs\n", len(runs))
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetaRunsFromENA(t *testing.T) {
+	runs := metaRunsFromENA([]map[string]string{
+		{
+			"run_accession":    "SRR1",
+			"sample_accession": "SAMN1",
+			"sample_alias":     "GSM1",
+			"sample_title":     "liver rep1",
+			"library_layout":   "PAIRED",
+			"instrument_model": "Illumina NovaSeq 6000",
+			"read_count":       "100",
+			"base_count":       "30000",
+			"fastq_ftp":        "ftp.sra.ebi.ac.uk/a_1.fastq.gz;ftp.sra.ebi.ac.uk/a_2.fastq.gz",
+		},
+		{"run_accession": "SRR2", "sample_alias": "GSM2"},
+	})
+	require.Len(t, runs, 2)
+	assert.Equal(t, "liver rep1", runs[0].SampleName)
+	assert.Equal(t, []string{"https://ftp.sra.ebi.ac.uk/a_1.fastq.gz", "https://ftp.sra.ebi.ac.uk/a_2.fastq.gz"}, runs[0].Links)
+	assert.Equal(t, "GSM2", runs[1].SampleName)
+	assert.Empty(t, runs[1].Links)
+}
+
+func TestMetaRunsFromRunInfo(t *testing.T) {
+	text := "Run,spots,bases,download_path,LibraryLayout,Model,BioSample,SampleName\n" +
+		"SRR1,100,30000,https://sra/SRR1,PAIRED,Illumina HiSeq 2500,SAMN1,GSM1\n" +
+		"Run,spots,bases,download_path,LibraryLayout,Model,BioSample,SampleName\n" +
+		"SRR2,50,7500,,SINGLE,NextSeq 500,SAMN2,GSM2\n"
+	runs, err := metaRunsFromRunInfo(text)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, metaRun{
+		Run: "SRR1", Sample: "SAMN1", SampleName: "GSM1", Layout: "PAIRED",
+		Instrument: "Illumina HiSeq 2500", Reads: "100", Bases: "30000", Links: []string{"https://sra/SRR1"},
+	}, runs[0])
+	assert.Equal(t, "SRR2", runs[1].Run)
+	assert.Nil(t, runs[1].Links)
+}
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	notifyChannel string
+	notifyExitOf  string
+)
+
+var notifyCmd = &cobra.Command{
+	Use:   "notify [message]",
+	Short: "Send a notification to your phone, Slack or email",
+	Long: `Sends a notification through a webhook configured in the environment:
+
+  ntfy   HEY_NTFY_URL       topic URL, e.g. https://ntfy.sh/my-secret-topic
+  slack  HEY_SLACK_WEBHOOK  incoming webhook URL
+  email  HEY_NOTIFY_EMAIL   address, sent with the local 'sendmail'
+
+Without --channel the first configured channel in this order is used.
+
+With --exit-of the command is run first (through 'sh -c', output passes through)
+and the notification reports its exit status and runtime. hey then exits with
+the status of the command, even if the notification cannot be sent, so it can
+wrap steps of a job script.
+
+Example:
+  hey notify "alignment finished"
+  hey notify --exit-of 'snakemake -j 32' "RNA-seq pipeline"
+  sbatch --wrap "hey notify -c slack --exit-of './run.sh'"`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		message := ""
+		if len(args) == 1 {
+			message = args[0]
+		}
+		if message == "" && notifyExitOf == "" {
+			return fmt.Errorf("nothing to send: pass a message or use --exit-of")
+		}
+		channel, err := resolveNotifyChannel(notifyChannel, os.Getenv)
+		if err != nil {
+			return err
+		}
+
+		var result *commandResult
+		if notifyExitOf != "" {
+			result = runWrappedCommand(notifyExitOf)
+		}
+		title, body := buildNotification(message, result)
+		if err := sendNotification(channel, title, body, result != nil && result.ExitCode != 0); err != nil {
+			if result == nil {
+				return fmt.Errorf("sending %s notification: %w", channel, err)
+			}
+			// The status of the wrapped command matters more to the job
+			// script than the notification.
+			fmt.Fprintln(os.Stderr, color.RedString("Sending %s notification failed: %v", channel, err))
+		} else {
+			fmt.Fprintln(os.Stderr, color.GreenString("Sent %s notification: %s", channel, title))
+		}
+		if result != nil && result.ExitCode != 0 {
+			os.Exit(result.ExitCode)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(notifyCmd)
+	notifyCmd.Flags().StringVarP(&notifyChannel, "channel", "c", "", "Channel to use: ntfy, slack or email (default: first configured)")
+	notifyCmd.Flags().StringVarP(&notifyExitOf, "exit-of", "e", "", "Run this command and report its exit status and runtime")
+}
+
+var notifyChannelEnv = []struct{ channel, env string }{
+	{"ntfy", "HEY_NTFY_URL"},
+	{"slack", "HEY_SLACK_WEBHOOK"},
+	{"email", "HEY_NOTIFY_EMAIL"},
+}
+
+func resolveNotifyChannel(channel string, getenv func(string) string) (string, error) {
+	for _, c := range notifyChannelEnv {
+		if channel == "" && getenv(c.env) != "" {
+			return c.channel, nil
+		}
+		if channel == c.channel {
+			if getenv(c.env) == "" {
+				return "", fmt.Errorf("channel %s is not configured: set %s", channel, c.env)
+			}
+			return channel, nil
+		}
+	}
+	if channel != "" {
+		return "", fmt.Errorf("unknown channel %q, use ntfy, slack or email", channel)
+	}
+	return "", fmt.Errorf("no notification channel configured: set HEY_NTFY_URL, HEY_SLACK_WEBHOOK or HEY_NOTIFY_EMAIL")
+}
+
+type commandResult struct {
+	Command  string
+	ExitCode int
+	Duration time.Duration
+}
+
+func runWrappedCommand(command string) *commandResult {
+	c := exec.Command("sh", "-c", command)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	start := time.Now()
+	err := c.Run()
+	result := &commandResult{Command: command, Duration: time.Since(start)}
+	if err != nil {
+		result.ExitCode = -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitCode()
+		} else {
+			fmt.Fprintf(os.Stderr, "Could not run %q: %v\n", command, err)
+		}
+	}
+	return result
+}
+
+// buildNotification composes the title and body, mentioning the host so that
+// messages from several cluster nodes can be told apart.
+func buildNotification(message string, result *commandResult) (string, string) {
+	host, _ := os.Hostname()
+	if result == nil {
+		return message, "from " + host
+	}
+	status := "succeeded"
+	if result.ExitCode != 0 {
+		status = fmt.Sprintf("failed (exit %d)", result.ExitCode)
+	}
+	title := message
+	if title == "" {
+		title = result.Command
+	}
+	title += " " + status
+	body := fmt.Sprintf("Command: %s\nRuntime: %s\nHost: %s", result.Command, result.Duration.Round(time.Second), host)
+	return title, body
+}
+
+func sendNotification(channel, title, body string, failed bool) error {
+	switch channel {
+	case "ntfy":
+		req, err := http.NewRequest(http.MethodPost, os.Getenv("HEY_NTFY_URL"), strings.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Title", title)
+		if failed {
+			req.Header.Set("Priority", "high")
+			req.Header.Set("Tags", "x")
+		}
+		return postNotification(req)
+	case "slack":
+		text := "*" + title + "*\n" + body
+		payload, err := json.Marshal(map[string]string{"text": text})
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequest(http.MethodPost, os.Getenv("HEY_SLACK_WEBHOOK"), bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return postNotification(req)
+	case "email":
+		mail := fmt.Sprintf("To: %s\nSubject: %s\n\n%s\n", os.Getenv("HEY_NOTIFY_EMAIL"), title, body)
+		c := exec.Command("sendmail", "-t")
+		c.Stdin = strings.NewReader(mail)
+		if out, err := c.CombinedOutput(); err != nil {
+			return fmt.Errorf("sendmail: %w: %s", err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown channel %q", channel)
+}
+
+func postNotification(req *http.Request) error {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
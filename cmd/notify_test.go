@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveNotifyChannel(t *testing.T) {
+	env := map[string]string{"HEY_SLACK_WEBHOOK": "https://hooks", "HEY_NOTIFY_EMAIL": "me@lab.org"}
+	getenv := func(key string) string { return env[key] }
+
+	channel, err := resolveNotifyChannel("", getenv)
+	require.NoError(t, err)
+	assert.Equal(t, "slack", channel)
+
+	channel, err = resolveNotifyChannel("email", getenv)
+	require.NoError(t, err)
+	assert.Equal(t, "email", channel)
+
+	_, err = resolveNotifyChannel("ntfy", getenv)
+	assert.ErrorContains(t, err, "HEY_NTFY_URL")
+
+	_, err = resolveNotifyChannel("pager", getenv)
+	assert.Error(t, err)
+
+	_, err = resolveNotifyChannel("", func(string) string { return "" })
+	assert.Error(t, err)
+}
+
+func TestBuildNotification(t *testing.T) {
+	title, body := buildNotification("done", nil)
+	assert.Equal(t, "done", title)
+	assert.True(t, strings.HasPrefix(body, "from "))
+
+	title, body = buildNotification("pipeline", &commandResult{Command: "make", ExitCode: 2, Duration: 90 * time.Second})
+	assert.Equal(t, "pipeline failed (exit 2)", title)
+	assert.Contains(t, body, "Runtime: 1m30s")
+
+	title, _ = buildNotification("", &commandResult{Command: "make"})
+	assert.Equal(t, "make succeeded", title)
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
@@ -13,27 +14,67 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"strconv"
+	"path"
 	"path/filepath"
 	"runtime/debug"
 	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/jackpal/gateway"
 	"github.com/skip2/go-qrcode"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 var (
-	inputAddress string
-	inputPort    string
-	openNoQR     bool
+	inputAddress     string
+	inputPort        string
+	openNoQR         bool
+	openPassword     string
+	openAllowManage  bool
+	openLogFile      string
+	openExpire       time.Duration
+	openTimeout      time.Duration
+	openMaxDownloads int
+	openMDNS         bool
+	openMDNSName     string
+	openAllIfaces    bool
 
 	openCmd = &cobra.Command{
-		Use:          "open [path]",
-		Short:        "Open file or directory in a browser with a beautiful, secure server UI",
-		Long:         `Serves a file or directory with a modern web interface protected by a unique access token.`,
+		Use:   "open [path]",
+		Short: "Open file or directory in a browser with a beautiful, secure server UI",
+		Long: `Serves a file or directory with a modern web interface protected by a unique access token.
+Directories can be downloaded as zip archives, streamed as they are made, from the web interface
+or from /zip?path=dir. Files and directories ticked in the list are downloaded together as one
+archive, also from /batch?path=a&path=b.
+Text files (code, configs, logs, TSV and CSV tables, ...) can be viewed in the browser, with
+syntax highlighting, from their view link or /view?path=file; only the first MiB is shown.
+Files and folders are uploaded into the directory browsed, creating the folders as needed.
+Uploads are sent in chunks checked with CRC-32, so that an upload that fails, e.g. when Wi-Fi
+drops, resumes where it stopped, also after dropping the file again.
+With --password, visitors without the token link get a login form instead, and stay logged in
+with a session cookie, for when a messaging app strips the token from the link.
+With --allow-manage, visitors can also rename and delete files and directories and create new
+directories, to tidy the share from a phone, also with POST /rename?path=&name=, /delete?path=
+and /mkdir?dir=&name=.
+Each request is logged with the client IP, path, status, bytes and duration, on stderr or as JSON
+lines in --log-file, and /stats shows what was downloaded and uploaded, and by whom, to check that
+a collaborator fetched the data (/stats?format=json for scripts).
+With --expire 2h the link, and the password login, stop working after two hours. The link
+button of a file creates a one-time link to it, valid for the time asked (not beyond --expire),
+which can be forwarded without sharing the whole directory and stops working once downloaded.
+The server stops on Ctrl+C, after --timeout, or after --max-downloads complete downloads, and
+first finishes the transfers in progress; press Ctrl+C again to stop at once.
+With --mdns the share is advertised on the LAN with mDNS/Bonjour, as an _http._tcp service on
+hey-open.local (see --mdns-name), so devices nearby can open http://hey-open.local:port/ instead
+of scanning the QR code or typing an IP. The token is not advertised.
+With --all-interfaces the server listens on every interface, IPv4 and IPv6, instead of --address
+(an IPv6 address also works there), and prints the link for each address, then asks which one to
+show the QR code of, or shows them all when not run in a terminal.`,
 		SilenceUsage: true,
 		Args: func(cmd *cobra.Command, args []string) error {
 			if len(args) < 1 {
@@ -42,7 +83,10 @@ var (
 			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			urlBase := fmt.Sprintf("%s:%s", inputAddress, inputPort)
+			urlBase := net.JoinHostPort(inputAddress, inputPort)
+			if openAllIfaces {
+				urlBase = net.JoinHostPort("", inputPort)
+			}
 			fileDir, fileBase, err := parsePath(args[0])
 			if err != nil {
 				return err
@@ -70,7 +114,8 @@ const htmlTemplate = `
         .upload-box { border: 3px dashed #007bff; border-radius: 10px; padding: 40px; text-align: center; margin-bottom: 30px; background-color: #fff; transition: background-color 0.2s ease-in-out; cursor: pointer; }
         .upload-box p { margin: 0 0 15px 0; font-size: 1.2em; }
         .upload-box:hover, .upload-box.dragover { background-color: #e9ecef; }
-        #file-input { display: none; }
+        #file-input, #folder-input { display: none; }
+        #folder-button { padding: 8px 16px; border: 1px solid #007bff; border-radius: 5px; background-color: #fff; color: #007bff; font: inherit; cursor: pointer; }
         .file-list { list-style: none; padding: 0; border: 1px solid #dee2e6; border-radius: 5px; background-color: #fff; }
         .file-list li { padding: 12px 15px; border-bottom: 1px solid #dee2e6; display: flex; align-items: center; transition: background-color 0.2s; }
         .file-list li:last-child { border-bottom: none; }
@@ -78,6 +123,15 @@ const htmlTemplate = `
         .file-list a { text-decoration: none; color: #495057; font-size: 1.1em; word-break: break-all; }
         .file-list .icon { margin-right: 15px; width: 24px; text-align: center; font-size: 1.4em; }
         .folder a { font-weight: bold; color: #0056b3; }
+        .heading-actions { float: right; margin-top: 0.4em; font-size: 0.6em; font-weight: normal; }
+        .heading-actions a, .heading-actions button { margin-left: 15px; padding: 0; border: none; background: none; font: inherit; color: #007bff; text-decoration: none; cursor: pointer; }
+        .heading-actions button:disabled { color: #adb5bd; cursor: default; }
+        .file-list input[type=checkbox] { margin: 0 12px 0 0; width: 18px; height: 18px; }
+        .file-list .spacer { display: inline-block; width: 30px; }
+        .file-list a.action { margin-left: auto; padding-left: 15px; font-size: 0.9em; font-weight: normal; color: #007bff; white-space: nowrap; }
+        .file-list button.action { margin-left: auto; padding: 0 0 0 15px; border: none; background: none; font: inherit; font-size: 0.9em; color: #007bff; white-space: nowrap; cursor: pointer; }
+        .file-list .action + .action { margin-left: 0; }
+        .file-list button.danger { color: #dc3545; }
         #upload-progress-container { width: 100%; background-color: #e9ecef; border-radius: 5px; display: none; margin-top: 15px; }
 		#upload-progress { width: 0%; height: 10px; background-color: #007bff; border-radius: 5px; transition: width 0.2s; }
     </style>
@@ -87,28 +141,40 @@ const htmlTemplate = `
         
         <h2>Upload Files</h2>
         <div id="drop-zone" class="upload-box">
-            <p>Drag & drop files here or click to select</p>
+            <p>Drag & drop files or folders here or click to select</p>
             <input type="file" id="file-input" multiple>
+            <button type="button" id="folder-button">Select a folder</button>
+            <input type="file" id="folder-input" webkitdirectory>
         </div>
         <div id="upload-progress-container"><div id="upload-progress"></div></div>
 
-        <h2>Files</h2>
+        <h2>Files
+            <span class="heading-actions">
+                {{if .Manage}}<button type="button" id="new-folder">New folder</button>{{end}}
+                <button type="submit" form="batch-form" id="batch-download" disabled>Download selected</button>
+                <a href="/zip?path={{.Path}}&token={{.Token}}">Download as zip</a>
+                <a href="/stats?token={{.Token}}">Stats</a>
+            </span>
+        </h2>
+        <form id="batch-form" method="post" action="/batch?token={{.Token}}">
         <ul class="file-list">
             {{if .ParentDir}}
-                <li class="folder"><span class="icon">📂</span><a href="{{.ParentDir}}?token={{.Token}}">.. (Parent Directory)</a></li>
+                <li class="folder"><span class="spacer"></span><span class="icon">📂</span><a href="{{.ParentDir}}?token={{.Token}}">.. (Parent Directory)</a></li>
             {{end}}
             {{range .Dirs}}
-                <li class="folder"><span class="icon">📁</span><a href="{{.}}/?token={{$.Token}}">{{.}}</a></li>
+                <li class="folder"><input type="checkbox" name="path" value="{{$.Path}}{{.}}"><span class="icon">📁</span><a href="{{.}}/?token={{$.Token}}">{{.}}</a><a class="action" href="/zip?path={{$.Path}}{{.}}&token={{$.Token}}">zip</a>{{if $.Manage}}<button type="button" class="action" data-manage="rename" data-path="{{$.Path}}{{.}}" data-name="{{.}}">rename</button><button type="button" class="action danger" data-manage="delete" data-path="{{$.Path}}{{.}}" data-name="{{.}}">delete</button>{{end}}</li>
             {{end}}
             {{range .Files}}
-                <li><span class="icon">📄</span><a href="{{.}}?token={{$.Token}}">{{.}}</a></li>
+                <li><input type="checkbox" name="path" value="{{$.Path}}{{.}}"><span class="icon">📄</span><a href="{{.}}?token={{$.Token}}">{{.}}</a>{{if viewable .}}<a class="action" href="/view?path={{$.Path}}{{.}}&token={{$.Token}}">view</a>{{end}}<button type="button" class="action" data-link="{{$.Path}}{{.}}">link</button>{{if $.Manage}}<button type="button" class="action" data-manage="rename" data-path="{{$.Path}}{{.}}" data-name="{{.}}">rename</button><button type="button" class="action danger" data-manage="delete" data-path="{{$.Path}}{{.}}" data-name="{{.}}">delete</button>{{end}}</li>
             {{end}}
         </ul>
+        </form>
     </div>
 
     <script>
         const dropZone = document.getElementById('drop-zone');
         const fileInput = document.getElementById('file-input');
+        const folderInput = document.getElementById('folder-input');
         const progressContainer = document.getElementById('upload-progress-container');
 		const progressBar = document.getElementById('upload-progress');
 		dropZone.addEventListener('click', () => fileInput.click());
@@ -123,37 +189,139 @@ const htmlTemplate = `
             dropZone.addEventListener(eventName, () => dropZone.classList.remove('dragover'), false);
         });
         dropZone.addEventListener('drop', handleDrop, false);
+        const batchForm = document.getElementById('batch-form');
+        const batchButton = document.getElementById('batch-download');
+        batchForm.addEventListener('change', () => {
+            batchButton.disabled = !batchForm.querySelector('input[name=path]:checked');
+        });
+        document.getElementById('folder-button').addEventListener('click', (e) => {
+            e.stopPropagation();
+            folderInput.click();
+        });
         fileInput.addEventListener('change', (e) => handleFiles(e.target.files));
+        folderInput.addEventListener('change', (e) => handleFiles(e.target.files));
         function preventDefaults(e) { e.preventDefault(); e.stopPropagation(); }
-        function handleDrop(e) { handleFiles(e.dataTransfer.files); }
+        async function handleDrop(e) {
+            // Entries must be taken before the event returns.
+            const entries = [...e.dataTransfer.items].map(item => item.webkitGetAsEntry && item.webkitGetAsEntry()).filter(Boolean);
+            if (entries.length === 0) return handleFiles(e.dataTransfer.files);
+            const uploads = [];
+            for (const entry of entries) await readEntry(entry, uploads);
+            uploadAll(uploads);
+        }
+        // readEntry adds the files under a dropped file or folder to uploads,
+        // with their path relative to the drop.
+        async function readEntry(entry, uploads) {
+            if (entry.isFile) {
+                const file = await new Promise((resolve, reject) => entry.file(resolve, reject));
+                uploads.push({file, path: entry.fullPath.replace(/^\//, '')});
+                return;
+            }
+            const reader = entry.createReader();
+            for (;;) {
+                const batch = await new Promise((resolve, reject) => reader.readEntries(resolve, reject));
+                if (batch.length === 0) return;
+                for (const child of batch) await readEntry(child, uploads);
+            }
+        }
         function handleFiles(files) {
-            if (files.length === 0) return;
+            uploadAll([...files].map(file => ({file, path: file.webkitRelativePath || file.name})));
+        }
+        // Files are uploaded into the directory browsed, folders keeping their
+        // layout.
+        async function uploadAll(uploads) {
+            if (uploads.length === 0) return;
             progressContainer.style.display = 'block';
-            progressBar.style.width = '0%';
-            uploadFile(files[0]);
+            try {
+                for (const upload of uploads) {
+                    progressBar.style.width = '0%';
+                    await uploadFile(upload.file, upload.path);
+                }
+            } catch (e) {
+                alert('Upload failed: ' + e.message + '\nDrop the file again to resume.');
+                progressContainer.style.display = 'none';
+                return;
+            }
+            const newUrl = new URL(window.location.href);
+            newUrl.searchParams.set('token', '{{.Token}}');
+            window.location.href = newUrl.href;
+            window.location.reload();
+        }
+        // Files are sent in chunks, each checked with its CRC-32, and resumed
+        // from what the server already has after a failure or a reload.
+        const chunkSize = 8 * 1024 * 1024;
+        const crcTable = new Uint32Array(256).map((_, n) => {
+            for (let k = 0; k < 8; k++) n = n & 1 ? 0xEDB88320 ^ (n >>> 1) : n >>> 1;
+            return n >>> 0;
+        });
+        function crc32(bytes) {
+            let c = 0xFFFFFFFF;
+            for (let i = 0; i < bytes.length; i++) c = crcTable[(c ^ bytes[i]) & 0xFF] ^ (c >>> 8);
+            return ((c ^ 0xFFFFFFFF) >>> 0).toString(16);
         }
-        function uploadFile(file) {
-            let url = '/upload?token={{.Token}}';
-            let formData = new FormData();
-            formData.append('file', file);
-            let xhr = new XMLHttpRequest();
-            xhr.open('POST', url, true);
-            xhr.upload.addEventListener('progress', (e) => {
-                let percent = (e.lengthComputable) ? (e.loaded / e.total) * 100 : 0;
-                progressBar.style.width = percent + '%';
-            });
-            xhr.addEventListener('readystatechange', () => {
-                if (xhr.readyState == 4 && xhr.status == 200) {
-                    const newUrl = new URL(window.location.href);
-                    newUrl.searchParams.set('token', '{{.Token}}');
-                    window.location.href = newUrl.href;
-                    window.location.reload();
-                } else if (xhr.readyState == 4 && xhr.status != 200) {
-                    alert('Upload failed: ' + xhr.statusText);
-                    progressContainer.style.display = 'none';
+        // File management, with --allow-manage, asks before changing anything.
+        async function manage(action, params) {
+            const res = await fetch('/' + action + '?token={{.Token}}', {method: 'POST', body: new URLSearchParams(params)});
+            if (!res.ok) {
+                alert('Could not ' + action + ': ' + await res.text());
+                return;
+            }
+            window.location.reload();
+        }
+        document.querySelectorAll('[data-manage]').forEach(button => button.addEventListener('click', () => {
+            const {manage: action, path, name} = button.dataset;
+            if (action === 'rename') {
+                const newName = prompt('Rename ' + name + ' to:', name);
+                if (newName && newName !== name) manage('rename', {path, name: newName});
+            } else if (confirm('Delete ' + name + (button.closest('.folder') ? ' and everything in it' : '') + '? This cannot be undone.')) {
+                manage('delete', {path});
+            }
+        }));
+        const newFolder = document.getElementById('new-folder');
+        if (newFolder) newFolder.addEventListener('click', () => {
+            const name = prompt('Name of the new folder:');
+            if (name) manage('mkdir', {dir: '{{.Path}}', name});
+        });
+        // One-time links can be forwarded: they work for one download, until
+        // they expire.
+        document.querySelectorAll('[data-link]').forEach(button => button.addEventListener('click', async () => {
+            const ttl = prompt('One-time link valid for (e.g. 30m, 2h, 24h):', '1h');
+            if (!ttl) return;
+            const res = await fetch('/link?token={{.Token}}', {method: 'POST', body: new URLSearchParams({path: button.dataset.link, ttl})});
+            if (!res.ok) {
+                alert('Could not create the link: ' + await res.text());
+                return;
+            }
+            const link = await res.json();
+            prompt('One-time link, valid until ' + new Date(link.expires).toLocaleString() + ':', window.location.origin + link.url);
+        }));
+        const sleep = (ms) => new Promise(resolve => setTimeout(resolve, ms));
+        async function uploadFile(file, path) {
+            const dir = '{{.Path}}';
+            const params = new URLSearchParams({token: '{{.Token}}', dir, name: path, size: file.size, id: file.size + '-' + file.lastModified + '-' + dir + path});
+            let res = await fetch('/upload?' + params);
+            if (!res.ok) throw new Error(await res.text());
+            let offset = (await res.json()).offset;
+            for (let failures = 0; ; ) {
+                progressBar.style.width = (file.size ? offset / file.size * 100 : 100) + '%';
+                const chunk = new Uint8Array(await file.slice(offset, offset + chunkSize).arrayBuffer());
+                params.set('offset', offset);
+                try {
+                    res = await fetch('/upload?' + params, {method: 'PUT', body: chunk, headers: {'X-Chunk-CRC32': crc32(chunk)}});
+                } catch (e) {
+                    if (++failures > 20) throw e;
+                    await sleep(3000); // Network down, wait for it
+                    continue;
+                }
+                if (res.ok || res.status === 409) {
+                    const status = await res.json();
+                    if (status.done) return;
+                    offset = status.offset;
+                    failures = 0;
+                } else if (res.status !== 422 || ++failures > 20) {
+                    throw new Error(await res.text());
                 }
-            });
-            xhr.send(formData);
+            }
         }
     </script>
 </body>
@@ -265,6 +433,15 @@ func init() {
 	}
 	openCmd.Flags().StringVarP(&inputAddress, "address", "a", defaultAddress, "set ip address")
 	openCmd.Flags().BoolVar(&openNoQR, "no-qr", false, "Print only the secure link, without the QR code")
+	openCmd.Flags().BoolVar(&openAllIfaces, "all-interfaces", false, "Listen on all interfaces, IPv4 and IPv6, instead of --address")
+	openCmd.Flags().StringVar(&openPassword, "password", "", "Also let visitors in with this password, on a login form")
+	openCmd.Flags().BoolVar(&openAllowManage, "allow-manage", false, "Let visitors rename and delete files and create directories")
+	openCmd.Flags().DurationVar(&openExpire, "expire", 0, "Stop accepting the link after this long, e.g. 2h (default never)")
+	openCmd.Flags().DurationVar(&openTimeout, "timeout", 0, "Stop the server after this long, e.g. 1h (default never)")
+	openCmd.Flags().IntVar(&openMaxDownloads, "max-downloads", 0, "Stop the server after this many complete downloads (default no limit)")
+	openCmd.Flags().BoolVar(&openMDNS, "mdns", false, "Advertise the share on the LAN with mDNS/Bonjour, as <mdns-name>.local")
+	openCmd.Flags().StringVar(&openMDNSName, "mdns-name", "hey-open", "Name to advertise with --mdns")
+	openCmd.Flags().StringVar(&openLogFile, "log-file", "", "Append the access log to this file, as JSON lines, instead of printing it")
 
 	// --- Port selection logic based on hostname ---
 	hostname, err := os.Hostname()
@@ -307,14 +484,14 @@ func printOpenLink(url string) {
 }
 
 func qrCode(url string) error {
-	q, err := qrcode.New(url, qrcode.Medium)
+	text, err := renderQR(url, qrcode.Medium, false)
 	if err != nil {
-		return fmt.Errorf("could not generate QR code: %w", err)
+		return err
 	}
 
 	fmt.Println()
 	fmt.Println("Scan with your phone:")
-	fmt.Print(q.ToSmallString(false))
+	fmt.Print(text)
 	return nil
 }
 
@@ -388,12 +565,16 @@ func panicMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func tokenAuthMiddleware(next http.Handler, token string) http.Handler {
+func tokenAuthMiddleware(next http.Handler, token, password string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/favicon.ico" {
 			http.NotFound(w, r)
 			return
 		}
+		if password != "" && r.URL.Path == "/login" {
+			handleLogin(w, r, token, password)
+			return
+		}
 		queryToken := r.URL.Query().Get("token")
 		cookieToken := ""
 		if cookie, err := r.Cookie("hey_token"); err == nil {
@@ -402,22 +583,29 @@ func tokenAuthMiddleware(next http.Handler, token string) http.Handler {
 
 		if queryToken == token || cookieToken == token {
 			if queryToken == token {
-				http.SetCookie(w, &http.Cookie{
-					Name:     "hey_token",
-					Value:    token,
-					Path:     "/",
-					MaxAge:   12 * 60 * 60,
-					HttpOnly: true,
-					SameSite: http.SameSiteLaxMode,
-				})
+				setTokenCookie(w, token)
 			}
 			next.ServeHTTP(w, r)
+		} else if password != "" {
+			writeLoginPage(w, http.StatusUnauthorized, r.URL.RequestURI(), "")
 		} else {
 			writeForbiddenPage(w, queryToken != "", cookieToken != "")
 		}
 	})
 }
 
+// setTokenCookie keeps the browser logged in for 12 hours.
+func setTokenCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "hey_token",
+		Value:    token,
+		Path:     "/",
+		MaxAge:   12 * 60 * 60,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
 func writeForbiddenPage(w http.ResponseWriter, hasQueryToken, hasCookieToken bool) {
 	data := forbiddenPageData{
 		Title:   "Secure link needed",
@@ -444,6 +632,10 @@ func writeForbiddenPage(w http.ResponseWriter, hasQueryToken, hasCookieToken boo
 func serveFiles(urlBase, fileDir, fileBase, token string) error {
 	appMux := http.NewServeMux()
 	appMux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodPut {
+			handleChunkedUpload(w, r, fileDir)
+			return
+		}
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -453,12 +645,7 @@ func serveFiles(urlBase, fileDir, fileBase, token string) error {
 			http.Error(w, "Bad request: expected multipart/form-data", http.StatusBadRequest)
 			return
 		}
-		absDir, absErr := filepath.Abs(fileDir)
-		if absErr != nil {
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-		absDir = filepath.Clean(absDir)
+		dir := r.URL.Query().Get("dir")
 		for {
 			part, err := reader.NextPart()
 			if err == io.EOF {
@@ -471,10 +658,8 @@ func serveFiles(urlBase, fileDir, fileBase, token string) error {
 			if part.FileName() == "" {
 				continue
 			}
-			dstPath := filepath.Join(fileDir, part.FileName())
-			absDst, absErr2 := filepath.Abs(dstPath)
-			if absErr2 != nil || !isPathWithin(absDst, absDir) {
-				http.Error(w, "Forbidden: path traversal not allowed", http.StatusForbidden)
+			absDir, dstPath, ok := uploadTarget(w, fileDir, dir, part.FileName())
+			if !ok || !prepareUploadDir(w, absDir, filepath.Dir(dstPath)) {
 				return
 			}
 			dst, err := os.Create(dstPath)
@@ -482,7 +667,7 @@ func serveFiles(urlBase, fileDir, fileBase, token string) error {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
-			_, copyErr := io.Copy(dst, part)
+			n, copyErr := io.Copy(dst, part)
 			closeErr := dst.Close()
 			if copyErr != nil {
 				http.Error(w, copyErr.Error(), http.StatusInternalServerError)
@@ -492,16 +677,56 @@ func serveFiles(urlBase, fileDir, fileBase, token string) error {
 				http.Error(w, closeErr.Error(), http.StatusInternalServerError)
 				return
 			}
-			log.Printf("Uploaded file: %q", part.FileName())
+			log.Printf("Uploaded file: %q", path.Join(dir, part.FileName()))
+			transfers.recordUpload(path.Join("/", dir, part.FileName()), clientIP(r), n)
 		}
 		w.WriteHeader(http.StatusOK)
 	})
 
+	appMux.HandleFunc("/zip", func(w http.ResponseWriter, r *http.Request) {
+		rec := &accessRecorder{ResponseWriter: w}
+		serveZip(rec, r, fileDir)
+		if rec.sent() {
+			transfers.recordDownload(path.Clean("/"+r.URL.Query().Get("path"))+" (zip)", clientIP(r), rec.bytes, rec.status == http.StatusOK)
+		}
+	})
+	appMux.HandleFunc("/batch", func(w http.ResponseWriter, r *http.Request) {
+		rec := &accessRecorder{ResponseWriter: w}
+		serveBatch(rec, r, fileDir)
+		if rec.sent() {
+			transfers.recordDownload(strings.Join(r.Form["path"], ", ")+" (zip)", clientIP(r), rec.bytes, rec.status == http.StatusOK)
+		}
+	})
+	appMux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		serveStats(w, r, token)
+	})
+	var expires time.Time
+	if openExpire > 0 {
+		expires = time.Now().Add(openExpire)
+	}
+	links := newShareLinks()
+	appMux.HandleFunc("/link", func(w http.ResponseWriter, r *http.Request) {
+		handleCreateLink(w, r, fileDir, links, expires)
+	})
+	appMux.HandleFunc("/view", func(w http.ResponseWriter, r *http.Request) {
+		serveView(w, r, fileDir, token)
+	})
+	if openAllowManage {
+		appMux.HandleFunc("/rename", func(w http.ResponseWriter, r *http.Request) {
+			handleRename(w, r, fileDir)
+		})
+		appMux.HandleFunc("/delete", func(w http.ResponseWriter, r *http.Request) {
+			handleDelete(w, r, fileDir)
+		})
+		appMux.HandleFunc("/mkdir", func(w http.ResponseWriter, r *http.Request) {
+			handleMkdir(w, r, fileDir)
+		})
+	}
+
 	appMux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		fullPath := filepath.Join(fileDir, r.URL.Path)
-		absFileDir, absErr := filepath.Abs(fileDir)
-		absFullPath, absPathErr := filepath.Abs(fullPath)
-		if absErr != nil || absPathErr != nil {
+		absFileDir, absFullPath, err := servedPath(fileDir, r.URL.Path)
+		if err != nil {
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
@@ -519,7 +744,11 @@ func serveFiles(urlBase, fileDir, fileBase, token string) error {
 			return
 		}
 		if !info.IsDir() {
-			http.ServeFile(w, r, fullPath)
+			rec := &accessRecorder{ResponseWriter: w}
+			http.ServeFile(rec, r, fullPath)
+			if r.Method == http.MethodGet && rec.sent() {
+				transfers.recordDownload(path.Clean(r.URL.Path), clientIP(r), rec.bytes, rec.status == http.StatusOK && rec.bytes == info.Size())
+			}
 			return
 		}
 		entries, err := os.ReadDir(fullPath)
@@ -529,6 +758,9 @@ func serveFiles(urlBase, fileDir, fileBase, token string) error {
 		}
 		var dirs, files []string
 		for _, entry := range entries {
+			if isUploadPart(entry.Name()) {
+				continue
+			}
 			if entry.IsDir() {
 				dirs = append(dirs, entry.Name())
 			} else {
@@ -542,12 +774,13 @@ func serveFiles(urlBase, fileDir, fileBase, token string) error {
 			parentDir = filepath.Join(r.URL.Path, "..")
 		}
 		data := struct {
-			Dirs, Files      []string
-			ParentDir, Token string
+			Dirs, Files            []string
+			ParentDir, Path, Token string
+			Manage                 bool
 		}{
-			Dirs: dirs, Files: files, ParentDir: parentDir, Token: token,
+			Dirs: dirs, Files: files, ParentDir: parentDir, Path: r.URL.Path, Token: token, Manage: openAllowManage,
 		}
-		tmpl, err := template.New("dir").Parse(htmlTemplate)
+		tmpl, err := template.New("dir").Funcs(template.FuncMap{"viewable": isViewable}).Parse(htmlTemplate)
 		if err != nil {
 			log.Printf("Template parsing error: %v", err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -561,7 +794,24 @@ func serveFiles(urlBase, fileDir, fileBase, token string) error {
 		}
 	})
 
-	finalHandler := panicMiddleware(tokenAuthMiddleware(appMux, token))
+	accessLog, logFile, err := openAccessLog(openLogFile)
+	if err != nil {
+		return fmt.Errorf("cannot open log file: %w", err)
+	}
+	if logFile != nil {
+		defer logFile.Close()
+	}
+	authHandler := tokenAuthMiddleware(appMux, token, openPassword)
+	if !expires.IsZero() {
+		authHandler = expireMiddleware(authHandler, expires)
+	}
+	// One-time links work without the token.
+	rootMux := http.NewServeMux()
+	rootMux.HandleFunc("/once/", func(w http.ResponseWriter, r *http.Request) {
+		serveOneTimeLink(w, r, fileDir, links)
+	})
+	rootMux.Handle("/", authHandler)
+	finalHandler := accessLogMiddleware(panicMiddleware(rootMux), accessLog)
 
 	listener, err := net.Listen("tcp", urlBase)
 	if err != nil {
@@ -571,18 +821,67 @@ func serveFiles(urlBase, fileDir, fileBase, token string) error {
 
 	actualURLBase := urlBase
 	listenPort, _ := strconv.Atoi(inputPort)
-	if _, port, err := net.SplitHostPort(listener.Addr().String()); err == nil && listenPort == 0 {
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	if err == nil && listenPort == 0 {
 		actualURLBase = net.JoinHostPort(inputAddress, port)
 	}
 
-	url := openURL(actualURLBase, fileBase, token)
-	printOpenLink(url)
-	if !openNoQR {
-		if err := qrCode(url); err != nil {
-			return err
+	if openAllIfaces {
+		var urls []string
+		for _, host := range shareHosts(reachableIPs(inputAddress), port) {
+			urls = append(urls, openURL(host, fileBase, token))
+		}
+		printOpenLinks(urls)
+		if !openNoQR {
+			if term.IsTerminal(int(os.Stdin.Fd())) && len(urls) > 1 {
+				urls = urls[pickURL(urls, os.Stdin, os.Stdout):][:1]
+			}
+			for i, url := range urls {
+				if len(urls) > 1 {
+					fmt.Printf("\nLink %d:", i+1)
+				}
+				if err := qrCode(url); err != nil {
+					return err
+				}
+			}
+		}
+		actualURLBase = net.JoinHostPort("::", port)
+	} else {
+		url := openURL(actualURLBase, fileBase, token)
+		printOpenLink(url)
+		if !openNoQR {
+			if err := qrCode(url); err != nil {
+				return err
+			}
 		}
 	}
 	fmt.Printf("\nServing: %s\nAddress: http://%s/\nStop:    Ctrl+C\n", fileDir, actualURLBase)
+	if openPassword != "" {
+		fmt.Println("Login:   without the token, with the password")
+	}
+	if !expires.IsZero() {
+		fmt.Printf("Expires: %s (in %s)\n", expires.Format(time.DateTime), openExpire)
+	}
+	if openTimeout > 0 {
+		fmt.Printf("Timeout: stops at %s (in %s)\n", time.Now().Add(openTimeout).Format(time.DateTime), openTimeout)
+	}
+	if openMaxDownloads > 0 {
+		fmt.Printf("Limit:   stops after %d complete downloads\n", openMaxDownloads)
+	}
+	if openMDNS {
+		mdnsHost := inputAddress
+		if openAllIfaces {
+			mdnsHost = ""
+		}
+		if ip := mdnsAddress(mdnsHost); ip == nil {
+			fmt.Println("mDNS:    not advertised, the server is only reachable from this machine")
+		} else if stop, err := advertiseMDNS(openMDNSName, ip, listener.Addr().(*net.TCPAddr).Port); err != nil {
+			fmt.Printf("mDNS:    not advertised: %v\n", err)
+		} else {
+			defer stop()
+			fmt.Printf("mDNS:    %s\n", openURL(net.JoinHostPort(openMDNSName+".local", strconv.Itoa(listener.Addr().(*net.TCPAddr).Port)), fileBase, token))
+		}
+	}
 
 	server := &http.Server{
 		Handler:      finalHandler,
@@ -590,12 +889,73 @@ func serveFiles(urlBase, fileDir, fileBase, token string) error {
 		WriteTimeout: 10 * time.Minute,
 		IdleTimeout:  120 * time.Second,
 	}
-	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		return fmt.Errorf("file server stopped unexpectedly: %w", err)
+	return runServer(server, listener)
+}
+
+// runServer serves until the server fails, an interrupt, --timeout or
+// --max-downloads, then shuts it down, letting the transfers in progress
+// finish unless interrupted again.
+func runServer(server *http.Server, listener net.Listener) error {
+	interruptChan := make(chan os.Signal, 1)
+	signal.Notify(interruptChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interruptChan)
+	var timeoutChan <-chan time.Time
+	if openTimeout > 0 {
+		timer := time.NewTimer(openTimeout)
+		defer timer.Stop()
+		timeoutChan = timer.C
 	}
+	var limitChan <-chan struct{}
+	if openMaxDownloads > 0 {
+		limitChan = transfers.stopAfter(openMaxDownloads)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.Serve(listener) }()
+	var reason string
+	select {
+	case err := <-serveErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("file server stopped unexpectedly: %w", err)
+		}
+		return nil
+	case <-interruptChan:
+		reason = "Interrupted"
+	case <-timeoutChan:
+		reason = fmt.Sprintf("Timeout of %s reached", openTimeout)
+	case <-limitChan:
+		reason = fmt.Sprintf("%d downloads complete", openMaxDownloads)
+	}
+
+	fmt.Printf("\n%s, stopping after the transfers in progress (Ctrl+C to stop now)...\n", reason)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-interruptChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	if err := server.Shutdown(ctx); err != nil {
+		server.Close()
+		fmt.Println("Stopped, transfers in progress were cut.")
+		return nil
+	}
+	fmt.Println("Stopped.")
 	return nil
 }
 
+// servedPath returns the absolute paths of the served directory and of the
+// path of a request in it, which callers must check with isPathWithin.
+func servedPath(fileDir, urlPath string) (absFileDir, absPath string, err error) {
+	if absFileDir, err = filepath.Abs(fileDir); err != nil {
+		return "", "", err
+	}
+	absPath, err = filepath.Abs(filepath.Join(fileDir, urlPath))
+	return absFileDir, absPath, err
+}
+
 func isPathWithin(path, root string) bool {
 	cleanPath := filepath.Clean(path)
 	cleanRoot := filepath.Clean(root)
@@ -0,0 +1,407 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"hash/crc32"
+	"html/template"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenAuthMiddlewarePassword(t *testing.T) {
+	loginFailureDelay = 0
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	handler := tokenAuthMiddleware(ok, "tok", "secret")
+	serve := func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+	login := func(password, next string) *httptest.ResponseRecorder {
+		form := url.Values{"password": {password}, "next": {next}}
+		r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return serve(r)
+	}
+
+	assert.Equal(t, http.StatusOK, serve(httptest.NewRequest(http.MethodGet, "/a.txt?token=tok", nil)).Code)
+	w := serve(httptest.NewRequest(http.MethodGet, "/dir/", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `name="next" value="/dir/"`)
+
+	assert.Equal(t, http.StatusUnauthorized, login("wrong", "/dir/").Code)
+	w = login("secret", "/dir/")
+	assert.Equal(t, http.StatusSeeOther, w.Code)
+	assert.Equal(t, "/dir/", w.Header().Get("Location"))
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, "tok", cookies[0].Value)
+
+	r := httptest.NewRequest(http.MethodGet, "/dir/", nil)
+	r.AddCookie(cookies[0])
+	assert.Equal(t, http.StatusOK, serve(r).Code)
+
+	assert.Equal(t, "/", login("secret", "//evil.example").Header().Get("Location"))
+}
+
+func TestTokenAuthMiddlewareNoPassword(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	handler := tokenAuthMiddleware(ok, "tok", "")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestServeZip(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "results", "sub"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "results", "a.txt"), []byte("hello"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "results", "sub", "b.fq.gz"), []byte("gz"), 0o644))
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		serveZip(w, httptest.NewRequest(http.MethodGet, "/zip?path="+url.QueryEscape(path), nil), dir)
+		return w
+	}
+
+	w := serve("/results/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename=results.zip`, w.Header().Get("Content-Disposition"))
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	assert.NoError(t, err)
+	methods := make(map[string]uint16)
+	for _, f := range zr.File {
+		methods[f.Name] = f.Method
+	}
+	assert.Equal(t, map[string]uint16{"results/": zip.Store, "results/a.txt": zip.Deflate, "results/sub/": zip.Store, "results/sub/b.fq.gz": zip.Store}, methods)
+
+	assert.Equal(t, http.StatusForbidden, serve("/../").Code)
+	assert.Equal(t, http.StatusBadRequest, serve("/results/a.txt").Code)
+	assert.Equal(t, http.StatusNotFound, serve("/missing").Code)
+}
+
+func TestServeBatch(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "results", "sub"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "results", "a.txt"), []byte("hello"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "results", "sub", "b.txt"), []byte("b"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "c.txt"), []byte("c"), 0o644))
+	serve := func(paths ...string) *httptest.ResponseRecorder {
+		form := url.Values{"path": paths}
+		r := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		serveBatch(w, r, dir)
+		return w
+	}
+
+	w := serve("/results/a.txt", "/results/sub", "/c.txt", "/c.txt")
+	assert.Equal(t, http.StatusOK, w.Code)
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	assert.NoError(t, err)
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.Equal(t, []string{"c.txt", "results/a.txt", "results/sub/", "results/sub/b.txt"}, names)
+
+	assert.Equal(t, http.StatusBadRequest, serve().Code)
+	assert.Equal(t, http.StatusForbidden, serve("/c.txt", "/../x").Code)
+	assert.Equal(t, http.StatusNotFound, serve("/missing.txt").Code)
+}
+
+func TestHandleChunkedUpload(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("@r1\nACGT\n+\nIIII\n")
+	request := func(method, name string, offset int, chunk []byte, crc string) *httptest.ResponseRecorder {
+		q := url.Values{"name": {name}, "size": {strconv.Itoa(len(content))}, "id": {"16-1-reads.fq"}, "offset": {strconv.Itoa(offset)}}
+		r := httptest.NewRequest(method, "/upload?"+q.Encode(), bytes.NewReader(chunk))
+		if crc != "" {
+			r.Header.Set("X-Chunk-CRC32", crc)
+		}
+		w := httptest.NewRecorder()
+		handleChunkedUpload(w, r, dir)
+		return w
+	}
+	crc := func(b []byte) string { return strconv.FormatUint(uint64(crc32.ChecksumIEEE(b)), 16) }
+
+	w := request(http.MethodGet, "reads.fq", 0, nil, "")
+	assert.JSONEq(t, `{"offset": 0}`, w.Body.String())
+	w = request(http.MethodPut, "reads.fq", 0, content[:10], crc(content[:10]))
+	assert.JSONEq(t, `{"offset": 10}`, w.Body.String())
+	assert.Equal(t, http.StatusUnprocessableEntity, request(http.MethodPut, "reads.fq", 10, content[10:], "0").Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPut, "reads.fq", 10, content[10:], "").Code)
+	w = request(http.MethodPut, "reads.fq", 0, content[10:], crc(content[10:]))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{"offset": 10}`, w.Body.String())
+
+	// The part file is hidden until the upload is complete.
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.True(t, isUploadPart(entries[0].Name()))
+
+	w = request(http.MethodPut, "reads.fq", 10, content[10:], crc(content[10:]))
+	assert.JSONEq(t, `{"offset": 16, "done": true}`, w.Body.String())
+	got, err := os.ReadFile(filepath.Join(dir, "reads.fq"))
+	assert.NoError(t, err)
+	assert.Equal(t, content, got)
+	entries, _ = os.ReadDir(dir)
+	assert.Len(t, entries, 1)
+
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodGet, "../reads.fq", 0, nil, "").Code)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, request(http.MethodPut, "big.fq", 0, append(content, 'x'), crc(append(content, 'x'))).Code)
+}
+
+func TestHighlightCode(t *testing.T) {
+	got := highlightCode("x := \"a<b\" // 42\nreturn 7\n", viewLanguages["go"])
+	assert.Equal(t, template.HTML(`<span class="line">x := <span class="str">&#34;a&lt;b&#34;</span> <span class="com">// 42</span></span>`+
+		`<span class="line"><span class="kw">return</span> <span class="num">7</span></span>`), got)
+}
+
+func TestServeView(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "run.log"), []byte("start\nERROR: out of memory\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "counts.tsv"), []byte("gene\tcount\nGAPDH\t12\n"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "reads.bam"), []byte("BAM\x01\x00"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "big.txt"), bytes.Repeat([]byte("0123456789abcde\n"), viewMaxBytes/16+1), 0o644))
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		serveView(w, httptest.NewRequest(http.MethodGet, "/view?path="+url.QueryEscape(path), nil), dir, "tok")
+		return w
+	}
+
+	w := serve("/run.log")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `<span class="line err">ERROR: out of memory</span>`)
+	assert.Contains(t, w.Body.String(), `href="/run.log?token=tok"`)
+	w = serve("/counts.tsv")
+	assert.Contains(t, w.Body.String(), "<th>count</th>")
+	assert.Contains(t, w.Body.String(), "<td>12</td>")
+
+	w = serve("/big.txt")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Showing the first")
+	assert.Equal(t, viewMaxBytes/16, strings.Count(w.Body.String(), "0123456789abcde"))
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, serve("/reads.bam").Code)
+	assert.Equal(t, http.StatusForbidden, serve("/../x.txt").Code)
+	assert.Equal(t, http.StatusBadRequest, serve("/").Code)
+
+	assert.True(t, isViewable("Snakefile"))
+	assert.True(t, isViewable("counts.TSV"))
+	assert.False(t, isViewable("reads.bam"))
+}
+
+func TestUploadIntoDirectory(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "runs"), 0o755))
+	outside := t.TempDir()
+	assert.NoError(t, os.Symlink(outside, filepath.Join(dir, "out")))
+	content := []byte("ACGT\n")
+	upload := func(uploadDir, name string) *httptest.ResponseRecorder {
+		q := url.Values{"dir": {uploadDir}, "name": {name}, "size": {strconv.Itoa(len(content))}, "id": {name}, "offset": {"0"}}
+		r := httptest.NewRequest(http.MethodPut, "/upload?"+q.Encode(), bytes.NewReader(content))
+		r.Header.Set("X-Chunk-CRC32", strconv.FormatUint(uint64(crc32.ChecksumIEEE(content)), 16))
+		w := httptest.NewRecorder()
+		handleChunkedUpload(w, r, dir)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, upload("/runs/", "a.txt").Code)
+	assert.FileExists(t, filepath.Join(dir, "runs", "a.txt"))
+	assert.Equal(t, http.StatusOK, upload("/runs/", "sample1/fastq/r1.fq").Code)
+	assert.FileExists(t, filepath.Join(dir, "runs", "sample1", "fastq", "r1.fq"))
+
+	assert.Equal(t, http.StatusBadRequest, upload("/", "sample1/../../x.txt").Code)
+	assert.Equal(t, http.StatusBadRequest, upload("/", "/etc/x.txt").Code)
+	assert.Equal(t, http.StatusForbidden, upload("/out/", "x.txt").Code)
+	assert.Equal(t, http.StatusForbidden, upload("/", "out/sub/x.txt").Code)
+	entries, err := os.ReadDir(outside)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestManageHandlers(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "runs", "old"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "runs", "old", "a.txt"), []byte("a"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b"), 0o644))
+	post := func(handler func(http.ResponseWriter, *http.Request, string), form url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handler(w, r, dir)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, post(handleRename, url.Values{"path": {"/runs/old"}, "name": {"new"}}).Code)
+	assert.FileExists(t, filepath.Join(dir, "runs", "new", "a.txt"))
+	assert.Equal(t, http.StatusConflict, post(handleRename, url.Values{"path": {"/b.txt"}, "name": {"runs"}}).Code)
+	assert.Equal(t, http.StatusBadRequest, post(handleRename, url.Values{"path": {"/b.txt"}, "name": {"../c.txt"}}).Code)
+	assert.Equal(t, http.StatusForbidden, post(handleRename, url.Values{"path": {"/"}, "name": {"x"}}).Code)
+	assert.Equal(t, http.StatusForbidden, post(handleRename, url.Values{"path": {"/../x"}, "name": {"x"}}).Code)
+
+	assert.Equal(t, http.StatusOK, post(handleMkdir, url.Values{"dir": {"/runs/"}, "name": {"2024/jan"}}).Code)
+	assert.DirExists(t, filepath.Join(dir, "runs", "2024", "jan"))
+	assert.Equal(t, http.StatusConflict, post(handleMkdir, url.Values{"dir": {"/"}, "name": {"b.txt"}}).Code)
+	assert.Equal(t, http.StatusBadRequest, post(handleMkdir, url.Values{"dir": {"/"}, "name": {".."}}).Code)
+
+	assert.Equal(t, http.StatusOK, post(handleDelete, url.Values{"path": {"/runs"}}).Code)
+	assert.NoDirExists(t, filepath.Join(dir, "runs"))
+	assert.Equal(t, http.StatusNotFound, post(handleDelete, url.Values{"path": {"/runs"}}).Code)
+	assert.Equal(t, http.StatusForbidden, post(handleDelete, url.Values{"path": {"/"}}).Code)
+	assert.FileExists(t, filepath.Join(dir, "b.txt"))
+
+	w := httptest.NewRecorder()
+	handleDelete(w, httptest.NewRequest(http.MethodGet, "/delete?path=/b.txt", nil), dir)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
+
+func TestAccessLogMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	handler := accessLogMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	}), logger)
+	r := httptest.NewRequest(http.MethodPut, "/upload?name=a.txt&token=secret", strings.NewReader("abc"))
+	r.RemoteAddr = "192.0.2.7:51234"
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	var entry map[string]any
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "192.0.2.7", entry["client"])
+	assert.Equal(t, "/upload?name=a.txt", entry["path"])
+	assert.EqualValues(t, http.StatusCreated, entry["status"])
+	assert.EqualValues(t, 5, entry["bytes"])
+	assert.EqualValues(t, 3, entry["received"])
+	assert.NotContains(t, buf.String(), "secret")
+}
+
+func TestTransferStats(t *testing.T) {
+	stats := &transferStats{files: make(map[string]*fileTransfers)}
+	limitHit := stats.stopAfter(2)
+	stats.recordDownload("/a.fq", "10.0.0.2", 100, true)
+	stats.recordDownload("/a.fq", "10.0.0.3", 40, false)
+	select {
+	case <-limitHit:
+		t.Fatal("limit hit after one complete download")
+	default:
+	}
+	stats.recordDownload("/a.fq", "10.0.0.2", 100, true)
+	select {
+	case <-limitHit:
+	default:
+		t.Fatal("limit not hit after two complete downloads")
+	}
+	stats.recordUpload("/b.fq", "10.0.0.2", 7)
+
+	files := stats.snapshot()
+	assert.Len(t, files, 2)
+	assert.False(t, files[0].Last.Before(files[1].Last))
+	byPath := make(map[string]fileTransfers)
+	for _, f := range files {
+		f.Last = time.Time{}
+		byPath[f.Path] = f
+	}
+	assert.Equal(t, fileTransfers{Path: "/a.fq", Downloads: 3, Complete: 2, BytesSent: 240, Clients: []string{"10.0.0.2", "10.0.0.3"}}, byPath["/a.fq"])
+	assert.Equal(t, fileTransfers{Path: "/b.fq", Uploads: 1, BytesReceived: 7, Clients: []string{"10.0.0.2"}}, byPath["/b.fq"])
+
+	w := httptest.NewRecorder()
+	serveStats(w, httptest.NewRequest(http.MethodGet, "/stats", nil), "tok")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Transfers")
+}
+
+func TestOneTimeLinks(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "reads.fq"), []byte("@r1\nACGT\n+\nIIII\n"), 0o644))
+	links := newShareLinks()
+	create := func(path, ttl string, expires time.Time) *httptest.ResponseRecorder {
+		form := url.Values{"path": {path}, "ttl": {ttl}}
+		r := httptest.NewRequest(http.MethodPost, "/link", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		handleCreateLink(w, r, dir, links, expires)
+		return w
+	}
+	open := func(method, url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		serveOneTimeLink(w, httptest.NewRequest(method, url, nil), dir, links)
+		return w
+	}
+
+	w := create("/reads.fq", "2h", time.Time{})
+	assert.Equal(t, http.StatusOK, w.Code)
+	var link struct {
+		URL     string    `json:"url"`
+		Expires time.Time `json:"expires"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &link))
+	assert.True(t, strings.HasPrefix(link.URL, "/once/"))
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), link.Expires, time.Minute)
+
+	// Showing the link, as link previews do, does not use it up.
+	assert.Equal(t, http.StatusOK, open(http.MethodGet, link.URL).Code)
+	w = open(http.MethodPost, link.URL)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "@r1\nACGT\n+\nIIII\n", w.Body.String())
+	assert.Equal(t, "attachment; filename=reads.fq", w.Header().Get("Content-Disposition"))
+	assert.Equal(t, http.StatusGone, open(http.MethodPost, link.URL).Code)
+	assert.Equal(t, http.StatusGone, open(http.MethodGet, link.URL).Code)
+
+	// Links do not outlive the main token.
+	w = create("/reads.fq", "", time.Now().Add(-time.Second))
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &link))
+	assert.Equal(t, http.StatusGone, open(http.MethodPost, link.URL).Code)
+
+	assert.Equal(t, http.StatusBadRequest, create("/reads.fq", "soon", time.Time{}).Code)
+	assert.Equal(t, http.StatusBadRequest, create("/", "1h", time.Time{}).Code)
+	assert.Equal(t, http.StatusNotFound, create("/../missing.fq", "1h", time.Time{}).Code)
+}
+
+func TestExpireMiddleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	w := httptest.NewRecorder()
+	expireMiddleware(ok, time.Now().Add(time.Hour)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = httptest.NewRecorder()
+	expireMiddleware(ok, time.Now().Add(-time.Second)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusGone, w.Code)
+}
+
+func TestShareHostsAndPickURL(t *testing.T) {
+	ips := []net.IP{net.ParseIP("192.168.1.20"), net.ParseIP("2001:db8::5")}
+	hosts := shareHosts(ips, "8080")
+	assert.Equal(t, []string{"192.168.1.20:8080", "[2001:db8::5]:8080"}, hosts)
+	assert.Equal(t, "http://[2001:db8::5]:8080/?token=tok", openURL(hosts[1], "", "tok"))
+	assert.Equal(t, []string{"localhost:8080"}, shareHosts(nil, "8080"))
+
+	urls := []string{"http://a/", "http://b/", "http://c/"}
+	var out bytes.Buffer
+	assert.Equal(t, 1, pickURL(urls, strings.NewReader("2\n"), &out))
+	assert.Contains(t, out.String(), "[1-3, Enter for 1]")
+	assert.Equal(t, 0, pickURL(urls, strings.NewReader("\n"), &out))
+	assert.Equal(t, 0, pickURL(urls, strings.NewReader("9\n"), &out))
+	assert.Equal(t, 2, pickURL(urls, strings.NewReader("3"), &out))
+
+	for _, ip := range reachableIPs("") {
+		assert.False(t, ip.IsLoopback())
+		assert.False(t, ip.IsLinkLocalUnicast())
+	}
+}
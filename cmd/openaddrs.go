@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// reachableIPs returns the addresses of the network interfaces, preferred
+// first and then IPv4 before IPv6. Loopback addresses are left out, and so
+// are IPv6 link-local ones, whose URLs need a zone browsers do not take.
+func reachableIPs(preferred string) []net.IP {
+	interfaceAddr, err := net.InterfaceAddrs()
+	if err != nil {
+		fmt.Printf("fail to get net interface addrs: %v", err)
+		return nil
+	}
+	var ips []net.IP
+	for _, address := range interfaceAddr {
+		ipNet, ok := address.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		ips = append(ips, ipNet.IP)
+	}
+	rank := func(ip net.IP) int {
+		switch {
+		case ip.String() == preferred:
+			return 0
+		case ip.To4() != nil:
+			return 1
+		default:
+			return 2
+		}
+	}
+	slices.SortStableFunc(ips, func(a, b net.IP) int { return rank(a) - rank(b) })
+	return ips
+}
+
+// shareHosts returns the host:port of each address the share listening on
+// all interfaces can be opened at, or of localhost without any.
+func shareHosts(ips []net.IP, port string) []string {
+	if len(ips) == 0 {
+		return []string{net.JoinHostPort("localhost", port)}
+	}
+	hosts := make([]string, len(ips))
+	for i, ip := range ips {
+		hosts[i] = net.JoinHostPort(ip.String(), port)
+	}
+	return hosts
+}
+
+func printOpenLinks(urls []string) {
+	fmt.Println()
+	fmt.Println("File server ready on all interfaces")
+	fmt.Println("Open one of these secure links in your browser:")
+	fmt.Println()
+	for i, url := range urls {
+		fmt.Printf("  %d. %s\n", i+1, url)
+	}
+}
+
+// pickURL asks which of urls to show the QR code of, and returns its index,
+// the first one on an empty or invalid answer.
+func pickURL(urls []string, in io.Reader, out io.Writer) int {
+	fmt.Fprintf(out, "\nShow the QR code of which link? [1-%d, Enter for 1]: ", len(urls))
+	line, _ := bufio.NewReader(in).ReadString('\n')
+	choice, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || choice < 1 || choice > len(urls) {
+		return 0
+	}
+	return choice - 1
+}
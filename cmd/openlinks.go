@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"encoding/json"
+	"html/template"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const linkTemplate = `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <meta name="robots" content="noindex">
+    <title>{{.Title}}</title>
+    <style>
+        :root { color-scheme: light dark; }
+        body {
+            margin: 0;
+            min-height: 100vh;
+            display: grid;
+            place-items: center;
+            font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, Helvetica, Arial, sans-serif;
+            background: radial-gradient(circle at top left, #dbeafe, transparent 32rem), linear-gradient(135deg, #f8fafc, #e2e8f0);
+            color: #0f172a;
+        }
+        .card {
+            width: min(92vw, 460px);
+            box-sizing: border-box;
+            padding: 34px;
+            border: 1px solid rgba(15, 23, 42, 0.12);
+            border-radius: 24px;
+            background: rgba(255, 255, 255, 0.82);
+            box-shadow: 0 24px 70px rgba(15, 23, 42, 0.16);
+        }
+        h1 { margin: 0 0 12px; font-size: 26px; word-break: break-all; }
+        p { margin: 0 0 10px; color: #475569; line-height: 1.6; }
+        button { width: 100%; margin-top: 14px; padding: 12px; border: 0; border-radius: 12px; background: #007bff; color: #fff; font-size: 16px; font-weight: 600; }
+        @media (prefers-color-scheme: dark) {
+            body { background: radial-gradient(circle at top left, #1e3a8a, transparent 32rem), linear-gradient(135deg, #020617, #111827); color: #e5e7eb; }
+            .card { background: rgba(15, 23, 42, 0.82); border-color: rgba(226, 232, 240, 0.12); }
+            p { color: #cbd5e1; }
+        }
+    </style>
+</head>
+<body>
+    <main class="card">
+        <h1>{{.Title}}</h1>
+        <p>{{.Message}}</p>
+        {{if .Download}}
+        <form method="post">
+            <button type="submit">Download</button>
+        </form>
+        {{end}}
+    </main>
+</body>
+</html>
+`
+
+type linkPageData struct {
+	Title, Message string
+	Download       bool
+}
+
+func writeLinkPage(w http.ResponseWriter, status int, data linkPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	tmpl, err := template.New("link").Parse(linkTemplate)
+	if err != nil {
+		_, _ = io.WriteString(w, data.Message)
+		return
+	}
+	_ = tmpl.Execute(w, data)
+}
+
+// writeExpiredPage tells that a link is no longer valid.
+func writeExpiredPage(w http.ResponseWriter) {
+	writeLinkPage(w, http.StatusGone, linkPageData{
+		Title:   "Link expired",
+		Message: "This link has expired or was already used. Ask the sender for a new one.",
+	})
+}
+
+// expireMiddleware refuses all requests once the token expires.
+func expireMiddleware(next http.Handler, expires time.Time) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if time.Now().After(expires) {
+			writeExpiredPage(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// shareLink is a single-use link to a file.
+type shareLink struct {
+	Path    string // URL path of the file
+	Expires time.Time
+}
+
+// shareLinks holds the one-time links not used yet.
+type shareLinks struct {
+	mu    sync.Mutex
+	links map[string]shareLink
+}
+
+func newShareLinks() *shareLinks {
+	return &shareLinks{links: make(map[string]shareLink)}
+}
+
+// create returns the id of a new link to urlPath, valid until expires, and
+// forgets the links that expired.
+func (s *shareLinks) create(urlPath string, expires time.Time) (string, error) {
+	id, err := generateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for other, link := range s.links {
+		if now.After(link.Expires) {
+			delete(s.links, other)
+		}
+	}
+	s.links[id] = shareLink{Path: urlPath, Expires: expires}
+	return id, nil
+}
+
+// get returns the link id if it is still valid, and with take removes it.
+func (s *shareLinks) get(id string, take bool) (shareLink, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	link, ok := s.links[id]
+	if !ok || time.Now().After(link.Expires) {
+		delete(s.links, id)
+		return shareLink{}, false
+	}
+	if take {
+		delete(s.links, id)
+	}
+	return link, true
+}
+
+// handleCreateLink creates a one-time link to the file at path, valid for ttl
+// (1h by default) but not beyond expires, if not zero, and returns it as
+// {"url": "/once/id", "expires": time}.
+func handleCreateLink(w http.ResponseWriter, r *http.Request, fileDir string, links *shareLinks, expires time.Time) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	urlPath := path.Clean("/" + r.FormValue("path"))
+	_, _, info, ok := statServedPath(w, r, fileDir, urlPath)
+	if !ok {
+		return
+	}
+	if info.IsDir() {
+		http.Error(w, "Bad request: not a file", http.StatusBadRequest)
+		return
+	}
+	ttl := time.Hour
+	if s := r.FormValue("ttl"); s != "" {
+		var err error
+		if ttl, err = time.ParseDuration(s); err != nil || ttl <= 0 {
+			http.Error(w, "Bad request: invalid ttl, e.g. 30m or 2h", http.StatusBadRequest)
+			return
+		}
+	}
+	linkExpires := time.Now().Add(ttl)
+	if !expires.IsZero() && linkExpires.After(expires) {
+		linkExpires = expires
+	}
+	id, err := links.create(urlPath, linkExpires)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Created one-time link to %q, valid until %s", urlPath, linkExpires.Format(time.DateTime))
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		URL     string    `json:"url"`
+		Expires time.Time `json:"expires"`
+	}{"/once/" + id, linkExpires})
+}
+
+// serveOneTimeLink serves /once/id. GET shows what the link is to and POST
+// downloads it, using up the link; link previews of messaging apps only GET,
+// so they do not use it up.
+func serveOneTimeLink(w http.ResponseWriter, r *http.Request, fileDir string, links *shareLinks) {
+	id := strings.TrimPrefix(r.URL.Path, "/once/")
+	if r.Method != http.MethodPost {
+		link, ok := links.get(id, false)
+		if !ok {
+			writeExpiredPage(w)
+			return
+		}
+		writeLinkPage(w, http.StatusOK, linkPageData{
+			Title:    path.Base(link.Path),
+			Message:  "This link can be used once, until " + link.Expires.Format(time.DateTime) + ".",
+			Download: true,
+		})
+		return
+	}
+
+	link, ok := links.get(id, true)
+	if !ok {
+		writeExpiredPage(w)
+		return
+	}
+	_, absPath, info, ok := statServedPath(w, r, fileDir, link.Path)
+	if !ok {
+		return
+	}
+	f, err := os.Open(absPath)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	rec := &accessRecorder{ResponseWriter: w}
+	rec.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": info.Name()}))
+	http.ServeContent(rec, r, info.Name(), info.ModTime(), f)
+	log.Printf("One-time link to %q used", link.Path)
+	transfers.recordDownload(link.Path, clientIP(r), rec.bytes, rec.status == http.StatusOK && rec.bytes == info.Size())
+}
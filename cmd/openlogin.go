@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"crypto/subtle"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const loginTemplate = `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Log In</title>
+    <style>
+        :root { color-scheme: light dark; }
+        body {
+            margin: 0;
+            min-height: 100vh;
+            display: grid;
+            place-items: center;
+            font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, Helvetica, Arial, sans-serif;
+            background: radial-gradient(circle at top left, #dbeafe, transparent 32rem), linear-gradient(135deg, #f8fafc, #e2e8f0);
+            color: #0f172a;
+        }
+        .card {
+            width: min(92vw, 420px);
+            box-sizing: border-box;
+            padding: 34px;
+            border: 1px solid rgba(15, 23, 42, 0.12);
+            border-radius: 24px;
+            background: rgba(255, 255, 255, 0.82);
+            box-shadow: 0 24px 70px rgba(15, 23, 42, 0.16);
+        }
+        h1 { margin: 0 0 18px; font-size: 28px; }
+        input[type=password] { width: 100%; box-sizing: border-box; padding: 12px 14px; border: 1px solid #cbd5e1; border-radius: 12px; font-size: 16px; }
+        button { width: 100%; margin-top: 14px; padding: 12px; border: 0; border-radius: 12px; background: #007bff; color: #fff; font-size: 16px; font-weight: 600; }
+        .error { margin: 0 0 14px; color: #b91c1c; }
+        @media (prefers-color-scheme: dark) {
+            body { background: radial-gradient(circle at top left, #1e3a8a, transparent 32rem), linear-gradient(135deg, #020617, #111827); color: #e5e7eb; }
+            .card { background: rgba(15, 23, 42, 0.82); border-color: rgba(226, 232, 240, 0.12); }
+            .error { color: #fca5a5; }
+        }
+    </style>
+</head>
+<body>
+    <main class="card">
+        <h1>Password required</h1>
+        {{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+        <form method="post" action="/login">
+            <input type="hidden" name="next" value="{{.Next}}">
+            <input type="password" name="password" placeholder="Password" autocomplete="current-password" autofocus required>
+            <button type="submit">Log in</button>
+        </form>
+    </main>
+</body>
+</html>
+`
+
+type loginPageData struct {
+	Next  string
+	Error string
+}
+
+// loginFailureDelay slows down guessing the password.
+var loginFailureDelay = time.Second
+
+func writeLoginPage(w http.ResponseWriter, status int, next, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	tmpl, err := template.New("login").Parse(loginTemplate)
+	if err != nil {
+		_, _ = io.WriteString(w, "Unauthorized")
+		return
+	}
+	_ = tmpl.Execute(w, loginPageData{Next: next, Error: message})
+}
+
+// handleLogin shows the login form, and on the right password sets the token
+// cookie and redirects to the page that was asked for.
+func handleLogin(w http.ResponseWriter, r *http.Request, token, password string) {
+	if r.Method != http.MethodPost {
+		writeLoginPage(w, http.StatusOK, localRedirect(r.URL.Query().Get("next")), "")
+		return
+	}
+	next := localRedirect(r.PostFormValue("next"))
+	if subtle.ConstantTimeCompare([]byte(r.PostFormValue("password")), []byte(password)) != 1 {
+		time.Sleep(loginFailureDelay)
+		writeLoginPage(w, http.StatusUnauthorized, next, "Wrong password, try again.")
+		return
+	}
+	setTokenCookie(w, token)
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+// localRedirect returns next if it is a path on this server, or else "/", so
+// that the login form cannot send visitors elsewhere.
+func localRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// manageTarget returns the file or directory at the path form value that a
+// management action changes, or writes the error and returns false. The
+// served directory itself cannot be changed.
+func manageTarget(w http.ResponseWriter, r *http.Request, fileDir string) (absPath string, ok bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return "", false
+	}
+	absFileDir, absPath, _, ok := statServedPath(w, r, fileDir, r.FormValue("path"))
+	if !ok {
+		return "", false
+	}
+	if absPath == absFileDir {
+		http.Error(w, "Forbidden: the served directory cannot be changed", http.StatusForbidden)
+		return "", false
+	}
+	return absPath, true
+}
+
+// handleRename renames the file or directory at path to name, in the same
+// directory, refusing to replace another one.
+func handleRename(w http.ResponseWriter, r *http.Request, fileDir string) {
+	absPath, ok := manageTarget(w, r, fileDir)
+	if !ok {
+		return
+	}
+	name := r.FormValue("name")
+	if !isValidUploadName(name) || strings.Contains(name, "/") {
+		http.Error(w, "Bad request: invalid name", http.StatusBadRequest)
+		return
+	}
+	dst := filepath.Join(filepath.Dir(absPath), name)
+	if _, err := os.Lstat(dst); err == nil {
+		http.Error(w, "Conflict: "+name+" already exists", http.StatusConflict)
+		return
+	}
+	if err := os.Rename(absPath, dst); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Renamed: %q to %q", r.FormValue("path"), name)
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleDelete deletes the file or directory, with its content, at path.
+func handleDelete(w http.ResponseWriter, r *http.Request, fileDir string) {
+	absPath, ok := manageTarget(w, r, fileDir)
+	if !ok {
+		return
+	}
+	if err := os.RemoveAll(absPath); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Deleted: %q", r.FormValue("path"))
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleMkdir creates the directory name, which may be a relative path, in
+// dir.
+func handleMkdir(w http.ResponseWriter, r *http.Request, fileDir string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	dir, name := r.FormValue("dir"), r.FormValue("name")
+	absFileDir, dst, ok := uploadTarget(w, fileDir, dir, name)
+	if !ok {
+		return
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		http.Error(w, "Conflict: "+name+" already exists", http.StatusConflict)
+		return
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !prepareUploadDir(w, absFileDir, dst) {
+		return
+	}
+	log.Printf("Created directory: %q", path.Join(dir, name))
+	w.WriteHeader(http.StatusOK)
+}
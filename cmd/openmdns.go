@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// mdnsGroup is where mDNS queries and announcements are sent.
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+var mdnsNameRegex = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+
+const (
+	mdnsHostTTL    = 120
+	mdnsServiceTTL = 4500
+	// mdnsLegacyTTL caps the TTLs of answers to plain DNS queries.
+	mdnsLegacyTTL = 10
+	// mdnsCacheFlush marks unique records, which replace any cached ones.
+	mdnsCacheFlush = 1 << 15
+)
+
+// mdnsService answers mDNS queries for name.local, and for the name._http._tcp
+// service on it, so that the share can be found on the LAN.
+type mdnsService struct {
+	host, instance, service, services dnsmessage.Name
+	ip                                [4]byte
+	port                              uint16
+}
+
+func newMDNSService(name string, ip net.IP, port int) (*mdnsService, error) {
+	if !mdnsNameRegex.MatchString(name) {
+		return nil, fmt.Errorf("invalid mDNS name %q: use letters, digits and hyphens", name)
+	}
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return nil, fmt.Errorf("mDNS needs an IPv4 address, not %s", ip)
+	}
+	s := &mdnsService{
+		host:     dnsmessage.MustNewName(name + ".local."),
+		instance: dnsmessage.MustNewName(name + "._http._tcp.local."),
+		service:  dnsmessage.MustNewName("_http._tcp.local."),
+		services: dnsmessage.MustNewName("_services._dns-sd._udp.local."),
+		port:     uint16(port),
+	}
+	copy(s.ip[:], ip4)
+	return s, nil
+}
+
+// records returns the records of the service, with ttl scaled by ttlScale: 1
+// normally, 0 to say goodbye.
+func (s *mdnsService) records(ttlScale uint32) []dnsmessage.Resource {
+	header := func(name dnsmessage.Name, typ dnsmessage.Type, ttl uint32, unique bool) dnsmessage.ResourceHeader {
+		class := dnsmessage.ClassINET
+		if unique {
+			class |= mdnsCacheFlush
+		}
+		return dnsmessage.ResourceHeader{Name: name, Type: typ, Class: class, TTL: ttl * ttlScale}
+	}
+	return []dnsmessage.Resource{
+		{Header: header(s.host, dnsmessage.TypeA, mdnsHostTTL, true), Body: &dnsmessage.AResource{A: s.ip}},
+		{Header: header(s.service, dnsmessage.TypePTR, mdnsServiceTTL, false), Body: &dnsmessage.PTRResource{PTR: s.instance}},
+		{Header: header(s.instance, dnsmessage.TypeSRV, mdnsHostTTL, true), Body: &dnsmessage.SRVResource{Target: s.host, Port: s.port}},
+		// The token is not advertised: visitors still need the link or the password.
+		{Header: header(s.instance, dnsmessage.TypeTXT, mdnsServiceTTL, true), Body: &dnsmessage.TXTResource{TXT: []string{"path=/"}}},
+		{Header: header(s.services, dnsmessage.TypePTR, mdnsServiceTTL, false), Body: &dnsmessage.PTRResource{PTR: s.service}},
+	}
+}
+
+// response returns the answer to query, or false if it asks for none of the
+// records. Legacy queries, sent from another port than 5353 by plain DNS
+// resolvers, get a unicast answer with the query ID and questions.
+func (s *mdnsService) response(query *dnsmessage.Message, legacy bool) ([]byte, bool) {
+	var answers []dnsmessage.Resource
+	for _, r := range s.records(1) {
+		for _, q := range query.Questions {
+			if (q.Type == r.Header.Type || q.Type == dnsmessage.TypeALL) && strings.EqualFold(q.Name.String(), r.Header.Name.String()) {
+				answers = append(answers, r)
+				break
+			}
+		}
+	}
+	if len(answers) == 0 {
+		return nil, false
+	}
+	msg := dnsmessage.Message{
+		Header:  dnsmessage.Header{Response: true, Authoritative: true},
+		Answers: answers,
+	}
+	if legacy {
+		msg.ID = query.ID
+		msg.Questions = query.Questions
+		for i := range msg.Answers {
+			msg.Answers[i].Header.Class &^= mdnsCacheFlush
+			if msg.Answers[i].Header.TTL > mdnsLegacyTTL {
+				msg.Answers[i].Header.TTL = mdnsLegacyTTL
+			}
+		}
+	}
+	packet, err := msg.Pack()
+	return packet, err == nil
+}
+
+// announcement returns an unsolicited response with all the records, or
+// their goodbye with ttlScale 0.
+func (s *mdnsService) announcement(ttlScale uint32) []byte {
+	msg := dnsmessage.Message{
+		Header:  dnsmessage.Header{Response: true, Authoritative: true},
+		Answers: s.records(ttlScale),
+	}
+	packet, _ := msg.Pack()
+	return packet
+}
+
+// serve answers the queries arriving on conn until it is closed.
+func (s *mdnsService) serve(conn *net.UDPConn) {
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		var query dnsmessage.Message
+		if query.Unpack(buf[:n]) != nil || query.Response {
+			continue
+		}
+		legacy := from.Port != mdnsGroup.Port
+		packet, ok := s.response(&query, legacy)
+		if !ok {
+			continue
+		}
+		to := mdnsGroup
+		if legacy {
+			to = from
+		}
+		_, _ = conn.WriteToUDP(packet, to)
+	}
+}
+
+// advertiseMDNS announces the share at ip and port as name.local until the
+// returned function is called, which says goodbye.
+func advertiseMDNS(name string, ip net.IP, port int) (func(), error) {
+	s, err := newMDNSService(name, ip, port)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+	if err != nil {
+		return nil, fmt.Errorf("cannot join the mDNS group: %w", err)
+	}
+	go s.serve(conn)
+	done := make(chan struct{})
+	go func() {
+		// Announce twice, a second apart, as RFC 6762 asks.
+		for range 2 {
+			_, _ = conn.WriteToUDP(s.announcement(1), mdnsGroup)
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		_, _ = conn.WriteToUDP(s.announcement(0), mdnsGroup)
+		conn.Close()
+	}, nil
+}
+
+// mdnsAddress returns the IPv4 address to advertise for the server address,
+// or nil if it is only reachable from this machine.
+func mdnsAddress(address string) net.IP {
+	ip := net.ParseIP(address)
+	if ip == nil || ip.IsUnspecified() {
+		if ips := getIPs(); len(ips) > 0 {
+			return net.ParseIP(ips[0]).To4()
+		}
+		return nil
+	}
+	if ip.IsLoopback() {
+		return nil
+	}
+	return ip.To4()
+}
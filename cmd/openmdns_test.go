@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func mdnsQuery(name string, typ dnsmessage.Type) *dnsmessage.Message {
+	return &dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 42},
+		Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName(name), Type: typ, Class: dnsmessage.ClassINET}},
+	}
+}
+
+func TestMDNSServiceResponse(t *testing.T) {
+	s, err := newMDNSService("hey-open", net.ParseIP("192.168.1.20"), 8080)
+	require.NoError(t, err)
+
+	packet, ok := s.response(mdnsQuery("Hey-Open.local.", dnsmessage.TypeA), false)
+	require.True(t, ok)
+	var msg dnsmessage.Message
+	require.NoError(t, msg.Unpack(packet))
+	assert.True(t, msg.Response)
+	assert.Empty(t, msg.Questions)
+	require.Len(t, msg.Answers, 1)
+	assert.Equal(t, [4]byte{192, 168, 1, 20}, msg.Answers[0].Body.(*dnsmessage.AResource).A)
+	assert.Equal(t, uint32(mdnsHostTTL), msg.Answers[0].Header.TTL)
+
+	packet, ok = s.response(mdnsQuery("_http._tcp.local.", dnsmessage.TypePTR), true)
+	require.True(t, ok)
+	require.NoError(t, msg.Unpack(packet))
+	assert.Equal(t, uint16(42), msg.ID)
+	assert.Len(t, msg.Questions, 1)
+	require.Len(t, msg.Answers, 1)
+	assert.Equal(t, "hey-open._http._tcp.local.", msg.Answers[0].Body.(*dnsmessage.PTRResource).PTR.String())
+	assert.Equal(t, uint32(mdnsLegacyTTL), msg.Answers[0].Header.TTL)
+
+	packet, ok = s.response(mdnsQuery("hey-open._http._tcp.local.", dnsmessage.TypeALL), false)
+	require.True(t, ok)
+	require.NoError(t, msg.Unpack(packet))
+	require.Len(t, msg.Answers, 2)
+	assert.Equal(t, uint16(8080), msg.Answers[0].Body.(*dnsmessage.SRVResource).Port)
+	assert.Equal(t, []string{"path=/"}, msg.Answers[1].Body.(*dnsmessage.TXTResource).TXT)
+
+	_, ok = s.response(mdnsQuery("printer.local.", dnsmessage.TypeA), false)
+	assert.False(t, ok)
+
+	require.NoError(t, msg.Unpack(s.announcement(0)))
+	assert.Len(t, msg.Answers, 5)
+	for _, r := range msg.Answers {
+		assert.Zero(t, r.Header.TTL)
+	}
+
+	_, err = newMDNSService("my share", net.ParseIP("192.168.1.20"), 8080)
+	assert.Error(t, err)
+	_, err = newMDNSService("hey-open", net.ParseIP("fe80::1"), 8080)
+	assert.Error(t, err)
+	assert.Nil(t, mdnsAddress("127.0.0.1"))
+}
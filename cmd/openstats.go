@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"cmp"
+	"encoding/json"
+	"html/template"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+const statsTemplate = `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Transfers</title>
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, Helvetica, Arial, sans-serif; margin: 0; padding: 20px; background-color: #f8f9fa; color: #343a40; }
+        .container { max-width: 1000px; margin: auto; }
+        h2 { color: #495057; border-bottom: 2px solid #dee2e6; padding-bottom: 10px; }
+        h2 a { float: right; font-size: 0.6em; font-weight: normal; color: #007bff; text-decoration: none; }
+        .summary { color: #6c757d; }
+        table { width: 100%; border-collapse: collapse; background-color: #fff; border: 1px solid #dee2e6; border-radius: 5px; }
+        th, td { padding: 10px 12px; border-bottom: 1px solid #dee2e6; text-align: left; }
+        th { background-color: #e9ecef; }
+        td.num { text-align: right; white-space: nowrap; }
+        td.path { word-break: break-all; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h2>Transfers <a href="/?token={{.Token}}">Back to files</a></h2>
+        <p class="summary">Since {{.Since}}: {{.Downloads}} downloads ({{.Sent}}) and {{.Uploads}} uploads ({{.Received}}).</p>
+        {{if .Files}}
+        <table>
+            <tr><th>Path</th><th>Downloads</th><th>Complete</th><th>Sent</th><th>Uploads</th><th>Received</th><th>Clients</th><th>Last</th></tr>
+            {{range .Files}}
+            <tr><td class="path">{{.Path}}</td><td class="num">{{.Downloads}}</td><td class="num">{{.Complete}}</td><td class="num">{{human .BytesSent}}</td><td class="num">{{.Uploads}}</td><td class="num">{{human .BytesReceived}}</td><td>{{range $i, $c := .Clients}}{{if $i}}, {{end}}{{$c}}{{end}}</td><td class="num">{{.Last.Format "2006-01-02 15:04:05"}}</td></tr>
+            {{end}}
+        </table>
+        {{else}}
+        <p>Nothing transferred yet.</p>
+        {{end}}
+    </div>
+</body>
+</html>
+`
+
+// fileTransfers is what was downloaded and uploaded of a path.
+type fileTransfers struct {
+	Path          string    `json:"path"`
+	Downloads     int       `json:"downloads"`
+	Complete      int       `json:"complete"`
+	BytesSent     int64     `json:"bytes_sent"`
+	Uploads       int       `json:"uploads"`
+	BytesReceived int64     `json:"bytes_received"`
+	Clients       []string  `json:"clients"`
+	Last          time.Time `json:"last"`
+}
+
+// transferStats counts the transfers of each path since the server started.
+type transferStats struct {
+	mu       sync.Mutex
+	started  time.Time
+	files    map[string]*fileTransfers
+	complete int           // Complete downloads of all paths
+	limit    int           // Complete downloads closing limitHit
+	limitHit chan struct{} // Closed after limit complete downloads
+}
+
+var transfers = &transferStats{started: time.Now(), files: make(map[string]*fileTransfers)}
+
+// entry returns the transfers of path, noting client and the time. It must be
+// called with s.mu held.
+func (s *transferStats) entry(path, client string) *fileTransfers {
+	f, ok := s.files[path]
+	if !ok {
+		f = &fileTransfers{Path: path}
+		s.files[path] = f
+	}
+	if !slices.Contains(f.Clients, client) {
+		f.Clients = append(f.Clients, client)
+	}
+	f.Last = time.Now()
+	return f
+}
+
+// recordDownload counts a download of bytes of path, complete if the whole
+// file or archive was sent.
+func (s *transferStats) recordDownload(path, client string, bytes int64, complete bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := s.entry(path, client)
+	f.Downloads++
+	f.BytesSent += bytes
+	if !complete {
+		return
+	}
+	f.Complete++
+	s.complete++
+	if s.limitHit != nil && s.complete == s.limit {
+		close(s.limitHit)
+	}
+}
+
+// stopAfter returns a channel closed once n more downloads are complete.
+func (s *transferStats) stopAfter(n int) <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit = s.complete + n
+	s.limitHit = make(chan struct{})
+	return s.limitHit
+}
+
+func (s *transferStats) recordUpload(path, client string, bytes int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := s.entry(path, client)
+	f.Uploads++
+	f.BytesReceived += bytes
+}
+
+// snapshot returns copies of the transfers, the latest first.
+func (s *transferStats) snapshot() []fileTransfers {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files := make([]fileTransfers, 0, len(s.files))
+	for _, f := range s.files {
+		c := *f
+		c.Clients = slices.Clone(f.Clients)
+		files = append(files, c)
+	}
+	slices.SortFunc(files, func(a, b fileTransfers) int {
+		return cmp.Or(b.Last.Compare(a.Last), cmp.Compare(a.Path, b.Path))
+	})
+	return files
+}
+
+// serveStats shows the transfers of each path, as JSON with ?format=json.
+func serveStats(w http.ResponseWriter, r *http.Request, token string) {
+	files := transfers.snapshot()
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(files)
+		return
+	}
+
+	data := struct {
+		Files              []fileTransfers
+		Token, Since       string
+		Downloads, Uploads int
+		Sent, Received     string
+	}{Files: files, Token: token, Since: transfers.started.Format("2006-01-02 15:04:05")}
+	var sent, received int64
+	for _, f := range files {
+		data.Downloads += f.Downloads
+		data.Uploads += f.Uploads
+		sent += f.BytesSent
+		received += f.BytesReceived
+	}
+	data.Sent, data.Received = humanSize(sent), humanSize(received)
+
+	tmpl, err := template.New("stats").Funcs(template.FuncMap{"human": humanSize}).Parse(statsTemplate)
+	if err != nil {
+		log.Printf("Template parsing error: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.Execute(w, data); err != nil {
+		log.Printf("Template execution error: %v", err)
+	}
+}
+
+// accessRecorder records the status and the size of a response.
+type accessRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (rec *accessRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *accessRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the connection.
+func (rec *accessRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// sent tells whether content was sent, in full or in part.
+func (rec *accessRecorder) sent() bool {
+	return rec.status == http.StatusOK || rec.status == http.StatusPartialContent
+}
+
+// countingReader counts the bytes read from a request body.
+type countingReader struct {
+	io.ReadCloser
+	bytes int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.bytes += int64(n)
+	return n, err
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// loggedTarget returns the path and query of r, without the token.
+func loggedTarget(r *http.Request) string {
+	q := r.URL.Query()
+	q.Del("token")
+	if len(q) == 0 {
+		return r.URL.Path
+	}
+	return r.URL.Path + "?" + q.Encode()
+}
+
+// openAccessLog returns the access logger, writing JSON lines to the file
+// path, which the caller closes, or text to stderr without one.
+func openAccessLog(path string) (*slog.Logger, *os.File, error) {
+	if path == "" {
+		return slog.New(slog.NewTextHandler(os.Stderr, nil)), nil, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	return slog.New(slog.NewJSONHandler(f, nil)), f, nil
+}
+
+// accessLogMiddleware logs each request, with the client, the status, the
+// bytes sent and received and how long it took.
+func accessLogMiddleware(next http.Handler, logger *slog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &accessRecorder{ResponseWriter: w}
+		body := &countingReader{ReadCloser: r.Body}
+		r.Body = body
+		next.ServeHTTP(rec, r)
+		logger.Info("request",
+			"client", clientIP(r),
+			"method", r.Method,
+			"path", loggedTarget(r),
+			"status", cmp.Or(rec.status, http.StatusOK),
+			"bytes", rec.bytes,
+			"received", body.bytes,
+			"duration", time.Since(start),
+		)
+	})
+}
@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxUploadChunk is the largest chunk accepted.
+const maxUploadChunk = 64 << 20
+
+var uploadPartRegex = regexp.MustCompile(`^\..+\.[0-9a-f]{8}\.part$`)
+
+// uploadLocks serializes the requests on each part file.
+var uploadLocks sync.Map
+
+type uploadStatus struct {
+	Offset int64 `json:"offset"`
+	Done   bool  `json:"done,omitempty"`
+}
+
+// errUploadOutside is returned for uploads into a link leading out of the
+// served directory.
+var errUploadOutside = errors.New("forbidden: path leads out of the served directory")
+
+// isValidUploadName tells whether name is a file name, or a relative path
+// with slashes from a folder upload, without any "." or ".." element.
+func isValidUploadName(name string) bool {
+	if name == "" || strings.HasPrefix(name, "/") || strings.Contains(name, "\\") {
+		return false
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// uploadTarget returns the served directory and the destination of an upload
+// of name into dir, the URL path of the directory browsed, or writes the error
+// and returns false.
+func uploadTarget(w http.ResponseWriter, fileDir, dir, name string) (absFileDir, dst string, ok bool) {
+	if !isValidUploadName(name) {
+		http.Error(w, "Bad request: invalid file name", http.StatusBadRequest)
+		return "", "", false
+	}
+	absFileDir, dst, err := servedPath(fileDir, path.Join("/", dir, name))
+	if err != nil || dst == absFileDir || !isPathWithin(dst, absFileDir) {
+		http.Error(w, "Forbidden: path traversal not allowed", http.StatusForbidden)
+		return "", "", false
+	}
+	return absFileDir, dst, true
+}
+
+// makeUploadDirs creates the directories from root down to dir, which is
+// within it, refusing to go through a link leading out of root.
+func makeUploadDirs(root, dir string) error {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return err
+	}
+	current := root
+	for _, elem := range strings.Split(rel, string(filepath.Separator)) {
+		if elem == "." {
+			continue
+		}
+		current = filepath.Join(current, elem)
+		if err := os.Mkdir(current, 0o755); err != nil && !errors.Is(err, fs.ErrExist) {
+			return err
+		}
+		real, err := filepath.EvalSymlinks(current)
+		if err != nil {
+			return err
+		}
+		if !isPathWithin(real, realRoot) {
+			return errUploadOutside
+		}
+	}
+	return nil
+}
+
+// prepareUploadDir creates dir and the directories above it, or writes the
+// error and returns false.
+func prepareUploadDir(w http.ResponseWriter, absFileDir, dir string) bool {
+	err := makeUploadDirs(absFileDir, dir)
+	switch {
+	case errors.Is(err, errUploadOutside):
+		http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+	return err == nil
+}
+
+// uploadPartPath returns the part file of the upload id to dst.
+func uploadPartPath(dst, id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(filepath.Dir(dst), "."+filepath.Base(dst)+"."+hex.EncodeToString(sum[:4])+".part")
+}
+
+// isUploadPart tells whether name is the part file of an upload in progress,
+// which directory listings hide.
+func isUploadPart(name string) bool {
+	return uploadPartRegex.MatchString(name)
+}
+
+func writeUploadStatus(w http.ResponseWriter, status int, s uploadStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(s)
+}
+
+// handleChunkedUpload answers the requests of resumable uploads, which send a
+// file in chunks to /upload?dir=&name=&size=&id=, dir being the directory to
+// upload into, name a file name or a relative path from a folder upload, and
+// id identifying the file on the client so that an upload can be resumed
+// after a failure or a reload:
+//
+//	GET returns {"offset": n}, the bytes already received.
+//	PUT &offset=n with a chunk as body, and its CRC-32 in hex in the
+//	X-Chunk-CRC32 header, appends it and returns the new offset, with
+//	"done": true once the file is complete. A chunk at another offset is
+//	refused with 409 and the current offset, one without the header with
+//	400, and a corrupt one with 422.
+//
+// Chunks are assembled in a hidden part file next to the destination, which
+// is renamed to it once all size bytes are received. Missing directories on
+// the way are created.
+func handleChunkedUpload(w http.ResponseWriter, r *http.Request, fileDir string) {
+	q := r.URL.Query()
+	name := q.Get("name")
+	size, err := strconv.ParseInt(q.Get("size"), 10, 64)
+	if err != nil || size < 0 || q.Get("id") == "" {
+		http.Error(w, "Bad request: expected a file name, size and id", http.StatusBadRequest)
+		return
+	}
+	absDir, dst, ok := uploadTarget(w, fileDir, q.Get("dir"), name)
+	if !ok {
+		return
+	}
+	part := uploadPartPath(dst, q.Get("id"))
+	lock, _ := uploadLocks.LoadOrStore(part, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	var offset int64
+	if info, err := os.Stat(part); err == nil {
+		offset = info.Size()
+	}
+	if r.Method == http.MethodGet {
+		writeUploadStatus(w, http.StatusOK, uploadStatus{Offset: offset})
+		return
+	}
+
+	if q.Get("offset") != strconv.FormatInt(offset, 10) {
+		writeUploadStatus(w, http.StatusConflict, uploadStatus{Offset: offset})
+		return
+	}
+	chunk, err := io.ReadAll(io.LimitReader(r.Body, maxUploadChunk+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(chunk) > maxUploadChunk || offset+int64(len(chunk)) > size {
+		http.Error(w, "Chunk too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	sum := r.Header.Get("X-Chunk-CRC32")
+	if sum == "" {
+		http.Error(w, "Bad request: missing X-Chunk-CRC32 header", http.StatusBadRequest)
+		return
+	}
+	want, err := strconv.ParseUint(sum, 16, 32)
+	if err != nil || crc32.ChecksumIEEE(chunk) != uint32(want) {
+		http.Error(w, "Chunk corrupted in transfer", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if !prepareUploadDir(w, absDir, filepath.Dir(dst)) {
+		return
+	}
+	if err := appendUploadChunk(part, offset, chunk); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	offset += int64(len(chunk))
+	if offset < size {
+		writeUploadStatus(w, http.StatusOK, uploadStatus{Offset: offset})
+		return
+	}
+	if info, err := os.Stat(part); err != nil || info.Size() != size {
+		os.Remove(part)
+		http.Error(w, "Upload incomplete, start again", http.StatusInternalServerError)
+		return
+	}
+	if err := os.Rename(part, dst); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	uploadLocks.Delete(part)
+	log.Printf("Uploaded file: %q", path.Join(q.Get("dir"), name))
+	transfers.recordUpload(path.Join("/", q.Get("dir"), name), clientIP(r), size)
+	writeUploadStatus(w, http.StatusOK, uploadStatus{Offset: offset, Done: true})
+}
+
+// appendUploadChunk writes chunk at offset of the part file, creating it.
+func appendUploadChunk(part string, offset int64, chunk []byte) error {
+	f, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(chunk, offset); err != nil {
+		f.Close()
+		return fmt.Errorf("writing upload: %w", err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,294 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// viewMaxBytes is how much of a file /view shows.
+const viewMaxBytes = 1 << 20
+
+// viewMaxRows is how many rows of a TSV or CSV file /view shows as a table.
+const viewMaxRows = 2000
+
+const viewTemplate = `
+<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Name}}</title>
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, Helvetica, Arial, sans-serif; margin: 0; background-color: #f8f9fa; color: #343a40; }
+        header { position: sticky; top: 0; display: flex; align-items: center; gap: 15px; padding: 12px 20px; background-color: #fff; border-bottom: 1px solid #dee2e6; }
+        header h1 { margin: 0; font-size: 1.1em; word-break: break-all; }
+        header a { color: #007bff; text-decoration: none; white-space: nowrap; }
+        .note { margin: 12px 20px; padding: 10px 14px; border-radius: 5px; background-color: #fff3cd; color: #664d03; }
+        pre { margin: 0; padding: 12px 0; font: 13px/1.5 ui-monospace, SFMono-Regular, Menlo, Monaco, Consolas, monospace; counter-reset: line; overflow-x: auto; }
+        pre .line { display: block; padding-right: 20px; white-space: pre; }
+        pre .line::before { counter-increment: line; content: counter(line); display: inline-block; width: 4em; margin-right: 1em; padding-right: 0.5em; text-align: right; color: #adb5bd; border-right: 1px solid #dee2e6; }
+        .kw { color: #d73a49; font-weight: 600; } .str { color: #032f62; } .num { color: #005cc5; } .com { color: #6a737d; font-style: italic; }
+        .err { background-color: #ffe3e3; } .warn { background-color: #fff3bf; }
+        table { margin: 12px 20px; border-collapse: collapse; font: 13px ui-monospace, SFMono-Regular, Menlo, Monaco, Consolas, monospace; background-color: #fff; }
+        th, td { padding: 4px 10px; border: 1px solid #dee2e6; text-align: left; white-space: nowrap; }
+        th { position: sticky; top: 48px; background-color: #e9ecef; }
+    </style>
+</head>
+<body>
+    <header><h1>{{.Name}}</h1><a href="{{.Download}}">Download</a></header>
+    {{if .Note}}<div class="note">{{.Note}}</div>{{end}}
+    {{if .Rows}}
+    <table>
+        {{range $i, $row := .Rows}}<tr>{{range $row}}{{if eq $i 0}}<th>{{.}}</th>{{else}}<td>{{.}}</td>{{end}}{{end}}</tr>
+        {{end}}
+    </table>
+    {{else}}
+    <pre>{{.Code}}</pre>
+    {{end}}
+</body>
+</html>
+`
+
+type viewPageData struct {
+	Name, Download, Note string
+	Rows                 [][]string
+	Code                 template.HTML
+}
+
+// viewLanguage is how to highlight a kind of source file.
+type viewLanguage struct {
+	comments []string // Line comment starts
+	keywords []string
+}
+
+var viewLanguages = map[string]viewLanguage{
+	"go":        {[]string{"//"}, strings.Fields("break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var nil true false")},
+	"python":    {[]string{"#"}, strings.Fields("and as assert break class continue def del elif else except False finally for from global if import in is lambda None not or pass raise return True try while with yield")},
+	"shell":     {[]string{"#"}, strings.Fields("case do done elif else esac export fi for function if in local return set then until while")},
+	"r":         {[]string{"#"}, strings.Fields("break else FALSE for function if in library next NULL repeat return TRUE while NA")},
+	"js":        {[]string{"//"}, strings.Fields("async await break case catch class const continue default else export false for from function if import let new null return switch this throw true try typeof var while")},
+	"nextflow":  {[]string{"//"}, strings.Fields("channel def else false if include input null output params process script shell true val path tuple workflow emit take main")},
+	"snakemake": {[]string{"#"}, strings.Fields("rule input output params shell run script wildcards threads resources log conda container include configfile def if else for in return True False None")},
+	"config":    {[]string{"#", ";"}, strings.Fields("true false null yes no")},
+	"sql":       {[]string{"--"}, strings.Fields("select from where and or not insert into values update set delete create table join on group by order limit as null")},
+	"text":      {},
+}
+
+// viewExtensions maps the extensions of the files /view shows, and the names
+// of some without one, to their language. log is highlighted by line and tsv
+// and csv are shown as tables.
+var viewExtensions = map[string]string{
+	".go": "go", ".py": "python", ".sh": "shell", ".bash": "shell", ".zsh": "shell", ".r": "r", ".rmd": "r",
+	".js": "js", ".ts": "js", ".json": "js", ".nf": "nextflow", ".smk": "snakemake", "snakefile": "snakemake",
+	".yaml": "config", ".yml": "config", ".toml": "config", ".ini": "config", ".cfg": "config", ".config": "config", ".conf": "config",
+	".sql": "sql", ".log": "log", ".out": "log", ".err": "log", ".tsv": "tsv", ".tab": "tsv", ".csv": "csv",
+	".txt": "text", ".md": "text", ".html": "text", ".xml": "text", ".sam": "text", ".vcf": "text", ".bed": "text",
+	".gtf": "text", ".gff": "text", ".gff3": "text", ".fa": "text", ".fasta": "text", ".fq": "text", ".fastq": "text",
+	"makefile": "shell", "dockerfile": "shell", "readme": "text",
+}
+
+// viewKind returns how /view shows the file name, or "" if it does not.
+func viewKind(name string) string {
+	lower := strings.ToLower(name)
+	if kind, ok := viewExtensions[lower]; ok {
+		return kind
+	}
+	return viewExtensions[filepath.Ext(lower)]
+}
+
+// isViewable tells whether the file list links name to /view.
+func isViewable(name string) bool {
+	return viewKind(name) != ""
+}
+
+// serveView shows the start of the text file at the path query parameter,
+// highlighted by its kind, and refuses binary files.
+func serveView(w http.ResponseWriter, r *http.Request, fileDir, token string) {
+	urlPath := r.URL.Query().Get("path")
+	_, absPath, info, ok := statServedPath(w, r, fileDir, urlPath)
+	if !ok {
+		return
+	}
+	if info.IsDir() {
+		http.Error(w, "Bad request: not a file", http.StatusBadRequest)
+		return
+	}
+	f, err := os.Open(absPath)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, viewMaxBytes))
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	truncated := info.Size() > int64(len(data))
+	if truncated {
+		// Cut at the last full line, which also keeps UTF-8 whole.
+		if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+			data = data[:i+1]
+		}
+	}
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		http.Error(w, "Unsupported media type: not a text file", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	name := filepath.Base(absPath)
+	page := viewPageData{
+		Name:     name,
+		Download: (&url.URL{Path: urlPath, RawQuery: "token=" + url.QueryEscape(token)}).String(),
+	}
+	if truncated {
+		page.Note = fmt.Sprintf("Showing the first %s of %s. Download the file to see all of it.", humanSize(int64(len(data))), humanSize(info.Size()))
+	}
+	switch kind := viewKind(name); kind {
+	case "tsv", "csv":
+		page.Rows = readViewTable(data, kind == "tsv")
+		if len(page.Rows) > viewMaxRows {
+			page.Rows = page.Rows[:viewMaxRows]
+			page.Note = fmt.Sprintf("Showing the first %d rows.", viewMaxRows-1)
+		}
+	case "log":
+		page.Code = highlightLog(string(data))
+	default:
+		page.Code = highlightCode(string(data), viewLanguages[kind])
+	}
+
+	tmpl, err := template.New("view").Parse(viewTemplate)
+	if err != nil {
+		log.Printf("Template parsing error: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.Execute(w, page); err != nil {
+		log.Printf("Template execution error: %v", err)
+	}
+}
+
+// readViewTable parses a TSV or CSV file, keeping rows of any length.
+func readViewTable(data []byte, tsv bool) [][]string {
+	reader := csv.NewReader(bytes.NewReader(data))
+	if tsv {
+		reader.Comma = '\t'
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	var rows [][]string
+	for len(rows) <= viewMaxRows {
+		row, err := reader.Read()
+		if err != nil {
+			break
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+var (
+	logErrorRegex   = regexp.MustCompile(`(?i)\b(error|fatal|exception|traceback|failed|killed)\b`)
+	logWarningRegex = regexp.MustCompile(`(?i)\bwarn(ing)?\b`)
+)
+
+// highlightLog marks the lines of a log with errors or warnings.
+func highlightLog(text string) template.HTML {
+	var b strings.Builder
+	for _, line := range splitViewLines(text) {
+		class := "line"
+		switch {
+		case logErrorRegex.MatchString(line):
+			class += " err"
+		case logWarningRegex.MatchString(line):
+			class += " warn"
+		}
+		b.WriteString(`<span class="` + class + `">` + template.HTMLEscapeString(line) + "</span>")
+	}
+	return template.HTML(b.String())
+}
+
+// highlightCode marks the comments, strings, numbers and keywords of text.
+// Strings and comments end with their line.
+func highlightCode(text string, lang viewLanguage) template.HTML {
+	var b strings.Builder
+	for _, line := range splitViewLines(text) {
+		b.WriteString(`<span class="line">`)
+		for i := 0; i < len(line); {
+			c := line[i]
+			if isLineCommentAt(line, i, lang.comments) {
+				writeViewToken(&b, "com", line[i:])
+				break
+			}
+			switch {
+			case c == '"' || c == '\'' || c == '`':
+				end := i + 1
+				for end < len(line) && line[end] != c {
+					if line[end] == '\\' {
+						end++
+					}
+					end++
+				}
+				end = min(end+1, len(line))
+				writeViewToken(&b, "str", line[i:end])
+				i = end
+			case isViewWordByte(c):
+				end := i
+				for end < len(line) && isViewWordByte(line[end]) {
+					end++
+				}
+				word := line[i:end]
+				switch {
+				case c >= '0' && c <= '9':
+					writeViewToken(&b, "num", word)
+				case slices.Contains(lang.keywords, word):
+					writeViewToken(&b, "kw", word)
+				default:
+					b.WriteString(template.HTMLEscapeString(word))
+				}
+				i = end
+			default:
+				b.WriteString(template.HTMLEscapeString(line[i : i+1]))
+				i++
+			}
+		}
+		b.WriteString("</span>")
+	}
+	return template.HTML(b.String())
+}
+
+func isLineCommentAt(line string, i int, comments []string) bool {
+	for _, comment := range comments {
+		if strings.HasPrefix(line[i:], comment) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeViewToken(b *strings.Builder, class, token string) {
+	b.WriteString(`<span class="` + class + `">` + template.HTMLEscapeString(token) + "</span>")
+}
+
+func isViewWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// splitViewLines splits text into lines, without the line ends.
+func splitViewLines(text string) []string {
+	text = strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	return strings.Split(text, "\n")
+}
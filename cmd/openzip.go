@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// storedExtensions are those of files already compressed, which are stored in
+// zip archives as they are rather than compressed again.
+var storedExtensions = []string{".gz", ".bgz", ".bz2", ".xz", ".zst", ".zip", ".bam", ".cram", ".png", ".jpg", ".jpeg", ".pdf"}
+
+// serveZip streams the directory at the path query parameter as a zip
+// archive named after it.
+func serveZip(w http.ResponseWriter, r *http.Request, fileDir string) {
+	_, dir, info, ok := statServedPath(w, r, fileDir, r.URL.Query().Get("path"))
+	if !ok {
+		return
+	}
+	if !info.IsDir() {
+		http.Error(w, "Bad request: not a directory", http.StatusBadRequest)
+		return
+	}
+
+	name := filepath.Base(dir)
+	zw := startZip(w, name+".zip")
+	if err := addDirToZip(zw, dir, name); err != nil {
+		log.Printf("Zip of %q failed: %v", dir, err)
+		return
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("Zip of %q failed: %v", dir, err)
+		return
+	}
+	log.Printf("Downloaded directory as zip: %q", name)
+}
+
+// serveBatch streams the files and directories of the path form values as one
+// zip archive, named by their paths in the served directory.
+func serveBatch(w http.ResponseWriter, r *http.Request, fileDir string) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	paths := slices.Compact(slices.Sorted(slices.Values(r.Form["path"])))
+	if len(paths) == 0 {
+		http.Error(w, "Bad request: no files selected", http.StatusBadRequest)
+		return
+	}
+	type entry struct {
+		path, name string
+		dir        bool
+	}
+	var entries []entry
+	for _, p := range paths {
+		absFileDir, absPath, info, ok := statServedPath(w, r, fileDir, p)
+		if !ok {
+			return
+		}
+		name, err := filepath.Rel(absFileDir, absPath)
+		if err != nil || name == "." {
+			name = filepath.Base(absFileDir)
+		}
+		entries = append(entries, entry{path: absPath, name: filepath.ToSlash(name), dir: info.IsDir()})
+	}
+
+	zw := startZip(w, filepath.Base(fileDir)+"-selected.zip")
+	for _, e := range entries {
+		var err error
+		if e.dir {
+			err = addDirToZip(zw, e.path, e.name)
+		} else {
+			err = addFileToZip(zw, e.path, e.name)
+		}
+		if err != nil {
+			log.Printf("Batch download failed at %q: %v", e.name, err)
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("Batch download failed: %v", err)
+		return
+	}
+	log.Printf("Downloaded %d selected files as zip", len(entries))
+}
+
+// statServedPath resolves a path of the served directory, writing an error
+// and returning false if it is outside of it or cannot be found.
+func statServedPath(w http.ResponseWriter, r *http.Request, fileDir, urlPath string) (absFileDir, absPath string, info os.FileInfo, ok bool) {
+	absFileDir, absPath, err := servedPath(fileDir, urlPath)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return "", "", nil, false
+	}
+	if !isPathWithin(absPath, absFileDir) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return "", "", nil, false
+	}
+	info, err = os.Stat(absPath)
+	if os.IsNotExist(err) {
+		http.NotFound(w, r)
+		return "", "", nil, false
+	}
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return "", "", nil, false
+	}
+	return absFileDir, absPath, info, true
+}
+
+// startZip writes the headers of a zip attachment named filename and returns
+// a writer of its content.
+func startZip(w http.ResponseWriter, filename string) *zip.Writer {
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	// Large archives take longer than the server write timeout.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	return zip.NewWriter(w)
+}
+
+// addDirToZip writes the directories and regular files under dir to zw,
+// named under prefix. Symbolic links are skipped, as they may point out of
+// the served directory.
+func addDirToZip(zw *zip.Writer, dir, prefix string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(filepath.Join(prefix, rel))
+		if d.IsDir() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			_, err = zw.CreateHeader(&zip.FileHeader{Name: name + "/", Modified: info.ModTime()})
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return addFileToZip(zw, path, name)
+	})
+}
+
+// addFileToZip writes the file at path to zw as name.
+func addFileToZip(zw *zip.Writer, path, name string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+	if slices.Contains(storedExtensions, strings.ToLower(filepath.Ext(name))) {
+		header.Method = zip.Store
+	}
+	fw, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(fw, f)
+	return err
+}
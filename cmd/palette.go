@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yech1990/hey/internal/render"
+	"gopkg.in/yaml.v3"
+)
+
+// paletteConfigPath is the file with user-defined palettes, for example:
+//
+//	mine:
+//	  A: lightred
+//	  C: lightblue
+func paletteConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "hey", "palettes.yaml")
+}
+
+// loadPalette returns the named palette from the built-in ones or the
+// palette config file. An empty name selects $HEY_PALETTE or "default".
+// Bases a user palette leaves out keep their default color.
+func loadPalette(name string) (render.Palette, error) {
+	if name == "" {
+		name = os.Getenv("HEY_PALETTE")
+	}
+	if name == "" {
+		name = "default"
+	}
+	if p, ok := render.Palettes[name]; ok {
+		return p, nil
+	}
+
+	path := paletteConfigPath()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("unknown palette %q, use %s or define it in %s", name, strings.Join(paletteNames(), ", "), path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var custom map[string]map[string]string
+	if err := yaml.Unmarshal(data, &custom); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	colors, ok := custom[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown palette %q, use %s or define it in %s", name, strings.Join(paletteNames(), ", "), path)
+	}
+	return customPalette(colors)
+}
+
+func customPalette(colors map[string]string) (render.Palette, error) {
+	p := make(render.Palette)
+	for base, color := range render.Palettes["default"] {
+		p[base] = color
+	}
+	for base, color := range colors {
+		if len(base) != 1 || !strings.Contains("ACGT-*", strings.ToUpper(base)) {
+			return nil, fmt.Errorf("invalid base %q in palette, use A, C, G, T, - or *", base)
+		}
+		color = strings.ToLower(color)
+		valid := false
+		for _, c := range render.Colors {
+			valid = valid || c == color
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid color %q for %s, use one of: %s", color, base, strings.Join(render.Colors, ", "))
+		}
+		p[strings.ToUpper(base)[0]] = color
+	}
+	return p, nil
+}
+
+func paletteNames() []string {
+	names := make([]string, 0, len(render.Palettes))
+	for name := range render.Palettes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPalette(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("HOME", configDir)
+	t.Setenv("HEY_PALETTE", "")
+	require.NoError(t, os.MkdirAll(filepath.Dir(paletteConfigPath()), 0o755))
+	require.NoError(t, os.WriteFile(paletteConfigPath(), []byte("mine:\n  a: LightRed\n  T: cyan\nbroken:\n  A: orange\n"), 0o644))
+
+	p, err := loadPalette("")
+	require.NoError(t, err)
+	assert.Equal(t, "red", p.Color('a'))
+
+	p, err = loadPalette("igv")
+	require.NoError(t, err)
+	assert.Equal(t, "green", p.Color('A'))
+
+	p, err = loadPalette("mine")
+	require.NoError(t, err)
+	assert.Equal(t, "lightred", p.Color('A'))
+	assert.Equal(t, "cyan", p.Color('T'))
+	assert.Equal(t, "blue", p.Color('C'), "unset bases keep the default color")
+
+	_, err = loadPalette("broken")
+	assert.ErrorContains(t, err, "orange")
+
+	_, err = loadPalette("nope")
+	assert.ErrorContains(t, err, "unknown palette")
+
+	t.Setenv("HEY_PALETTE", "colorblind")
+	p, err = loadPalette("")
+	require.NoError(t, err)
+	assert.Equal(t, "cyan", p.Color('T'))
+}
@@ -0,0 +1,310 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/liamg/tml"
+	"github.com/spf13/cobra"
+)
+
+var (
+	plotX      int
+	plotY      []int
+	plotSep    string
+	plotLine   bool
+	plotWidth  int
+	plotHeight int
+)
+
+var plotColors = []string{"blue", "red", "green", "yellow", "magenta", "cyan"}
+
+var plotCmd = &cobra.Command{
+	Use:   "plot [filename]",
+	Short: "Draw a scatter or line plot in the terminal",
+	Long: `Draws a quick scatter (default) or line plot of numeric columns using braille
+characters. Reads from stdin or a file; gzip files (.gz) are supported.
+A header line, if present, is used to label the series. Other non-numeric lines are skipped.
+
+Use --x 0 to plot against the row number, and repeat --y (or pass a comma list)
+to overlay several series in different colors.
+
+Example:
+  hey plot qc.tsv --x 1 --y 2
+  hey plot qc.tsv --x 0 --y 3,4 --line`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if plotWidth < 10 || plotHeight < 3 {
+			return fmt.Errorf("plot size too small (minimum 10x3)")
+		}
+		filename := "-"
+		if len(args) == 1 {
+			filename = args[0]
+		}
+		series, err := readPlotSeries(filename, plotX, plotY, plotSep)
+		if err != nil {
+			return err
+		}
+		printPlot(series, plotWidth, plotHeight, plotLine)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(plotCmd)
+	plotCmd.Flags().IntVarP(&plotX, "x", "x", 1, "1-based column for the x axis (0 = row number)")
+	plotCmd.Flags().IntSliceVarP(&plotY, "y", "y", []int{2}, "1-based column(s) for the y axis")
+	plotCmd.Flags().StringVarP(&plotSep, "sep", "s", "\t", "Column separator (default: tab)")
+	plotCmd.Flags().BoolVarP(&plotLine, "line", "l", false, "Connect points with lines")
+	plotCmd.Flags().IntVarP(&plotWidth, "width", "W", 60, "Plot width in characters")
+	plotCmd.Flags().IntVarP(&plotHeight, "height", "H", 15, "Plot height in characters")
+}
+
+type plotSeries struct {
+	name string
+	xs   []float64
+	ys   []float64
+}
+
+func readPlotSeries(filename string, xCol int, yCols []int, sep string) ([]plotSeries, error) {
+	if xCol < 0 {
+		return nil, fmt.Errorf("--x must be 0 or a 1-based column, got %d", xCol)
+	}
+	if len(yCols) == 0 {
+		return nil, fmt.Errorf("at least one --y column is required")
+	}
+	series := make([]plotSeries, len(yCols))
+	for i, c := range yCols {
+		if c < 1 {
+			return nil, fmt.Errorf("--y must be a 1-based column, got %d", c)
+		}
+		series[i].name = fmt.Sprintf("column %d", c)
+	}
+
+	input, err := openInput(filename)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", filename, err)
+	}
+	defer input.Close()
+
+	parse := func(fields []string, col int) (float64, bool) {
+		if col > len(fields) {
+			return 0, false
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(fields[col-1]), 64)
+		return v, err == nil && !math.IsNaN(v) && !math.IsInf(v, 0)
+	}
+
+	scanner := newLineScanner(input)
+	first := true
+	row := 0
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, sep)
+		if first {
+			first = false
+			// Use a non-numeric first line as series names.
+			if _, ok := parse(fields, yCols[0]); !ok {
+				for i, c := range yCols {
+					if c <= len(fields) {
+						series[i].name = fields[c-1]
+					}
+				}
+				continue
+			}
+		}
+		row++
+		x := float64(row)
+		if xCol > 0 {
+			var ok bool
+			if x, ok = parse(fields, xCol); !ok {
+				continue
+			}
+		}
+		for i, c := range yCols {
+			if y, ok := parse(fields, c); ok {
+				series[i].xs = append(series[i].xs, x)
+				series[i].ys = append(series[i].ys, y)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", filename, err)
+	}
+	total := 0
+	for _, s := range series {
+		total += len(s.xs)
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("no numeric points found in %s", filename)
+	}
+	return series, nil
+}
+
+// brailleCanvas is a character grid where every cell holds a 2x4 braille dot
+// matrix, giving twice the horizontal and four times the vertical resolution.
+type brailleCanvas struct {
+	width, height int
+	cells         []rune
+	colors        []int
+}
+
+func newBrailleCanvas(width, height int) *brailleCanvas {
+	c := &brailleCanvas{
+		width:  width,
+		height: height,
+		cells:  make([]rune, width*height),
+		colors: make([]int, width*height),
+	}
+	for i := range c.colors {
+		c.colors[i] = -1
+	}
+	return c
+}
+
+// brailleDots maps a dot position inside a cell to its bit in the braille block.
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// set turns on the dot at (px, py); py grows downwards.
+func (c *brailleCanvas) set(px, py, color int) {
+	if px < 0 || py < 0 || px >= c.width*2 || py >= c.height*4 {
+		return
+	}
+	idx := (py/4)*c.width + px/2
+	c.cells[idx] |= brailleDots[py%4][px%2]
+	c.colors[idx] = color
+}
+
+// line draws a straight line between two dots using Bresenham's algorithm.
+func (c *brailleCanvas) line(x0, y0, x1, y1, color int) {
+	dx := abs(x1 - x0)
+	dy := -abs(y1 - y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+	for {
+		c.set(x0, y0, color)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+func (c *brailleCanvas) row(y int) string {
+	var sb strings.Builder
+	for x := 0; x < c.width; x++ {
+		idx := y*c.width + x
+		if c.cells[idx] == 0 {
+			sb.WriteByte(' ')
+			continue
+		}
+		color := plotColors[c.colors[idx]%len(plotColors)]
+		sb.WriteString("<" + color + ">" + string(0x2800+c.cells[idx]) + "</" + color + ">")
+	}
+	return sb.String()
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func printPlot(series []plotSeries, width, height int, connect bool) {
+	xMin, xMax := math.Inf(1), math.Inf(-1)
+	yMin, yMax := math.Inf(1), math.Inf(-1)
+	for _, s := range series {
+		for i := range s.xs {
+			xMin, xMax = math.Min(xMin, s.xs[i]), math.Max(xMax, s.xs[i])
+			yMin, yMax = math.Min(yMin, s.ys[i]), math.Max(yMax, s.ys[i])
+		}
+	}
+	if xMax == xMin {
+		xMin, xMax = xMin-1, xMax+1
+	}
+	if yMax == yMin {
+		yMin, yMax = yMin-1, yMax+1
+	}
+
+	canvas := newBrailleCanvas(width, height)
+	toDot := func(x, y float64) (int, int) {
+		px := int(math.Round((x - xMin) / (xMax - xMin) * float64(width*2-1)))
+		py := int(math.Round((yMax - y) / (yMax - yMin) * float64(height*4-1)))
+		return px, py
+	}
+	for si, s := range series {
+		for i := range s.xs {
+			px, py := toDot(s.xs[i], s.ys[i])
+			if connect && i > 0 {
+				qx, qy := toDot(s.xs[i-1], s.ys[i-1])
+				canvas.line(qx, qy, px, py, si)
+			} else {
+				canvas.set(px, py, si)
+			}
+		}
+	}
+
+	yTop := strconv.FormatFloat(yMax, 'g', 4, 64)
+	yBottom := strconv.FormatFloat(yMin, 'g', 4, 64)
+	labelWidth := max(len(yTop), len(yBottom))
+	for y := 0; y < height; y++ {
+		label := ""
+		switch y {
+		case 0:
+			label = yTop
+		case height - 1:
+			label = yBottom
+		}
+		printMarkup(fmt.Sprintf("<darkgrey>%*s ┤</darkgrey>", labelWidth, label) + canvas.row(y))
+	}
+	tml.Printf("<darkgrey>%s └%s</darkgrey>\n", strings.Repeat(" ", labelWidth), strings.Repeat("─", width))
+	xLeft := strconv.FormatFloat(xMin, 'g', 4, 64)
+	xRight := strconv.FormatFloat(xMax, 'g', 4, 64)
+	gap := max(1, width-len(xLeft)-len(xRight))
+	tml.Printf("<darkgrey>%s  %s%s%s</darkgrey>\n", strings.Repeat(" ", labelWidth), xLeft, strings.Repeat(" ", gap), xRight)
+
+	if len(series) > 1 {
+		var legend []string
+		for i, s := range series {
+			color := plotColors[i%len(plotColors)]
+			legend = append(legend, fmt.Sprintf("<%s>⣿</%s> %s", color, color, s.name))
+		}
+		printMarkup(strings.Repeat(" ", labelWidth+2) + strings.Join(legend, "  "))
+	}
+}
+
+// printMarkup renders a line that already contains tml tags. Unlike
+// tml.Printf it does not treat the line as a format string.
+func printMarkup(line string) {
+	rendered, err := tml.Parse(line)
+	if err != nil {
+		rendered = line
+	}
+	fmt.Println(rendered)
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadPlotSeries(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "qc.tsv")
+	require.NoError(t, os.WriteFile(path, []byte("cycle\tq30\tgc\n1\t95\t40\n2\t94\tNA\n\n3\tx\t42\n4\t90\t43\n"), 0o644))
+
+	tests := []struct {
+		name     string
+		x        int
+		y        []int
+		expected []plotSeries
+		wantErr  string
+	}{
+		{
+			name: "header names series and non-numeric cells are skipped",
+			x:    1,
+			y:    []int{2, 3},
+			expected: []plotSeries{
+				{name: "q30", xs: []float64{1, 2, 4}, ys: []float64{95, 94, 90}},
+				{name: "gc", xs: []float64{1, 3, 4}, ys: []float64{40, 42, 43}},
+			},
+		},
+		{
+			name: "row number as x",
+			x:    0,
+			y:    []int{3},
+			expected: []plotSeries{
+				{name: "gc", xs: []float64{1, 3, 4}, ys: []float64{40, 42, 43}},
+			},
+		},
+		{name: "negative x", x: -1, y: []int{2}, wantErr: "--x must be"},
+		{name: "zero y", x: 1, y: []int{0}, wantErr: "--y must be"},
+		{name: "no numbers", x: 1, y: []int{9}, wantErr: "no numeric points"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series, err := readPlotSeries(path, tt.x, tt.y, "\t")
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, series)
+		})
+	}
+}
+
+func TestBrailleCanvas(t *testing.T) {
+	c := newBrailleCanvas(2, 1)
+	c.set(0, 0, 0)
+	c.set(3, 3, 1)
+	c.set(4, 0, 0) // Outside the canvas
+	assert.Equal(t, "<blue>⠁</blue><red>⢀</red>", c.row(0))
+
+	c = newBrailleCanvas(1, 1)
+	c.line(0, 0, 1, 3, 2)
+	assert.Equal(t, "<green>⢣</green>", c.row(0))
+
+	assert.Equal(t, " ", newBrailleCanvas(1, 1).row(0))
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/skip2/go-qrcode"
+	"github.com/spf13/cobra"
+)
+
+var (
+	qrPNG          string
+	qrSize         int
+	qrLevel        string
+	qrInvert       bool
+	qrWifiSSID     string
+	qrWifiPassword string
+	qrWifiSecurity string
+)
+
+var qrCmd = &cobra.Command{
+	Use:   "qr [TEXT|URL]...",
+	Short: "Render a QR code in the terminal or to a PNG file",
+	Long: `Encodes text or a URL as a QR code and prints it in the terminal, or writes it
+to a PNG image with --png. Without arguments the text is read from stdin.
+
+Use --wifi to encode a Wi-Fi network, so that phones can join it by scanning.
+If the code looks wrong on a light terminal background, try --invert.
+
+Example:
+  hey qr https://github.com/y9c/hey
+  echo "some text" | hey qr --png note.png
+  hey qr --wifi LabGuest --password 'secret' --security WPA`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		level, err := parseQRLevel(qrLevel)
+		if err != nil {
+			return err
+		}
+		var content string
+		switch {
+		case qrWifiSSID != "":
+			if len(args) > 0 {
+				return fmt.Errorf("do not pass text together with --wifi")
+			}
+			content = wifiQRContent(qrWifiSSID, qrWifiPassword, qrWifiSecurity)
+		case len(args) > 0:
+			content = strings.Join(args, " ")
+		default:
+			stat, _ := os.Stdin.Stat()
+			if (stat.Mode() & os.ModeCharDevice) != 0 {
+				return fmt.Errorf("nothing to encode: pass some text or pipe it into 'hey qr'")
+			}
+			data, err := io.ReadAll(os.Stdin)
+			if err != nil {
+				return fmt.Errorf("reading stdin: %w", err)
+			}
+			content = strings.TrimRight(string(data), "\r\n")
+		}
+		if content == "" {
+			return fmt.Errorf("nothing to encode")
+		}
+
+		if qrPNG != "" {
+			if err := qrcode.WriteFile(content, level, qrSize, qrPNG); err != nil {
+				return fmt.Errorf("could not write QR code: %w", err)
+			}
+			color.Green("Wrote QR code to %s", qrPNG)
+			return nil
+		}
+		text, err := renderQR(content, level, qrInvert)
+		if err != nil {
+			return err
+		}
+		fmt.Print(text)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(qrCmd)
+	qrCmd.Flags().StringVar(&qrPNG, "png", "", "Write the QR code to this PNG file instead of the terminal")
+	qrCmd.Flags().IntVar(&qrSize, "size", 256, "Width and height of the PNG in pixels")
+	qrCmd.Flags().StringVarP(&qrLevel, "level", "l", "M", "Error correction level: L, M, Q or H")
+	qrCmd.Flags().BoolVarP(&qrInvert, "invert", "i", false, "Invert colors (for light terminal backgrounds)")
+	qrCmd.Flags().StringVar(&qrWifiSSID, "wifi", "", "Encode a Wi-Fi network with this SSID")
+	qrCmd.Flags().StringVar(&qrWifiPassword, "password", "", "Wi-Fi password (with --wifi)")
+	qrCmd.Flags().StringVar(&qrWifiSecurity, "security", "WPA", "Wi-Fi security: WPA, WEP or nopass (with --wifi)")
+}
+
+// renderQR returns the QR code of content drawn with half-block characters.
+func renderQR(content string, level qrcode.RecoveryLevel, inverse bool) (string, error) {
+	q, err := qrcode.New(content, level)
+	if err != nil {
+		return "", fmt.Errorf("could not generate QR code: %w", err)
+	}
+	return q.ToSmallString(inverse), nil
+}
+
+func parseQRLevel(level string) (qrcode.RecoveryLevel, error) {
+	switch strings.ToUpper(level) {
+	case "L":
+		return qrcode.Low, nil
+	case "M":
+		return qrcode.Medium, nil
+	case "Q":
+		return qrcode.High, nil
+	case "H":
+		return qrcode.Highest, nil
+	}
+	return 0, fmt.Errorf("invalid --level %q, use L, M, Q or H", level)
+}
+
+// wifiQRContent builds the WIFI: payload understood by phone cameras, escaping
+// the characters that are special in it.
+func wifiQRContent(ssid, password, security string) string {
+	escape := strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, `:`, `\:`, `"`, `\"`)
+	if password == "" {
+		security = "nopass"
+	}
+	content := "WIFI:T:" + security + ";S:" + escape.Replace(ssid) + ";"
+	if security != "nopass" {
+		content += "P:" + escape.Replace(password) + ";"
+	}
+	return content + ";"
+}
@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWifiQRContent(t *testing.T) {
+	assert.Equal(t, "WIFI:T:WPA;S:LabGuest;P:secret;;", wifiQRContent("LabGuest", "secret", "WPA"))
+	assert.Equal(t, `WIFI:T:WPA;S:My\;Net;P:a\:b\\c;;`, wifiQRContent("My;Net", `a:b\c`, "WPA"))
+	assert.Equal(t, "WIFI:T:nopass;S:Open;;", wifiQRContent("Open", "", "WPA"))
+}
+
+func TestParseQRLevel(t *testing.T) {
+	level, err := parseQRLevel("h")
+	assert.NoError(t, err)
+	assert.Equal(t, qrcode.Highest, level)
+	_, err = parseQRLevel("X")
+	assert.Error(t, err)
+}
@@ -0,0 +1,401 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/table"
+	"github.com/fatih/color"
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	refDir    string
+	refSource string
+	refTypes  []string
+	refForce  bool
+)
+
+var refCmd = &cobra.Command{
+	Use:   "ref",
+	Short: "Download and manage reference genomes",
+	Long: `Subcommands to download reference genome files (FASTA, GTF) from Ensembl or
+UCSC into a local cache, and to list what is available and already downloaded.
+
+The cache directory is taken from --dir, then $HEY_REF_DIR, and defaults to
+the user cache directory (e.g. ~/.cache/hey/ref).`,
+}
+
+var refGetCmd = &cobra.Command{
+	Use:   "get <genome>",
+	Short: "Download reference files of a genome",
+	Long: `Downloads the reference files of a genome into the cache directory and
+verifies them against the checksum file published next to them (CHECKSUMS for
+Ensembl, md5sum.txt for UCSC). Files already in the index are not downloaded
+again unless --force is set. The path of every file is printed at the end.
+
+Genomes: GRCh38 (hg38), GRCh37 (hg19), GRCm39 (mm39), GRCm38 (mm10).
+
+Example:
+  hey ref get GRCh38
+  hey ref get mm10 --source ucsc --type fasta,gtf`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		genome, ok := resolveGenome(args[0])
+		if !ok {
+			return fmt.Errorf("unknown genome %q, see 'hey ref list'", args[0])
+		}
+		dir, err := refCacheDir()
+		if err != nil {
+			return err
+		}
+		index, err := loadRefIndex(dir)
+		if err != nil {
+			return err
+		}
+		for _, fileType := range refTypes {
+			entry, ok := findRefEntry(genome, refSource, fileType)
+			if !ok {
+				return fmt.Errorf("no %s %s file for %s", refSource, fileType, genome)
+			}
+			local, err := getRefFile(dir, index, entry, refForce)
+			if err != nil {
+				return fmt.Errorf("%s %s: %w", genome, fileType, err)
+			}
+			fmt.Println(local)
+		}
+		return nil
+	},
+}
+
+var refListCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List available and downloaded reference files",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := refCacheDir()
+		if err != nil {
+			return err
+		}
+		index, err := loadRefIndex(dir)
+		if err != nil {
+			return err
+		}
+		printRefList(dir, index)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(refCmd)
+	refCmd.AddCommand(refGetCmd)
+	refCmd.AddCommand(refListCmd)
+	refCmd.PersistentFlags().StringVar(&refDir, "dir", "", "Reference cache directory (default: $HEY_REF_DIR or the user cache directory)")
+	refGetCmd.Flags().StringVarP(&refSource, "source", "s", "ensembl", "Where to download from: ensembl or ucsc")
+	refGetCmd.Flags().StringSliceVarP(&refTypes, "type", "t", []string{"fasta"}, "File types to download: fasta, gtf")
+	refGetCmd.Flags().BoolVarP(&refForce, "force", "f", false, "Download again even if the file is already in the cache")
+}
+
+type refEntry struct {
+	Genome string
+	Source string
+	Type   string
+	URL    string
+}
+
+const (
+	ensemblFTP = "https://ftp.ensembl.org/pub"
+	ucscBigZip = "https://hgdownload.soe.ucsc.edu/goldenPath"
+)
+
+var refCatalog = []refEntry{
+	{"GRCh38", "ensembl", "fasta", ensemblFTP + "/release-112/fasta/homo_sapiens/dna/Homo_sapiens.GRCh38.dna.primary_assembly.fa.gz"},
+	{"GRCh38", "ensembl", "gtf", ensemblFTP + "/release-112/gtf/homo_sapiens/Homo_sapiens.GRCh38.112.gtf.gz"},
+	{"GRCh37", "ensembl", "fasta", ensemblFTP + "/grch37/release-87/fasta/homo_sapiens/dna/Homo_sapiens.GRCh37.dna.primary_assembly.fa.gz"},
+	{"GRCh37", "ensembl", "gtf", ensemblFTP + "/grch37/release-87/gtf/homo_sapiens/Homo_sapiens.GRCh37.87.gtf.gz"},
+	{"GRCm39", "ensembl", "fasta", ensemblFTP + "/release-112/fasta/mus_musculus/dna/Mus_musculus.GRCm39.dna.primary_assembly.fa.gz"},
+	{"GRCm39", "ensembl", "gtf", ensemblFTP + "/release-112/gtf/mus_musculus/Mus_musculus.GRCm39.112.gtf.gz"},
+	{"GRCm38", "ensembl", "fasta", ensemblFTP + "/release-102/fasta/mus_musculus/dna/Mus_musculus.GRCm38.dna.primary_assembly.fa.gz"},
+	{"GRCm38", "ensembl", "gtf", ensemblFTP + "/release-102/gtf/mus_musculus/Mus_musculus.GRCm38.102.gtf.gz"},
+	{"GRCh38", "ucsc", "fasta", ucscBigZip + "/hg38/bigZips/hg38.fa.gz"},
+	{"GRCh38", "ucsc", "gtf", ucscBigZip + "/hg38/bigZips/genes/hg38.ncbiRefSeq.gtf.gz"},
+	{"GRCh37", "ucsc", "fasta", ucscBigZip + "/hg19/bigZips/hg19.fa.gz"},
+	{"GRCh37", "ucsc", "gtf", ucscBigZip + "/hg19/bigZips/genes/hg19.ncbiRefSeq.gtf.gz"},
+	{"GRCm39", "ucsc", "fasta", ucscBigZip + "/mm39/bigZips/mm39.fa.gz"},
+	{"GRCm39", "ucsc", "gtf", ucscBigZip + "/mm39/bigZips/genes/mm39.ncbiRefSeq.gtf.gz"},
+	{"GRCm38", "ucsc", "fasta", ucscBigZip + "/mm10/bigZips/mm10.fa.gz"},
+	{"GRCm38", "ucsc", "gtf", ucscBigZip + "/mm10/bigZips/genes/mm10.ncbiRefSeq.gtf.gz"},
+}
+
+var refAliases = map[string]string{
+	"hg38": "GRCh38",
+	"hg19": "GRCh37",
+	"mm39": "GRCm39",
+	"mm10": "GRCm38",
+}
+
+// resolveGenome maps a genome name or UCSC alias to its catalog name,
+// ignoring case.
+func resolveGenome(name string) (string, bool) {
+	for alias, genome := range refAliases {
+		if strings.EqualFold(name, alias) {
+			return genome, true
+		}
+	}
+	for _, e := range refCatalog {
+		if strings.EqualFold(name, e.Genome) {
+			return e.Genome, true
+		}
+	}
+	return "", false
+}
+
+func findRefEntry(genome, source, fileType string) (refEntry, bool) {
+	for _, e := range refCatalog {
+		if e.Genome == genome && strings.EqualFold(e.Source, source) && strings.EqualFold(e.Type, fileType) {
+			return e, true
+		}
+	}
+	return refEntry{}, false
+}
+
+func (e refEntry) key() string {
+	return e.Genome + "/" + e.Source + "/" + e.Type
+}
+
+func (e refEntry) localPath(dir string) string {
+	return filepath.Join(dir, e.Genome, e.Source, path.Base(e.URL))
+}
+
+func refCacheDir() (string, error) {
+	if refDir != "" {
+		return refDir, nil
+	}
+	if dir := os.Getenv("HEY_REF_DIR"); dir != "" {
+		return dir, nil
+	}
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine cache directory, use --dir: %w", err)
+	}
+	return filepath.Join(cache, "hey", "ref"), nil
+}
+
+// refIndexEntry records a downloaded file in index.yaml of the cache directory.
+type refIndexEntry struct {
+	URL        string    `yaml:"url"`
+	Path       string    `yaml:"path"`
+	Size       int64     `yaml:"size"`
+	Checksum   string    `yaml:"checksum,omitempty"`
+	Downloaded time.Time `yaml:"downloaded"`
+}
+
+func loadRefIndex(dir string) (map[string]refIndexEntry, error) {
+	index := make(map[string]refIndexEntry)
+	data, err := os.ReadFile(filepath.Join(dir, "index.yaml"))
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading reference index: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("parsing reference index: %w", err)
+	}
+	return index, nil
+}
+
+func saveRefIndex(dir string, index map[string]refIndexEntry) error {
+	data, err := yaml.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "index.yaml"), data, 0o644)
+}
+
+func getRefFile(dir string, index map[string]refIndexEntry, entry refEntry, force bool) (string, error) {
+	if known, ok := index[entry.key()]; ok && !force && known.URL == entry.URL && fileExists(known.Path) {
+		color.Green("%s is already downloaded.", entry.key())
+		return known.Path, nil
+	}
+
+	dest := entry.localPath(dir)
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return "", err
+	}
+
+	size := int64(-1)
+	if resp, err := http.Head(entry.URL); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			size = resp.ContentLength
+		}
+	}
+	bar := progressbar.NewOptions64(size,
+		progressbar.OptionSetDescription("[cyan]"+path.Base(entry.URL)),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionShowBytes(true),
+		progressbar.OptionShowElapsedTimeOnFinish(),
+		progressbar.OptionEnableColorCodes(true),
+		progressbar.OptionSetTheme(progressbar.Theme{Saucer: "[green]=[reset]", SaucerHead: "[green]>[reset]", SaucerPadding: " ", BarStart: "[", BarEnd: "]"}),
+	)
+	partPath := dest + ".part"
+	if force {
+		os.Remove(partPath)
+	}
+	err := resumeDownload(entry.URL, partPath, bar)
+	_ = bar.Finish()
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+
+	checksum, err := verifyRefChecksum(entry, partPath)
+	if err != nil {
+		os.Remove(partPath)
+		return "", err
+	}
+	if err := os.Rename(partPath, dest); err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(dest)
+	if err != nil {
+		return "", err
+	}
+	index[entry.key()] = refIndexEntry{
+		URL:        entry.URL,
+		Path:       dest,
+		Size:       info.Size(),
+		Checksum:   checksum,
+		Downloaded: time.Now().Truncate(time.Second),
+	}
+	if err := saveRefIndex(dir, index); err != nil {
+		return "", fmt.Errorf("updating reference index: %w", err)
+	}
+	return dest, nil
+}
+
+// verifyRefChecksum compares a downloaded file with the checksum file that is
+// published in the same directory. It returns the verified checksum, or ""
+// with a warning when no checksum is available.
+func verifyRefChecksum(entry refEntry, filename string) (string, error) {
+	checksumName := "CHECKSUMS"
+	if entry.Source == "ucsc" {
+		checksumName = "md5sum.txt"
+	}
+	body, err := httpGetBody(path.Dir(entry.URL) + "/" + checksumName)
+	if err != nil {
+		color.Yellow("Warning: no %s to verify %s (%v)", checksumName, path.Base(entry.URL), err)
+		return "", nil
+	}
+	expected, ok := findChecksum(string(body), path.Base(entry.URL))
+	if !ok {
+		color.Yellow("Warning: %s is not listed in %s, skipping verification", path.Base(entry.URL), checksumName)
+		return "", nil
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	var got string
+	if entry.Source == "ucsc" {
+		hash := md5.New()
+		if _, err := io.Copy(hash, file); err != nil {
+			return "", err
+		}
+		got = hex.EncodeToString(hash.Sum(nil))
+	} else {
+		sum, blocks, err := bsdSum(file)
+		if err != nil {
+			return "", err
+		}
+		got = fmt.Sprintf("%d %d", sum, blocks)
+	}
+	if got != expected {
+		return "", fmt.Errorf("checksum mismatch: expected %s, got %s", expected, got)
+	}
+	color.Green("Verified %s against %s.", path.Base(entry.URL), checksumName)
+	return got, nil
+}
+
+// findChecksum looks up a file in a checksum listing. Ensembl CHECKSUMS lines
+// are "<sum> <blocks> <file>" and UCSC md5sum.txt lines are "<md5>  <file>";
+// everything before the file name is returned, with single spaces.
+func findChecksum(listing, name string) (string, bool) {
+	scanner := bufio.NewScanner(strings.NewReader(listing))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		if strings.TrimPrefix(fields[len(fields)-1], "*") == name {
+			return strings.Join(fields[:len(fields)-1], " "), true
+		}
+	}
+	return "", false
+}
+
+// bsdSum computes the BSD 16-bit checksum and 1 KiB block count, as printed
+// by 'sum' and used in Ensembl CHECKSUMS files.
+func bsdSum(r io.Reader) (uint16, int64, error) {
+	var checksum uint16
+	var total int64
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := r.Read(buf)
+		for _, b := range buf[:n] {
+			checksum = (checksum >> 1) | (checksum << 15)
+			checksum += uint16(b)
+		}
+		total += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return checksum, (total + 1023) / 1024, nil
+}
+
+func printRefList(dir string, index map[string]refIndexEntry) {
+	t := table.New(os.Stdout)
+	t.SetHeaders("Genome", "Alias", "Source", "Type", "Status")
+	t.SetHeaderStyle(table.StyleBold)
+	t.SetLineStyle(table.StyleBlue)
+	t.SetDividers(table.UnicodeRoundedDividers)
+
+	for _, e := range refCatalog {
+		var aliases []string
+		for alias, genome := range refAliases {
+			if genome == e.Genome {
+				aliases = append(aliases, alias)
+			}
+		}
+		slices.Sort(aliases)
+		status := color.HiBlackString("-")
+		if known, ok := index[e.key()]; ok && fileExists(known.Path) {
+			status = color.GreenString("✓ ") + humanSize(known.Size) + ", " + known.Downloaded.Format("2006-01-02")
+		}
+		t.AddRow(e.Genome, strings.Join(aliases, ","), e.Source, e.Type, status)
+	}
+	t.Render()
+	fmt.Printf("Cache directory: %s\n", dir)
+}
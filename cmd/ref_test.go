@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBsdSum(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedSum    uint16
+		expectedBlocks int64
+	}{
+		{"", 0, 0},
+		{"hello\n", 36979, 1},
+		{strings.Repeat("a", 5000), 41146, 5},
+	}
+	for _, tt := range tests {
+		sum, blocks, err := bsdSum(strings.NewReader(tt.input))
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expectedSum, sum)
+		assert.Equal(t, tt.expectedBlocks, blocks)
+	}
+}
+
+func TestFindChecksum(t *testing.T) {
+	ensembl := "12345 67890 Homo_sapiens.GRCh38.dna.primary_assembly.fa.gz\n111 2 README\n"
+	ucsc := "0123456789abcdef0123456789abcdef  hg38.fa.gz\nfedcba  hg38.2bit\n"
+
+	sum, ok := findChecksum(ensembl, "Homo_sapiens.GRCh38.dna.primary_assembly.fa.gz")
+	assert.True(t, ok)
+	assert.Equal(t, "12345 67890", sum)
+
+	sum, ok = findChecksum(ucsc, "hg38.fa.gz")
+	assert.True(t, ok)
+	assert.Equal(t, "0123456789abcdef0123456789abcdef", sum)
+
+	_, ok = findChecksum(ucsc, "hg38.fa")
+	assert.False(t, ok)
+}
+
+func TestResolveGenome(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		ok       bool
+	}{
+		{"GRCh38", "GRCh38", true},
+		{"grch37", "GRCh37", true},
+		{"mm10", "GRCm38", true},
+		{"HG38", "GRCh38", true},
+		{"dm6", "", false},
+	}
+	for _, tt := range tests {
+		genome, ok := resolveGenome(tt.name)
+		assert.Equal(t, tt.ok, ok, tt.name)
+		assert.Equal(t, tt.expected, genome, tt.name)
+	}
+}
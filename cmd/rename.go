@@ -0,0 +1,313 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/table"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	renameDryRun  bool
+	renameUndoLog string
+	renameUndo    bool
+)
+
+var renameCmd = &cobra.Command{
+	Use:   "rename 's/PATTERN/REPLACEMENT/[gi]' <file>...",
+	Short: "Rename files with a regular expression",
+	Long: `Renames files by applying a sed-style substitution to their base names.
+PATTERN is a Go regular expression; REPLACEMENT may refer to groups as \1 or ${1}.
+Flags after the last delimiter: g replaces all matches, i ignores case.
+
+All new names are shown in a table first. Nothing is renamed if two files would
+get the same name or a new name already exists. Every rename is appended to an
+undo log; 'hey rename --undo' reverts the most recent batch.
+
+Example:
+  hey rename -n 's/_S\d+_L001//' *.fastq.gz
+  hey rename 's/^(\w+)_R([12])/\1.\2/' raw/*.fq.gz
+  hey rename --undo`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if renameUndo {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.MinimumNArgs(2)(cmd, args)
+	},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if renameUndo {
+			return undoRenames(renameUndoLog, renameDryRun)
+		}
+		re, repl, global, err := parseSedExpr(args[0])
+		if err != nil {
+			return err
+		}
+		plans := planRenames(args[1:], re, repl, global)
+		if len(plans) == 0 {
+			color.Yellow("No file names match %s", args[0])
+			return nil
+		}
+		printRenamePlan(plans)
+		if conflicts := findRenameConflicts(plans, fileExists); len(conflicts) > 0 {
+			for _, c := range conflicts {
+				color.Red(c)
+			}
+			return fmt.Errorf("%d conflicts, nothing renamed", len(conflicts))
+		}
+		if renameDryRun {
+			color.Yellow("Dry run, nothing renamed.")
+			return nil
+		}
+		return applyRenames(plans, renameUndoLog)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(renameCmd)
+	renameCmd.Flags().BoolVarP(&renameDryRun, "dry-run", "n", false, "Only show what would be renamed")
+	renameCmd.Flags().StringVar(&renameUndoLog, "undo-log", ".hey_rename_undo.tsv", "File that records renames for --undo")
+	renameCmd.Flags().BoolVar(&renameUndo, "undo", false, "Revert the most recent batch of renames in the undo log")
+}
+
+type renamePlan struct {
+	From string
+	To   string
+}
+
+var sedGroupRegex = regexp.MustCompile(`\\(\d)`)
+
+// parseSedExpr parses s/PATTERN/REPLACEMENT/FLAGS. Any character may be used as
+// delimiter, and an escaped delimiter is taken literally.
+func parseSedExpr(expr string) (*regexp.Regexp, string, bool, error) {
+	if len(expr) < 4 || expr[0] != 's' {
+		return nil, "", false, fmt.Errorf("expression must look like s/PATTERN/REPLACEMENT/, got %q", expr)
+	}
+	delim := expr[1]
+	var parts []string
+	var current strings.Builder
+	for i := 2; i < len(expr); i++ {
+		switch {
+		case expr[i] == '\\' && i+1 < len(expr) && expr[i+1] == delim:
+			current.WriteByte(delim)
+			i++
+		case expr[i] == delim:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(expr[i])
+		}
+	}
+	if len(parts) != 2 {
+		return nil, "", false, fmt.Errorf("expression must look like s/PATTERN/REPLACEMENT/, got %q", expr)
+	}
+	pattern, repl, flags := parts[0], parts[1], current.String()
+
+	global := false
+	for _, f := range flags {
+		switch f {
+		case 'g':
+			global = true
+		case 'i':
+			pattern = "(?i)" + pattern
+		default:
+			return nil, "", false, fmt.Errorf("unknown flag %q in %q", f, expr)
+		}
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, "", false, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return re, sedGroupRegex.ReplaceAllString(repl, "$${$1}"), global, nil
+}
+
+// substitute replaces the first match of re in s, or all matches if global.
+func substitute(re *regexp.Regexp, s, repl string, global bool) string {
+	if global {
+		return re.ReplaceAllString(s, repl)
+	}
+	loc := re.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return s
+	}
+	expanded := re.ExpandString(nil, repl, s, loc)
+	return s[:loc[0]] + string(expanded) + s[loc[1]:]
+}
+
+// planRenames applies the substitution to the base name of every file and
+// returns the files whose name changes.
+func planRenames(files []string, re *regexp.Regexp, repl string, global bool) []renamePlan {
+	var plans []renamePlan
+	for _, f := range files {
+		dir, base := filepath.Split(f)
+		newBase := substitute(re, base, repl, global)
+		if newBase != base {
+			plans = append(plans, renamePlan{From: f, To: dir + newBase})
+		}
+	}
+	return plans
+}
+
+// findRenameConflicts reports new names that are empty, contain a path
+// separator, are shared by several files, or are already taken by a file that
+// is not renamed itself.
+func findRenameConflicts(plans []renamePlan, exists func(string) bool) []string {
+	var conflicts []string
+	sources := make(map[string]bool, len(plans))
+	for _, p := range plans {
+		sources[filepath.Clean(p.From)] = true
+	}
+	targets := make(map[string]string, len(plans))
+	for _, p := range plans {
+		_, newBase := filepath.Split(p.To)
+		if newBase == "" {
+			conflicts = append(conflicts, fmt.Sprintf("%s would get an empty name", p.From))
+			continue
+		}
+		if filepath.Dir(p.To) != filepath.Dir(p.From) {
+			conflicts = append(conflicts, fmt.Sprintf("%s would move to another directory (%s)", p.From, p.To))
+			continue
+		}
+		to := filepath.Clean(p.To)
+		if other, ok := targets[to]; ok {
+			conflicts = append(conflicts, fmt.Sprintf("%s and %s would both become %s", other, p.From, p.To))
+			continue
+		}
+		targets[to] = p.From
+		if !sources[to] && exists(to) {
+			conflicts = append(conflicts, fmt.Sprintf("%s would overwrite existing %s", p.From, p.To))
+		}
+	}
+	return conflicts
+}
+
+func printRenamePlan(plans []renamePlan) {
+	t := table.New(os.Stdout)
+	t.SetHeaders("#", "Old name", "New name")
+	t.SetHeaderStyle(table.StyleBold)
+	t.SetLineStyle(table.StyleBlue)
+	t.SetDividers(table.UnicodeRoundedDividers)
+	for i, p := range plans {
+		t.AddRow(fmt.Sprint(i+1), color.RedString(p.From), color.GreenString(p.To))
+	}
+	t.Render()
+}
+
+// applyRenames renames the files and records them in the undo log as one batch.
+func applyRenames(plans []renamePlan, undoLog string) error {
+	logFile, err := os.OpenFile(undoLog, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening undo log: %w", err)
+	}
+	defer logFile.Close()
+
+	batch := time.Now().Format("20060102-150405.000")
+	renamed, err := moveFiles(plans, func(p renamePlan) {
+		fmt.Fprintf(logFile, "%s\t%s\t%s\n", batch, absPath(p.From), absPath(p.To))
+	})
+	if err != nil {
+		return err
+	}
+	color.Green("Renamed %d files (undo log: %s)", renamed, undoLog)
+	return nil
+}
+
+// moveFiles moves every file to a temporary name first, so that chains and
+// swaps (a->b, b->a) work. done is called for every finished rename.
+func moveFiles(plans []renamePlan, done func(renamePlan)) (int, error) {
+	stamp := time.Now().Format("20060102150405")
+	temps := make([]string, len(plans))
+	for i, p := range plans {
+		temps[i] = fmt.Sprintf("%s.hey-rename-%s-%d", p.From, stamp, i)
+		if err := os.Rename(p.From, temps[i]); err != nil {
+			// Put back what was already moved before giving up.
+			for j := i - 1; j >= 0; j-- {
+				os.Rename(temps[j], plans[j].From)
+			}
+			return 0, fmt.Errorf("renaming %s: %w", p.From, err)
+		}
+	}
+	renamed := 0
+	for i, p := range plans {
+		if err := os.Rename(temps[i], p.To); err != nil {
+			color.Red("Renaming %s to %s failed: %v (file left as %s)", p.From, p.To, err, temps[i])
+			continue
+		}
+		if done != nil {
+			done(p)
+		}
+		renamed++
+	}
+	if renamed < len(plans) {
+		return renamed, fmt.Errorf("renamed %d of %d files", renamed, len(plans))
+	}
+	return renamed, nil
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// undoRenames reverts the most recent batch of renames in the undo log and
+// drops it from the log. Older batches can be reverted by running it again.
+func undoRenames(undoLog string, dryRun bool) error {
+	data, err := os.ReadFile(undoLog)
+	if err != nil {
+		return fmt.Errorf("reading undo log: %w", err)
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	var lastBatch string
+	for _, line := range lines {
+		if batch, _, ok := strings.Cut(line, "\t"); ok {
+			lastBatch = batch
+		}
+	}
+	var plans []renamePlan
+	var keep []string
+	for _, line := range lines {
+		fields := strings.Split(line, "\t")
+		if len(fields) == 3 && fields[0] == lastBatch {
+			plans = append(plans, renamePlan{From: fields[2], To: fields[1]})
+		} else if line != "" {
+			keep = append(keep, line)
+		}
+	}
+	if len(plans) == 0 {
+		color.Yellow("Nothing to undo.")
+		return nil
+	}
+
+	printRenamePlan(plans)
+	if conflicts := findRenameConflicts(plans, fileExists); len(conflicts) > 0 {
+		for _, c := range conflicts {
+			color.Red(c)
+		}
+		return fmt.Errorf("%d conflicts, nothing reverted", len(conflicts))
+	}
+	if dryRun {
+		color.Yellow("Dry run, nothing renamed.")
+		return nil
+	}
+	if _, err := moveFiles(plans, nil); err != nil {
+		return err
+	}
+	if len(keep) == 0 {
+		err = os.Remove(undoLog)
+	} else {
+		err = os.WriteFile(undoLog, []byte(strings.Join(keep, "\n")+"\n"), 0o644)
+	}
+	if err != nil {
+		return fmt.Errorf("updating undo log: %w", err)
+	}
+	color.Green("Reverted %d renames.", len(plans))
+	return nil
+}
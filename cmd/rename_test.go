@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSedExprAndSubstitute(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		input    string
+		expected string
+	}{
+		{"first match only", "s/a/b/", "aaa.fq", "baa.fq"},
+		{"global", "s/a/b/g", "aaa.fq", "bbb.fq"},
+		{"ignore case", "s/sample/S/i", "SAMPLE_1.fq", "S_1.fq"},
+		{"backreferences", `s/^(\w+)_R([12])/\1.\2/`, "liver_R1_001.fq.gz", "liver.1_001.fq.gz"},
+		{"group followed by letters", `s/(\d)/\1x/`, "s1.fq", "s1x.fq"},
+		{"other delimiter", "s|_S\\d+_L001||", "a_S12_L001_R1.fq", "a_R1.fq"},
+		{"escaped delimiter", `s/\//-/`, "a/b", "a-b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re, repl, global, err := parseSedExpr(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, substitute(re, tt.input, repl, global))
+		})
+	}
+
+	for _, bad := range []string{"y/a/b/", "s/a/b", "s/(/b/", "s/a/b/x"} {
+		_, _, _, err := parseSedExpr(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestFindRenameConflicts(t *testing.T) {
+	existing := map[string]bool{"b.fq": true, "taken.fq": true}
+	exists := func(p string) bool { return existing[p] }
+
+	// A swap is fine because both targets are renamed away.
+	assert.Empty(t, findRenameConflicts([]renamePlan{{"a.fq", "b.fq"}, {"b.fq", "a.fq"}}, exists))
+
+	conflicts := findRenameConflicts([]renamePlan{
+		{"x1.fq", "x.fq"},
+		{"x2.fq", "x.fq"},
+		{"y.fq", "taken.fq"},
+	}, exists)
+	assert.Len(t, conflicts, 2)
+}
+
+func TestFindRenameConflictsInvalidNames(t *testing.T) {
+	none := func(string) bool { return false }
+	assert.Len(t, findRenameConflicts([]renamePlan{{"raw/a.fq", "raw/"}}, none), 1)
+	assert.Len(t, findRenameConflicts([]renamePlan{{"raw/a_b.fq", "raw/a/b.fq"}}, none), 1)
+	assert.Empty(t, findRenameConflicts([]renamePlan{{"raw/a.fq", "raw/b.fq"}}, none))
+}
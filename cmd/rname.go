@@ -116,24 +116,16 @@ flow cell type, and lane. Supports multiple inputs and different output formats.
 					continue
 				}
 
-				inputParts := strings.Split(rname, ":")
-				if len(inputParts) < 3 {
-					currentData.ErrorParsing = fmt.Errorf("invalid rname format in '%s': %s (expected at least 3 colon-separated parts)", inputArg, rname)
+				parsed, err := parseRname(rname)
+				if err != nil {
+					currentData.ErrorParsing = fmt.Errorf("invalid rname format in '%s': %w", inputArg, err)
 					allResults = append(allResults, currentData)
 					continue
 				}
-
-				currentData.InstrumentID = inputParts[0]
-				currentData.InstrumentRun = inputParts[1]
-				currentData.FlowcellID = inputParts[2]
-				currentData.LaneID = "N/A"
-				if len(inputParts) >= 4 {
-					currentData.LaneID = inputParts[3]
-				}
-
-				currentData.InstrumentType = printInstrumentType(currentData.InstrumentID)
-				currentData.FlowcellType = printFlowCellType(currentData.FlowcellID)
-				allResults = append(allResults, currentData)
+				parsed.InputName = inputArg
+				parsed.InstrumentType = printInstrumentType(parsed.InstrumentID)
+				parsed.FlowcellType = printFlowCellType(parsed.FlowcellID)
+				allResults = append(allResults, parsed)
 			}
 
 			outputResults(allResults, prettyPrint)
@@ -305,6 +297,25 @@ func extractRname(inputArg string) (string, error) {
 	return "", fmt.Errorf("no data read from input source '%s'", inputArg)
 }
 
+// parseRname splits an Illumina read name (without '@' and comment) into the
+// instrument, run, flow cell and lane. The lane is "N/A" if the name has none.
+func parseRname(rname string) (RnameOutputData, error) {
+	inputParts := strings.Split(rname, ":")
+	if len(inputParts) < 3 {
+		return RnameOutputData{}, fmt.Errorf("%s (expected at least 3 colon-separated parts)", rname)
+	}
+	data := RnameOutputData{
+		InstrumentID:  inputParts[0],
+		InstrumentRun: inputParts[1],
+		FlowcellID:    inputParts[2],
+		LaneID:        "N/A",
+	}
+	if len(inputParts) >= 4 {
+		data.LaneID = inputParts[3]
+	}
+	return data, nil
+}
+
 func printInstrumentType(instrumentID string) string {
 	if instrumentID == "" || instrumentID == "N/A" {
 		return "N/A"
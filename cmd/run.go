@@ -0,0 +1,283 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aquasecurity/table"
+	"github.com/fatih/color"
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+)
+
+var (
+	runJobs   int
+	runInput  string
+	runLogDir string
+	runDryRun bool
+	runHalt   bool
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run 'COMMAND {}'",
+	Short: "Run a command for every input line in parallel",
+	Long: `Runs a templated shell command once per input line, with a pool of workers.
+Input lines are read from stdin, or from a file given with --input. Empty lines are skipped.
+
+Placeholders:
+  {}    the input line
+  {.}   the input without its extension (and without .gz)
+  {/}   the basename of the input
+  {//}  the directory of the input
+  {/.}  the basename without extension
+  {#}   the 1-based task number
+If the command has no placeholder, the input is appended as the last argument.
+Values are single-quoted for the shell, so inputs with spaces, quotes or
+other special characters stay a single argument: write {.}.bam, not '{.}.bam'.
+
+The combined stdout/stderr of every task is written to <log-dir>/<task>.log,
+and a summary of failed tasks is printed at the end.
+
+Example:
+  ls *.fastq.gz | hey run -j 8 'fastqc -o qc {}'
+  hey run -i samples.txt 'samtools index {/.}.bam'`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if runJobs < 1 {
+			return fmt.Errorf("--jobs must be at least 1")
+		}
+		source := runInput
+		if source == "" {
+			stat, _ := os.Stdin.Stat()
+			if (stat.Mode() & os.ModeCharDevice) != 0 {
+				return fmt.Errorf("no input: pipe a list of inputs into 'hey run' or use --input FILE")
+			}
+			source = "-"
+		}
+		inputs, err := readInputLines(source)
+		if err != nil {
+			return err
+		}
+		if len(inputs) == 0 {
+			color.Yellow("No inputs to process.")
+			return nil
+		}
+		return runParallel(args[0], inputs)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+	runCmd.Flags().IntVarP(&runJobs, "jobs", "j", defaultMaxWorkers, "Number of tasks to run at the same time")
+	runCmd.Flags().StringVarP(&runInput, "input", "i", "", "Read inputs from this file instead of stdin")
+	runCmd.Flags().StringVarP(&runLogDir, "log-dir", "o", "hey_run_logs", "Directory for per-task log files")
+	runCmd.Flags().BoolVarP(&runDryRun, "dry-run", "n", false, "Print the commands without running them")
+	runCmd.Flags().BoolVarP(&runHalt, "halt", "x", false, "Stop starting new tasks after the first failure")
+}
+
+func readInputLines(filename string) ([]string, error) {
+	input, err := openInput(filename)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", filename, err)
+	}
+	defer input.Close()
+
+	var lines []string
+	scanner := newLineScanner(input)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", filename, err)
+	}
+	return lines, nil
+}
+
+// trimExt removes the last extension of a path, looking through a trailing .gz.
+func trimExt(path string) string {
+	path = strings.TrimSuffix(path, ".gz")
+	return strings.TrimSuffix(path, filepath.Ext(path))
+}
+
+var runPlaceholders = []string{"{}", "{.}", "{/}", "{//}", "{/.}", "{#}"}
+
+// expandTemplate fills the placeholders of tmpl with values derived from
+// input, each quoted for the shell like GNU parallel does, so that spaces,
+// quotes and other shell characters in the input stay one argument.
+func expandTemplate(tmpl, input string, index int) string {
+	replacer := strings.NewReplacer(
+		"{//}", shellQuote(filepath.Dir(input)),
+		"{/.}", shellQuote(trimExt(filepath.Base(input))),
+		"{/}", shellQuote(filepath.Base(input)),
+		"{.}", shellQuote(trimExt(input)),
+		"{#}", shellQuote(strconv.Itoa(index)),
+		"{}", shellQuote(input),
+	)
+	for _, p := range runPlaceholders {
+		if strings.Contains(tmpl, p) {
+			return replacer.Replace(tmpl)
+		}
+	}
+	return tmpl + " " + shellQuote(input)
+}
+
+// shellQuote puts s in single quotes for sh, closing and reopening them
+// around every single quote in s.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+type runTask struct {
+	Index    int
+	Input    string
+	Command  string
+	LogPath  string
+	ExitCode int
+	Duration time.Duration
+	Err      error
+	Skipped  bool
+}
+
+func runParallel(tmpl string, inputs []string) error {
+	tasks := make([]*runTask, len(inputs))
+	for i, in := range inputs {
+		tasks[i] = &runTask{
+			Index:   i + 1,
+			Input:   in,
+			Command: expandTemplate(tmpl, in, i+1),
+			LogPath: filepath.Join(runLogDir, fmt.Sprintf("%d.log", i+1)),
+		}
+	}
+
+	if runDryRun {
+		for _, t := range tasks {
+			fmt.Println(t.Command)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(runLogDir, 0o755); err != nil {
+		return fmt.Errorf("creating log directory: %w", err)
+	}
+
+	bar := progressbar.NewOptions(len(tasks),
+		progressbar.OptionSetDescription("[cyan]Running tasks..."),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionShowCount(),
+		progressbar.OptionShowElapsedTimeOnFinish(),
+		progressbar.OptionEnableColorCodes(true),
+		progressbar.OptionSetTheme(progressbar.Theme{Saucer: "[green]=[reset]", SaucerHead: "[green]>[reset]", SaucerPadding: " ", BarStart: "[", BarEnd: "]"}),
+	)
+
+	var halted atomic.Bool // set once a task fails when --halt is used
+	jobs := make(chan *runTask, len(tasks))
+	var wg sync.WaitGroup
+	numWorkers := min(runJobs, len(tasks))
+	wg.Add(numWorkers)
+	for range numWorkers {
+		go func() {
+			defer wg.Done()
+			for t := range jobs {
+				if halted.Load() {
+					t.Skipped = true
+				} else {
+					executeTask(t)
+					if t.Err != nil && runHalt {
+						halted.Store(true)
+					}
+				}
+				_ = bar.Add(1)
+			}
+		}()
+	}
+	for _, t := range tasks {
+		jobs <- t
+	}
+	close(jobs)
+	wg.Wait()
+	_ = bar.Finish()
+	fmt.Fprintln(os.Stderr)
+
+	return summarizeTasks(tasks)
+}
+
+func executeTask(t *runTask) {
+	logFile, err := os.Create(t.LogPath)
+	if err != nil {
+		t.Err = err
+		t.ExitCode = -1
+		return
+	}
+	defer logFile.Close()
+
+	c := exec.Command("sh", "-c", t.Command)
+	c.Stdout = logFile
+	c.Stderr = logFile
+	start := time.Now()
+	err = c.Run()
+	t.Duration = time.Since(start)
+	if err != nil {
+		t.Err = err
+		t.ExitCode = -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			t.ExitCode = exitErr.ExitCode()
+		}
+	}
+}
+
+func summarizeTasks(tasks []*runTask) error {
+	var failedTasks []*runTask
+	skipped := 0
+	for _, t := range tasks {
+		switch {
+		case t.Skipped:
+			skipped++
+		case t.Err != nil:
+			failedTasks = append(failedTasks, t)
+		}
+	}
+	succeeded := len(tasks) - len(failedTasks) - skipped
+
+	if len(failedTasks) > 0 {
+		t := table.New(os.Stdout)
+		t.SetHeaders("#", "Exit", "Time", "Input", "Log")
+		t.SetHeaderStyle(table.StyleBold)
+		t.SetLineStyle(table.StyleBlue)
+		t.SetDividers(table.UnicodeRoundedDividers)
+		for _, task := range failedTasks {
+			t.AddRow(
+				strconv.Itoa(task.Index),
+				color.RedString("%d", task.ExitCode),
+				task.Duration.Round(time.Millisecond).String(),
+				task.Input,
+				task.LogPath,
+			)
+		}
+		t.Render()
+	}
+
+	summary := fmt.Sprintf("%d succeeded, %d failed", succeeded, len(failedTasks))
+	if skipped > 0 {
+		summary += fmt.Sprintf(", %d skipped", skipped)
+	}
+	summary += fmt.Sprintf(" (logs in %s)", runLogDir)
+	if len(failedTasks) > 0 || skipped > 0 {
+		color.Red(summary)
+		return fmt.Errorf("%d of %d tasks failed", len(failedTasks), len(tasks))
+	}
+	color.Green(summary)
+	return nil
+}
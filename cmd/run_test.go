@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		tmpl     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "whole input",
+			tmpl:     "fastqc {}",
+			input:    "raw/a.fastq.gz",
+			expected: "fastqc 'raw/a.fastq.gz'",
+		},
+		{
+			name:     "without extension",
+			tmpl:     "samtools sort -o {.}.sorted.bam {}",
+			input:    "aln/s1.bam",
+			expected: "samtools sort -o 'aln/s1'.sorted.bam 'aln/s1.bam'",
+		},
+		{
+			name:     "basename and directory",
+			tmpl:     "cp {} out/{/} && echo {//}",
+			input:    "raw/lane1/a.fq",
+			expected: "cp 'raw/lane1/a.fq' out/'a.fq' && echo 'raw/lane1'",
+		},
+		{
+			name:     "basename without gz extension",
+			tmpl:     "echo {/.}",
+			input:    "raw/a.fastq.gz",
+			expected: "echo 'a'",
+		},
+		{
+			name:     "task number",
+			tmpl:     "echo {#} {}",
+			input:    "x",
+			expected: "echo '3' 'x'",
+		},
+		{
+			name:     "no placeholder appends input",
+			tmpl:     "gzip -t",
+			input:    "a.gz",
+			expected: "gzip -t 'a.gz'",
+		},
+		{
+			name:     "braces that are not placeholders",
+			tmpl:     "awk '{print $1}'",
+			input:    "a.tsv",
+			expected: "awk '{print $1}' 'a.tsv'",
+		},
+		{
+			name:     "spaces",
+			tmpl:     "echo {}",
+			input:    "my file.txt",
+			expected: "echo 'my file.txt'",
+		},
+		{
+			name:     "shell characters",
+			tmpl:     "cat {} > {/.}.out",
+			input:    "a;rm -rf $HOME`x`.txt",
+			expected: "cat 'a;rm -rf $HOME`x`.txt' > 'a;rm -rf $HOME`x`'.out",
+		},
+		{
+			name:     "single quotes",
+			tmpl:     "echo {}",
+			input:    "it's.txt",
+			expected: `echo 'it'\''s.txt'`,
+		},
+		{
+			name:     "no placeholder quotes appended input",
+			tmpl:     "wc -l",
+			input:    "a b.txt",
+			expected: "wc -l 'a b.txt'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, expandTemplate(tt.tmpl, tt.input, 3))
+		})
+	}
+}
+
+func TestShellQuoteRoundTrip(t *testing.T) {
+	for _, input := range []string{"my file.txt", "it's", `a"b`, "$(id);`x`", "tab\there"} {
+		out, err := exec.Command("sh", "-c", "printf %s "+shellQuote(input)).Output()
+		assert.NoError(t, err)
+		assert.Equal(t, input, string(out))
+	}
+}
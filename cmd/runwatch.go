@@ -0,0 +1,303 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aquasecurity/table"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	runwatchInterval time.Duration
+	runwatchNotify   string
+)
+
+var runwatchCmd = &cobra.Command{
+	Use:   "runwatch <run-folder|parent-folder>...",
+	Short: "Monitor Illumina sequencing run folders",
+	Long: `Shows the progress of Illumina run folders in a table.
+
+Each argument may be a run folder (containing RunInfo.xml) or a folder whose
+direct subdirectories are run folders. The total number of cycles is read from
+RunInfo.xml; the current cycle is taken from the BaseCalls (or Thumbnail_Images)
+cycle directories. A run is finished once RTAComplete.txt is written, and fully
+transferred once CopyComplete.txt appears.
+
+With --interval the table is refreshed until Ctrl+C. Use --notify to run a
+shell command whenever a run completes while watching; '{}' is replaced by the
+run folder path, quoted for the shell, so do not put it in quotes yourself.
+
+Example:
+  hey runwatch /seq/runs
+  hey runwatch /seq/runs -i 5m --notify 'echo {} | mail -s "Run done" me@lab.org'`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if runwatchInterval <= 0 {
+			runs, err := scanRunFolders(args)
+			if err != nil {
+				return err
+			}
+			printRunTable(runs)
+			return nil
+		}
+		return watchRuns(args, runwatchInterval)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(runwatchCmd)
+	runwatchCmd.Flags().DurationVarP(&runwatchInterval, "interval", "i", 0, "Refresh interval, e.g. 30s or 5m (0 = print once)")
+	runwatchCmd.Flags().StringVar(&runwatchNotify, "notify", "", "Shell command to run when a run completes ('{}' = run path)")
+}
+
+// runInfoXML mirrors the parts of RunInfo.xml that are needed here.
+type runInfoXML struct {
+	Run struct {
+		ID         string `xml:"Id,attr"`
+		Flowcell   string `xml:"Flowcell"`
+		Instrument string `xml:"Instrument"`
+		Date       string `xml:"Date"`
+		Reads      []struct {
+			NumCycles int    `xml:"NumCycles,attr"`
+			IsIndexed string `xml:"IsIndexedRead,attr"`
+		} `xml:"Reads>Read"`
+	} `xml:"Run"`
+}
+
+type runStatus struct {
+	Path         string
+	Name         string
+	Instrument   string
+	Flowcell     string
+	ReadLayout   string
+	TotalCycles  int
+	CurrentCycle int
+	RTAComplete  bool
+	CopyComplete bool
+	LastUpdate   time.Time
+	Err          error
+}
+
+func (r runStatus) done() bool {
+	return r.RTAComplete && r.CopyComplete
+}
+
+// scanRunFolders resolves the arguments into run folders and reads their status.
+func scanRunFolders(paths []string) ([]runStatus, error) {
+	var runDirs []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("cannot access %q: %w", p, err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("%q is not a directory", p)
+		}
+		if fileExists(filepath.Join(p, "RunInfo.xml")) {
+			runDirs = append(runDirs, p)
+			continue
+		}
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, fmt.Errorf("reading %q: %w", p, err)
+		}
+		for _, e := range entries {
+			sub := filepath.Join(p, e.Name())
+			if e.IsDir() && fileExists(filepath.Join(sub, "RunInfo.xml")) {
+				runDirs = append(runDirs, sub)
+			}
+		}
+	}
+	sort.Strings(runDirs)
+
+	runs := make([]runStatus, 0, len(runDirs))
+	for _, dir := range runDirs {
+		runs = append(runs, readRunStatus(dir))
+	}
+	return runs, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func readRunStatus(dir string) runStatus {
+	status := runStatus{Path: dir, Name: filepath.Base(dir)}
+
+	data, err := os.ReadFile(filepath.Join(dir, "RunInfo.xml"))
+	if err != nil {
+		status.Err = err
+		return status
+	}
+	var info runInfoXML
+	if err := xml.Unmarshal(data, &info); err != nil {
+		status.Err = fmt.Errorf("parsing RunInfo.xml: %w", err)
+		return status
+	}
+	status.Instrument = info.Run.Instrument
+	status.Flowcell = info.Run.Flowcell
+	var layout []string
+	for _, read := range info.Run.Reads {
+		status.TotalCycles += read.NumCycles
+		if strings.EqualFold(read.IsIndexed, "Y") {
+			layout = append(layout, fmt.Sprintf("i%d", read.NumCycles))
+		} else {
+			layout = append(layout, strconv.Itoa(read.NumCycles))
+		}
+	}
+	status.ReadLayout = strings.Join(layout, "+")
+
+	status.RTAComplete = fileExists(filepath.Join(dir, "RTAComplete.txt"))
+	status.CopyComplete = fileExists(filepath.Join(dir, "CopyComplete.txt"))
+	status.CurrentCycle, status.LastUpdate = currentCycle(dir)
+	if status.RTAComplete {
+		status.CurrentCycle = status.TotalCycles
+	}
+	return status
+}
+
+// currentCycle returns the highest cycle directory (C<n>.1) found for lane 1
+// and the time it was last modified.
+func currentCycle(dir string) (int, time.Time) {
+	candidates := []string{
+		filepath.Join(dir, "Data", "Intensities", "BaseCalls", "L001"),
+		filepath.Join(dir, "Thumbnail_Images", "L001"),
+	}
+	for _, laneDir := range candidates {
+		entries, err := os.ReadDir(laneDir)
+		if err != nil {
+			continue
+		}
+		maxCycle := 0
+		var modified time.Time
+		for _, e := range entries {
+			name := e.Name()
+			if !e.IsDir() || !strings.HasPrefix(name, "C") || !strings.HasSuffix(name, ".1") {
+				continue
+			}
+			n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "C"), ".1"))
+			if err != nil || n <= maxCycle {
+				continue
+			}
+			maxCycle = n
+			if info, err := e.Info(); err == nil {
+				modified = info.ModTime()
+			}
+		}
+		if maxCycle > 0 {
+			return maxCycle, modified
+		}
+	}
+	return 0, time.Time{}
+}
+
+func progressBar(current, total, width int) string {
+	if total <= 0 {
+		return strings.Repeat("·", width)
+	}
+	filled := min(width, current*width/total)
+	return strings.Repeat("█", filled) + strings.Repeat("·", width-filled)
+}
+
+func printRunTable(runs []runStatus) {
+	if len(runs) == 0 {
+		color.Yellow("No run folders (with RunInfo.xml) found.")
+		return
+	}
+	t := table.New(os.Stdout)
+	t.SetHeaders("Run", "Instrument", "Flowcell", "Reads", "Cycle", "Progress", "Status")
+	t.SetHeaderStyle(table.StyleBold)
+	t.SetLineStyle(table.StyleBlue)
+	t.SetDividers(table.UnicodeRoundedDividers)
+
+	for _, r := range runs {
+		if r.Err != nil {
+			t.AddRow(r.Name, "N/A", "N/A", "N/A", "N/A", "", color.RedString("Error: %v", r.Err))
+			continue
+		}
+		var status string
+		switch {
+		case r.done():
+			status = color.GreenString("Complete")
+		case r.RTAComplete:
+			status = color.CyanString("Copying")
+		case r.CurrentCycle == 0:
+			status = color.YellowString("Starting")
+		default:
+			status = color.YellowString("Sequencing")
+			if !r.LastUpdate.IsZero() {
+				status += fmt.Sprintf(" (%s ago)", time.Since(r.LastUpdate).Round(time.Minute))
+			}
+		}
+		pct := 0.0
+		if r.TotalCycles > 0 {
+			pct = float64(r.CurrentCycle) / float64(r.TotalCycles) * 100
+		}
+		t.AddRow(
+			r.Name,
+			r.Instrument,
+			r.Flowcell,
+			r.ReadLayout,
+			fmt.Sprintf("%d/%d", r.CurrentCycle, r.TotalCycles),
+			fmt.Sprintf("%s %3.0f%%", progressBar(r.CurrentCycle, r.TotalCycles, 20), pct),
+			status,
+		)
+	}
+	t.Render()
+}
+
+func watchRuns(paths []string, interval time.Duration) error {
+	interruptChan := make(chan os.Signal, 1)
+	signal.Notify(interruptChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interruptChan)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	wasDone := make(map[string]bool)
+	first := true
+	for {
+		runs, err := scanRunFolders(paths)
+		if err != nil {
+			return err
+		}
+		fmt.Print("\033[H\033[2J") // Clear the screen before redrawing
+		printRunTable(runs)
+		fmt.Printf("Updated %s, refreshing every %s (Ctrl+C to stop)\n", time.Now().Format("15:04:05"), interval)
+
+		for _, r := range runs {
+			if r.done() && !wasDone[r.Path] && !first && runwatchNotify != "" {
+				notifyRunComplete(runwatchNotify, r.Path)
+			}
+			wasDone[r.Path] = r.done()
+		}
+		first = false
+
+		select {
+		case <-interruptChan:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func notifyRunComplete(command, runPath string) {
+	shellCmd := strings.ReplaceAll(command, "{}", shellQuote(runPath))
+	out, err := exec.Command("sh", "-c", shellCmd).CombinedOutput()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Notification command failed for %s: %v\n%s", runPath, err, out)
+	}
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRunInfo = `<?xml version="1.0"?>
+<RunInfo Version="5">
+  <Run Id="240101_A00123_0001_AHXXXXXX" Number="1">
+    <Flowcell>HXXXXXX</Flowcell>
+    <Instrument>A00123</Instrument>
+    <Date>1/1/2024</Date>
+    <Reads>
+      <Read Number="1" NumCycles="151" IsIndexedRead="N" />
+      <Read Number="2" NumCycles="8" IsIndexedRead="Y" />
+      <Read Number="3" NumCycles="151" IsIndexedRead="N" />
+    </Reads>
+  </Run>
+</RunInfo>
+`
+
+// writeTestRun creates the run folder name in dir, with the lane 1 cycle
+// directories up to cycles and the given marker files.
+func writeTestRun(t *testing.T, dir, name string, cycles int, markers ...string) string {
+	t.Helper()
+	run := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(run, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(run, "RunInfo.xml"), []byte(testRunInfo), 0o644))
+	for c := 1; c <= cycles; c++ {
+		cycleDir := filepath.Join(run, "Data", "Intensities", "BaseCalls", "L001", "C"+strconv.Itoa(c)+".1")
+		require.NoError(t, os.MkdirAll(cycleDir, 0o755))
+	}
+	for _, m := range markers {
+		require.NoError(t, os.WriteFile(filepath.Join(run, m), nil, 0o644))
+	}
+	return run
+}
+
+func TestReadRunStatus(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name         string
+		cycles       int
+		markers      []string
+		currentCycle int
+		rtaComplete  bool
+		copyComplete bool
+	}{
+		{name: "starting", cycles: 0},
+		{name: "sequencing", cycles: 42, currentCycle: 42},
+		{name: "copying", cycles: 310, markers: []string{"RTAComplete.txt"}, currentCycle: 310, rtaComplete: true},
+		{name: "complete", cycles: 3, markers: []string{"RTAComplete.txt", "CopyComplete.txt"}, currentCycle: 310, rtaComplete: true, copyComplete: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := writeTestRun(t, dir, tt.name, tt.cycles, tt.markers...)
+			status := readRunStatus(run)
+			require.NoError(t, status.Err)
+			assert.Equal(t, tt.name, status.Name)
+			assert.Equal(t, "A00123", status.Instrument)
+			assert.Equal(t, "HXXXXXX", status.Flowcell)
+			assert.Equal(t, "151+i8+151", status.ReadLayout)
+			assert.Equal(t, 310, status.TotalCycles)
+			assert.Equal(t, tt.currentCycle, status.CurrentCycle)
+			assert.Equal(t, tt.rtaComplete, status.RTAComplete)
+			assert.Equal(t, tt.copyComplete, status.CopyComplete)
+			assert.Equal(t, tt.rtaComplete && tt.copyComplete, status.done())
+		})
+	}
+
+	t.Run("invalid RunInfo.xml", func(t *testing.T) {
+		run := filepath.Join(dir, "broken")
+		require.NoError(t, os.MkdirAll(run, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(run, "RunInfo.xml"), []byte("<RunInfo>"), 0o644))
+		assert.Error(t, readRunStatus(run).Err)
+	})
+}
+
+func TestCurrentCycle(t *testing.T) {
+	dir := t.TempDir()
+	run := writeTestRun(t, dir, "run", 12)
+	laneDir := filepath.Join(run, "Data", "Intensities", "BaseCalls", "L001")
+	// Neither files nor other directories are cycles.
+	require.NoError(t, os.WriteFile(filepath.Join(laneDir, "C99.1"), nil, 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(laneDir, "C50.2"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(laneDir, "Cx.1"), 0o755))
+
+	cycle, modified := currentCycle(run)
+	assert.Equal(t, 12, cycle)
+	assert.False(t, modified.IsZero())
+
+	thumbs := filepath.Join(dir, "thumbs")
+	require.NoError(t, os.MkdirAll(filepath.Join(thumbs, "Thumbnail_Images", "L001", "C7.1"), 0o755))
+	cycle, _ = currentCycle(thumbs)
+	assert.Equal(t, 7, cycle)
+
+	cycle, modified = currentCycle(filepath.Join(dir, "missing"))
+	assert.Equal(t, 0, cycle)
+	assert.True(t, modified.IsZero())
+}
+
+func TestScanRunFolders(t *testing.T) {
+	dir := t.TempDir()
+	writeTestRun(t, dir, "run_b", 1)
+	writeTestRun(t, dir, "run_a", 1)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "not_a_run"), 0o755))
+
+	runs, err := scanRunFolders([]string{dir})
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "run_a", runs[0].Name)
+	assert.Equal(t, "run_b", runs[1].Name)
+
+	runs, err = scanRunFolders([]string{filepath.Join(dir, "run_b")})
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, "run_b", runs[0].Name)
+
+	_, err = scanRunFolders([]string{filepath.Join(dir, "missing")})
+	assert.Error(t, err)
+}
+
+func TestProgressBar(t *testing.T) {
+	tests := []struct {
+		current, total, width int
+		expected              string
+	}{
+		{0, 10, 10, "··········"},
+		{5, 10, 10, "█████·····"},
+		{10, 10, 10, "██████████"},
+		{15, 10, 10, "██████████"},
+		{1, 3, 4, "█···"},
+		{3, 0, 4, "····"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, progressBar(tt.current, tt.total, tt.width))
+	}
+}
+
+func TestNotifyRunCompleteQuotesPath(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "notified")
+	for _, name := range []string{"run one", "it's done", "run;touch pwned", `a"b$(id)`} {
+		run := filepath.Join(dir, name)
+		notifyRunComplete("printf %s {} > "+shellQuote(out), run)
+		got, err := os.ReadFile(out)
+		require.NoError(t, err)
+		assert.Equal(t, run, string(got))
+	}
+	assert.NoFileExists(t, "pwned")
+	assert.NoFileExists(t, filepath.Join(dir, "pwned"))
+}
@@ -1,27 +1,67 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
+	"io"
+	"math/rand"
 	"os"
 	"os/signal"
-	"strconv"
+	"regexp"
+	"slices"
 	"strings"
 	"syscall"
+	"time"
+	"unicode/utf8"
 
+	"sync"
 	"sync/atomic"
 
 	"github.com/liamg/tml"
 	"github.com/spf13/cobra"
+	"github.com/yech1990/hey/internal/render"
+	"github.com/yech1990/hey/pkg/sam"
 )
 
+// samStyle is how sam2pairwise renders bases, set from --palette, -q and
+// --no-color.
+var samStyle = render.Options{Palette: render.Palettes["default"]}
+
 var (
-	knownMutation     string
-	knownMutationMark string
+	knownMutations     []string
+	knownMutationMarks []string
 	filterForward     bool
 	filterReverse     bool
 	tagKeys           []string // For storing custom tags from -t flag
 	qualityCutoff     int      // Quality score cutoff
+	samReference      string   // Reference FASTA for CRAM input
+	samRegionFlag     string   // Only show reads overlapping this region
+	samMinMapQ        int      // Skip alignments with a lower MAPQ
+	samRequireFlags   string   // Only show alignments with all of these flags
+	samExcludeFlags   string   // Skip alignments with any of these flags
+	samHTML           string   // Write alignments to this HTML file
+	samNoColor        bool     // Print plain text without color tags
+	samPalette        string   // Name of the base color palette
+	samShowMods       bool     // Overlay MM/ML base modification calls
+	samModThreshold   float64  // Minimum probability of shown modifications
+	samWidth          int      // Wrap alignments wider than this
+	samRuler          bool     // Show a reference coordinate track
+	samSummaryFlag    bool     // Print match/mismatch statistics at the end
+	samOutDir         string   // Write every alignment to a file in this directory
+	samOutFormat      string   // Format of the files in samOutDir
+	samNames          []string // Only show reads with these names
+	samHideClips      bool     // Collapse soft clips into their length
+	samShowQual       string   // Show base qualities as "blocks" or "raw"
+	samBisulfite      bool     // Mark C>T or G>A per read strand
+	samThreads        int      // Number of records rendered at the same time
+	samNumRecords     int      // Stop after this many rendered records
+	samSample         float64  // Fraction of records to render
+	samSeed           int64    // Random seed for samSample
+	samSkipLowQuality bool     // Leave bases below qualityCutoff out of the summary
+	samIndelsOnly     bool     // Collapse long runs of matches
+	samContext        int      // Matches kept around indels and mismatches with samIndelsOnly
+	samChunkSize      int      // Stream alignments with more columns in chunks of this size
+	samProgress       bool     // Show the progress of streamed alignments
+	samNameRegex      string   // Only show reads with names matching this
 )
 
 const (
@@ -30,10 +70,23 @@ const (
 )
 
 var sam2pairwiseCmd = &cobra.Command{
-	Use:     "sam2pairwise [-m REF>ALT] [-l MARK] [-f] [-r] [-t TAG]...",
+	Use:     "sam2pairwise [-m REF>ALT]... [-l MARK]... [-f] [-r] [-t TAG]... [file]...",
 	Aliases: []string{"sam", "s2p"}, // Alias added
-	Short:   "Convert SAM records from stdin into pairwise alignment format",
+	Short:   "Convert SAM/BAM records into pairwise alignment format",
 	Long: `Processes SAM records, parsing CIGAR and MD tags to generate pairwise alignments.
+Records are read from the given files in order, or from stdin without files.
+Gzipped SAM, BAM and CRAM input are detected automatically. CRAM is decoded
+with samtools, which must be in PATH; pass the FASTA it was compressed against
+with --reference.
+Use -n to stop after the first N records shown, and --sample 0.01 to show a
+random 1% of the records (reproducibly with --seed), for a quick look at a
+large file.
+Records are rendered by --threads workers at the same time (default 4); the
+output keeps the order of the input.
+Records without a sequence (SEQ "*", e.g. secondary alignments) are shown
+with ? for every read base and the reference bases the MD tag gives. For
+CIGARs with =/X operations no MD tag is needed to tell matches from
+mismatches.
 
 Highlighting Logic (with -m REF>ALT, e.g., -m C>T):
   - The specific REF>ALT mutation (C>T) is NOT highlighted.
@@ -41,6 +94,22 @@ Highlighting Logic (with -m REF>ALT, e.g., -m C>T):
   - Other mutations involving the REF base (e.g., C>A, C>G) ARE highlighted.
   - All other mismatches not involving the specified REF base ARE highlighted.
   - Matches not involving the specified REF base are NOT highlighted.
+  -m can be repeated, e.g. -m C>T -m G>A for bisulfite or RNA-editing data
+  where both strands show expected changes. Each mutation is marked with the
+  -l mark at the same position (-l . -l :), or the last -l given.
+
+Bisulfite Mode (--bisulfite):
+  The conversion expected for the strand a read comes from is treated like a
+  -m mutation: C>T for the original top strand, G>A for the bottom strand.
+  The strand is taken from the XG (Bismark), YD (bwa-meth) or XS tag, or else
+  from the flags of a directional library (read 1 forward or read 2 reverse
+  is top strand). Converted bases get the first -l mark and only unexpected
+  mismatches are highlighted.
+
+Base Quality (-q INT):
+  Bases with a quality below INT are dimmed. Their mismatches are not
+  highlighted, to tell likely sequencing errors from real variation. With
+  --summary-skip-lowq, they are also left out of the --summary counts.
 
 Highlighting Logic (without -m):
   - All mismatches are highlighted with a colored background.
@@ -50,6 +119,16 @@ Filtering Options:
   -f, --forward: Only process Read 1 Forward or Read 2 Reverse reads.
   -r, --reverse: Only process Read 1 Reverse or Read 2 Forward reads.
   (If neither -f nor -r is specified, all reads are processed).
+  --min-mapq INT: Skip alignments with a mapping quality below INT.
+  --require-flags, --exclude-flags: Keep only alignments with all of the given
+  FLAG bits, or drop those with any of them, like samtools view -f/-F. Bits
+  can be given as a number (0x900) or by name (SECONDARY,SUPPLEMENTARY,DUP,
+  UNMAP, ...).
+  --name NAME, --name-regex PATTERN: Only show the reads with one of the given
+  names, or whose name matches PATTERN, to pick out single reads from a large
+  file.
+  --region chr:start-end: Only process reads overlapping the interval. Indexed
+  BAM files (with a .bai next to them) are read from the region directly.
 
 Marking Mismatches:
   Optionally, use -m REF>ALT (e.g., -m C>T) and -l MARK (e.g., -l '.')
@@ -60,6 +139,73 @@ Output Tags:
   information line. If -t is not used, the MD tag is shown by default.
   Multiple -t flags can be used.
 
+Plain Text:
+  Use --no-color, or set NO_COLOR, to print the alignments without any color
+  codes, for redirecting to files or piping into grep.
+
+Base Modifications:
+  With --mods, modification calls from the MM/ML tags (e.g. 5mC and 6mA from
+  nanopore basecallers) with a probability of at least --mod-threshold are
+  shown as lowercase, underlined bases on the read, colored by type: m (5mC)
+  magenta, h (5hmC) cyan, a (6mA) light red and other codes green.
+
+Colors:
+  --palette selects the background colors of highlighted bases: default,
+  igv, colorblind, or a palette defined in hey/palettes.yaml of the user
+  config directory (~/.config on Linux), which maps palette names to
+  base: color pairs, e.g. "mine: {A: lightred, T: cyan}".
+  $HEY_PALETTE sets the palette for sam2pairwise and fastq.
+
+HTML Report:
+  Use --html out.html to write the colored alignments to a standalone web page
+  instead of the terminal, to share them with people who don't use one.
+
+Base Qualities:
+  --show-qual adds a row below the reference with the quality of every read
+  base, as the colored blocks of hey fastq (│ Q40+, ▓ Q30+, ▒ Q20+, ░ Q10+,
+  · below), or as the raw phred characters with --show-qual=raw. Deletions
+  are shown as -.
+
+Long Reads:
+  Alignments longer than --chunk-size columns (default 10000), like PacBio or
+  ONT reads, are aligned, rendered and printed in chunks of that size as they
+  go instead of all at once, so only one chunk is held in memory. Each chunk
+  is wrapped like a shorter alignment.
+  --progress shows how far a long alignment is on stderr.
+
+Indels Only:
+  --indels-only collapses every long run of matches into its length, e.g.
+  ..120=.., keeping --context matches (default 10) on each side, so that only
+  the windows around insertions, deletions and mismatches of long reads are
+  shown.
+
+Soft Clips:
+  Soft-clipped bases are shown on the read against dots. With --hide-clips,
+  every clip is collapsed into its length, e.g. [120S], so that clipped
+  adapters of long reads don't dominate the display.
+
+Wrapping:
+  Alignments wider than the terminal (or --width) are cut into blocks, like
+  BLAST pairwise output, with the first and last read (Query) and reference
+  (Ref) position of every block. Output that does not go to a terminal is
+  only wrapped with --width.
+  --ruler adds a track below the reference with a tick at every genomic
+  position divisible by 10, labelled where there is room.
+
+Exporting:
+  --out-dir DIR writes every alignment shown to DIR as well, in a file named
+  after the read, for loading into tools like Jalview. --out-format fasta
+  (default) writes the reference and read as aligned FASTA (.fa), emboss
+  writes the pair format of EMBOSS needle (.pair). Reads seen more than once
+  get a numbered suffix.
+
+Summary:
+  --summary prints tables after the alignments with the number of reads,
+  matches, mismatches, insertions and deletions, and the count of every
+  substitution type (e.g. C>T) relative to the reference base and to all
+  mismatches. With -m, the rate of each known mutation is shown as well, for
+  quick damage or editing profiles. Bases without an MD tag are not counted.
+
 Long Intron Formatting (>20 Ns):
   Introns (N operations) longer than 20 bases are condensed in the output:
   Ref:   <darkgrey>NNNNN..[count]nt...NNNNN</darkgrey>
@@ -67,31 +213,210 @@ Long Intron Formatting (>20 Ns):
   Marker:        [spaces matching width]`,
 	SilenceUsage: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if len(knownMutationMark) > 1 {
-			return fmt.Errorf("-l mark must be a single character")
-		}
-		if knownMutation != "" && (len(knownMutation) != 3 || knownMutation[1] != '>') {
-			return fmt.Errorf("-m mutation format must be REF>ALT, for example C>T")
+		mutations, err := parseKnownMutations(knownMutations, knownMutationMarks)
+		if err != nil {
+			return err
 		}
 		if filterForward && filterReverse {
 			return fmt.Errorf("cannot use -f and -r flags simultaneously")
 		}
-		processSAMStdin()
-		return nil
+		if samMinMapQ < 0 || samMinMapQ > 255 {
+			return fmt.Errorf("--min-mapq must be between 0 and 255")
+		}
+		if samShowQual != "" && samShowQual != "blocks" && samShowQual != "raw" {
+			return fmt.Errorf("--show-qual must be blocks or raw")
+		}
+		if samSample <= 0 || samSample > 1 {
+			return fmt.Errorf("--sample must be a fraction between 0 and 1")
+		}
+		if samNumRecords < 0 {
+			return fmt.Errorf("--num-records must not be negative")
+		}
+		if samChunkSize < 0 {
+			return fmt.Errorf("--chunk-size must not be negative")
+		}
+		if samContext < 0 {
+			return fmt.Errorf("--context must not be negative")
+		}
+		if samModThreshold < 0 || samModThreshold > 1 {
+			return fmt.Errorf("--mod-threshold must be between 0 and 1")
+		}
+		palette, err := loadPalette(samPalette)
+		if err != nil {
+			return err
+		}
+		samStyle = render.Options{Palette: palette, QualityCutoff: qualityCutoff}
+		requireFlags, err := parseSAMFlags(samRequireFlags)
+		if err != nil {
+			return fmt.Errorf("--require-flags: %w", err)
+		}
+		excludeFlags, err := parseSAMFlags(samExcludeFlags)
+		if err != nil {
+			return fmt.Errorf("--exclude-flags: %w", err)
+		}
+		var region *samRegion
+		if samRegionFlag != "" {
+			if region, err = parseSAMRegion(samRegionFlag); err != nil {
+				return err
+			}
+		}
+		filter := samNameFilter{}
+		if len(samNames) > 0 {
+			filter.names = make(map[string]bool)
+			for _, name := range samNames {
+				filter.names[name] = true
+			}
+		}
+		if samNameRegex != "" {
+			if filter.regex, err = regexp.Compile(samNameRegex); err != nil {
+				return fmt.Errorf("--name-regex: %w", err)
+			}
+		}
+		if len(args) == 0 {
+			args = []string{"-"}
+		}
+		return processSAM(args, region, requireFlags, excludeFlags, mutations, filter)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(sam2pairwiseCmd)
-	sam2pairwiseCmd.Flags().StringVarP(&knownMutation, "mutation", "m", "", "Known mutation to affect highlighting (e.g., C>T)")
-	sam2pairwiseCmd.Flags().StringVarP(&knownMutationMark, "mark", "l", ".", "Single character to use for marking the known mutation")
+	sam2pairwiseCmd.Flags().StringSliceVarP(&knownMutations, "mutation", "m", nil, "Known mutation to affect highlighting (e.g., C>T). Can be used multiple times.")
+	sam2pairwiseCmd.Flags().StringSliceVarP(&knownMutationMarks, "mark", "l", []string{"."}, "Single character to use for marking the known mutation, one per -m (the last is reused)")
 	sam2pairwiseCmd.Flags().BoolVarP(&filterForward, "forward", "f", false, "Filter for Read 1 Forward or Read 2 Reverse")
 	sam2pairwiseCmd.Flags().BoolVarP(&filterReverse, "reverse", "r", false, "Filter for Read 1 Reverse or Read 2 Forward")
 	sam2pairwiseCmd.Flags().StringSliceVarP(&tagKeys, "tag", "t", []string{"MD"}, "Tag(s) to show in the name line (default MD). Can be used multiple times.")
-	sam2pairwiseCmd.Flags().IntVarP(&qualityCutoff, "quality-cutoff", "q", 0, "Quality score cutoff for highlighting bases (default 0, disabled)")
+	sam2pairwiseCmd.Flags().IntVarP(&qualityCutoff, "quality-cutoff", "q", 0, "Dim bases below this quality and don't highlight their mismatches (default 0, disabled)")
+	sam2pairwiseCmd.Flags().StringVar(&samReference, "reference", "", "Reference FASTA for decoding CRAM input")
+	sam2pairwiseCmd.Flags().IntVar(&samMinMapQ, "min-mapq", 0, "Skip alignments with MAPQ below this value (like samtools view -q)")
+	sam2pairwiseCmd.Flags().StringVar(&samRequireFlags, "require-flags", "", "Only show alignments with all of these FLAG bits (number or names, like samtools view -f)")
+	sam2pairwiseCmd.Flags().StringVar(&samExcludeFlags, "exclude-flags", "", "Skip alignments with any of these FLAG bits (number or names, like samtools view -F)")
+	sam2pairwiseCmd.Flags().StringVar(&samHTML, "html", "", "Write the alignments to this standalone HTML file instead of the terminal")
+	sam2pairwiseCmd.Flags().BoolVar(&samNoColor, "no-color", false, "Print plain text without colors (also enabled by the NO_COLOR environment variable)")
+	sam2pairwiseCmd.Flags().StringVar(&samPalette, "palette", "", "Base colors: default, igv, colorblind or a palette from the config file (default $HEY_PALETTE)")
+	sam2pairwiseCmd.Flags().BoolVar(&samShowMods, "mods", false, "Mark base modification calls from MM/ML tags on the read")
+	sam2pairwiseCmd.Flags().Float64Var(&samModThreshold, "mod-threshold", 0.5, "Minimum probability of modification calls shown with --mods")
+	sam2pairwiseCmd.Flags().IntVarP(&samWidth, "width", "w", 0, "Wrap alignments into blocks of this width (default: terminal width, -1 to disable)")
+	sam2pairwiseCmd.Flags().BoolVar(&samRuler, "ruler", false, "Show a reference coordinate ruler below each alignment")
+	sam2pairwiseCmd.Flags().BoolVar(&samHideClips, "hide-clips", false, "Collapse soft-clipped bases into their count, e.g. [120S]")
+	sam2pairwiseCmd.Flags().StringVar(&samShowQual, "show-qual", "", "Show base qualities below the reference as blocks (like hey fastq) or raw phred characters")
+	sam2pairwiseCmd.Flags().Lookup("show-qual").NoOptDefVal = "blocks"
+	sam2pairwiseCmd.Flags().BoolVar(&samBisulfite, "bisulfite", false, "Mark the bisulfite conversion of each read's strand (C>T or G>A) instead of highlighting it")
+	sam2pairwiseCmd.Flags().IntVar(&samThreads, "threads", defaultMaxWorkers, "Number of records to render at the same time; output keeps the input order")
+	sam2pairwiseCmd.Flags().IntVarP(&samNumRecords, "num-records", "n", 0, "Stop after rendering this many records (default 0, all)")
+	sam2pairwiseCmd.Flags().Float64Var(&samSample, "sample", 1, "Render a random fraction of the records, e.g. 0.01")
+	sam2pairwiseCmd.Flags().Int64Var(&samSeed, "seed", 0, "Random seed for --sample (default 0, different every run)")
+	sam2pairwiseCmd.Flags().BoolVar(&samIndelsOnly, "indels-only", false, "Collapse long runs of matches, showing only windows around indels and mismatches")
+	sam2pairwiseCmd.Flags().IntVar(&samContext, "context", 10, "Matches shown on each side of indels and mismatches with --indels-only")
+	sam2pairwiseCmd.Flags().IntVar(&samChunkSize, "chunk-size", 10000, "Render alignments longer than this many columns in chunks as they are read (0 to disable)")
+	sam2pairwiseCmd.Flags().BoolVar(&samProgress, "progress", false, "Show a progress bar on stderr while rendering chunked alignments")
+	sam2pairwiseCmd.Flags().BoolVar(&samSummaryFlag, "summary", false, "Print match, mismatch, substitution and indel counts after the alignments")
+	sam2pairwiseCmd.Flags().BoolVar(&samSkipLowQuality, "summary-skip-lowq", false, "Leave bases with a quality below -q out of the --summary counts")
+	sam2pairwiseCmd.Flags().StringVar(&samOutDir, "out-dir", "", "Also write every alignment to a file named after the read in this directory")
+	sam2pairwiseCmd.Flags().StringVar(&samOutFormat, "out-format", "fasta", "Format of the files written to --out-dir: fasta or emboss")
+	sam2pairwiseCmd.Flags().StringSliceVar(&samNames, "name", nil, "Only show reads with this name. Can be used multiple times.")
+	sam2pairwiseCmd.Flags().StringVar(&samNameRegex, "name-regex", "", "Only show reads whose name matches this regular expression")
+	sam2pairwiseCmd.Flags().StringVar(&samRegionFlag, "region", "", "Only show reads overlapping chr:start-end (uses the .bai index of BAM files)")
+}
+
+// samMutation is an expected substitution given with -m, which is marked
+// instead of highlighted.
+type samMutation struct {
+	Ref, Alt byte
+	Mark     rune
+}
+
+// parseKnownMutations parses the -m REF>ALT specs, pairing each with the -l
+// mark at the same position, or the last mark if there are fewer marks.
+func parseKnownMutations(specs, marks []string) ([]samMutation, error) {
+	for _, mark := range marks {
+		if utf8.RuneCountInString(mark) != 1 {
+			return nil, fmt.Errorf("-l mark must be a single character")
+		}
+	}
+	var mutations []samMutation
+	for i, spec := range specs {
+		if len(spec) != 3 || spec[1] != '>' {
+			return nil, fmt.Errorf("-m mutation format must be REF>ALT, for example C>T")
+		}
+		mark := '.'
+		if len(marks) > 0 {
+			mark = []rune(marks[min(i, len(marks)-1)])[0]
+		}
+		mutations = append(mutations, samMutation{Ref: spec[0], Alt: spec[2], Mark: mark})
+	}
+	return mutations, nil
+}
+
+func findKnownMutation(mutations []samMutation, refBase, readBase byte) (samMutation, bool) {
+	for _, m := range mutations {
+		if m.Ref == refBase && m.Alt == readBase {
+			return m, true
+		}
+	}
+	return samMutation{}, false
+}
+
+// bisulfiteMutation returns the conversion expected in the bisulfite reads of
+// record against the reference: C>T for reads from the original top strand,
+// G>A for the bottom strand. The strand is taken from the XG (Bismark), YD
+// (bwa-meth) or XS tag if present, otherwise from the flags assuming a
+// directional library: read 1 forward and read 2 reverse are top strand.
+func bisulfiteMutation(record *samRecord, mark rune) samMutation {
+	top := samMutation{Ref: 'C', Alt: 'T', Mark: mark}
+	bottom := samMutation{Ref: 'G', Alt: 'A', Mark: mark}
+	if xg, ok := record.tag("XG"); ok && (xg == "CT" || xg == "GA") {
+		if xg == "CT" {
+			return top
+		}
+		return bottom
+	}
+	if yd, ok := record.tag("YD"); ok && (yd == "f" || yd == "r") {
+		if yd == "f" {
+			return top
+		}
+		return bottom
+	}
+	if xs, ok := record.tag("XS"); ok && (xs == "+" || xs == "-") {
+		if xs == "+" {
+			return top
+		}
+		return bottom
+	}
+	isRead2 := record.Flag&0x1 != 0 && record.Flag&0x80 != 0
+	isReverse := record.Flag&0x10 != 0
+	if isReverse != isRead2 {
+		return bottom
+	}
+	return top
+}
+
+func isKnownMutationRef(mutations []samMutation, refBase byte) bool {
+	for _, m := range mutations {
+		if m.Ref == refBase {
+			return true
+		}
+	}
+	return false
 }
 
-func processSAMStdin() {
+// samNameFilter selects reads by name, from a list of names and/or a regular
+// expression. A read has to pass both if both are given.
+type samNameFilter struct {
+	names map[string]bool
+	regex *regexp.Regexp
+}
+
+func (f samNameFilter) match(name string) bool {
+	if f.names != nil && !f.names[name] {
+		return false
+	}
+	return f.regex == nil || f.regex.MatchString(name)
+}
+
+// processSAM renders the SAM, BAM or CRAM records of the files in order;
+// "-" reads stdin. If region is set, only reads overlapping it are shown.
+func processSAM(filenames []string, region *samRegion, requireFlags, excludeFlags int, mutations []samMutation, filter samNameFilter) error {
 	interruptChan := make(chan os.Signal, 1)
 	signal.Notify(interruptChan, syscall.SIGINT, syscall.SIGTERM)
 	continueProcessing := int32(1)
@@ -101,459 +426,356 @@ func processSAMStdin() {
 		atomic.StoreInt32(&continueProcessing, 0)
 	}()
 
-	scanner := bufio.NewScanner(os.Stdin)
+	reader := &multiSAMReader{filenames: filenames, reference: samReference, region: region}
 
-	var knownRefBase, knownAltBase byte
-	useKnownMutation := false
-	if knownMutation != "" {
-		knownRefBase = knownMutation[0]
-		knownAltBase = knownMutation[2]
-		useKnownMutation = true
+	noColor := samNoColor || os.Getenv("NO_COLOR") != ""
+	samStyle.NoColor = noColor && samHTML == ""
+	width := samWidth
+	if width == 0 && samHTML == "" {
+		width = terminalWidth()
 	}
-	markChar := '.'
-	if len(knownMutationMark) > 0 {
-		markChar = []rune(knownMutationMark)[0]
+	var htmlWriter *htmlReport
+	numWritten := 0
+	bisulfiteMark := '.'
+	if len(knownMutationMarks) > 0 {
+		bisulfiteMark = []rune(knownMutationMarks[0])[0]
 	}
-
-	for atomic.LoadInt32(&continueProcessing) == 1 && scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "@") {
-			continue
+	var summary *samSummary
+	if samSummaryFlag {
+		summary = newSAMSummary()
+	}
+	var exporter *samExporter
+	if samOutDir != "" {
+		var err error
+		if exporter, err = newSAMExporter(samOutDir, samOutFormat); err != nil {
+			return err
 		}
-		if atomic.LoadInt32(&continueProcessing) == 0 {
-			break
+	}
+	if samHTML != "" {
+		var err error
+		htmlWriter, err = newHTMLReport(samHTML, "hey sam2pairwise "+strings.Join(filenames, " "))
+		if err != nil {
+			return fmt.Errorf("creating HTML report: %w", err)
 		}
-
-		fields := strings.Fields(line)
-		if len(fields) < 11 {
-			if atomic.LoadInt32(&continueProcessing) == 1 {
-				fmt.Fprintln(os.Stderr, "Skipping invalid SAM record (less than 11 fields):", line)
+		defer func() {
+			if htmlWriter != nil {
+				htmlWriter.Close()
 			}
-			continue
-		}
+		}()
+	}
 
-		readName := fields[0]
-		flagStr := fields[1]
-		refName := fields[2]
-		pos := fields[3]
-		cigar := fields[5]
-		seq := fields[9]
-		qual := fields[10]
-
-		var outputTagValues []string // To store the values of the requested tags for the info line
-
-		// Extract specified tags for the info line
-		for _, requestedTagKey := range tagKeys {
-			foundTagValue := "" // Default to empty string if tag not found
-			for _, field := range fields[11:] {
-				// SAM tags are typically TAG:TYPE:VALUE
-				parts := strings.SplitN(field, ":", 3)
-				if len(parts) == 3 && parts[0] == requestedTagKey {
-					foundTagValue = parts[2] // The value part
-					break
-				}
+	// Records are rendered by a pool of workers. Every record gets a result
+	// channel, which is queued in input order so that the output keeps it.
+	threads := max(samThreads, 1)
+	type samJob struct {
+		record *samRecord
+		result chan *samRenderedRecord
+	}
+	jobs := make(chan samJob, threads*4)
+	queue := make(chan chan *samRenderedRecord, threads*4)
+	var readErr error
+	var stopped atomic.Bool
+	seed := samSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(seed))
+	go func() {
+		defer close(queue)
+		defer close(jobs)
+		defer reader.Close()
+		for atomic.LoadInt32(&continueProcessing) == 1 && !stopped.Load() {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
 			}
-			outputTagValues = append(outputTagValues, foundTagValue)
-		}
-		outputTagsString := strings.Join(outputTagValues, "|") // Join multiple tag values with a semicolon
-
-		// Apply filtering based on flags
-		if filterForward || filterReverse {
-			flag, err := strconv.Atoi(flagStr)
 			if err != nil {
 				if atomic.LoadInt32(&continueProcessing) == 1 {
-					fmt.Fprintf(os.Stderr, "Skipping invalid SAM record (invalid flag %s): %s\n", flagStr, line)
+					readErr = err
 				}
+				break
+			}
+			if record.MapQ < samMinMapQ || record.Flag&requireFlags != requireFlags || record.Flag&excludeFlags != 0 || !filter.match(record.Name) || !passesStrandFilter(record.Flag) {
 				continue
 			}
-
-			isPaired := (flag & 0x1) != 0
-			isRead1 := (flag & 0x40) != 0
-			isRead2 := (flag & 0x80) != 0
-			isReverse := (flag & 0x10) != 0
-
-			if filterForward {
-				if !isPaired {
-					if isReverse {
-						continue
-					}
-				} else {
-					if !((isRead1 && !isReverse) || (isRead2 && isReverse)) {
-						continue
-					}
-				}
-			} else if filterReverse {
-				if !isPaired {
-					if !isReverse {
-						continue
-					}
-				} else {
-					if !((isRead1 && isReverse) || (isRead2 && !isReverse)) {
-						continue
-					}
-				}
+			if samSample < 1 && rng.Float64() >= samSample {
+				continue
 			}
+			result := make(chan *samRenderedRecord, 1)
+			jobs <- samJob{record: record, result: result}
+			queue <- result
 		}
-
-		// Extract MD tag specifically for samToPairwise function, as its logic depends on it.
-		mdTagForAlignment := ""
-		for _, field := range fields[11:] {
-			if strings.HasPrefix(field, "MD:Z:") {
-				mdTagForAlignment = field[5:]
-				break
+	}()
+	var workers sync.WaitGroup
+	for range threads {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for job := range jobs {
+				job.result <- renderSAMRecord(job.record, mutations, bisulfiteMark, width)
 			}
+		}()
+	}
+	// stop stops reading and waits for the workers.
+	stop := func() {
+		stopped.Store(true)
+		for range queue {
 		}
+		workers.Wait()
+	}
 
-		refSeq, alignedSeq, markers, err := samToPairwise(seq, qual, qualityCutoff, cigar, mdTagForAlignment, useKnownMutation, knownRefBase, knownAltBase, markChar)
-		if err != nil {
-			if atomic.LoadInt32(&continueProcessing) == 1 {
-				// Suppress error for potentially truncated final lines if interrupted
-				// fmt.Fprintf(os.Stderr, "Error processing read %s: %v\n", readName, err)
-			}
-			continue
+	// emit writes a block of lines, followed by an empty line.
+	emit := func(lines []string) {
+		if htmlWriter != nil {
+			htmlWriter.writeLines(lines)
+			return
 		}
-
-		if atomic.LoadInt32(&continueProcessing) == 1 {
-			tml.Printf("<darkgrey><italic>%s %s %s %s %s %s</italic></darkgrey>\n", readName, flagStr, refName, pos, cigar, outputTagsString)
-			fmt.Println(tml.Sprintf("%s", alignedSeq))
-			fmt.Println(markers)
-			fmt.Println(tml.Sprintf("%s", refSeq))
-			fmt.Println()
+		for _, line := range lines {
+			if noColor {
+				fmt.Println(stripMarkup(line))
+			} else {
+				printMarkup(line)
+			}
 		}
+		fmt.Println()
 	}
 
-	if err := scanner.Err(); err != nil {
-		if atomic.LoadInt32(&continueProcessing) == 1 {
-			fmt.Fprintln(os.Stderr, "Error reading standard input:", err)
+	numRendered := 0
+	for result := range queue {
+		rendered := <-result
+		if rendered.err != nil || atomic.LoadInt32(&continueProcessing) == 0 {
+			continue
+		}
+		numRendered++
+		if exporter != nil {
+			if err := exporter.write(rendered.record, rendered.md); err != nil {
+				stop()
+				return fmt.Errorf("exporting %s: %w", rendered.record.Name, err)
+			}
+		}
+		if rendered.stream != nil {
+			if err := rendered.stream(emit, summary); err != nil {
+				fmt.Fprintf(os.Stderr, "Stopped rendering %s: %v\n", rendered.record.Name, err)
+			}
+		} else {
+			if summary != nil {
+				summary.add(rendered.read, rendered.ref, rendered.columns, rendered.record.Qual, summaryMinQual())
+			}
+			emit(rendered.lines)
+		}
+		if htmlWriter != nil {
+			numWritten++
+		}
+		if numRendered == samNumRecords {
+			stop()
+			break
 		}
 	}
+	if readErr != nil {
+		return readErr
+	}
 
 	if atomic.LoadInt32(&continueProcessing) == 0 {
 		tml.Fprintln(os.Stderr, "<yellow><bold>\nSignal received. Finishing current record and exiting.</bold></yellow>")
 	}
+	if htmlWriter != nil {
+		err := htmlWriter.Close()
+		htmlWriter = nil
+		if err != nil {
+			return fmt.Errorf("writing HTML report: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d alignments to %s\n", numWritten, samHTML)
+	}
+	if exporter != nil {
+		fmt.Fprintf(os.Stderr, "Wrote %d alignment files to %s\n", exporter.written, samOutDir)
+	}
+	if summary != nil {
+		if samBisulfite {
+			mutations = append(mutations, samMutation{Ref: 'C', Alt: 'T'}, samMutation{Ref: 'G', Alt: 'A'})
+		}
+		summary.print(os.Stdout, mutations, noColor)
+	}
+	return nil
 }
 
-// MDTagEntry holds parsed information from an MD tag component.
-type MDTagEntry struct {
-	Num     int    // Number of matching bases
-	Changes string // Reference bases for mismatch or deletion
-	IsDel   bool   // True if this entry represents a deletion (^)
+// samRenderedRecord is a record rendered by renderSAMRecord.
+type samRenderedRecord struct {
+	record    *samRecord
+	md        string
+	lines     []string // With tml markup
+	read, ref string   // Read and reference lines without markup, not condensed
+	columns   []alignmentColumn
+	err       error
+
+	// stream is set instead of lines for alignments longer than
+	// samChunkSize. It aligns and renders the read chunk by chunk, passing
+	// the lines of every chunk to emit and counting it in summary if not nil.
+	stream func(emit func([]string), summary *samSummary) error
 }
 
-// parseMDTag breaks down an MD:Z tag string into usable components.
-func parseMDTag(mdTag string) ([]MDTagEntry, error) {
-	var entries []MDTagEntry
-	var numStr strings.Builder
-	var changeStr strings.Builder
-	state := "num"
+// renderSAMRecord renders the alignment of record with its info line, wrapped
+// to width if that is positive.
+func renderSAMRecord(record *samRecord, mutations []samMutation, bisulfiteMark rune, width int) *samRenderedRecord {
+	var outputTagValues []string // To store the values of the requested tags for the info line
 
-	if mdTag == "" {
-		return entries, nil
+	// Extract specified tags for the info line
+	for _, requestedTagKey := range tagKeys {
+		foundTagValue, _ := record.tag(requestedTagKey) // Empty string if tag not found
+		outputTagValues = append(outputTagValues, foundTagValue)
 	}
+	outputTagsString := strings.Join(outputTagValues, "|") // Join multiple tag values with a semicolon
 
-	addNumEntry := func() error {
-		if numStr.Len() > 0 {
-			num, err := strconv.Atoi(numStr.String())
-			if err != nil {
-				return fmt.Errorf("internal error: invalid number '%s' in MD tag parse", numStr.String())
+	// Extract MD tag specifically for the alignment, as its logic depends on it.
+	mdTagForAlignment, _ := record.tag("MD")
+
+	var mods map[int]baseModification
+	if samShowMods {
+		all, err := recordBaseModifications(record)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Ignoring base modifications of %s: %v\n", record.Name, err)
+		}
+		mods = make(map[int]baseModification)
+		for pos, mod := range all {
+			if mod.Probability >= samModThreshold {
+				mods[pos] = mod
 			}
-			entries = append(entries, MDTagEntry{Num: num})
-			numStr.Reset()
 		}
-		return nil
 	}
 
-	addDelEntry := func() error {
-		if changeStr.Len() > 0 {
-			entries = append(entries, MDTagEntry{Changes: changeStr.String(), IsDel: true})
-			changeStr.Reset()
-			return nil
-		}
-		return fmt.Errorf("empty deletion sequence found after '^' in MD tag")
+	if samBisulfite {
+		mutations = append(slices.Clip(mutations), bisulfiteMutation(record, bisulfiteMark))
 	}
-
-	addMismatchEntry := func() error {
-		if changeStr.Len() == 1 {
-			entries = append(entries, MDTagEntry{Changes: changeStr.String(), IsDel: false, Num: 0})
-			changeStr.Reset()
-			return nil
+	info := fmt.Sprintf("<darkgrey><italic>%s %d %s %d %s %s</italic></darkgrey>", record.Name, record.Flag, record.RName, record.Pos, record.Cigar, outputTagsString)
+	if columnCount, err := sam.Width(record.Cigar); err == nil && samChunkSize > 0 && columnCount > samChunkSize {
+		// Aligned in the stream, a chunk at a time, to keep long reads out
+		// of memory.
+		return &samRenderedRecord{
+			record: record,
+			md:     mdTagForAlignment,
+			stream: func(emit func([]string), summary *samSummary) error {
+				return streamAlignment(record, mdTagForAlignment, columnCount, info, mutations, mods, width, emit, summary)
+			},
 		}
-		return fmt.Errorf("invalid mismatch sequence '%s' (must be 1 base) in MD tag", changeStr.String())
+	}
+	alignment, err := sam.Align(record.Seq, record.Cigar, mdTagForAlignment)
+	if err != nil {
+		return &samRenderedRecord{record: record, err: err}
 	}
 
-	for i, char := range mdTag {
-		isLastChar := (i == len(mdTag)-1)
-
-		switch state {
-		case "num":
-			if char >= '0' && char <= '9' {
-				numStr.WriteRune(char)
-			} else {
-				if err := addNumEntry(); err != nil {
-					return nil, err
-				}
-				if char == '^' {
-					state = "del_start"
-				} else if (char >= 'A' && char <= 'Z') || (char >= 'a' && char <= 'z') {
-					changeStr.WriteRune(char)
-					state = "change"
-				} else {
-					return nil, fmt.Errorf("unexpected character '%c' after number in MD tag at position %d", char, i)
-				}
-			}
-		case "change":
-			if err := addMismatchEntry(); err != nil {
-				return nil, err
-			}
-			if char >= '0' && char <= '9' {
-				numStr.WriteRune(char)
-				state = "num"
-			} else if char == '^' {
-				state = "del_start"
-			} else {
-				return nil, fmt.Errorf("unexpected character '%c' after mismatch base in MD tag at position %d", char, i)
-			}
-		case "del_start":
-			if (char >= 'A' && char <= 'Z') || (char >= 'a' && char <= 'z') {
-				changeStr.WriteRune(char)
-				state = "del"
-			} else {
-				return nil, fmt.Errorf("expected base after deletion '^' in MD tag, got '%c' at position %d", char, i)
-			}
-		case "del":
-			if (char >= 'A' && char <= 'Z') || (char >= 'a' && char <= 'z') {
-				changeStr.WriteRune(char)
-			} else {
-				if err := addDelEntry(); err != nil {
-					return nil, err
-				}
-				if char >= '0' && char <= '9' {
-					numStr.WriteRune(char)
-					state = "num"
-				} else if char == '^' {
-					state = "del_start"
-				} else {
-					return nil, fmt.Errorf("unexpected character '%c' after deletion sequence in MD tag at position %d", char, i)
-				}
-			}
-		}
+	refSeq, alignedSeq, markers := renderPairwiseColumns(alignment, record.Qual, mutations, mods, samHideClips)
+	columns := layoutColumns(alignment, samHideClips)
+	rendered := &samRenderedRecord{record: record, md: mdTagForAlignment, read: stripMarkup(alignedSeq), ref: stripMarkup(refSeq), columns: columns}
+	rendered.lines = append([]string{info}, layoutAlignment(alignedSeq, markers, refSeq, record.Qual, columns, 1, record.Pos, width)...)
+	return rendered
+}
 
-		if isLastChar {
-			switch state {
-			case "num":
-				if err := addNumEntry(); err != nil {
-					return nil, err
-				}
-		case "change":
-			if err := addMismatchEntry(); err != nil {
-				return nil, err
-			}
-			case "del":
-				if err := addDelEntry(); err != nil {
-					return nil, err
-				}
-			case "del_start":
-				return nil, fmt.Errorf("MD tag cannot end with deletion start '^'")
-			}
-		}
+// layoutAlignment returns the lines showing the rendered read, marker and
+// reference lines of an alignment (or a part of it), starting at read
+// position readStart and reference position refStart, with the options for
+// condensing, qualities, the ruler and wrapping to width. qual is the QUAL
+// of the read bases of the alignment.
+func layoutAlignment(alignedSeq, markers, refSeq, qual string, columns []alignmentColumn, readStart, refStart, width int) []string {
+	if samIndelsOnly {
+		alignedSeq, markers, refSeq, columns = condenseMatches(alignedSeq, markers, refSeq, columns, samContext)
+	}
+	read := stripMarkup(alignedSeq)
+	qualRow := ""
+	if samShowQual != "" {
+		qualRow = qualityLine(qual, read, columns, samShowQual == "raw")
+	}
+	if width > 0 && utf8.RuneCountInString(read) > width {
+		return wrapAlignment(alignedSeq, markers, refSeq, qualRow, columns, readStart, refStart, width, samRuler)
+	}
+	lines := []string{alignedSeq, markers, refSeq}
+	if qualRow != "" {
+		lines = append(lines, qualRow)
+	}
+	if samRuler {
+		lines = append(lines, "<darkgrey>"+rulerLine(columns, refStart)+"</darkgrey>")
 	}
-	return entries, nil
+	return lines
 }
 
-func samToPairwise(seq string, qual string, qualityCutoff int, cigar string, mdTag string, useKnownMutation bool, knownRefBase byte, knownAltBase byte, markChar rune) (refSeqColored string, alignedSeqColored string, markers string, err error) {
-	var refBuilder, alignedSeqBuilder, markerBuilder strings.Builder
-	seqPos := 0
-
-	cigarOps, err := parseCigar(cigar)
-	if err != nil {
-		return "", "", "", fmt.Errorf("error parsing CIGAR '%s': %w", cigar, err)
+// summaryMinQual is the quality below which bases are left out of the
+// summary.
+func summaryMinQual() int {
+	if samSkipLowQuality {
+		return qualityCutoff
 	}
+	return 0
+}
 
-	var mdEntries []MDTagEntry
-	mdIndex := 0
-	mdSubPos := 0
-	hasMD := mdTag != ""
-	mdParseErr := false
-	if hasMD {
-		mdEntries, err = parseMDTag(mdTag)
-		if err != nil {
-			hasMD = false
-			mdParseErr = true
-			mdEntries = nil
+// passesStrandFilter applies -f and -r to the flags of a record.
+func passesStrandFilter(flag int) bool {
+	isPaired := (flag & 0x1) != 0
+	isRead1 := (flag & 0x40) != 0
+	isRead2 := (flag & 0x80) != 0
+	isReverse := (flag & 0x10) != 0
+
+	if filterForward {
+		if !isPaired {
+			return !isReverse
 		}
+		return (isRead1 && !isReverse) || (isRead2 && isReverse)
+	} else if filterReverse {
+		if !isPaired {
+			return isReverse
+		}
+		return (isRead1 && isReverse) || (isRead2 && !isReverse)
 	}
+	return true
+}
 
-	for _, op := range cigarOps {
-		length := op.Length
-		opType := op.Op
+// renderPairwiseColumns renders columns from sam.Align, which may be a part
+// of an alignment, as colored reference, read and marker lines.
+func renderPairwiseColumns(columns []sam.Column, qual string, mutations []samMutation, mods map[int]baseModification, hideClips bool) (refSeqColored string, alignedSeqColored string, markers string) {
+	var refBuilder, alignedSeqBuilder, markerBuilder strings.Builder
 
-		switch opType {
-		case 'M', '=', 'X':
-			for range length { // Modernized loop
-				if seqPos >= len(seq) {
-					return "", "", "", fmt.Errorf("CIGAR M/=/X asks for base %d but sequence length is %d", seqPos+1, len(seq))
-				}
-				readBase := seq[seqPos]
-				var refBase byte = 'N'
-				isMismatch := false
-				marker := '|'
-
-				if hasMD {
-					for mdIndex < len(mdEntries) && mdEntries[mdIndex].Num == 0 && len(mdEntries[mdIndex].Changes) == 0 && !mdEntries[mdIndex].IsDel {
-						mdIndex++
-					}
-					if mdIndex >= len(mdEntries) {
-						if !mdParseErr {
-							// Suppress warning for cleaner output
-						}
-						hasMD = false
-						refBase = 'N'
-						isMismatch = true
-					} else {
-						currentMdEntry := &mdEntries[mdIndex]
-						if currentMdEntry.IsDel {
-							return "", "", "", fmt.Errorf("MD tag indicates deletion (^) during CIGAR M/=/X at MD index %d (MD: %s)", mdIndex, mdTag)
-						}
-						if currentMdEntry.Num > 0 {
-							refBase = readBase
-							isMismatch = false
-							mdSubPos++
-							if mdSubPos == currentMdEntry.Num {
-								mdIndex++
-								mdSubPos = 0
-							}
-						} else {
-							if len(currentMdEntry.Changes) != 1 {
-								refBase = 'N'
-							} else {
-								refBase = currentMdEntry.Changes[0]
-							}
-							isMismatch = true
-							mdIndex++
-							mdSubPos = 0
-						}
-					}
-				} else {
-					if opType == '=' {
-						refBase = readBase
-						isMismatch = false
-					} else {
-						refBase = 'N'
-						isMismatch = true
-					}
-				}
+	for i, column := range columns {
+		lowQuality := samStyle.LowQuality(qual, column.ReadPos)
 
-				shouldHighlightRead := false
-				shouldHighlightRef := false
-				if isMismatch {
-					marker = ' '
-					if useKnownMutation {
-						if refBase == knownRefBase && readBase == knownAltBase {
-							marker = markChar
-						} else if refBase == knownRefBase {
-							shouldHighlightRead = true
-							shouldHighlightRef = true
-						} else {
-							shouldHighlightRead = true
-							shouldHighlightRef = true
-						}
-					} else {
-						shouldHighlightRead = true
-						shouldHighlightRef = true
-					}
+		switch column.Op {
+		case 'M', '=', 'X':
+			marker := '|'
+			shouldHighlight := false
+			if column.Mismatch {
+				marker = ' '
+				if mutation, ok := findKnownMutation(mutations, column.Ref, column.Read); ok {
+					marker = mutation.Mark
 				} else {
-					marker = '|'
-					if useKnownMutation && refBase == knownRefBase {
-						shouldHighlightRead = true
-						shouldHighlightRef = true
-					}
-				}
-				lowQuality := false
-				if qualityCutoff > 0 && seqPos < len(qual) {
-					qualityScore := int(qual[seqPos]) - 33
-					if qualityScore < qualityCutoff {
-						lowQuality = true
-					}
+					// Likely sequencing errors are dimmed rather than highlighted.
+					shouldHighlight = !lowQuality
 				}
-				applyColor(&alignedSeqBuilder, readBase, shouldHighlightRead, lowQuality)
-				applyColor(&refBuilder, refBase, shouldHighlightRef, false)
-				markerBuilder.WriteRune(marker)
-				seqPos++
+			} else if isKnownMutationRef(mutations, column.Ref) {
+				shouldHighlight = true
 			}
-
-		case 'I':
-			for range length { // Modernized loop
-				if seqPos >= len(seq) {
-					return "", "", "", fmt.Errorf("CIGAR I asks for base %d but sequence length is %d", seqPos+1, len(seq))
+			applyReadColor(&alignedSeqBuilder, column.Read, shouldHighlight, lowQuality, mods, column.ReadPos)
+			samStyle.WriteBase(&refBuilder, column.Ref, shouldHighlight, false)
+			markerBuilder.WriteRune(marker)
+		case 'I', 'S':
+			if column.Op == 'S' && hideClips {
+				if i > 0 && columns[i-1].Op == 'S' {
+					continue
 				}
-				readBase := seq[seqPos]
-				lowQuality := false
-				if qualityCutoff > 0 && seqPos < len(qual) {
-					qualityScore := int(qual[seqPos]) - 33
-					if qualityScore < qualityCutoff {
-						lowQuality = true
-					}
-				}
-				applyColor(&alignedSeqBuilder, readBase, true, lowQuality)
-				applyColor(&refBuilder, '-', true, false)
-				markerBuilder.WriteByte(' ')
-				seqPos++
-			}
-		case 'D':
-			if !hasMD {
-				if !mdParseErr {
-					// Suppress warning
-				}
-				for range length { // Modernized loop
-					applyColor(&alignedSeqBuilder, '-', true, false)
-					applyColor(&refBuilder, 'N', true, false)
-					markerBuilder.WriteByte(' ')
-				}
-			} else {
-				deletedBasesFound := 0
-				for deletedBasesFound < length {
-					for mdIndex < len(mdEntries) && mdEntries[mdIndex].Num == 0 && len(mdEntries[mdIndex].Changes) == 0 && !mdEntries[mdIndex].IsDel {
-						mdIndex++
-					}
-					if mdIndex >= len(mdEntries) {
-						if !mdParseErr {
-							// Suppress warning
-						}
-						for range length - deletedBasesFound { // Modernized loop
-							applyColor(&alignedSeqBuilder, '-', true, false)
-							applyColor(&refBuilder, 'N', true, false)
-							markerBuilder.WriteByte(' ')
-						}
-						deletedBasesFound = length
-						hasMD = false
-						break
-					}
-
-					currentMdEntry := &mdEntries[mdIndex]
-					if currentMdEntry.IsDel {
-						delSeqLen := len(currentMdEntry.Changes)
-						basesAvailableInMd := delSeqLen - mdSubPos
-						basesNeededForCigar := length - deletedBasesFound
-						basesToTake := min(basesNeededForCigar, basesAvailableInMd)
-
-						for j := range basesToTake { // Modernized loop
-							delBase := currentMdEntry.Changes[mdSubPos+j]
-							applyColor(&alignedSeqBuilder, '-', true, false)
-							applyColor(&refBuilder, delBase, true, false)
-							markerBuilder.WriteByte(' ')
-						}
-						deletedBasesFound += basesToTake
-						mdSubPos += basesToTake
-						if mdSubPos == delSeqLen {
-							mdIndex++
-							mdSubPos = 0
-						}
-					} else {
-						return "", "", "", fmt.Errorf("MD tag indicates match/mismatch (Num: %d, Changes: '%s') during CIGAR D op at MD index %d (MD: %s)", currentMdEntry.Num, currentMdEntry.Changes, mdIndex, mdTag)
-					}
+				length := 1
+				for i+length < len(columns) && columns[i+length].Op == 'S' {
+					length++
 				}
+				label := clipLabel(length)
+				alignedSeqBuilder.WriteString("<darkgrey>" + label + "</darkgrey>")
+				refBuilder.WriteString("<darkgrey>" + strings.Repeat(".", len(label)) + "</darkgrey>")
+				markerBuilder.WriteString(strings.Repeat(" ", len(label)))
+				continue
 			}
+			applyReadColor(&alignedSeqBuilder, column.Read, true, lowQuality, mods, column.ReadPos)
+			samStyle.WriteBase(&refBuilder, column.Ref, column.Op == 'I', false)
+			markerBuilder.WriteByte(' ')
+		case 'D', 'P':
+			samStyle.WriteBase(&alignedSeqBuilder, column.Read, true, false)
+			samStyle.WriteBase(&refBuilder, column.Ref, true, false)
+			markerBuilder.WriteByte(' ')
 		case 'N':
+			length := column.RefSpan
 			if length > minIntronCompressLength {
 				middleStr := fmt.Sprintf("..%dnt..", length)
 				displayWidth := condensedNSEdgeLength*2 + len(middleStr)
@@ -570,159 +792,34 @@ func samToPairwise(seq string, qual string, qualityCutoff int, cigar string, mdT
 				markerBuilder.WriteString(strings.Repeat(" ", displayWidth))
 			} else {
 				for range length { // Modernized loop
-					applyColor(&alignedSeqBuilder, '.', false, false)
-					applyColor(&refBuilder, 'N', false, false)
+					samStyle.WriteBase(&alignedSeqBuilder, '.', false, false)
+					samStyle.WriteBase(&refBuilder, 'N', false, false)
 					markerBuilder.WriteByte(' ')
 				}
 			}
-		case 'S':
-			for range length { // Modernized loop
-				if seqPos >= len(seq) {
-					return "", "", "", fmt.Errorf("CIGAR S asks for base %d but sequence length is %d", seqPos+1, len(seq))
-				}
-				readBase := seq[seqPos]
-				lowQuality := false
-				if qualityCutoff > 0 && seqPos < len(qual) {
-					qualityScore := int(qual[seqPos]) - 33
-					if qualityScore < qualityCutoff {
-						lowQuality = true
-					}
-				}
-				applyColor(&alignedSeqBuilder, readBase, true, lowQuality)
-				applyColor(&refBuilder, '.', false, false)
-				markerBuilder.WriteByte(' ')
-				seqPos++
-			}
-		case 'H':
-			continue
-		case 'P':
-			for range length { // Modernized loop
-				applyColor(&alignedSeqBuilder, '*', true, false)
-				applyColor(&refBuilder, '*', true, false)
-				markerBuilder.WriteByte(' ')
-			}
-		default:
-			return "", "", "", fmt.Errorf("unsupported CIGAR operation: %c", opType)
-		}
-	}
-
-	expectedSeqLenConsumed := 0
-	for _, op := range cigarOps {
-		if strings.ContainsRune("MIS=X", op.Op) {
-			expectedSeqLenConsumed += op.Length
 		}
 	}
-	if seqPos != expectedSeqLenConsumed && len(seq) > 0 {
-		// Suppress warning
-	}
 
-	return refBuilder.String(), alignedSeqBuilder.String(), markerBuilder.String(), nil
+	return refBuilder.String(), alignedSeqBuilder.String(), markerBuilder.String()
 }
 
-func applyColor(builder *strings.Builder, base byte, shouldHighlight bool, lowQuality bool) {
-	if lowQuality {
-		builder.WriteString("<darkgrey>")
-	}
-
-	color := ""
-	if shouldHighlight {
-		switch base {
-		case 'A', 'a':
-			color = "red"
-		case 'T', 't':
-			color = "green"
-		case 'G', 'g':
-			color = "yellow"
-		case 'C', 'c':
-			color = "blue"
-		case '-':
-			color = "black"
-		case '*':
-			color = "magenta"
-		}
-	}
-
-	switch base {
-	case 'A', 'a', 'T', 't', 'G', 'g', 'C', 'c':
-		if shouldHighlight && color != "" {
-			builder.WriteString("<bg-" + color + ">")
-			builder.WriteByte(base)
-			builder.WriteString("</bg-" + color + ">")
-		} else {
-			builder.WriteByte(base)
-		}
-	case '-':
-		if shouldHighlight && color == "black" {
-			builder.WriteString("<bg-black>")
-			builder.WriteByte(base)
-			builder.WriteString("</bg-black>")
-		} else {
-			builder.WriteByte(base)
-		}
-	case '*':
-		if shouldHighlight && color == "magenta" {
-			builder.WriteString("<bg-magenta>")
-			builder.WriteByte(base)
-			builder.WriteString("</bg-magenta>")
-		} else {
-			builder.WriteByte(base)
-		}
-	case 'N', 'n':
-		if lowQuality {
-			builder.WriteByte(base)
-		} else {
-			builder.WriteString(tml.Sprintf("<darkgrey>%c</darkgrey>", base))
-		}
-	case '.':
-		if lowQuality {
-			builder.WriteByte(base)
-		} else {
-			builder.WriteString(tml.Sprintf("<darkgrey>%c</darkgrey>", base))
-		}
-	default:
-		builder.WriteByte(base)
-	}
-
-	if lowQuality {
-		builder.WriteString("</darkgrey>")
-	}
-}
-
-type CigarOp struct {
-	Length int
-	Op     rune
-}
-
-func parseCigar(cigar string) ([]CigarOp, error) {
-	if cigar == "*" || cigar == "" {
-		return []CigarOp{}, nil
-	}
-	var ops []CigarOp
-	var lengthStr strings.Builder
-	for i, char := range cigar {
-		if char >= '0' && char <= '9' {
-			lengthStr.WriteRune(char)
-		} else if strings.ContainsRune("MIDNSHP=X", char) {
-			if lengthStr.Len() == 0 {
-				return nil, fmt.Errorf("CIGAR operation '%c' at index %d has no preceding length", char, i)
-			}
-			length, err := strconv.Atoi(lengthStr.String())
-			if err != nil {
-				return nil, fmt.Errorf("invalid CIGAR length format '%s' before op '%c': %w", lengthStr.String(), char, err)
-			}
-			if length <= 0 {
-				return nil, fmt.Errorf("invalid non-positive CIGAR length %d for operation '%c'", length, char)
-			}
-			ops = append(ops, CigarOp{Length: length, Op: char})
-			lengthStr.Reset()
-		} else {
-			return nil, fmt.Errorf("invalid character '%c' in CIGAR string at index %d", char, i)
-		}
+// applyReadColor colors a base of the read like samStyle.WriteBase and marks
+// base modification calls: the base is lowercased, underlined and colored by
+// modification type.
+func applyReadColor(builder *strings.Builder, base byte, shouldHighlight bool, lowQuality bool, mods map[int]baseModification, seqPos int) {
+	mod, ok := mods[seqPos]
+	if !ok {
+		samStyle.WriteBase(builder, base, shouldHighlight, lowQuality)
+		return
 	}
-	if lengthStr.Len() > 0 {
-		return nil, fmt.Errorf("CIGAR string ends with an incomplete operation (number '%s' without type)", lengthStr.String())
+	if samStyle.NoColor {
+		samStyle.WriteBase(builder, base|0x20, shouldHighlight, lowQuality)
+		return
 	}
-	return ops, nil
+	color := baseModColor(mod.Code)
+	builder.WriteString("<underline><" + color + ">")
+	samStyle.WriteBase(builder, base|0x20, shouldHighlight, lowQuality)
+	builder.WriteString("</" + color + "></underline>")
 }
 
 func min(a, b int) int {
@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKnownMutations(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		marks   []string
+		want    []samMutation
+		wantErr bool
+	}{
+		{"none", nil, []string{"."}, nil, false},
+		{"one", []string{"C>T"}, []string{"."}, []samMutation{{'C', 'T', '.'}}, false},
+		{"reused mark", []string{"C>T", "G>A"}, []string{"."}, []samMutation{{'C', 'T', '.'}, {'G', 'A', '.'}}, false},
+		{"marks per mutation", []string{"C>T", "G>A"}, []string{".", ":"}, []samMutation{{'C', 'T', '.'}, {'G', 'A', ':'}}, false},
+		{"bad spec", []string{"CT"}, []string{"."}, nil, true},
+		{"bad mark", []string{"C>T"}, []string{".."}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseKnownMutations(tt.specs, tt.marks)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSAMNameFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter samNameFilter
+		read   string
+		want   bool
+	}{
+		{"no filter", samNameFilter{}, "r1", true},
+		{"listed name", samNameFilter{names: map[string]bool{"r1": true}}, "r1", true},
+		{"unlisted name", samNameFilter{names: map[string]bool{"r1": true}}, "r2", false},
+		{"regex match", samNameFilter{regex: regexp.MustCompile(`^r\d$`)}, "r2", true},
+		{"regex mismatch", samNameFilter{regex: regexp.MustCompile(`^r\d$`)}, "s1", false},
+		{"both", samNameFilter{names: map[string]bool{"s1": true}, regex: regexp.MustCompile(`^r`)}, "s1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.match(tt.read))
+		})
+	}
+}
+
+func TestBisulfiteMutation(t *testing.T) {
+	tests := []struct {
+		name string
+		flag int
+		tags []string
+		want byte
+	}{
+		{"single forward", 0, nil, 'C'},
+		{"single reverse", 0x10, nil, 'G'},
+		{"read 1 forward", 0x1 | 0x40, nil, 'C'},
+		{"read 1 reverse", 0x1 | 0x40 | 0x10, nil, 'G'},
+		{"read 2 reverse", 0x1 | 0x80 | 0x10, nil, 'C'},
+		{"read 2 forward", 0x1 | 0x80, nil, 'G'},
+		{"Bismark", 0x10, []string{"XG:Z:CT"}, 'C'},
+		{"bwa-meth", 0, []string{"YD:Z:r"}, 'G'},
+		{"XS", 0, []string{"XS:A:-"}, 'G'},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := bisulfiteMutation(&samRecord{Flag: tt.flag, Tags: tt.tags}, ':')
+			assert.Equal(t, tt.want, got.Ref)
+			assert.Equal(t, ':', got.Mark)
+		})
+	}
+}
+
+// testSAM returns n records named r0, r1, ..., of growing length and with
+// MAPQ i, so that they take different times to render.
+func testSAM(n int) string {
+	var sb strings.Builder
+	for i := range n {
+		seq := strings.Repeat("ACGT", i%10+1)
+		fmt.Fprintf(&sb, "r%d\t0\tchr1\t%d\t%d\t%dM\t*\t0\t0\t%s\t%s\tMD:Z:%d\n",
+			i, i+1, i%256, len(seq), seq, strings.Repeat("I", len(seq)), len(seq))
+	}
+	return sb.String()
+}
+
+// runProcessSAM runs processSAM on the SAM text input with plain output, after
+// set has changed the flags, and returns the names of the records printed.
+// It fails the test if processSAM does not return within a few seconds.
+func runProcessSAM(t *testing.T, input string, set func()) ([]string, error) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "in.sam")
+	require.NoError(t, os.WriteFile(file, []byte(input), 0o644))
+
+	origNoColor, origWidth, origThreads := samNoColor, samWidth, samThreads
+	origNum, origSample, origSeed := samNumRecords, samSample, samSeed
+	origMinMapQ, origOutDir, origOutFormat := samMinMapQ, samOutDir, samOutFormat
+	origStdout := os.Stdout
+	defer func() {
+		samNoColor, samWidth, samThreads = origNoColor, origWidth, origThreads
+		samNumRecords, samSample, samSeed = origNum, origSample, origSeed
+		samMinMapQ, samOutDir, samOutFormat = origMinMapQ, origOutDir, origOutFormat
+		os.Stdout = origStdout
+	}()
+	samNoColor, samWidth, samThreads = true, -1, 1
+	samNumRecords, samSample, samSeed = 0, 1, 0
+	samMinMapQ, samOutDir, samOutFormat = 0, "", "fasta"
+	if set != nil {
+		set()
+	}
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stdout = w
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+	done := make(chan error, 1)
+	go func() {
+		done <- processSAM([]string{file}, nil, 0, 0, nil, samNameFilter{})
+	}()
+	select {
+	case err = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("processSAM did not return")
+	}
+	w.Close()
+
+	var names []string
+	for _, block := range strings.Split(strings.TrimSpace(<-output), "\n\n") {
+		if block != "" {
+			names = append(names, strings.Fields(block)[0])
+		}
+	}
+	return names, err
+}
+
+// recordNames returns r<from> to r<to-1>.
+func recordNames(from, to int) []string {
+	var names []string
+	for i := from; i < to; i++ {
+		names = append(names, fmt.Sprintf("r%d", i))
+	}
+	return names
+}
+
+func TestProcessSAMKeepsInputOrder(t *testing.T) {
+	for _, threads := range []int{1, 4, 16} {
+		t.Run(fmt.Sprintf("%d threads", threads), func(t *testing.T) {
+			names, err := runProcessSAM(t, testSAM(300), func() { samThreads = threads })
+			require.NoError(t, err)
+			assert.Equal(t, recordNames(0, 300), names)
+		})
+	}
+}
+
+func TestProcessSAMStopsEarly(t *testing.T) {
+	t.Run("num records", func(t *testing.T) {
+		// Far more records than the queues hold are still waiting to be read.
+		names, err := runProcessSAM(t, testSAM(1000), func() {
+			samThreads = 4
+			samNumRecords = 3
+		})
+		require.NoError(t, err)
+		assert.Equal(t, recordNames(0, 3), names)
+	})
+
+	t.Run("exporter error", func(t *testing.T) {
+		outDir := t.TempDir()
+		// A directory where the alignment of r5 goes makes writing it fail.
+		require.NoError(t, os.Mkdir(filepath.Join(outDir, "r5.fa"), 0o755))
+		names, err := runProcessSAM(t, testSAM(1000), func() {
+			samThreads = 4
+			samOutDir = outDir
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "exporting r5")
+		assert.Equal(t, recordNames(0, 5), names)
+		assert.FileExists(t, filepath.Join(outDir, "r4.fa"))
+		assert.NoFileExists(t, filepath.Join(outDir, "r6.fa"))
+	})
+}
+
+func TestProcessSAMNumRecords(t *testing.T) {
+	names, err := runProcessSAM(t, testSAM(10), func() { samNumRecords = 2 })
+	require.NoError(t, err)
+	assert.Equal(t, []string{"r0", "r1"}, names)
+}
+
+func TestProcessSAMSample(t *testing.T) {
+	sample := func(seed int64) []string {
+		names, err := runProcessSAM(t, testSAM(200), func() {
+			samThreads = 4
+			samSample = 0.3
+			samSeed = seed
+		})
+		require.NoError(t, err)
+		return names
+	}
+
+	first := sample(42)
+	assert.Equal(t, first, sample(42))
+	assert.NotEqual(t, first, sample(43))
+	assert.Greater(t, len(first), 20)
+	assert.Less(t, len(first), 100)
+	assert.Subset(t, recordNames(0, 200), first)
+}
+
+func TestSAM2PairwiseRejectsInvalidSampling(t *testing.T) {
+	origSample, origNum := samSample, samNumRecords
+	defer func() { samSample, samNumRecords = origSample, origNum }()
+
+	tests := []struct {
+		name       string
+		sample     float64
+		numRecords int
+		wantErr    string
+	}{
+		{"sample 0", 0, 0, "--sample"},
+		{"negative sample", -0.5, 0, "--sample"},
+		{"sample above 1", 1.5, 0, "--sample"},
+		{"negative num records", 1, -1, "--num-records"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samSample, samNumRecords = tt.sample, tt.numRecords
+			err := sam2pairwiseCmd.RunE(sam2pairwiseCmd, []string{filepath.Join(t.TempDir(), "missing.sam")})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/yech1990/hey/pkg/sam"
+)
+
+// samExportBlockWidth is the number of columns per block in EMBOSS pair files.
+const samExportBlockWidth = 50
+
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// samExporter writes every alignment to its own file in a directory, as an
+// aligned FASTA or EMBOSS pair file named after the read.
+type samExporter struct {
+	dir     string
+	format  string // "fasta" or "emboss"
+	names   map[string]int
+	written int
+}
+
+func newSAMExporter(dir, format string) (*samExporter, error) {
+	if format != "fasta" && format != "emboss" {
+		return nil, fmt.Errorf("--out-format must be fasta or emboss")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating output directory: %w", err)
+	}
+	return &samExporter{dir: dir, format: format, names: make(map[string]int)}, nil
+}
+
+// write exports the alignment of record, given its MD tag. Reads whose name
+// was already written, like mates or supplementary alignments, get a numbered
+// suffix.
+func (e *samExporter) write(record *samRecord, md string) error {
+	columns, err := sam.Align(record.Seq, record.Cigar, md)
+	if err != nil {
+		return err
+	}
+	read, ref := gappedAlignment(columns)
+	refName := fmt.Sprintf("%s:%d-%d", record.RName, record.Pos, record.Pos+max(cigarRefLength(record.Cigar), 1)-1)
+
+	base := unsafeFileNameChars.ReplaceAllString(record.Name, "_")
+	e.names[base]++
+	if n := e.names[base]; n > 1 {
+		base = fmt.Sprintf("%s_%d", base, n)
+	}
+	var content, ext string
+	if e.format == "emboss" {
+		content, ext = formatEMBOSSPair(record.Name, refName, read, ref, record.Pos), ".pair"
+	} else {
+		content, ext = formatAlignedFASTA(record.Name, refName, read, ref), ".fa"
+	}
+	if err := os.WriteFile(filepath.Join(e.dir, base+ext), []byte(content), 0o644); err != nil {
+		return err
+	}
+	e.written++
+	return nil
+}
+
+// gappedAlignment returns the read and reference rows of an alignment as they
+// are written to alignment files: all gaps are '-', introns are written out
+// and unknown bases are 'N'.
+func gappedAlignment(columns []sam.Column) (read, ref string) {
+	read, ref = sam.Strings(columns)
+	readReplacer := strings.NewReplacer(".", "-", "*", "-", string(sam.MissingBase), "N")
+	refReplacer := strings.NewReplacer(".", "-", "*", "-")
+	return readReplacer.Replace(read), refReplacer.Replace(ref)
+}
+
+func formatAlignedFASTA(readName, refName, read, ref string) string {
+	return fmt.Sprintf(">%s\n%s\n>%s\n%s\n", refName, ref, readName, read)
+}
+
+// formatEMBOSSPair formats an alignment like the pair output of EMBOSS
+// needle, in blocks of samExportBlockWidth columns. refName is chrom:start-end
+// and refStart is the position of the first reference base.
+func formatEMBOSSPair(readName, refName, read, ref string, refStart int) string {
+	var b strings.Builder
+	chrom := refName
+	if i := strings.LastIndexByte(refName, ':'); i > 0 {
+		chrom = refName[:i]
+	}
+	identity, gaps := 0, 0
+	var markers strings.Builder
+	for i := range len(read) {
+		switch {
+		case read[i] == '-' || ref[i] == '-':
+			gaps++
+			markers.WriteByte(' ')
+		case read[i] == ref[i] && read[i] != 'N':
+			identity++
+			markers.WriteByte('|')
+		default:
+			markers.WriteByte('.')
+		}
+	}
+	fmt.Fprintf(&b, "########################################\n")
+	fmt.Fprintf(&b, "# Program: hey sam2pairwise\n")
+	fmt.Fprintf(&b, "########################################\n")
+	fmt.Fprintf(&b, "#=======================================\n#\n")
+	fmt.Fprintf(&b, "# Aligned_sequences: 2\n")
+	fmt.Fprintf(&b, "# 1: %s\n", readName)
+	fmt.Fprintf(&b, "# 2: %s\n", refName)
+	fmt.Fprintf(&b, "# Length: %d\n", len(read))
+	fmt.Fprintf(&b, "# Identity:   %7s (%s)\n", fmt.Sprintf("%d/%d", identity, len(read)), percentOf(identity, len(read)))
+	fmt.Fprintf(&b, "# Gaps:       %7s (%s)\n", fmt.Sprintf("%d/%d", gaps, len(read)), percentOf(gaps, len(read)))
+	fmt.Fprintf(&b, "#\n#=======================================\n")
+
+	readPos, refPos := 1, refStart
+	for start := 0; start < len(read); start += samExportBlockWidth {
+		end := min(start+samExportBlockWidth, len(read))
+		readFrom, refFrom := readPos, refPos
+		readPos += len(read[start:end]) - strings.Count(read[start:end], "-")
+		refPos += len(ref[start:end]) - strings.Count(ref[start:end], "-")
+		fmt.Fprintf(&b, "\n%-13.13s %6d %s %6d\n", readName, readFrom, read[start:end], max(readPos-1, readFrom))
+		fmt.Fprintf(&b, "%21s%s\n", "", markers.String()[start:end])
+		fmt.Fprintf(&b, "%-13.13s %6d %s %6d\n", chrom, refFrom, ref[start:end], max(refPos-1, refFrom))
+	}
+	return b.String()
+}
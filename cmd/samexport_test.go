@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yech1990/hey/pkg/sam"
+)
+
+func TestGappedAlignment(t *testing.T) {
+	columns, err := sam.Align("TTACGGAATTA", "2S3M1I2M1D2M3N1M", "3A1^C3")
+	assert.NoError(t, err)
+	read, ref := gappedAlignment(columns)
+	assert.Equal(t, "TTACGGAA-TT---A", read)
+	assert.Equal(t, "--ACG-AACTTNNNA", ref)
+}
+
+func TestFormatAlignedFASTA(t *testing.T) {
+	assert.Equal(t, ">chr1:5-12\n--ACG-AACTT\n>r1\nTTACGGAA-TT\n", formatAlignedFASTA("r1", "chr1:5-12", "TTACGGAA-TT", "--ACG-AACTT"))
+}
+
+func TestFormatEMBOSSPair(t *testing.T) {
+	got := formatEMBOSSPair("r1", "chr1:5-12", "TTACGGAA-TT", "--ACG-AACTT", 5)
+	assert.Contains(t, got, "# 2: chr1:5-12\n")
+	assert.Contains(t, got, "# Identity:      7/11 (63.64%)\n")
+	assert.Contains(t, got, "# Gaps:          4/11 (36.36%)\n")
+	assert.Contains(t, got, "\nr1                 1 TTACGGAA-TT     10\n"+
+		"                       ||| || ||\n"+
+		"chr1               5 --ACG-AACTT     12\n")
+}
@@ -0,0 +1,268 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/yech1990/hey/pkg/sam"
+	"golang.org/x/term"
+)
+
+// alignmentColumn tells how many read and reference bases one column of a
+// rendered alignment stands for.
+type alignmentColumn struct {
+	ReadAdvance int
+	RefAdvance  int
+}
+
+// alignmentColumns lays out the columns of the alignment that
+// renderPairwiseColumns renders for cigar. A condensed intron is several
+// columns of which the first advances the reference by the whole intron, and
+// so is a hidden soft clip with hideClips.
+func alignmentColumns(cigar string, hideClips bool) ([]alignmentColumn, error) {
+	columns, err := sam.Align("*", cigar, "")
+	if err != nil {
+		return nil, err
+	}
+	return layoutColumns(columns, hideClips), nil
+}
+
+// layoutColumns lays out the rendered columns of columns from sam.Align, which
+// may be a part of an alignment, like alignmentColumns.
+func layoutColumns(columns []sam.Column, hideClips bool) []alignmentColumn {
+	var layout []alignmentColumn
+	add := func(n int, column alignmentColumn) {
+		for range n {
+			layout = append(layout, column)
+		}
+	}
+	for i, c := range columns {
+		switch {
+		case c.Op == 'S' && hideClips:
+			if i > 0 && columns[i-1].Op == 'S' {
+				continue
+			}
+			length := 1
+			for i+length < len(columns) && columns[i+length].Op == 'S' {
+				length++
+			}
+			layout = append(layout, alignmentColumn{length, 0})
+			add(len(clipLabel(length))-1, alignmentColumn{})
+		case c.Op == 'N' && c.RefSpan > minIntronCompressLength:
+			width := condensedNSEdgeLength*2 + len(fmt.Sprintf("..%dnt..", c.RefSpan))
+			layout = append(layout, alignmentColumn{0, c.RefSpan})
+			add(width-1, alignmentColumn{})
+		case c.Op == 'N':
+			add(c.RefSpan, alignmentColumn{0, 1})
+		case c.ReadPos >= 0:
+			layout = append(layout, alignmentColumn{1, c.RefSpan})
+		default:
+			layout = append(layout, alignmentColumn{0, c.RefSpan})
+		}
+	}
+	return layout
+}
+
+// clipLabel is shown instead of a soft clip of length bases with --hide-clips.
+func clipLabel(length int) string {
+	return fmt.Sprintf("[%dS]", length)
+}
+
+// condenseMatches collapses every run of matches (| markers) that is longer
+// than the context columns kept on both sides of it plus its label into a
+// label with its length, like "..120=..", leaving the windows around indels
+// and mismatches. It returns the new lines and their columns.
+func condenseMatches(read, markers, ref string, columns []alignmentColumn, context int) (string, string, string, []alignmentColumn) {
+	readCells := splitMarkupCells(read)
+	markerCells := splitMarkupCells(markers)
+	refCells := splitMarkupCells(ref)
+	if len(readCells) != len(columns) || len(markerCells) != len(columns) || len(refCells) != len(columns) {
+		return read, markers, ref, columns
+	}
+	var newRead, newMarkers, newRef strings.Builder
+	var newColumns []alignmentColumn
+	keep := func(from, to int) {
+		for i := from; i < to; i++ {
+			newRead.WriteString(readCells[i])
+			newMarkers.WriteString(markerCells[i])
+			newRef.WriteString(refCells[i])
+		}
+		newColumns = append(newColumns, columns[from:to]...)
+	}
+	start := 0
+	for start < len(columns) {
+		end := start
+		for end < len(columns) && markerCells[end] == "|" {
+			end++
+		}
+		if end == start {
+			keep(start, start+1)
+			start++
+			continue
+		}
+		hidden := end - start - 2*context
+		label := fmt.Sprintf("..%d=..", hidden)
+		if hidden <= len(label) {
+			keep(start, end)
+			start = end
+			continue
+		}
+		keep(start, start+context)
+		newRead.WriteString("<darkgrey>" + label + "</darkgrey>")
+		newMarkers.WriteString(strings.Repeat(" ", len(label)))
+		newRef.WriteString("<darkgrey>" + label + "</darkgrey>")
+		newColumns = append(newColumns, alignmentColumn{hidden, hidden})
+		for range len(label) - 1 {
+			newColumns = append(newColumns, alignmentColumn{})
+		}
+		keep(end-context, end)
+		start = end
+	}
+	return newRead.String(), newMarkers.String(), newRef.String(), newColumns
+}
+
+// splitMarkupCells splits a line with tml markup into one self-contained
+// piece of markup per visible character, so that lines can be cut at any
+// column without breaking tags.
+func splitMarkupCells(line string) []string {
+	var cells []string
+	var open []string
+	for len(line) > 0 {
+		if line[0] == '<' {
+			if end := strings.IndexByte(line, '>'); end > 0 {
+				tag := line[1:end]
+				if strings.HasPrefix(tag, "/") && isMarkupTag(tag[1:]) {
+					for i := len(open) - 1; i >= 0; i-- {
+						if open[i] == tag[1:] {
+							open = append(open[:i], open[i+1:]...)
+							break
+						}
+					}
+					line = line[end+1:]
+					continue
+				}
+				if isMarkupTag(tag) {
+					open = append(open, tag)
+					line = line[end+1:]
+					continue
+				}
+			}
+		}
+		r := []rune(line)[0]
+		var cell strings.Builder
+		for _, tag := range open {
+			cell.WriteString("<" + tag + ">")
+		}
+		cell.WriteRune(r)
+		for i := len(open) - 1; i >= 0; i-- {
+			cell.WriteString("</" + open[i] + ">")
+		}
+		cells = append(cells, cell.String())
+		line = line[len(string(r)):]
+	}
+	return cells
+}
+
+// rulerLine returns a coordinate track for columns: a tick at every
+// reference position divisible by 10, followed by the position where it
+// fits. refStart is the reference position of the first column.
+func rulerLine(columns []alignmentColumn, refStart int) string {
+	line := []byte(strings.Repeat(" ", len(columns)))
+	pos := refStart
+	for i, column := range columns {
+		if column.RefAdvance == 1 && pos%10 == 0 {
+			line[i] = '|'
+			label := strconv.Itoa(pos)
+			if i+len(label) < len(line) && strings.TrimSpace(string(line[i+1:i+1+len(label)])) == "" {
+				copy(line[i+1:], label)
+			}
+		}
+		pos += column.RefAdvance
+	}
+	return strings.TrimRight(string(line), " ")
+}
+
+// wrapAlignment cuts the read, marker and reference lines into blocks that
+// fit width, like BLAST pairwise output, labelling every block with the
+// first and last read and reference positions it covers. readStart and
+// refStart are the positions of the first bases of the alignment. A
+// non-empty qual row from qualityLine is shown below the reference, and with
+// ruler every block gets a coordinate track below that.
+func wrapAlignment(read, markers, ref, qual string, columns []alignmentColumn, readStart, refStart, width int, ruler bool) []string {
+	readCells := splitMarkupCells(read)
+	markerCells := splitMarkupCells(markers)
+	refCells := splitMarkupCells(ref)
+	qualCells := splitMarkupCells(qual)
+	numColumns := min(len(readCells), min(len(markerCells), len(refCells)))
+
+	digits := len(strconv.Itoa(max(readStart, refStart) + len(columns)))
+	blockWidth := max(width-2*digits-8, 10) // Labels take "Query " + digits + 2 spaces before and after
+
+	var lines []string
+	readPos, refPos := readStart, refStart
+	for start := 0; start < numColumns; start += blockWidth {
+		end := min(start+blockWidth, numColumns)
+		readFrom, refFrom := readPos, refPos
+		blockColumns := columns[min(start, len(columns)):min(end, len(columns))]
+		for _, column := range blockColumns {
+			readPos += column.ReadAdvance
+			refPos += column.RefAdvance
+		}
+		if start > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines,
+			fmt.Sprintf("Query %*d  %s  %d", digits, readFrom, strings.Join(readCells[start:end], ""), max(readPos-1, readFrom)),
+			strings.Repeat(" ", digits+8)+strings.Join(markerCells[start:end], ""),
+			fmt.Sprintf("Ref   %*d  %s  %d", digits, refFrom, strings.Join(refCells[start:end], ""), max(refPos-1, refFrom)),
+		)
+		if start < len(qualCells) {
+			lines = append(lines, strings.Repeat(" ", digits+8)+strings.Join(qualCells[start:min(end, len(qualCells))], ""))
+		}
+		if ruler {
+			lines = append(lines, "<darkgrey>"+strings.Repeat(" ", digits+8)+rulerLine(blockColumns, refFrom)+"</darkgrey>")
+		}
+	}
+	return lines
+}
+
+// qualityLine returns a row with the base quality of every read base of an
+// alignment, as phred characters with raw or as the colored blocks of hey
+// fastq otherwise. Deletions are '-'. read is the rendered read line without
+// markup. Without qualities (QUAL "*") the row is empty.
+func qualityLine(qual, read string, columns []alignmentColumn, raw bool) string {
+	if qual == "*" || qual == "" {
+		return ""
+	}
+	readChars := []rune(read)
+	var b strings.Builder
+	qualPos := 0
+	for i, column := range columns {
+		switch {
+		case column.ReadAdvance == 1 && qualPos < len(qual):
+			if raw {
+				b.WriteString("<darkgrey>" + string(qual[qualPos]) + "</darkgrey>")
+			} else {
+				b.WriteString(samStyle.Quality(qual[qualPos : qualPos+1]))
+			}
+		case column.ReadAdvance == 0 && column.RefAdvance == 1 && i < len(readChars) && readChars[i] == '-':
+			b.WriteByte('-')
+		default:
+			b.WriteByte(' ')
+		}
+		qualPos += column.ReadAdvance
+	}
+	return strings.TrimRight(b.String(), " ")
+}
+
+// terminalWidth returns the width of the terminal on stdout, or 0 if stdout
+// is not a terminal.
+func terminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlignmentColumns(t *testing.T) {
+	columns, err := alignmentColumns("2S2M1I1D", false)
+	require.NoError(t, err)
+	assert.Equal(t, []alignmentColumn{{1, 0}, {1, 0}, {1, 1}, {1, 1}, {1, 0}, {0, 1}}, columns)
+
+	// A condensed intron is as wide as its "NNNNN..100nt..NNNNN" text.
+	columns, err = alignmentColumns("1M100N1M", false)
+	require.NoError(t, err)
+	require.Len(t, columns, 2+len("NNNNN..100nt..NNNNN"))
+	assert.Equal(t, alignmentColumn{0, 100}, columns[1])
+	assert.Equal(t, alignmentColumn{}, columns[2])
+
+	// A hidden soft clip is as wide as its "[12S]" label.
+	columns, err = alignmentColumns("12S2M", true)
+	require.NoError(t, err)
+	assert.Equal(t, []alignmentColumn{{12, 0}, {}, {}, {}, {}, {1, 1}, {1, 1}}, columns)
+}
+
+func TestSplitMarkupCells(t *testing.T) {
+	cells := splitMarkupCells("A<bg-red>CG</bg-red><darkgrey><italic>T</italic></darkgrey>")
+	assert.Equal(t, []string{"A", "<bg-red>C</bg-red>", "<bg-red>G</bg-red>", "<darkgrey><italic>T</italic></darkgrey>"}, cells)
+	assert.Equal(t, []string{"|", " ", "<"}, splitMarkupCells("| <"))
+}
+
+func TestWrapAlignment(t *testing.T) {
+	read := "AAAA<bg-red>C</bg-red>AAAAA"
+	ref := "AAAA<bg-red>G</bg-red>AA--A"
+	markers := "||||    | |"
+	columns, err := alignmentColumns("7M2I1M", false)
+	require.NoError(t, err)
+	lines := wrapAlignment(read, markers[:10], ref, "", columns, 1, 100, 24, false)
+	// 24 columns leave 10 for the sequence.
+	for _, line := range lines {
+		if strings.HasPrefix(line, "Query") || strings.HasPrefix(line, "Ref") {
+			assert.LessOrEqual(t, len(stripMarkup(line)), 24+4)
+		}
+	}
+	assert.Equal(t, "Query   1  AAAA<bg-red>C</bg-red>AAAAA  10", lines[0])
+	assert.Equal(t, "Ref   100  AAAA<bg-red>G</bg-red>AA--A  107", lines[2])
+
+	long := strings.Repeat("A", 25)
+	columns, err = alignmentColumns("25M", false)
+	require.NoError(t, err)
+	lines = wrapAlignment(long, strings.Repeat("|", 25), long, "", columns, 1, 100, 30, true)
+	require.Len(t, lines, 9)
+	assert.Equal(t, "Query   1  "+long[:16]+"  16", lines[0])
+	assert.Equal(t, "           "+strings.Repeat("|", 16), lines[1])
+	assert.Equal(t, "Ref   100  "+long[:16]+"  115", lines[2])
+	assert.Equal(t, "<darkgrey>           |100      |110</darkgrey>", lines[3])
+	assert.Equal(t, "", lines[4])
+	assert.Equal(t, "Query  17  "+long[:9]+"  25", lines[5])
+	assert.Equal(t, "Ref   116  "+long[:9]+"  124", lines[7])
+	assert.Equal(t, "<darkgrey>               |120</darkgrey>", lines[8])
+}
+
+func TestRulerLine(t *testing.T) {
+	columns, err := alignmentColumns("2S5M1I10M", false)
+	require.NoError(t, err)
+	// Reference positions 8-12 and 13-22, after two soft-clipped bases.
+	assert.Equal(t, "    |10        |20", rulerLine(columns, 8))
+	columns, _ = alignmentColumns("12M", false)
+	assert.Equal(t, "  |1000", rulerLine(columns, 998))
+	// Labels that do not fit are left out.
+	columns, _ = alignmentColumns("5M", false)
+	assert.Equal(t, "  |", rulerLine(columns, 998))
+}
+
+func TestQualityLine(t *testing.T) {
+	columns, err := alignmentColumns("2S3M1D2M2N2M", true)
+	require.NoError(t, err)
+	assert.Equal(t, "    5?I-II  I#", stripMarkup(qualityLine("#+5?IIII#", "[2S]GTA-CG..TA", columns, true)))
+	assert.Equal(t, "    ▒▓│-││  │·", stripMarkup(qualityLine("#+5?IIII#", "[2S]GTA-CG..TA", columns, false)))
+	assert.Equal(t, "", qualityLine("*", "[2S]GTA-CG..TA", columns, false))
+}
+
+func TestCondenseMatches(t *testing.T) {
+	columns, err := alignmentColumns("30M1I2M", false)
+	require.NoError(t, err)
+	read := strings.Repeat("A", 30) + "<bg-red>G</bg-red>AC"
+	markers := strings.Repeat("|", 30) + " ||"
+	ref := strings.Repeat("A", 30) + "<bg-red>-</bg-red>AC"
+
+	newRead, newMarkers, newRef, newColumns := condenseMatches(read, markers, ref, columns, 2)
+	assert.Equal(t, "AA..26=..AAGAC", stripMarkup(newRead))
+	assert.Equal(t, "||       || ||", newMarkers)
+	assert.Equal(t, "AA..26=..AA-AC", stripMarkup(newRef))
+	require.Len(t, newColumns, 14)
+	assert.Equal(t, alignmentColumn{26, 26}, newColumns[2])
+	assert.Equal(t, alignmentColumn{}, newColumns[3])
+	assert.Equal(t, alignmentColumn{1, 0}, newColumns[11])
+
+	// Runs not much longer than the context are kept.
+	_, newMarkers, _, _ = condenseMatches(read, markers, ref, columns, 12)
+	assert.Equal(t, markers, newMarkers)
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sampleRun is one run (R1/R2 pair) of a sample in a checkbarcode style YAML:
+//
+//	samples:
+//	  name:
+//	    data:            # or the list directly under the sample (legacy)
+//	      - R1: a_R1.fq.gz
+//	        R2: a_R2.fq.gz
+type sampleRun struct {
+	Sample  string
+	Index   int // 1-based position of the run within its sample
+	R1      string
+	R2      string
+	Barcode string
+	Line    int
+}
+
+// sampleConfigIssue is a problem found while reading a sample YAML.
+type sampleConfigIssue struct {
+	Line    int
+	Message string
+}
+
+type sampleConfig struct {
+	Path    string
+	Samples []string // In file order
+	Runs    []sampleRun
+	Issues  []sampleConfigIssue
+	// SampleLines records where every sample is defined, including duplicates.
+	SampleLines map[string][]int
+}
+
+// loadSampleConfig reads a sample YAML, keeping the file order and line numbers.
+// Structural problems are collected in Issues instead of stopping at the first.
+func loadSampleConfig(path, topKey string) (*sampleConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading YAML file '%s': %w", path, err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing YAML file '%s': %w", path, err)
+	}
+	cfg := &sampleConfig{Path: path, SampleLines: make(map[string][]int)}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		cfg.addIssue(doc.Line, "top level is not a mapping")
+		return cfg, nil
+	}
+
+	samples := mappingValue(doc.Content[0], topKey)
+	if samples == nil {
+		cfg.addIssue(doc.Content[0].Line, fmt.Sprintf("top-level key '%s' not found", topKey))
+		return cfg, nil
+	}
+	if samples.Kind != yaml.MappingNode {
+		cfg.addIssue(samples.Line, fmt.Sprintf("'%s' must be a map of samples", topKey))
+		return cfg, nil
+	}
+
+	for i := 0; i+1 < len(samples.Content); i += 2 {
+		nameNode, sampleNode := samples.Content[i], samples.Content[i+1]
+		name := nameNode.Value
+		if len(cfg.SampleLines[name]) == 0 {
+			cfg.Samples = append(cfg.Samples, name)
+		}
+		cfg.SampleLines[name] = append(cfg.SampleLines[name], nameNode.Line)
+
+		runs := sampleNode
+		sampleBarcode := ""
+		if sampleNode.Kind == yaml.MappingNode {
+			runs = mappingValue(sampleNode, "data")
+			if bc := mappingValue(sampleNode, "barcode"); bc != nil {
+				sampleBarcode = bc.Value
+			}
+			if runs == nil {
+				cfg.addIssue(sampleNode.Line, fmt.Sprintf("sample '%s' has no 'data' list", name))
+				continue
+			}
+		}
+		if runs.Kind != yaml.SequenceNode {
+			cfg.addIssue(runs.Line, fmt.Sprintf("runs of sample '%s' must be a list", name))
+			continue
+		}
+		for j, runNode := range runs.Content {
+			if runNode.Kind != yaml.MappingNode {
+				cfg.addIssue(runNode.Line, fmt.Sprintf("sample '%s', run %d is not a map", name, j+1))
+				continue
+			}
+			run := sampleRun{Sample: name, Index: j + 1, Line: runNode.Line, Barcode: sampleBarcode}
+			if n := mappingValue(runNode, "R1"); n != nil {
+				run.R1 = n.Value
+			}
+			if n := mappingValue(runNode, "R2"); n != nil {
+				run.R2 = n.Value
+			}
+			if n := mappingValue(runNode, "barcode"); n != nil {
+				run.Barcode = n.Value
+			}
+			cfg.Runs = append(cfg.Runs, run)
+		}
+	}
+	return cfg, nil
+}
+
+func (c *sampleConfig) addIssue(line int, message string) {
+	c.Issues = append(c.Issues, sampleConfigIssue{Line: line, Message: message})
+}
+
+// resolve turns a path from the YAML into an absolute path. Relative paths are
+// relative to the YAML file, and a leading ~ is the home directory.
+func (c *sampleConfig) resolve(path string) string {
+	if strings.HasPrefix(path, "~") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(c.Path), path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadSampleConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "samples.yaml")
+	content := `samples:
+  liver:
+    barcode: ACGT
+    data:
+      - R1: raw/liver_R1.fq.gz
+        R2: raw/liver_R2.fq.gz
+      - R1: raw/liver2_R1.fq.gz
+  heart:
+    - R1: /abs/heart_R1.fq.gz
+  broken:
+    other: 1
+  liver:
+    - R1: dup.fq.gz
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	cfg, err := loadSampleConfig(path, "samples")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"liver", "heart", "broken"}, cfg.Samples)
+	assert.Equal(t, []int{2, 12}, cfg.SampleLines["liver"])
+	require.Len(t, cfg.Runs, 4)
+	assert.Equal(t, sampleRun{Sample: "liver", Index: 1, R1: "raw/liver_R1.fq.gz", R2: "raw/liver_R2.fq.gz", Barcode: "ACGT", Line: 5}, cfg.Runs[0])
+	assert.Equal(t, 2, cfg.Runs[1].Index)
+	assert.Equal(t, "heart", cfg.Runs[2].Sample)
+	require.Len(t, cfg.Issues, 1)
+	assert.Equal(t, 11, cfg.Issues[0].Line)
+
+	assert.Equal(t, filepath.Join(dir, "raw/liver_R1.fq.gz"), cfg.resolve("raw/liver_R1.fq.gz"))
+	assert.Equal(t, "/abs/heart_R1.fq.gz", cfg.resolve("/abs/heart_R1.fq.gz"))
+
+	cfg, err = loadSampleConfig(path, "missing")
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Runs)
+	assert.Len(t, cfg.Issues, 1)
+}
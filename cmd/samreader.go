@@ -0,0 +1,330 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/yech1990/hey/pkg/sam"
+)
+
+// samRecord is one alignment in the text representation of SAM, whichever
+// format it was read from.
+type samRecord struct {
+	Name  string
+	Flag  int
+	RName string
+	Pos   int // 1-based, 0 if unmapped
+	MapQ  int
+	Cigar string
+	RNext string
+	PNext int
+	TLen  int
+	Seq   string
+	Qual  string
+	Tags  []string // TAG:TYPE:VALUE
+}
+
+// tag returns the value of the optional field key.
+func (r *samRecord) tag(key string) (string, bool) {
+	for _, field := range r.Tags {
+		if len(field) >= 5 && field[:2] == key && field[2] == ':' && field[4] == ':' {
+			return field[5:], true
+		}
+	}
+	return "", false
+}
+
+// samRecordReader returns alignments one by one and io.EOF at the end.
+type samRecordReader interface {
+	Read() (*samRecord, error)
+}
+
+var bamMagic = []byte("BAM\x01")
+
+// openSAMReader detects whether r holds BAM, CRAM, gzipped SAM or plain SAM
+// and returns a reader for its records. reference is only used for CRAM.
+func openSAMReader(r io.Reader, reference string) (samRecordReader, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	if magic, _ := br.Peek(4); bytes.Equal(magic, cramMagic) {
+		return newCRAMReader(br, reference)
+	}
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		// BAM is a series of gzip members (BGZF), which gzip.Reader reads
+		// as one stream.
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReaderSize(gz, 64*1024)
+		if magic, _ := br.Peek(4); bytes.Equal(magic, bamMagic) {
+			return newBAMReader(br)
+		}
+	}
+	return &textSAMReader{scanner: newLineScanner(br)}, nil
+}
+
+// textSAMReader reads SAM text, skipping header lines and reporting invalid
+// records on stderr.
+type textSAMReader struct {
+	scanner *bufio.Scanner
+}
+
+func (t *textSAMReader) Read() (*samRecord, error) {
+	for t.scanner.Scan() {
+		line := t.scanner.Text()
+		if line == "" || strings.HasPrefix(line, "@") {
+			continue
+		}
+		record, err := parseSAMLine(line)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Skipping invalid SAM record (%v): %s\n", err, line)
+			continue
+		}
+		return record, nil
+	}
+	if err := t.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func parseSAMLine(line string) (*samRecord, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 11 {
+		return nil, fmt.Errorf("less than 11 fields")
+	}
+	var numbers [5]int
+	for i, col := range []int{1, 3, 4, 7, 8} {
+		n, err := strconv.Atoi(fields[col])
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s in column %d", fields[col], col+1)
+		}
+		numbers[i] = n
+	}
+	return &samRecord{
+		Name:  fields[0],
+		Flag:  numbers[0],
+		RName: fields[2],
+		Pos:   numbers[1],
+		MapQ:  numbers[2],
+		Cigar: fields[5],
+		RNext: fields[6],
+		PNext: numbers[3],
+		TLen:  numbers[4],
+		Seq:   fields[9],
+		Qual:  fields[10],
+		Tags:  fields[11:],
+	}, nil
+}
+
+// multiSAMReader reads the records of several files one after another,
+// opening each file only when the previous one is exhausted.
+type multiSAMReader struct {
+	filenames []string
+	reference string
+	region    *samRegion // Only records overlapping it, if set
+	current   samRecordReader
+	file      io.Closer
+	decoder   io.Closer // Process decoding the current file, like samtools for CRAM
+}
+
+func (m *multiSAMReader) Read() (*samRecord, error) {
+	for {
+		if m.current == nil {
+			if len(m.filenames) == 0 {
+				return nil, io.EOF
+			}
+			if err := m.open(m.filenames[0]); err != nil {
+				return nil, err
+			}
+		}
+		record, err := m.current.Read()
+		if err == io.EOF {
+			m.closeCurrent()
+			m.filenames = m.filenames[1:]
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.displayName(), err)
+		}
+		return record, nil
+	}
+}
+
+func (m *multiSAMReader) open(filename string) error {
+	if m.region != nil && filename != "-" {
+		reader, file, err := openIndexedBAM(filename, m.region)
+		if err != nil {
+			return err
+		}
+		if reader != nil {
+			m.current, m.file = reader, file
+			return nil
+		}
+	}
+	file, err := openInput(filename)
+	if err != nil {
+		return err
+	}
+	reader, err := openSAMReader(file, m.reference)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("%s: %w", m.displayName(), err)
+	}
+	m.decoder, _ = reader.(io.Closer)
+	if m.region != nil {
+		reader = &regionReader{reader: reader, region: m.region}
+	}
+	m.current, m.file = reader, file
+	return nil
+}
+
+// closeCurrent closes the file being read, stopping its decoder if it has not
+// finished.
+func (m *multiSAMReader) closeCurrent() {
+	if m.decoder != nil {
+		m.decoder.Close()
+	}
+	m.file.Close()
+	m.current, m.file, m.decoder = nil, nil, nil
+}
+
+// Close stops reading, without reading the remaining files.
+func (m *multiSAMReader) Close() error {
+	if m.current != nil {
+		m.closeCurrent()
+	}
+	m.filenames = nil
+	return nil
+}
+
+func (m *multiSAMReader) displayName() string {
+	if m.filenames[0] == "-" {
+		return "stdin"
+	}
+	return m.filenames[0]
+}
+
+// samRegion is a genomic interval with 1-based, inclusive coordinates. An
+// End of 0 extends to the end of the chromosome.
+type samRegion struct {
+	Chrom      string
+	Start, End int
+}
+
+// parseSAMRegion parses regions written as chr, chr:start or chr:start-end,
+// allowing thousands separators in the numbers.
+func parseSAMRegion(s string) (*samRegion, error) {
+	region := &samRegion{Chrom: s, Start: 1}
+	i := strings.LastIndexByte(s, ':')
+	if i < 0 {
+		return region, nil
+	}
+	region.Chrom = s[:i]
+	startStr, endStr, hasEnd := strings.Cut(strings.ReplaceAll(s[i+1:], ",", ""), "-")
+	start, err := strconv.Atoi(startStr)
+	if err != nil || start < 1 || region.Chrom == "" {
+		return nil, fmt.Errorf("invalid region %q, use chr:start-end", s)
+	}
+	region.Start = start
+	if hasEnd {
+		end, err := strconv.Atoi(endStr)
+		if err != nil || end < start {
+			return nil, fmt.Errorf("invalid region %q, use chr:start-end", s)
+		}
+		region.End = end
+	}
+	return region, nil
+}
+
+// overlaps reports whether the alignment of record covers part of the region.
+func (g *samRegion) overlaps(record *samRecord) bool {
+	if record.RName != g.Chrom || record.Pos < 1 {
+		return false
+	}
+	end := record.Pos + max(cigarRefLength(record.Cigar), 1) - 1
+	return end >= g.Start && (g.End == 0 || record.Pos <= g.End)
+}
+
+// cigarRefLength returns the number of reference bases an alignment spans.
+func cigarRefLength(cigar string) int {
+	ops, err := sam.ParseCigar(cigar)
+	if err != nil {
+		return 0
+	}
+	length := 0
+	for _, op := range ops {
+		if strings.ContainsRune("MDN=X", op.Op) {
+			length += op.Length
+		}
+	}
+	return length
+}
+
+// regionReader passes on the records that overlap region. If the input is
+// sorted by coordinate it stops at the first record past the region.
+type regionReader struct {
+	reader samRecordReader
+	region *samRegion
+	sorted bool
+}
+
+func (r *regionReader) Read() (*samRecord, error) {
+	for {
+		record, err := r.reader.Read()
+		if err != nil {
+			return nil, err
+		}
+		if r.region.overlaps(record) {
+			return record, nil
+		}
+		if r.sorted && (record.RName != r.region.Chrom || (r.region.End > 0 && record.Pos > r.region.End)) {
+			return nil, io.EOF
+		}
+	}
+}
+
+// samFlagNames are the names samtools uses for the bits of the FLAG field.
+var samFlagNames = []struct {
+	name string
+	bit  int
+}{
+	{"PAIRED", 0x1}, {"PROPER_PAIR", 0x2}, {"UNMAP", 0x4}, {"MUNMAP", 0x8},
+	{"REVERSE", 0x10}, {"MREVERSE", 0x20}, {"READ1", 0x40}, {"READ2", 0x80},
+	{"SECONDARY", 0x100}, {"QCFAIL", 0x200}, {"DUP", 0x400}, {"SUPPLEMENTARY", 0x800},
+}
+
+// parseSAMFlags parses a FLAG bitmask given as a decimal or 0x-prefixed hex
+// number, or as a comma-separated list of flag names such as
+// SECONDARY,SUPPLEMENTARY.
+func parseSAMFlags(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if n, err := strconv.ParseInt(s, 0, 32); err == nil {
+		if n < 0 || n > 0xfff {
+			return 0, fmt.Errorf("flag value %s is out of range", s)
+		}
+		return int(n), nil
+	}
+	mask := 0
+	for _, name := range strings.Split(s, ",") {
+		found := false
+		for _, f := range samFlagNames {
+			if strings.EqualFold(strings.TrimSpace(name), f.name) {
+				mask |= f.bit
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown flag %q", name)
+		}
+	}
+	return mask, nil
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenSAMReaderText(t *testing.T) {
+	input := "@HD\tVN:1.6\nr1\t0\tchr1\t5\t60\t3M\t*\t0\t0\tACG\tIII\tMD:Z:3\nbad line\n"
+	reader, err := openSAMReader(strings.NewReader(input), "")
+	require.NoError(t, err)
+	record, err := reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "r1", record.Name)
+	assert.Equal(t, 5, record.Pos)
+	assert.Equal(t, []string{"MD:Z:3"}, record.Tags)
+	_, err = reader.Read()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestMultiSAMReader(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "a.sam")
+	require.NoError(t, os.WriteFile(plain, []byte("r1\t0\tchr1\t1\t60\t1M\t*\t0\t0\tA\tI\n"), 0o644))
+	zipped := filepath.Join(dir, "b.sam.gz")
+	f, err := os.Create(zipped)
+	require.NoError(t, err)
+	gz := gzip.NewWriter(f)
+	_, err = gz.Write([]byte("@HD\tVN:1.6\nr2\t16\tchr1\t2\t60\t1M\t*\t0\t0\tC\tI\n"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	require.NoError(t, f.Close())
+
+	reader := &multiSAMReader{filenames: []string{plain, zipped}}
+	var names []string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names = append(names, record.Name)
+	}
+	assert.Equal(t, []string{"r1", "r2"}, names)
+
+	reader = &multiSAMReader{filenames: []string{filepath.Join(dir, "missing.sam")}}
+	_, err = reader.Read()
+	assert.Error(t, err)
+}
+
+func TestParseSAMRegion(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    samRegion
+		wantErr bool
+	}{
+		{"chr1", samRegion{"chr1", 1, 0}, false},
+		{"chr1:1,000-2,000", samRegion{"chr1", 1000, 2000}, false},
+		{"chr1:500", samRegion{"chr1", 500, 0}, false},
+		{"HLA-A*01:01:1-10", samRegion{"HLA-A*01:01", 1, 10}, false},
+		{"chr1:20-10", samRegion{}, true},
+		{"chr1:x", samRegion{}, true},
+		{":1-10", samRegion{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseSAMRegion(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *got)
+		})
+	}
+}
+
+func TestSAMRegionOverlaps(t *testing.T) {
+	region := &samRegion{Chrom: "chr1", Start: 100, End: 200}
+	record := func(chrom string, pos int, cigar string) *samRecord {
+		return &samRecord{RName: chrom, Pos: pos, Cigar: cigar}
+	}
+	assert.True(t, region.overlaps(record("chr1", 95, "10M")))
+	assert.False(t, region.overlaps(record("chr1", 90, "10M")))
+	assert.True(t, region.overlaps(record("chr1", 50, "10M1000N10M")))
+	assert.True(t, region.overlaps(record("chr1", 200, "5S10M")))
+	assert.False(t, region.overlaps(record("chr1", 201, "10M")))
+	assert.False(t, region.overlaps(record("chr2", 150, "10M")))
+	assert.False(t, region.overlaps(record("*", 0, "*")))
+}
+
+func TestParseSAMFlags(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"4", 4, false},
+		{"0x900", 0x900, false},
+		{"SECONDARY,SUPPLEMENTARY", 0x900, false},
+		{"unmap, dup", 0x404, false},
+		{"SECONDARY,FOO", 0, true},
+		{"0x1000", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseSAMFlags(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMinMapQ(t *testing.T) {
+	// The MAPQ of record ri is i.
+	names, err := runProcessSAM(t, testSAM(200), func() { samMinMapQ = 195 })
+	require.NoError(t, err)
+	assert.Equal(t, []string{"r195", "r196", "r197", "r198", "r199"}, names)
+
+	names, err = runProcessSAM(t, testSAM(5), func() { samMinMapQ = 0 })
+	require.NoError(t, err)
+	assert.Len(t, names, 5)
+
+	origMinMapQ := samMinMapQ
+	defer func() { samMinMapQ = origMinMapQ }()
+	for _, mapq := range []int{-1, 256} {
+		samMinMapQ = mapq
+		err := sam2pairwiseCmd.RunE(sam2pairwiseCmd, []string{filepath.Join(t.TempDir(), "missing.sam")})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "--min-mapq")
+	}
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aquasecurity/table"
+	"github.com/yech1990/hey/pkg/sam"
+)
+
+// samSummary accumulates match, mismatch and indel counts over the rendered
+// alignments of sam2pairwise.
+type samSummary struct {
+	Reads         int
+	Matches       int
+	Mismatches    int
+	Insertions    int // Events
+	InsertedBases int
+	Deletions     int // Events
+	DeletedBases  int
+	Substitutions map[string]int // By "REF>ALT", e.g. "C>T"
+	RefBases      map[byte]int   // Aligned read bases by reference base
+}
+
+func newSAMSummary() *samSummary {
+	return &samSummary{Substitutions: make(map[string]int), RefBases: make(map[byte]int)}
+}
+
+// add counts the columns of one alignment, given its read and reference
+// lines without markup and the layout from alignmentColumns. Positions where
+// the reference base is unknown (no MD tag) are not counted, nor are read
+// bases with a quality in qual below minQual.
+func (s *samSummary) add(read, ref string, columns []alignmentColumn, qual string, minQual int) {
+	s.Reads++
+	s.addColumns(read, ref, columns, qual, minQual)
+}
+
+// addColumns counts a part of an alignment like add, without counting a read.
+// qual starts at the first read base of the part.
+func (s *samSummary) addColumns(read, ref string, columns []alignmentColumn, qual string, minQual int) {
+	inInsertion, inDeletion := false, false
+	readPos := 0
+	for i, column := range columns {
+		lowQuality := minQual > 0 && qual != "*" && readPos < len(qual) && int(qual[readPos])-33 < minQual
+		readPos += column.ReadAdvance
+		if i >= len(read) || i >= len(ref) {
+			break
+		}
+		readBase := strings.ToUpper(read[i : i+1])[0]
+		refBase := strings.ToUpper(ref[i : i+1])[0]
+		isInsertion := column.ReadAdvance == 1 && column.RefAdvance == 0 && refBase == '-'
+		isDeletion := column.ReadAdvance == 0 && column.RefAdvance == 1 && readBase == '-'
+		if isInsertion {
+			s.InsertedBases++
+			if !inInsertion {
+				s.Insertions++
+			}
+		}
+		if isDeletion {
+			s.DeletedBases++
+			if !inDeletion {
+				s.Deletions++
+			}
+		}
+		inInsertion, inDeletion = isInsertion, isDeletion
+
+		if column.ReadAdvance != 1 || column.RefAdvance != 1 || refBase == 'N' || readBase == sam.MissingBase || lowQuality {
+			continue
+		}
+		s.RefBases[refBase]++
+		if readBase == refBase {
+			s.Matches++
+		} else {
+			s.Mismatches++
+			s.Substitutions[string(refBase)+">"+string(readBase)]++
+		}
+	}
+}
+
+// print writes the summary to w as tables, with the rates of the -m
+// mutations. With plain, as with --no-color, the tables are tab-separated
+// text without borders or styling, one blank line apart.
+func (s *samSummary) print(w io.Writer, mutations []samMutation, plain bool) {
+	rows := [][]string{
+		{"Reads", fmt.Sprint(s.Reads)},
+		{"Matches", fmt.Sprint(s.Matches)},
+		{"Mismatches", fmt.Sprintf("%d (%s)", s.Mismatches, percentOf(s.Mismatches, s.Matches+s.Mismatches))},
+		{"Insertions", fmt.Sprintf("%d (%d bases)", s.Insertions, s.InsertedBases)},
+		{"Deletions", fmt.Sprintf("%d (%d bases)", s.Deletions, s.DeletedBases)},
+	}
+	for _, m := range mutations {
+		known := string(m.Ref) + ">" + string(m.Alt)
+		n := s.Substitutions[known]
+		rows = append(rows,
+			[]string{known + " of " + string(m.Ref), fmt.Sprintf("%d / %d (%s)", n, s.RefBases[m.Ref], percentOf(n, s.RefBases[m.Ref]))},
+			[]string{known + " of mismatches", percentOf(n, s.Mismatches)})
+	}
+	printSummaryTable(w, plain, []string{"Metric", "Value"}, rows)
+
+	if len(s.Substitutions) == 0 {
+		return
+	}
+	subs := make([]string, 0, len(s.Substitutions))
+	for sub := range s.Substitutions {
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		if s.Substitutions[subs[i]] != s.Substitutions[subs[j]] {
+			return s.Substitutions[subs[i]] > s.Substitutions[subs[j]]
+		}
+		return subs[i] < subs[j]
+	})
+	rows = rows[:0]
+	for _, sub := range subs {
+		n := s.Substitutions[sub]
+		rows = append(rows, []string{sub, fmt.Sprint(n), percentOf(n, s.RefBases[sub[0]]), percentOf(n, s.Mismatches)})
+	}
+	if plain {
+		fmt.Fprintln(w)
+	}
+	printSummaryTable(w, plain, []string{"Substitution", "Count", "Of REF bases", "Of mismatches"}, rows)
+}
+
+// printSummaryTable writes a table of the summary, left aligning the first
+// column and right aligning the others, or as tab-separated lines if plain.
+func printSummaryTable(w io.Writer, plain bool, headers []string, rows [][]string) {
+	if plain {
+		fmt.Fprintln(w, strings.Join(headers, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		return
+	}
+	t := table.New(w)
+	t.SetHeaders(headers...)
+	t.SetHeaderStyle(table.StyleBold)
+	t.SetLineStyle(table.StyleBlue)
+	t.SetDividers(table.UnicodeRoundedDividers)
+	alignments := []table.Alignment{table.AlignLeft}
+	for range headers[1:] {
+		alignments = append(alignments, table.AlignRight)
+	}
+	t.SetAlignment(alignments...)
+	for _, row := range rows {
+		t.AddRow(row...)
+	}
+	t.Render()
+}
+
+func percentOf(n, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f%%", 100*float64(n)/float64(total))
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSAMSummaryAdd(t *testing.T) {
+	tests := []struct {
+		name          string
+		cigar         string
+		read, ref     string
+		matches       int
+		mismatches    int
+		insertions    int
+		insertedBases int
+		deletions     int
+		deletedBases  int
+		substitutions map[string]int
+	}{
+		{"matches", "4M", "ACGT", "ACGT", 4, 0, 0, 0, 0, 0, map[string]int{}},
+		{"clip and indels", "2S3M1I2M1D2M", "TTACGGAA-TT", "..ACG-AACTT", 7, 0, 1, 1, 1, 1, map[string]int{}},
+		{"mismatch", "3M", "ATT", "ACT", 2, 1, 0, 0, 0, 0, map[string]int{"C>T": 1}},
+		{"modified base", "3M", "AcT", "ACT", 3, 0, 0, 0, 0, 0, map[string]int{}},
+		{"unknown reference", "3M", "ACT", "NNN", 0, 0, 0, 0, 0, 0, map[string]int{}},
+		{"long insertion", "1M3I1M", "AGGGA", "A---A", 2, 0, 1, 3, 0, 0, map[string]int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, err := alignmentColumns(tt.cigar, false)
+			assert.NoError(t, err)
+			s := newSAMSummary()
+			s.add(tt.read, tt.ref, columns, "*", 0)
+			assert.Equal(t, 1, s.Reads)
+			assert.Equal(t, tt.matches, s.Matches)
+			assert.Equal(t, tt.mismatches, s.Mismatches)
+			assert.Equal(t, tt.insertions, s.Insertions)
+			assert.Equal(t, tt.insertedBases, s.InsertedBases)
+			assert.Equal(t, tt.deletions, s.Deletions)
+			assert.Equal(t, tt.deletedBases, s.DeletedBases)
+			assert.Equal(t, tt.substitutions, s.Substitutions)
+		})
+	}
+}
+
+func TestSAMSummaryAddSkipsLowQuality(t *testing.T) {
+	columns, err := alignmentColumns("4M", false)
+	assert.NoError(t, err)
+	s := newSAMSummary()
+	s.add("ATTT", "ACGT", columns, "I#II", 20)
+	assert.Equal(t, 2, s.Matches)
+	assert.Equal(t, 1, s.Mismatches)
+	assert.Equal(t, map[string]int{"G>T": 1}, s.Substitutions)
+}
+
+func TestPercentOf(t *testing.T) {
+	assert.Equal(t, "25.00%", percentOf(1, 4))
+	assert.Equal(t, "-", percentOf(1, 0))
+}
+
+func TestSAMSummaryPrintPlain(t *testing.T) {
+	s := newSAMSummary()
+	s.Reads, s.Matches, s.Mismatches = 1, 3, 1
+	s.Substitutions["C>T"] = 1
+	s.RefBases['C'] = 2
+	var b strings.Builder
+	s.print(&b, []samMutation{{Ref: 'C', Alt: 'T'}}, true)
+	assert.Equal(t, "Metric\tValue\n"+
+		"Reads\t1\n"+
+		"Matches\t3\n"+
+		"Mismatches\t1 (25.00%)\n"+
+		"Insertions\t0 (0 bases)\n"+
+		"Deletions\t0 (0 bases)\n"+
+		"C>T of C\t1 / 2 (50.00%)\n"+
+		"C>T of mismatches\t100.00%\n"+
+		"\n"+
+		"Substitution\tCount\tOf REF bases\tOf mismatches\n"+
+		"C>T\t1\t50.00%\t100.00%\n", b.String())
+	assert.NotContains(t, b.String(), "\x1b[")
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/schollz/progressbar/v3"
+	"github.com/yech1990/hey/pkg/sam"
+)
+
+// streamAlignment aligns a long read with its MD tag md and renders the
+// columns samChunkSize at a time, passing the lines of every chunk to emit as
+// soon as they are ready, so that only one chunk of columns is held at a
+// time. width is the number of columns (sam.Width) and wrap the terminal
+// width. The first chunk starts with the info line. Soft clips are not split,
+// so that a hidden clip keeps its single label. On an error, the chunks
+// before it have been emitted.
+func streamAlignment(record *samRecord, md string, width int, info string, mutations []samMutation, mods map[int]baseModification, wrap int, emit func([]string), summary *samSummary) error {
+	var bar *progressbar.ProgressBar
+	if samProgress {
+		bar = progressbar.NewOptions(width,
+			progressbar.OptionSetDescription("[cyan]"+record.Name),
+			progressbar.OptionSetWriter(os.Stderr),
+			progressbar.OptionShowCount(),
+			progressbar.OptionEnableColorCodes(true),
+			progressbar.OptionClearOnFinish(),
+			progressbar.OptionSetTheme(progressbar.Theme{Saucer: "[green]=[reset]", SaucerHead: "[green]>[reset]", SaucerPadding: " ", BarStart: "[", BarEnd: "]"}),
+		)
+		defer bar.Finish()
+	}
+	if summary != nil {
+		summary.Reads++
+	}
+
+	readPos, refPos := 1, record.Pos
+	first := true
+	chunk := make([]sam.Column, 0, samChunkSize)
+	flush := func() {
+		refSeq, alignedSeq, markers := renderPairwiseColumns(chunk, record.Qual, mutations, mods, samHideClips)
+		layout := layoutColumns(chunk, samHideClips)
+
+		qual := record.Qual
+		if qual != "*" {
+			qual = qual[min(readPos-1, len(qual)):]
+		}
+		if summary != nil {
+			summary.addColumns(stripMarkup(alignedSeq), stripMarkup(refSeq), layout, qual, summaryMinQual())
+		}
+		lines := layoutAlignment(alignedSeq, markers, refSeq, qual, layout, readPos, refPos, wrap)
+		if first {
+			lines = append([]string{info}, lines...)
+			first = false
+		}
+		for _, column := range layout {
+			readPos += column.ReadAdvance
+			refPos += column.RefAdvance
+		}
+		emit(lines)
+
+		if bar != nil {
+			bar.Add(len(chunk))
+		}
+		chunk = chunk[:0]
+	}
+	err := sam.AlignFunc(record.Seq, record.Cigar, md, func(c sam.Column) {
+		if len(chunk) >= samChunkSize && (c.Op != 'S' || chunk[len(chunk)-1].Op != 'S') {
+			flush()
+		}
+		chunk = append(chunk, c)
+	})
+	if len(chunk) > 0 {
+		flush()
+	}
+	return err
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamAlignment(t *testing.T) {
+	defer func(size int) { samChunkSize = size }(samChunkSize)
+	samChunkSize = 4
+
+	record := &samRecord{Name: "r1", Pos: 5, Cigar: "2S3M1I2M1D2M", Seq: "TTACGGAATT", Qual: "*"}
+	var blocks [][]string
+	summary := newSAMSummary()
+	err := streamAlignment(record, "3A1^C2", 11, "info", nil, nil, 0, func(lines []string) { blocks = append(blocks, lines) }, summary)
+	require.NoError(t, err)
+
+	require.Len(t, blocks, 3)
+	assert.Equal(t, []string{"info", "TTAC", "  ||", "..AC"}, stripAll(blocks[0]))
+	assert.Equal(t, []string{"-TT", " ||", "CTT"}, stripAll(blocks[2]))
+	assert.Equal(t, 1, summary.Reads)
+	assert.Equal(t, 1, summary.Deletions)
+	assert.Equal(t, 1, summary.Insertions)
+}
+
+func TestStreamAlignmentKeepsClipsWhole(t *testing.T) {
+	defer func(size int, hide bool) { samChunkSize, samHideClips = size, hide }(samChunkSize, samHideClips)
+	samChunkSize, samHideClips = 4, true
+
+	record := &samRecord{Name: "r1", Pos: 1, Cigar: "6S2M", Seq: "AAAAAAAC", Qual: "*"}
+	var blocks [][]string
+	err := streamAlignment(record, "2", 8, "info", nil, nil, 0, func(lines []string) { blocks = append(blocks, lines) }, nil)
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+	assert.Equal(t, "[6S]", stripAll(blocks[0])[1])
+}
+
+func TestStreamAlignmentError(t *testing.T) {
+	defer func(size int) { samChunkSize = size }(samChunkSize)
+	samChunkSize = 2
+
+	record := &samRecord{Name: "r1", Pos: 1, Cigar: "6M", Seq: "ACGT", Qual: "*"}
+	var blocks [][]string
+	err := streamAlignment(record, "", 6, "info", nil, nil, 0, func(lines []string) { blocks = append(blocks, lines) }, nil)
+	assert.ErrorContains(t, err, "sequence length is 4")
+	require.Len(t, blocks, 2)
+	assert.Equal(t, "info", stripAll(blocks[0])[0])
+}
+
+func stripAll(lines []string) []string {
+	stripped := make([]string, len(lines))
+	for i, line := range lines {
+		stripped[i] = stripMarkup(line)
+	}
+	return stripped
+}
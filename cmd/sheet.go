@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	sheetTo           string
+	sheetOutput       string
+	sheetKey          string
+	sheetStrandedness string
+)
+
+// sheetFormats lists the supported samplesheet layouts.
+var sheetFormats = map[string]struct {
+	description string
+	write       func(w io.Writer, cfg *sampleConfig) error
+}{
+	"nf-core/rnaseq":  {"CSV: sample,fastq_1,fastq_2,strandedness", writeNfcoreRnaseqSheet},
+	"nf-core/atacseq": {"CSV: sample,fastq_1,fastq_2,replicate", writeNfcoreAtacseqSheet},
+	"snakemake":       {"TSV units table: sample_name,unit_name,fq1,fq2", writeSnakemakeSheet},
+}
+
+var sheetCmd = &cobra.Command{
+	Use:   "sheet <yaml-file>",
+	Short: "Convert a sample YAML into a pipeline samplesheet",
+	Long: `Converts a sample YAML (the format used by 'checkbarcode') into the
+samplesheet of a workflow framework. All paths are written as absolute paths.
+
+The YAML is validated first (see 'hey validate'); nothing is written if it has
+errors such as missing files.
+
+Formats (--to):
+` + sheetFormatList() + `
+Example:
+  hey sheet --to nf-core/rnaseq samples.yaml -o samplesheet.csv
+  hey sheet --to snakemake samples.yaml > config/units.tsv`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, ok := sheetFormats[sheetTo]
+		if !ok {
+			return fmt.Errorf("unknown format %q, use one of: %s", sheetTo, strings.Join(sheetFormatNames(), ", "))
+		}
+		cfg, err := loadSampleConfig(args[0], sheetKey)
+		if err != nil {
+			return err
+		}
+		var errs []validationProblem
+		for _, p := range validateSampleConfig(cfg, fileExists) {
+			if p.Error {
+				errs = append(errs, p)
+			}
+		}
+		if len(errs) > 0 {
+			printValidationProblems(args[0], errs)
+			return fmt.Errorf("%s has %d errors, no samplesheet written", args[0], len(errs))
+		}
+
+		out := os.Stdout
+		if sheetOutput != "" {
+			out, err = os.Create(sheetOutput)
+			if err != nil {
+				return fmt.Errorf("creating %s: %w", sheetOutput, err)
+			}
+			defer out.Close()
+		}
+		if err := format.write(out, cfg); err != nil {
+			return fmt.Errorf("writing samplesheet: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sheetCmd)
+	sheetCmd.Flags().StringVarP(&sheetTo, "to", "t", "nf-core/rnaseq", "Samplesheet format: "+strings.Join(sheetFormatNames(), ", "))
+	sheetCmd.Flags().StringVarP(&sheetOutput, "output", "o", "", "Output file (default: stdout)")
+	sheetCmd.Flags().StringVarP(&sheetKey, "key", "k", "samples", "Top-level key in YAML file containing sample definitions")
+	sheetCmd.Flags().StringVar(&sheetStrandedness, "strandedness", "auto", "Strandedness column for nf-core/rnaseq: auto, forward, reverse or unstranded")
+}
+
+func sheetFormatNames() []string {
+	names := make([]string, 0, len(sheetFormats))
+	for name := range sheetFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sheetFormatList() string {
+	var b strings.Builder
+	for _, name := range sheetFormatNames() {
+		fmt.Fprintf(&b, "  %-16s %s\n", name, sheetFormats[name].description)
+	}
+	return b.String()
+}
+
+// sheetSampleName replaces characters that pipelines reject in sample names.
+func sheetSampleName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == ',' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+func writeCSVRows(w io.Writer, comma rune, rows [][]string) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+func resolveOptional(cfg *sampleConfig, path string) string {
+	if path == "" {
+		return ""
+	}
+	return cfg.resolve(path)
+}
+
+func writeNfcoreRnaseqSheet(w io.Writer, cfg *sampleConfig) error {
+	rows := [][]string{{"sample", "fastq_1", "fastq_2", "strandedness"}}
+	for _, run := range cfg.Runs {
+		rows = append(rows, []string{sheetSampleName(run.Sample), cfg.resolve(run.R1), resolveOptional(cfg, run.R2), sheetStrandedness})
+	}
+	return writeCSVRows(w, ',', rows)
+}
+
+// writeNfcoreAtacseqSheet numbers the samples of the YAML as replicates of the
+// condition named by their prefix before the last '_rep' (or of themselves).
+func writeNfcoreAtacseqSheet(w io.Writer, cfg *sampleConfig) error {
+	rows := [][]string{{"sample", "fastq_1", "fastq_2", "replicate"}}
+	for _, run := range cfg.Runs {
+		condition, replicate := splitReplicate(sheetSampleName(run.Sample))
+		rows = append(rows, []string{condition, cfg.resolve(run.R1), resolveOptional(cfg, run.R2), strconv.Itoa(replicate)})
+	}
+	return writeCSVRows(w, ',', rows)
+}
+
+// splitReplicate splits names such as "liver_rep2" or "liver_R2" into the
+// condition and replicate number; other names are replicate 1.
+func splitReplicate(name string) (string, int) {
+	lower := strings.ToLower(name)
+	for _, sep := range []string{"_rep", "_r"} {
+		i := strings.LastIndex(lower, sep)
+		if i <= 0 {
+			continue
+		}
+		if n, err := strconv.Atoi(name[i+len(sep):]); err == nil && n > 0 {
+			return name[:i], n
+		}
+	}
+	return name, 1
+}
+
+func writeSnakemakeSheet(w io.Writer, cfg *sampleConfig) error {
+	rows := [][]string{{"sample_name", "unit_name", "fq1", "fq2"}}
+	units := make(map[string]int)
+	for _, run := range cfg.Runs {
+		units[run.Sample]++
+		rows = append(rows, []string{sheetSampleName(run.Sample), strconv.Itoa(units[run.Sample]), cfg.resolve(run.R1), resolveOptional(cfg, run.R2)})
+	}
+	return writeCSVRows(w, '\t', rows)
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitReplicate(t *testing.T) {
+	tests := []struct {
+		name              string
+		expectedCondition string
+		expectedReplicate int
+	}{
+		{"liver_rep2", "liver", 2},
+		{"liver_REP10", "liver", 10},
+		{"heart_R3", "heart", 3},
+		{"ctrl", "ctrl", 1},
+		{"ctrl_rep", "ctrl_rep", 1},
+		{"_rep1", "_rep1", 1},
+	}
+	for _, tt := range tests {
+		condition, replicate := splitReplicate(tt.name)
+		assert.Equal(t, tt.expectedCondition, condition, tt.name)
+		assert.Equal(t, tt.expectedReplicate, replicate, tt.name)
+	}
+}
+
+func TestWriteSheets(t *testing.T) {
+	cfg := &sampleConfig{
+		Path: "/data/samples.yaml",
+		Runs: []sampleRun{
+			{Sample: "liver rep1", R1: "a_R1.fq.gz", R2: "a_R2.fq.gz"},
+			{Sample: "liver rep1", R1: "b_R1.fq.gz", R2: "b_R2.fq.gz"},
+			{Sample: "heart", R1: "/abs/h.fq.gz"},
+		},
+	}
+
+	var buf bytes.Buffer
+	sheetStrandedness = "reverse"
+	defer func() { sheetStrandedness = "auto" }()
+	require.NoError(t, writeNfcoreRnaseqSheet(&buf, cfg))
+	assert.Equal(t, "sample,fastq_1,fastq_2,strandedness\n"+
+		"liver_rep1,/data/a_R1.fq.gz,/data/a_R2.fq.gz,reverse\n"+
+		"liver_rep1,/data/b_R1.fq.gz,/data/b_R2.fq.gz,reverse\n"+
+		"heart,/abs/h.fq.gz,,reverse\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, writeSnakemakeSheet(&buf, cfg))
+	assert.Equal(t, "sample_name\tunit_name\tfq1\tfq2\n"+
+		"liver_rep1\t1\t/data/a_R1.fq.gz\t/data/a_R2.fq.gz\n"+
+		"liver_rep1\t2\t/data/b_R1.fq.gz\t/data/b_R2.fq.gz\n"+
+		"heart\t1\t/abs/h.fq.gz\t\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, writeNfcoreAtacseqSheet(&buf, cfg))
+	assert.Contains(t, buf.String(), "liver,/data/a_R1.fq.gz,/data/a_R2.fq.gz,1\n")
+}
@@ -0,0 +1,317 @@
+package cmd
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sraPaired  bool
+	sraThreads int
+	sraOutDir  string
+)
+
+const enaPortalAPI = "https://www.ebi.ac.uk/ena/portal/api"
+
+var sraCmd = &cobra.Command{
+	Use:   "sra <accession>...",
+	Short: "Download FASTQ files of SRA/ENA accessions",
+	Long: `Resolves the FASTQ files of run, experiment, sample or study accessions
+(SRR/ERR/DRR, SRX, SRS, SRP, PRJNA, ...) through the ENA portal API and
+downloads them over HTTPS.
+
+Files are downloaded concurrently, interrupted downloads are resumed from the
+partial .part file, and the MD5 checksum reported by ENA is verified. Files
+that are already complete are skipped.
+
+With --paired only the _1/_2 read files of paired-end runs are fetched, skipping
+the extra file of unpaired reads that ENA sometimes provides.
+
+Example:
+  hey sra SRR1234567
+  hey sra PRJNA123456 --paired -t 8 -o raw/`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if sraThreads < 1 {
+			return fmt.Errorf("--threads must be at least 1")
+		}
+		var files []sraFile
+		for _, accession := range args {
+			rows, err := enaFileReport(accession, []string{"run_accession", "fastq_ftp", "fastq_md5", "fastq_bytes"})
+			if err != nil {
+				return err
+			}
+			found, err := sraFilesFromReport(rows, sraPaired)
+			if err != nil {
+				return fmt.Errorf("%s: %w", accession, err)
+			}
+			if len(found) == 0 {
+				return fmt.Errorf("no FASTQ files found for %s", accession)
+			}
+			files = append(files, found...)
+		}
+		if err := os.MkdirAll(sraOutDir, 0o755); err != nil {
+			return fmt.Errorf("creating output directory: %w", err)
+		}
+		return downloadSraFiles(files, sraOutDir, sraThreads)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sraCmd)
+	sraCmd.Flags().BoolVarP(&sraPaired, "paired", "p", false, "Only download the _1/_2 files of paired-end runs")
+	sraCmd.Flags().IntVarP(&sraThreads, "threads", "t", defaultMaxWorkers, "Number of files to download at the same time")
+	sraCmd.Flags().StringVarP(&sraOutDir, "outdir", "o", ".", "Directory to save the files in")
+}
+
+// enaFileReport queries the ENA filereport endpoint for the runs below an
+// accession and returns one map per run, keyed by field name.
+func enaFileReport(accession string, fields []string) ([]map[string]string, error) {
+	query := url.Values{}
+	query.Set("accession", accession)
+	query.Set("result", "read_run")
+	query.Set("fields", strings.Join(fields, ","))
+	query.Set("format", "tsv")
+	return enaGetTSV(enaPortalAPI + "/filereport?" + query.Encode())
+}
+
+func enaGetTSV(reqURL string) ([]map[string]string, error) {
+	resp, err := http.Get(reqURL)
+	if err != nil {
+		return nil, fmt.Errorf("querying ENA: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading ENA response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ENA returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return parseTSVRecords(string(body)), nil
+}
+
+// parseTSVRecords turns a TSV document with a header line into one map per row.
+func parseTSVRecords(text string) []map[string]string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) < 2 {
+		return nil
+	}
+	headers := strings.Split(strings.TrimRight(lines[0], "\r"), "\t")
+	var records []map[string]string
+	for _, line := range lines[1:] {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+		values := strings.Split(line, "\t")
+		record := make(map[string]string, len(headers))
+		for i, h := range headers {
+			if i < len(values) {
+				record[h] = values[i]
+			}
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+type sraFile struct {
+	Run  string
+	URL  string
+	MD5  string
+	Size int64
+}
+
+func (f sraFile) name() string {
+	return path.Base(f.URL)
+}
+
+// sraFilesFromReport expands the ';' separated file lists of a filereport.
+func sraFilesFromReport(rows []map[string]string, paired bool) ([]sraFile, error) {
+	var files []sraFile
+	for _, row := range rows {
+		if row["fastq_ftp"] == "" {
+			continue
+		}
+		urls := strings.Split(row["fastq_ftp"], ";")
+		md5s := strings.Split(row["fastq_md5"], ";")
+		sizes := strings.Split(row["fastq_bytes"], ";")
+		for i, u := range urls {
+			f := sraFile{Run: row["run_accession"], URL: "https://" + strings.TrimPrefix(u, "ftp://")}
+			if paired && !strings.HasSuffix(f.name(), "_1.fastq.gz") && !strings.HasSuffix(f.name(), "_2.fastq.gz") {
+				continue
+			}
+			if i < len(md5s) {
+				f.MD5 = md5s[i]
+			}
+			if i < len(sizes) && sizes[i] != "" {
+				size, err := strconv.ParseInt(sizes[i], 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("invalid file size %q for %s", sizes[i], f.name())
+				}
+				f.Size = size
+			}
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+func downloadSraFiles(files []sraFile, outDir string, threads int) error {
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+	color.Cyan("Downloading %d files (%s) to %s", len(files), humanSize(total), outDir)
+
+	bar := progressbar.NewOptions64(total,
+		progressbar.OptionSetDescription("[cyan]Downloading..."),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionShowBytes(true),
+		progressbar.OptionShowElapsedTimeOnFinish(),
+		progressbar.OptionEnableColorCodes(true),
+		progressbar.OptionSetTheme(progressbar.Theme{Saucer: "[green]=[reset]", SaucerHead: "[green]>[reset]", SaucerPadding: " ", BarStart: "[", BarEnd: "]"}),
+	)
+
+	jobs := make(chan int, len(files))
+	errs := make([]error, len(files))
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+	numWorkers := min(threads, len(files))
+	wg.Add(numWorkers)
+	for range numWorkers {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				errs[i] = downloadSraFile(files[i], filepath.Join(outDir, files[i].name()), bar)
+			}
+		}()
+	}
+	wg.Wait()
+	_ = bar.Finish()
+	fmt.Fprintln(os.Stderr)
+
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			failed++
+			color.Red("%s: %v", files[i].name(), err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d downloads failed; run the same command again to resume", failed, len(files))
+	}
+	color.Green("All %d files downloaded and verified.", len(files))
+	return nil
+}
+
+// downloadSraFile fetches one file into dest, resuming from dest.part if present.
+func downloadSraFile(f sraFile, dest string, bar *progressbar.ProgressBar) error {
+	if info, err := os.Stat(dest); err == nil && info.Size() == f.Size {
+		if err := verifyMD5(dest, f.MD5); err == nil {
+			_ = bar.Add64(f.Size)
+			return nil
+		}
+	}
+
+	partPath := dest + ".part"
+	if err := resumeDownload(f.URL, partPath, bar); err != nil {
+		return err
+	}
+	return finishSraFile(partPath, dest, f.MD5)
+}
+
+// resumeDownload downloads rawURL into partPath, continuing after the bytes
+// already present in partPath when the server supports range requests.
+func resumeDownload(rawURL, partPath string, bar *progressbar.ProgressBar) error {
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		flags |= os.O_APPEND
+		_ = bar.Add64(offset)
+	case http.StatusOK:
+		flags |= os.O_TRUNC // Server ignored the range, start over
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is already complete.
+		_ = bar.Add64(offset)
+		return nil
+	default:
+		return fmt.Errorf("server returned %s", resp.Status)
+	}
+
+	out, err := os.OpenFile(partPath, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(io.MultiWriter(out, bar), resp.Body); err != nil {
+		out.Close()
+		return fmt.Errorf("download interrupted: %w", err)
+	}
+	return out.Close()
+}
+
+func finishSraFile(partPath, dest, checksum string) error {
+	if err := verifyMD5(partPath, checksum); err != nil {
+		// A corrupt partial file cannot be resumed, so start from scratch next time.
+		os.Remove(partPath)
+		return err
+	}
+	return os.Rename(partPath, dest)
+}
+
+func verifyMD5(filename, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(got, expected) {
+		return fmt.Errorf("MD5 checksum mismatch: expected %s, got %s", expected, got)
+	}
+	return nil
+}
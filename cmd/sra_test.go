@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTSVRecords(t *testing.T) {
+	records := parseTSVRecords("run_accession\tfastq_bytes\r\nSRR1\t10\r\nSRR2\n\n")
+	require.Len(t, records, 2)
+	assert.Equal(t, map[string]string{"run_accession": "SRR1", "fastq_bytes": "10"}, records[0])
+	assert.Equal(t, map[string]string{"run_accession": "SRR2"}, records[1])
+	assert.Nil(t, parseTSVRecords("run_accession\n"))
+}
+
+func TestSraFilesFromReport(t *testing.T) {
+	rows := []map[string]string{
+		{
+			"run_accession": "SRR1",
+			"fastq_ftp":     "ftp.sra.ebi.ac.uk/vol1/fastq/SRR1/SRR1.fastq.gz;ftp.sra.ebi.ac.uk/vol1/fastq/SRR1/SRR1_1.fastq.gz;ftp.sra.ebi.ac.uk/vol1/fastq/SRR1/SRR1_2.fastq.gz",
+			"fastq_md5":     "a;b;c",
+			"fastq_bytes":   "1;2;3",
+		},
+		{"run_accession": "SRR2", "fastq_ftp": ""},
+	}
+
+	files, err := sraFilesFromReport(rows, false)
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	assert.Equal(t, sraFile{Run: "SRR1", URL: "https://ftp.sra.ebi.ac.uk/vol1/fastq/SRR1/SRR1.fastq.gz", MD5: "a", Size: 1}, files[0])
+
+	files, err = sraFilesFromReport(rows, true)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, "SRR1_1.fastq.gz", files[0].name())
+	assert.Equal(t, "c", files[1].MD5)
+	assert.Equal(t, int64(3), files[1].Size)
+
+	_, err = sraFilesFromReport([]map[string]string{{"fastq_ftp": "x/y.fastq.gz", "fastq_bytes": "big"}}, false)
+	assert.Error(t, err)
+}
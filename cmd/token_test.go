@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenWords(t *testing.T) {
+	words := tokenWords()
+	require.Len(t, words, 256)
+	seen := make(map[string]bool)
+	for _, w := range words {
+		assert.False(t, seen[w], "duplicate word %q", w)
+		seen[w] = true
+	}
+	assert.Equal(t, 48.0, tokenEntropy("words", 6))
+}
+
+func TestGenerateToken(t *testing.T) {
+	tests := []struct {
+		format  string
+		length  int
+		pattern string
+	}{
+		{"hex", 32, `^[0-9a-f]{32}$`},
+		{"hex", 7, `^[0-9a-f]{7}$`},
+		{"base64", 20, `^[A-Za-z0-9_-]{20}$`},
+		{"base64", 1, `^[A-Za-z0-9_-]$`},
+	}
+	for _, tt := range tests {
+		token, err := generateToken(tt.format, tt.length, "-")
+		require.NoError(t, err)
+		assert.Regexp(t, regexp.MustCompile(tt.pattern), token)
+	}
+
+	phrase, err := generateToken("words", 4, ".")
+	require.NoError(t, err)
+	assert.Len(t, strings.Split(phrase, "."), 4)
+
+	_, err = generateToken("emoji", 4, "-")
+	assert.Error(t, err)
+}
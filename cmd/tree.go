@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	treeDepth    int
+	treeInclude  []string
+	treeExclude  []string
+	treeDirsOnly bool
+	treeAll      bool
+)
+
+var treeCmd = &cobra.Command{
+	Use:   "tree [path]",
+	Short: "Print a colorized directory tree with sizes",
+	Long: `Prints a directory tree with the size of every file and the total size and
+file count of every directory. Hidden entries are skipped unless --all is set.
+
+With --include only matching files are shown (and counted), and directories
+without any matching file are left out.
+
+Example:
+  hey tree
+  hey tree /project/raw -L 2 --include '*.fastq.gz'
+  hey tree --dirs-only`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := "."
+		if len(args) == 1 {
+			root = args[0]
+		}
+		for _, pattern := range append(treeInclude, treeExclude...) {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+		info, err := os.Stat(root)
+		if err != nil {
+			return fmt.Errorf("cannot access %q: %w", root, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%q is not a directory", root)
+		}
+		node := buildTree(root, filepath.Clean(root))
+		printTree(os.Stdout, node)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(treeCmd)
+	treeCmd.Flags().IntVarP(&treeDepth, "level", "L", 0, "Maximum depth to display (0 = unlimited)")
+	treeCmd.Flags().StringSliceVarP(&treeInclude, "include", "i", nil, "Only show files matching this glob (repeatable)")
+	treeCmd.Flags().StringSliceVarP(&treeExclude, "exclude", "e", nil, "Skip entries matching this glob (repeatable)")
+	treeCmd.Flags().BoolVarP(&treeDirsOnly, "dirs-only", "d", false, "Only show directories")
+	treeCmd.Flags().BoolVarP(&treeAll, "all", "a", false, "Include hidden files and directories")
+}
+
+type treeNode struct {
+	Name     string
+	Mode     os.FileMode
+	Size     int64 // Total size for directories
+	Files    int   // Number of (matching) files below a directory
+	Children []*treeNode
+	Err      error
+}
+
+// buildTree reads the whole directory below path. Sizes and counts are always
+// computed over the full tree, even beyond the displayed depth.
+func buildTree(path, name string) *treeNode {
+	node := &treeNode{Name: name, Mode: os.ModeDir}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		node.Err = err
+		return node
+	}
+	for _, e := range entries {
+		if !treeAll && strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		if isExcluded(e.Name(), treeExclude) {
+			continue
+		}
+		childPath := filepath.Join(path, e.Name())
+		if e.IsDir() {
+			child := buildTree(childPath, e.Name())
+			if len(treeInclude) > 0 && child.Files == 0 && child.Err == nil {
+				continue
+			}
+			node.Size += child.Size
+			node.Files += child.Files
+			node.Children = append(node.Children, child)
+			continue
+		}
+		if len(treeInclude) > 0 && !isExcluded(e.Name(), treeInclude) {
+			continue
+		}
+		child := &treeNode{Name: e.Name(), Mode: e.Type()}
+		if info, err := e.Info(); err == nil {
+			child.Size = info.Size()
+			child.Mode = info.Mode()
+		} else {
+			child.Err = err
+		}
+		node.Size += child.Size
+		node.Files++
+		node.Children = append(node.Children, child)
+	}
+	// Directories first, then files, each sorted by name.
+	sort.Slice(node.Children, func(i, j int) bool {
+		a, b := node.Children[i], node.Children[j]
+		if a.Mode.IsDir() != b.Mode.IsDir() {
+			return a.Mode.IsDir()
+		}
+		return a.Name < b.Name
+	})
+	return node
+}
+
+func treeLabel(n *treeNode) string {
+	if n.Mode.IsDir() {
+		label := color.New(color.FgBlue, color.Bold).Sprint(n.Name+"/") +
+			color.HiBlackString(" (%s, %s)", humanSize(n.Size), countNoun(n.Files, "file", "files"))
+		if n.Err != nil {
+			label += color.RedString(" [%v]", n.Err)
+		}
+		return label
+	}
+	name := n.Name
+	switch {
+	case n.Mode&os.ModeSymlink != 0:
+		name = color.CyanString(name)
+	case n.Mode&0o111 != 0:
+		name = color.GreenString(name)
+	}
+	return name + color.HiBlackString(" [%s]", humanSize(n.Size))
+}
+
+// countNoun returns n followed by the singular or plural noun.
+func countNoun(n int, singular, plural string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
+
+func printTree(w io.Writer, root *treeNode) {
+	fmt.Fprintln(w, treeLabel(root))
+	dirs := 0
+	var walk func(n *treeNode, prefix string, level int)
+	walk = func(n *treeNode, prefix string, level int) {
+		if treeDepth > 0 && level >= treeDepth {
+			return
+		}
+		var visible []*treeNode
+		for _, c := range n.Children {
+			if !treeDirsOnly || c.Mode.IsDir() {
+				visible = append(visible, c)
+			}
+		}
+		for i, c := range visible {
+			branch, next := "├── ", "│   "
+			if i == len(visible)-1 {
+				branch, next = "└── ", "    "
+			}
+			fmt.Fprintln(w, color.HiBlackString(prefix+branch)+treeLabel(c))
+			if c.Mode.IsDir() {
+				dirs++
+				walk(c, prefix+next, level+1)
+			}
+		}
+	}
+	walk(root, "", 0)
+	fmt.Fprintf(w, "\n%s, %s, %s\n", countNoun(dirs, "directory", "directories"), countNoun(root.Files, "file", "files"), humanSize(root.Size))
+}
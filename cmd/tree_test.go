@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTree(t *testing.T) {
+	defer func(include, exclude []string, dirsOnly, all bool, depth int) {
+		treeInclude, treeExclude, treeDirsOnly, treeAll, treeDepth = include, exclude, dirsOnly, all, depth
+	}(treeInclude, treeExclude, treeDirsOnly, treeAll, treeDepth)
+
+	dir := t.TempDir()
+	writeTestTree(t, dir, map[string]int{
+		"raw/a.fastq.gz":  100,
+		"raw/b.fastq.gz":  200,
+		"raw/md5.txt":     10,
+		"qc/report.html":  50,
+		".hidden/x.fq.gz": 1,
+		"notes.txt":       5,
+	})
+
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		dirsOnly bool
+		all      bool
+		depth    int
+		expected string
+	}{
+		{
+			name: "all files",
+			expected: "root/ (365 B, 5 files)\n" +
+				"├── qc/ (50 B, 1 file)\n" +
+				"│   └── report.html [50 B]\n" +
+				"├── raw/ (310 B, 3 files)\n" +
+				"│   ├── a.fastq.gz [100 B]\n" +
+				"│   ├── b.fastq.gz [200 B]\n" +
+				"│   └── md5.txt [10 B]\n" +
+				"└── notes.txt [5 B]\n" +
+				"\n2 directories, 5 files, 365 B\n",
+		},
+		{
+			name:    "include drops directories without matches",
+			include: []string{"*.gz"},
+			all:     true,
+			expected: "root/ (301 B, 3 files)\n" +
+				"├── .hidden/ (1 B, 1 file)\n" +
+				"│   └── x.fq.gz [1 B]\n" +
+				"└── raw/ (300 B, 2 files)\n" +
+				"    ├── a.fastq.gz [100 B]\n" +
+				"    └── b.fastq.gz [200 B]\n" +
+				"\n2 directories, 3 files, 301 B\n",
+		},
+		{
+			name:     "dirs only with one directory",
+			exclude:  []string{"raw"},
+			dirsOnly: true,
+			expected: "root/ (55 B, 2 files)\n" +
+				"└── qc/ (50 B, 1 file)\n" +
+				"\n1 directory, 2 files, 55 B\n",
+		},
+		{
+			name:  "depth counts files below it",
+			depth: 1,
+			expected: "root/ (365 B, 5 files)\n" +
+				"├── qc/ (50 B, 1 file)\n" +
+				"├── raw/ (310 B, 3 files)\n" +
+				"└── notes.txt [5 B]\n" +
+				"\n2 directories, 5 files, 365 B\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			treeInclude, treeExclude, treeDirsOnly, treeAll, treeDepth = tt.include, tt.exclude, tt.dirsOnly, tt.all, tt.depth
+			var out strings.Builder
+			printTree(&out, buildTree(dir, "root"))
+			assert.Equal(t, tt.expected, out.String())
+		})
+	}
+}
+
+func TestCountNoun(t *testing.T) {
+	assert.Equal(t, "0 files", countNoun(0, "file", "files"))
+	assert.Equal(t, "1 directory", countNoun(1, "directory", "directories"))
+	assert.Equal(t, "2 directories", countNoun(2, "directory", "directories"))
+}
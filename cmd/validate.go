@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var validateKey string
+
+var validateCmd = &cobra.Command{
+	Use:   "validate <yaml-file>",
+	Short: "Check a sample YAML for problems",
+	Long: `Checks a sample YAML (the format used by 'checkbarcode') and reports all
+problems at once, with the line they were found on:
+
+  - the top-level key and the structure of every sample and run
+  - every run has an R1, and R1/R2 files exist
+  - R1 and R2 look like mates, and a sample does not mix paired and single runs
+  - sample names and files are not listed twice
+  - barcodes only contain A, C, G, T, N and '+'
+
+Exits with a non-zero status if any error is found; warnings are reported
+but do not fail the check.
+
+Example:
+  hey validate samples.yaml
+  hey validate config.yaml -k libraries`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadSampleConfig(args[0], validateKey)
+		if err != nil {
+			return err
+		}
+		problems := validateSampleConfig(cfg, fileExists)
+		printValidationProblems(args[0], problems)
+
+		numErrors := 0
+		for _, p := range problems {
+			if p.Error {
+				numErrors++
+			}
+		}
+		if numErrors > 0 {
+			return fmt.Errorf("%s has %d errors", args[0], numErrors)
+		}
+		color.Green("%s is valid: %d samples, %d runs.", args[0], len(cfg.Samples), len(cfg.Runs))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	validateCmd.Flags().StringVarP(&validateKey, "key", "k", "samples", "Top-level key in YAML file containing sample definitions")
+}
+
+type validationProblem struct {
+	Line    int
+	Error   bool // false for warnings
+	Message string
+}
+
+var sampleBarcodeRegex = regexp.MustCompile(`^[ACGTN+]+$`)
+
+func validateSampleConfig(cfg *sampleConfig, exists func(string) bool) []validationProblem {
+	var problems []validationProblem
+	report := func(line int, isError bool, format string, args ...any) {
+		problems = append(problems, validationProblem{Line: line, Error: isError, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, issue := range cfg.Issues {
+		report(issue.Line, true, "%s", issue.Message)
+	}
+
+	for _, name := range cfg.Samples {
+		if lines := cfg.SampleLines[name]; len(lines) > 1 {
+			for _, line := range lines[1:] {
+				report(line, true, "sample '%s' is already defined on line %d", name, lines[0])
+			}
+		}
+	}
+
+	fileLines := make(map[string]int)
+	paired := make(map[string][2]int) // Per sample: number of paired and single runs
+	for _, run := range cfg.Runs {
+		where := fmt.Sprintf("sample '%s', run %d", run.Sample, run.Index)
+		if run.R1 == "" {
+			report(run.Line, true, "%s has no R1", where)
+		}
+		for _, read := range []struct{ name, path string }{{"R1", run.R1}, {"R2", run.R2}} {
+			if read.path == "" {
+				continue
+			}
+			abs := cfg.resolve(read.path)
+			if !exists(abs) {
+				report(run.Line, true, "%s: %s file not found: %s", where, read.name, read.path)
+			}
+			if first, ok := fileLines[abs]; ok {
+				report(run.Line, true, "%s: %s %s is also listed on line %d", where, read.name, read.path, first)
+			} else {
+				fileLines[abs] = run.Line
+			}
+		}
+
+		counts := paired[run.Sample]
+		if run.R1 != "" && run.R2 != "" {
+			counts[0]++
+			if msg := checkMates(run.R1, run.R2); msg != "" {
+				report(run.Line, false, "%s: %s", where, msg)
+			}
+		} else {
+			counts[1]++
+		}
+		paired[run.Sample] = counts
+
+		if run.Barcode != "" && !sampleBarcodeRegex.MatchString(run.Barcode) {
+			report(run.Line, true, "%s: invalid barcode %q (only A, C, G, T, N and '+' are allowed)", where, run.Barcode)
+		}
+	}
+
+	for _, name := range cfg.Samples {
+		if counts := paired[name]; counts[0] > 0 && counts[1] > 0 {
+			report(cfg.SampleLines[name][0], false, "sample '%s' mixes %d paired and %d single-end runs", name, counts[0], counts[1])
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+	return problems
+}
+
+// checkMates describes why two files do not look like the R1/R2 files of the
+// same run, or returns "" if they do.
+func checkMates(r1, r2 string) string {
+	if filepath.Clean(r1) == filepath.Clean(r2) {
+		return "R1 and R2 are the same file"
+	}
+	run1, _, read1 := parseFastqName(filepath.Base(r1))
+	run2, _, read2 := parseFastqName(filepath.Base(r2))
+	switch {
+	case read1 == 2 && read2 == 1:
+		return "R1 and R2 look swapped"
+	case read1 != 0 && read1 != 1:
+		return fmt.Sprintf("R1 file %s does not look like read 1", filepath.Base(r1))
+	case read2 != 0 && read2 != 2:
+		return fmt.Sprintf("R2 file %s does not look like read 2", filepath.Base(r2))
+	case run1 != run2:
+		return fmt.Sprintf("R1 and R2 names do not match (%s vs %s)", filepath.Base(r1), filepath.Base(r2))
+	}
+	return ""
+}
+
+func printValidationProblems(path string, problems []validationProblem) {
+	for _, p := range problems {
+		location := fmt.Sprintf("%s:%d:", path, p.Line)
+		if p.Error {
+			fmt.Println(color.New(color.Bold).Sprint(location), color.RedString("error:"), p.Message)
+		} else {
+			fmt.Println(color.New(color.Bold).Sprint(location), color.YellowString("warning:"), p.Message)
+		}
+	}
+	if len(problems) > 0 {
+		var errs, warns int
+		for _, p := range problems {
+			if p.Error {
+				errs++
+			} else {
+				warns++
+			}
+		}
+		fmt.Printf("%d errors, %d warnings\n", errs, warns)
+	}
+}
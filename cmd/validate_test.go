@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckMates(t *testing.T) {
+	tests := []struct {
+		r1, r2   string
+		expected string
+	}{
+		{"a_S1_L001_R1_001.fastq.gz", "a_S1_L001_R2_001.fastq.gz", ""},
+		{"x/a_1.fq.gz", "y/a_2.fq.gz", ""},
+		{"a_R1.fq.gz", "a_R1.fq.gz", "R1 and R2 are the same file"},
+		{"a_R2.fq.gz", "a_R1.fq.gz", "R1 and R2 look swapped"},
+		{"a_R1.fq.gz", "b_R2.fq.gz", "R1 and R2 names do not match (a_R1.fq.gz vs b_R2.fq.gz)"},
+		{"a_R2.fq.gz", "a_R2_001.fq.gz", "R1 file a_R2.fq.gz does not look like read 1"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, checkMates(tt.r1, tt.r2), tt.r1+" "+tt.r2)
+	}
+}
+
+func TestValidateSampleConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "samples.yaml")
+	content := `samples:
+  a:
+    barcode: ACGX
+    data:
+      - R1: a_R1.fq.gz
+        R2: a_R2.fq.gz
+      - R1: a2_R1.fq.gz
+  b:
+    - R2: b_R2.fq.gz
+    - R1: a_R1.fq.gz
+  a:
+    - R1: missing.fq.gz
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	cfg, err := loadSampleConfig(path, "samples")
+	require.NoError(t, err)
+
+	missing := filepath.Join(dir, "missing.fq.gz")
+	problems := validateSampleConfig(cfg, func(p string) bool { return p != missing })
+
+	var got []string
+	for _, p := range problems {
+		kind := "warning"
+		if p.Error {
+			kind = "error"
+		}
+		got = append(got, kind+": "+p.Message)
+	}
+	assert.Equal(t, []string{
+		"warning: sample 'a' mixes 1 paired and 2 single-end runs",
+		`error: sample 'a', run 1: invalid barcode "ACGX" (only A, C, G, T, N and '+' are allowed)`,
+		`error: sample 'a', run 2: invalid barcode "ACGX" (only A, C, G, T, N and '+' are allowed)`,
+		"error: sample 'b', run 1 has no R1",
+		"error: sample 'b', run 2: R1 a_R1.fq.gz is also listed on line 5",
+		"error: sample 'a' is already defined on line 2",
+		"error: sample 'a', run 1: R1 file not found: missing.fq.gz",
+	}, got)
+}
@@ -6,14 +6,18 @@ require (
 	github.com/aquasecurity/table v1.9.0
 	github.com/fatih/color v1.15.0
 	github.com/gizak/termui/v3 v3.1.0
-	github.com/golang/text v0.3.0
 	github.com/ivanpirog/coloredcobra v1.0.1
 	github.com/jackpal/gateway v1.1.1
+	github.com/klauspost/compress v1.18.0
 	github.com/liamg/tml v0.7.0
 	github.com/mattn/go-runewidth v0.0.16
 	github.com/schollz/progressbar/v3 v3.18.0
 	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	github.com/spf13/cobra v1.9.1
+	github.com/stretchr/testify v1.10.0
+	github.com/ulikunitz/xz v0.5.15
+	golang.org/x/net v0.39.0
+	golang.org/x/term v0.31.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -29,9 +33,5 @@ require (
 	github.com/rivo/uniseg v0.4.7 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
-	github.com/stretchr/testify v1.10.0 // indirect
-	golang.org/x/net v0.39.0 // indirect
 	golang.org/x/sys v0.32.0 // indirect
-	golang.org/x/term v0.31.0 // indirect
-	golang.org/x/text v0.24.0 // indirect
 )
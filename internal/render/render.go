@@ -0,0 +1,200 @@
+// Package render colors DNA sequences as tml markup. It is shared by hey fastq
+// and hey sam2pairwise so that both show bases the same way.
+package render
+
+import (
+	"strings"
+
+	"github.com/yech1990/hey/pkg/sam"
+)
+
+// Palette maps bases (and the gap '-' and padding '*') to the tml color used
+// as their background when highlighted.
+type Palette map[byte]string
+
+// Palettes are the built-in palettes by name.
+var Palettes = map[string]Palette{
+	"default":    {'A': "red", 'C': "blue", 'G': "yellow", 'T': "green", '-': "black", '*': "magenta"},
+	"igv":        {'A': "green", 'C': "blue", 'G': "yellow", 'T': "red", '-': "black", '*': "magenta"},
+	"colorblind": {'A': "blue", 'C': "yellow", 'G': "magenta", 'T': "cyan", '-': "black", '*': "lightgrey"},
+}
+
+// Colors are the tml colors that can be used as backgrounds.
+var Colors = []string{
+	"black", "red", "green", "yellow", "blue", "magenta", "cyan", "lightgrey", "darkgrey",
+	"lightred", "lightgreen", "lightyellow", "lightblue", "lightmagenta", "lightcyan", "white",
+}
+
+// Color returns the color of base, ignoring case, or "" if it has none.
+func (p Palette) Color(base byte) string {
+	if base >= 'a' && base <= 'z' {
+		base -= 'a' - 'A'
+	}
+	return p[base]
+}
+
+// Options are the settings of how bases are rendered.
+type Options struct {
+	Palette Palette
+	// QualityCutoff is the quality score below which bases are low quality,
+	// 0 for none.
+	QualityCutoff int
+	// LowQualityColor is the color of low-quality bases, "darkgrey" if empty.
+	LowQualityColor string
+	// ColorByQuality colors the bases of Sequence by their quality, on a
+	// QualityGradientColor background, instead of by nucleotide.
+	ColorByQuality bool
+	// NoColor writes the bases without markup.
+	NoColor bool
+}
+
+// LowQuality tells whether the base at i of a read is below the quality
+// cutoff. qual holds the phred+33 scores of the read, or is "" or "*" if the
+// read has none.
+func (o Options) LowQuality(qual string, i int) bool {
+	return o.QualityCutoff > 0 && qual != "*" && i >= 0 && i < len(qual) && int(qual[i])-33 < o.QualityCutoff
+}
+
+// WriteBase writes the markup of base to b. Bases, gaps and padding are shown
+// on their palette color if highlight is set; Ns, intron dots and missing
+// bases are dark grey. A low-quality base is shown in LowQualityColor
+// instead, still on its palette color if highlighted.
+func (o Options) WriteBase(b *strings.Builder, base byte, highlight, lowQuality bool) {
+	if o.NoColor {
+		b.WriteByte(base)
+		return
+	}
+	lowColor := o.LowQualityColor
+	if lowColor == "" {
+		lowColor = "darkgrey"
+	}
+	if lowQuality {
+		b.WriteString("<" + lowColor + ">")
+	}
+
+	switch base {
+	case 'A', 'a', 'T', 't', 'G', 'g', 'C', 'c', '-', '*':
+		color := ""
+		if highlight {
+			color = o.Palette.Color(base)
+		}
+		if color != "" {
+			b.WriteString("<bg-" + color + ">")
+			b.WriteByte(base)
+			b.WriteString("</bg-" + color + ">")
+		} else {
+			b.WriteByte(base)
+		}
+	case 'N', 'n', '.', sam.MissingBase:
+		if lowQuality {
+			b.WriteByte(base)
+		} else {
+			b.WriteString("<darkgrey>" + string(base) + "</darkgrey>")
+		}
+	default:
+		b.WriteByte(base)
+	}
+
+	if lowQuality {
+		b.WriteString("</" + lowColor + ">")
+	}
+}
+
+// Sequence returns the markup of seq with every base highlighted, except the
+// bases below the quality cutoff of qual, which are shown in LowQualityColor
+// only. With ColorByQuality, bases with a quality in qual are colored by it.
+func (o Options) Sequence(seq, qual string) string {
+	var b strings.Builder
+	b.Grow(len(seq) * 16)
+	byQuality := o.ColorByQuality && !o.NoColor && qual != "*"
+	for i := 0; i < len(seq); i++ {
+		switch {
+		case o.LowQuality(qual, i):
+			o.WriteBase(&b, seq[i], false, true)
+		case byQuality && i < len(qual):
+			color := QualityGradientColor(Score(qual[i]))
+			b.WriteString("<bg-" + color + "><black>" + string(seq[i]) + "</black></bg-" + color + ">")
+		default:
+			o.WriteBase(&b, seq[i], true, false)
+		}
+	}
+	return b.String()
+}
+
+// Score returns the quality score of a phred+33 quality character.
+func Score(q byte) int {
+	return max(int(q)-33, 0)
+}
+
+// QualityColor returns the color of the quality bar for score: green (30 and
+// up), yellow (20-29), red (10-19) or dark grey.
+func QualityColor(score int) string {
+	switch {
+	case score >= 30:
+		return "green"
+	case score >= 20:
+		return "yellow"
+	case score >= 10:
+		return "red"
+	}
+	return "darkgrey"
+}
+
+// QualityGradientColor returns the background color of a base of quality
+// score when bases are colored by quality, in finer steps than QualityColor.
+func QualityGradientColor(score int) string {
+	switch {
+	case score >= 35:
+		return "lightgreen"
+	case score >= 30:
+		return "green"
+	case score >= 25:
+		return "lightyellow"
+	case score >= 20:
+		return "yellow"
+	case score >= 15:
+		return "lightred"
+	case score >= 10:
+		return "red"
+	}
+	return "darkgrey"
+}
+
+// QualityChar returns the block of the quality bar for score, fuller for
+// better scores.
+func QualityChar(score int) rune {
+	switch {
+	case score >= 40:
+		return '│'
+	case score >= 30:
+		return '▓'
+	case score >= 20:
+		return '▒'
+	case score >= 10:
+		return '░'
+	}
+	return '·'
+}
+
+// Quality returns the markup of the quality bar of qual: a block per base,
+// colored by QualityColor.
+func (o Options) Quality(qual string) string {
+	var b strings.Builder
+	b.Grow(len(qual) * 4)
+	color := ""
+	for i := 0; i < len(qual); i++ {
+		score := Score(qual[i])
+		if next := QualityColor(score); next != color && !o.NoColor {
+			if color != "" {
+				b.WriteString("</" + color + ">")
+			}
+			color = next
+			b.WriteString("<" + color + ">")
+		}
+		b.WriteRune(QualityChar(score))
+	}
+	if color != "" {
+		b.WriteString("</" + color + ">")
+	}
+	return b.String()
+}
@@ -0,0 +1,76 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaletteColor(t *testing.T) {
+	p := Palettes["default"]
+	assert.Equal(t, "red", p.Color('A'))
+	assert.Equal(t, "red", p.Color('a'))
+	assert.Equal(t, "", p.Color('N'))
+	assert.Equal(t, "", Palette(nil).Color('A'))
+}
+
+func TestLowQuality(t *testing.T) {
+	o := Options{QualityCutoff: 20}
+	assert.True(t, o.LowQuality("I#", 1))
+	assert.False(t, o.LowQuality("I#", 0))
+	assert.False(t, o.LowQuality("I#", 2))
+	assert.False(t, o.LowQuality("*", 0))
+	assert.False(t, o.LowQuality("", 0))
+	assert.False(t, Options{}.LowQuality("#", 0))
+}
+
+func TestWriteBase(t *testing.T) {
+	o := Options{Palette: Palettes["default"]}
+	tests := []struct {
+		base                  byte
+		highlight, lowQuality bool
+		want                  string
+	}{
+		{'A', true, false, "<bg-red>A</bg-red>"},
+		{'g', true, false, "<bg-yellow>g</bg-yellow>"},
+		{'A', false, false, "A"},
+		{'-', true, false, "<bg-black>-</bg-black>"},
+		{'N', true, false, "<darkgrey>N</darkgrey>"},
+		{'?', false, false, "<darkgrey>?</darkgrey>"},
+		{'N', false, true, "<darkgrey>N</darkgrey>"},
+		{'C', true, true, "<darkgrey><bg-blue>C</bg-blue></darkgrey>"},
+		{'R', true, false, "R"},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		o.WriteBase(&b, tt.base, tt.highlight, tt.lowQuality)
+		assert.Equal(t, tt.want, b.String(), "%c", tt.base)
+	}
+}
+
+func TestSequence(t *testing.T) {
+	o := Options{Palette: Palettes["igv"], QualityCutoff: 20, LowQualityColor: "cyan"}
+	assert.Equal(t, "<bg-green>A</bg-green><cyan>T</cyan><darkgrey>N</darkgrey>", o.Sequence("ATN", "I#I"))
+	assert.Equal(t, "<bg-green>A</bg-green><bg-red>T</bg-red>", o.Sequence("AT", ""))
+
+	o.NoColor = true
+	assert.Equal(t, "ATN", o.Sequence("ATN", "I#I"))
+}
+
+func TestSequenceByQuality(t *testing.T) {
+	o := Options{Palette: Palettes["default"], QualityCutoff: 10, ColorByQuality: true}
+	assert.Equal(t, "<bg-lightgreen><black>A</black></bg-lightgreen><bg-red><black>C</black></bg-red><darkgrey>G</darkgrey>",
+		o.Sequence("ACG", "I/#"))
+	// Without qualities bases keep their palette colors.
+	assert.Equal(t, "<bg-red>A</bg-red>", o.Sequence("A", ""))
+	assert.Equal(t, "<bg-red>A</bg-red>", o.Sequence("A", "*"))
+}
+
+func TestQuality(t *testing.T) {
+	assert.Equal(t, "<green>│▓</green><yellow>▒</yellow><red>░</red><darkgrey>·</darkgrey><green>▓</green>",
+		Options{}.Quality("I?5+!?"))
+	assert.Equal(t, "│▓▒", Options{NoColor: true}.Quality("I?5"))
+	assert.Equal(t, 0, Score(' '))
+	assert.Equal(t, 40, Score('I'))
+}
@@ -0,0 +1,202 @@
+package sam
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Column is one column of a pairwise alignment.
+type Column struct {
+	Op       rune // CIGAR operation of the column: M, =, X, I, D, N, S or P
+	Read     byte // Read base; '-' in deletions, '.' in introns, '*' in padding, MissingBase if SEQ is "*"
+	Ref      byte // Reference base; '-' in insertions, '.' in soft clips, 'N' if unknown, '*' in padding
+	ReadPos  int  // 0-based position of Read in SEQ, or -1 if the column has no read base
+	RefSpan  int  // Number of reference bases the column covers: 0 for I, S and P, the intron length for N, 1 otherwise
+	Mismatch bool // Whether an M, = or X column is a mismatch per the MD tag or, without one, is not an = operation
+}
+
+// MissingBase stands for the read bases of records without a sequence (SEQ
+// "*"), such as secondary alignments.
+const MissingBase = '?'
+
+// Align reconstructs the pairwise alignment of a read from its SEQ, CIGAR and
+// MD tag. Reference bases come from the MD tag, so without one they are 'N'
+// (except in = operations). Every base of the read and of the covered
+// reference gets its own column, except an intron, which is a single N column.
+// If seq is "*", the read bases are MissingBase and only the mismatching and
+// deleted reference bases are known, from the MD tag.
+func Align(seq, cigar, md string) ([]Column, error) {
+	var columns []Column
+	err := AlignFunc(seq, cigar, md, func(c Column) {
+		columns = append(columns, c)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+// AlignFunc is Align for long reads: it passes the columns to fn one at a
+// time instead of collecting them. On an error, fn has seen the columns
+// before it.
+func AlignFunc(seq, cigar, md string, fn func(Column)) error {
+	noSeq := seq == "*"
+	readBase := func(op rune, pos int) (byte, error) {
+		if noSeq {
+			return MissingBase, nil
+		}
+		if pos >= len(seq) {
+			return 0, fmt.Errorf("CIGAR %c asks for base %d but sequence length is %d", op, pos+1, len(seq))
+		}
+		return seq[pos], nil
+	}
+	ops, err := ParseCigar(cigar)
+	if err != nil {
+		return fmt.Errorf("error parsing CIGAR '%s': %w", cigar, err)
+	}
+
+	var entries []MDEntry
+	mdIndex := 0
+	mdSubPos := 0
+	hasMD := md != ""
+	if hasMD {
+		if entries, err = ParseMD(md); err != nil {
+			hasMD = false
+			entries = nil
+		}
+	}
+	skipEmptyEntries := func() {
+		for mdIndex < len(entries) && entries[mdIndex].Num == 0 && len(entries[mdIndex].Changes) == 0 && !entries[mdIndex].IsDel {
+			mdIndex++
+		}
+	}
+
+	seqPos := 0
+	for _, op := range ops {
+		switch op.Op {
+		case 'M', '=', 'X':
+			for range op.Length {
+				base, err := readBase(op.Op, seqPos)
+				if err != nil {
+					return err
+				}
+				refBase := byte('N')
+				isMismatch := true
+				if hasMD {
+					skipEmptyEntries()
+					if mdIndex >= len(entries) {
+						hasMD = false
+					} else if entry := &entries[mdIndex]; entry.IsDel {
+						return fmt.Errorf("MD tag indicates deletion (^) during CIGAR M/=/X at MD index %d (MD: %s)", mdIndex, md)
+					} else if entry.Num > 0 {
+						if !noSeq {
+							refBase = base
+						}
+						isMismatch = false
+						mdSubPos++
+						if mdSubPos == entry.Num {
+							mdIndex++
+							mdSubPos = 0
+						}
+					} else {
+						if len(entry.Changes) == 1 {
+							refBase = entry.Changes[0]
+						}
+						mdIndex++
+						mdSubPos = 0
+					}
+				} else if op.Op == '=' {
+					if !noSeq {
+						refBase = base
+					}
+					isMismatch = false
+				}
+				fn(Column{Op: op.Op, Read: base, Ref: refBase, ReadPos: seqPos, RefSpan: 1, Mismatch: isMismatch})
+				seqPos++
+			}
+		case 'I', 'S':
+			ref := byte('-')
+			if op.Op == 'S' {
+				ref = '.'
+			}
+			for range op.Length {
+				base, err := readBase(op.Op, seqPos)
+				if err != nil {
+					return err
+				}
+				fn(Column{Op: op.Op, Read: base, Ref: ref, ReadPos: seqPos})
+				seqPos++
+			}
+		case 'D':
+			for deleted := 0; deleted < op.Length; {
+				if hasMD {
+					skipEmptyEntries()
+					if mdIndex >= len(entries) {
+						hasMD = false
+					}
+				}
+				if !hasMD {
+					fn(Column{Op: 'D', Read: '-', Ref: 'N', ReadPos: -1, RefSpan: 1})
+					deleted++
+					continue
+				}
+				entry := &entries[mdIndex]
+				if !entry.IsDel {
+					return fmt.Errorf("MD tag indicates match/mismatch (Num: %d, Changes: '%s') during CIGAR D op at MD index %d (MD: %s)", entry.Num, entry.Changes, mdIndex, md)
+				}
+				fn(Column{Op: 'D', Read: '-', Ref: entry.Changes[mdSubPos], ReadPos: -1, RefSpan: 1})
+				deleted++
+				mdSubPos++
+				if mdSubPos == len(entry.Changes) {
+					mdIndex++
+					mdSubPos = 0
+				}
+			}
+		case 'N':
+			fn(Column{Op: 'N', Read: '.', Ref: 'N', ReadPos: -1, RefSpan: op.Length})
+		case 'H':
+		case 'P':
+			for range op.Length {
+				fn(Column{Op: 'P', Read: '*', Ref: '*', ReadPos: -1})
+			}
+		default:
+			return fmt.Errorf("unsupported CIGAR operation: %c", op.Op)
+		}
+	}
+	return nil
+}
+
+// Width returns the number of columns of the alignment of a read with
+// cigar, without aligning it.
+func Width(cigar string) (int, error) {
+	ops, err := ParseCigar(cigar)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing CIGAR '%s': %w", cigar, err)
+	}
+	width := 0
+	for _, op := range ops {
+		switch op.Op {
+		case 'N':
+			width++
+		case 'H':
+		default:
+			width += op.Length
+		}
+	}
+	return width, nil
+}
+
+// Strings returns the read and reference lines of an alignment, with introns
+// written out in full.
+func Strings(columns []Column) (read, ref string) {
+	var readBuilder, refBuilder strings.Builder
+	for _, c := range columns {
+		n := 1
+		if c.Op == 'N' {
+			n = c.RefSpan
+		}
+		readBuilder.WriteString(strings.Repeat(string(c.Read), n))
+		refBuilder.WriteString(strings.Repeat(string(c.Ref), n))
+	}
+	return readBuilder.String(), refBuilder.String()
+}
@@ -0,0 +1,71 @@
+package sam
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlign(t *testing.T) {
+	tests := []struct {
+		name            string
+		seq, cigar, md  string
+		wantRead        string
+		wantRef         string
+		wantMismatchPos []int
+		wantErr         bool
+	}{
+		{"match", "ACGT", "4M", "4", "ACGT", "ACGT", nil, false},
+		{"mismatch", "ACGT", "4M", "1A2", "ACGT", "AAGT", []int{1}, false},
+		{"clip insertion deletion", "TTACGGAATT", "2S3M1I2M1D2M", "3A1^C2", "TTACGGAA-TT", "..ACG-AACTT", []int{6}, false},
+		{"intron", "ACGT", "2M3N2M", "4", "AC...GT", "ACNNNGT", nil, false},
+		{"no MD", "ACGT", "2=2X", "", "ACGT", "ACNN", []int{2, 3}, false},
+		{"hard clip and padding", "ACGT", "2H2M1P2M", "4", "AC*GT", "AC*GT", nil, false},
+		{"deletion without MD", "ACGT", "2M2D2M", "", "AC--GT", "NNNNNN", []int{0, 1, 2, 3}, false},
+		{"no sequence", "*", "2S3M1D2M", "1A1^C2", "?????-??", "..NANCNN", []int{3}, false},
+		{"no sequence without MD", "*", "2=1X", "", "???", "NNN", []int{2}, false},
+		{"sequence too short", "AC", "4M", "", "", "", nil, true},
+		{"MD deletion in match", "ACGT", "4M", "2^A2", "", "", nil, true},
+		{"MD match in deletion", "ACGT", "2M1D2M", "4", "", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, err := Align(tt.seq, tt.cigar, tt.md)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			read, ref := Strings(columns)
+			assert.Equal(t, tt.wantRead, read)
+			assert.Equal(t, tt.wantRef, ref)
+			var mismatches []int
+			for _, c := range columns {
+				if c.Mismatch {
+					mismatches = append(mismatches, c.ReadPos)
+				}
+			}
+			assert.Equal(t, tt.wantMismatchPos, mismatches)
+		})
+	}
+}
+
+func TestAlignIntronColumn(t *testing.T) {
+	columns, err := Align("ACGT", "2M100N2M", "4")
+	assert.NoError(t, err)
+	assert.Len(t, columns, 5)
+	assert.Equal(t, Column{Op: 'N', Read: '.', Ref: 'N', ReadPos: -1, RefSpan: 100}, columns[2])
+}
+
+func TestWidth(t *testing.T) {
+	for cigar, want := range map[string]int{"2S3M1I2M1D2M": 11, "2M3N2M": 5, "2H2M1P2M": 5} {
+		width, err := Width(cigar)
+		assert.NoError(t, err)
+		assert.Equal(t, want, width, cigar)
+		columns, err := Align("ACGTACGTACGT", cigar, "")
+		assert.NoError(t, err)
+		assert.Len(t, columns, want, cigar)
+	}
+	_, err := Width("3Q")
+	assert.Error(t, err)
+}
@@ -0,0 +1,48 @@
+// Package sam parses the CIGAR strings and MD tags of SAM records and
+// reconstructs the pairwise alignment of a read against the reference.
+package sam
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CigarOp is one operation of a CIGAR string, e.g. 10M.
+type CigarOp struct {
+	Length int
+	Op     rune
+}
+
+// ParseCigar splits a CIGAR string into its operations. "*" and "" have none.
+func ParseCigar(cigar string) ([]CigarOp, error) {
+	if cigar == "*" || cigar == "" {
+		return []CigarOp{}, nil
+	}
+	var ops []CigarOp
+	var lengthStr strings.Builder
+	for i, char := range cigar {
+		if char >= '0' && char <= '9' {
+			lengthStr.WriteRune(char)
+		} else if strings.ContainsRune("MIDNSHP=X", char) {
+			if lengthStr.Len() == 0 {
+				return nil, fmt.Errorf("CIGAR operation '%c' at index %d has no preceding length", char, i)
+			}
+			length, err := strconv.Atoi(lengthStr.String())
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIGAR length format '%s' before op '%c': %w", lengthStr.String(), char, err)
+			}
+			if length <= 0 {
+				return nil, fmt.Errorf("invalid non-positive CIGAR length %d for operation '%c'", length, char)
+			}
+			ops = append(ops, CigarOp{Length: length, Op: char})
+			lengthStr.Reset()
+		} else {
+			return nil, fmt.Errorf("invalid character '%c' in CIGAR string at index %d", char, i)
+		}
+	}
+	if lengthStr.Len() > 0 {
+		return nil, fmt.Errorf("CIGAR string ends with an incomplete operation (number '%s' without type)", lengthStr.String())
+	}
+	return ops, nil
+}
@@ -0,0 +1,59 @@
+package sam
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCigar(t *testing.T) {
+	tests := []struct {
+		cigar   string
+		want    []CigarOp
+		wantErr bool
+	}{
+		{"*", []CigarOp{}, false},
+		{"10M", []CigarOp{{10, 'M'}}, false},
+		{"2S3M1I2M1D2M", []CigarOp{{2, 'S'}, {3, 'M'}, {1, 'I'}, {2, 'M'}, {1, 'D'}, {2, 'M'}}, false},
+		{"M", nil, true},
+		{"3Q", nil, true},
+		{"0M", nil, true},
+		{"10", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cigar, func(t *testing.T) {
+			got, err := ParseCigar(tt.cigar)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseMD(t *testing.T) {
+	tests := []struct {
+		md      string
+		want    []MDEntry
+		wantErr bool
+	}{
+		{"10", []MDEntry{{Num: 10}}, false},
+		{"3A1^C2", []MDEntry{{Num: 3}, {Changes: "A"}, {Num: 1}, {Changes: "C", IsDel: true}, {Num: 2}}, false},
+		{"0C34^AC10", []MDEntry{{Num: 0}, {Changes: "C"}, {Num: 34}, {Changes: "AC", IsDel: true}, {Num: 10}}, false},
+		{"5^", nil, true},
+		{"5^3", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.md, func(t *testing.T) {
+			got, err := ParseMD(tt.md)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
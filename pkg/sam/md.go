@@ -0,0 +1,135 @@
+package sam
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MDEntry is one component of an MD tag: a run of matching bases, a
+// mismatching reference base, or the reference bases of a deletion.
+type MDEntry struct {
+	Num     int    // Number of matching bases
+	Changes string // Reference bases for mismatch or deletion
+	IsDel   bool   // True if this entry represents a deletion (^)
+}
+
+// ParseMD breaks down an MD:Z tag string into its components.
+func ParseMD(mdTag string) ([]MDEntry, error) {
+	var entries []MDEntry
+	var numStr strings.Builder
+	var changeStr strings.Builder
+	state := "num"
+
+	if mdTag == "" {
+		return entries, nil
+	}
+
+	addNumEntry := func() error {
+		if numStr.Len() > 0 {
+			num, err := strconv.Atoi(numStr.String())
+			if err != nil {
+				return fmt.Errorf("internal error: invalid number '%s' in MD tag parse", numStr.String())
+			}
+			entries = append(entries, MDEntry{Num: num})
+			numStr.Reset()
+		}
+		return nil
+	}
+
+	addDelEntry := func() error {
+		if changeStr.Len() > 0 {
+			entries = append(entries, MDEntry{Changes: changeStr.String(), IsDel: true})
+			changeStr.Reset()
+			return nil
+		}
+		return fmt.Errorf("empty deletion sequence found after '^' in MD tag")
+	}
+
+	addMismatchEntry := func() error {
+		if changeStr.Len() == 1 {
+			entries = append(entries, MDEntry{Changes: changeStr.String(), IsDel: false, Num: 0})
+			changeStr.Reset()
+			return nil
+		}
+		return fmt.Errorf("invalid mismatch sequence '%s' (must be 1 base) in MD tag", changeStr.String())
+	}
+
+	for i, char := range mdTag {
+		isLastChar := (i == len(mdTag)-1)
+
+		switch state {
+		case "num":
+			if char >= '0' && char <= '9' {
+				numStr.WriteRune(char)
+			} else {
+				if err := addNumEntry(); err != nil {
+					return nil, err
+				}
+				if char == '^' {
+					state = "del_start"
+				} else if (char >= 'A' && char <= 'Z') || (char >= 'a' && char <= 'z') {
+					changeStr.WriteRune(char)
+					state = "change"
+				} else {
+					return nil, fmt.Errorf("unexpected character '%c' after number in MD tag at position %d", char, i)
+				}
+			}
+		case "change":
+			if err := addMismatchEntry(); err != nil {
+				return nil, err
+			}
+			if char >= '0' && char <= '9' {
+				numStr.WriteRune(char)
+				state = "num"
+			} else if char == '^' {
+				state = "del_start"
+			} else {
+				return nil, fmt.Errorf("unexpected character '%c' after mismatch base in MD tag at position %d", char, i)
+			}
+		case "del_start":
+			if (char >= 'A' && char <= 'Z') || (char >= 'a' && char <= 'z') {
+				changeStr.WriteRune(char)
+				state = "del"
+			} else {
+				return nil, fmt.Errorf("expected base after deletion '^' in MD tag, got '%c' at position %d", char, i)
+			}
+		case "del":
+			if (char >= 'A' && char <= 'Z') || (char >= 'a' && char <= 'z') {
+				changeStr.WriteRune(char)
+			} else {
+				if err := addDelEntry(); err != nil {
+					return nil, err
+				}
+				if char >= '0' && char <= '9' {
+					numStr.WriteRune(char)
+					state = "num"
+				} else if char == '^' {
+					state = "del_start"
+				} else {
+					return nil, fmt.Errorf("unexpected character '%c' after deletion sequence in MD tag at position %d", char, i)
+				}
+			}
+		}
+
+		if isLastChar {
+			switch state {
+			case "num":
+				if err := addNumEntry(); err != nil {
+					return nil, err
+				}
+			case "change":
+				if err := addMismatchEntry(); err != nil {
+					return nil, err
+				}
+			case "del":
+				if err := addDelEntry(); err != nil {
+					return nil, err
+				}
+			case "del_start":
+				return nil, fmt.Errorf("MD tag cannot end with deletion start '^'")
+			}
+		}
+	}
+	return entries, nil
+}